@@ -0,0 +1,149 @@
+// Package kubernetes contains a minimal in-cluster client of the Kubernetes API, used to read PKCS#12 keystores from
+// secrets
+package kubernetes
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	envServiceHost = "KUBERNETES_SERVICE_HOST"
+	envServicePort = "KUBERNETES_SERVICE_PORT"
+
+	// serviceAccountDir holds the credentials mounted in every pod for its service account
+	serviceAccountDir   = "/var/run/secrets/kubernetes.io/serviceaccount"
+	serviceAccountToken = serviceAccountDir + "/token"
+	serviceAccountCA    = serviceAccountDir + "/ca.crt"
+	serviceAccountNS    = serviceAccountDir + "/namespace"
+
+	// KeyKeystore and KeyPassword are the keys of the PKCS#12 keystore and of its password in the secret
+	KeyKeystore = "keystore.p12"
+	KeyPassword = "password"
+
+	requestTimeout     = 30 * time.Second
+	msgKubernetesError = "kubernetes client error"
+)
+
+// ErrNotInCluster is returned when the provider does not run in a Kubernetes pod
+var ErrNotInCluster = errors.New("not running in a Kubernetes cluster")
+
+type Client struct {
+	address    string
+	token      string
+	namespace  string
+	httpClient *http.Client
+}
+
+// Keystore is the content of the secret holding a PKCS#12 keystore
+type Keystore struct {
+	Data     []byte
+	Password string
+}
+
+// NewInCluster builds a client out of the service account of the pod the provider runs in
+func NewInCluster() (*Client, error) {
+	host, port := os.Getenv(envServiceHost), os.Getenv(envServicePort)
+	if host == "" || port == "" {
+		return nil, fmt.Errorf("%s: %w, %s and %s are not set", msgKubernetesError, ErrNotInCluster, envServiceHost, envServicePort)
+	}
+	token, err := os.ReadFile(serviceAccountToken)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w, unable to read the service account token: %w", msgKubernetesError, ErrNotInCluster, err)
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+	caCert, err := os.ReadFile(serviceAccountCA)
+	if err != nil {
+		return nil, fmt.Errorf("%s: unable to read the cluster CA certificate: %w", msgKubernetesError, err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caCert) {
+		return nil, fmt.Errorf("%s: no certificate found in [%s]", msgKubernetesError, serviceAccountCA)
+	}
+	tlsConfig.RootCAs = pool
+
+	// The namespace of the pod, used when the secret namespace is not set
+	namespace, _ := os.ReadFile(serviceAccountNS)
+
+	return &Client{
+		address:   "https://" + net.JoinHostPort(host, port),
+		token:     strings.TrimSpace(string(token)),
+		namespace: strings.TrimSpace(string(namespace)),
+		httpClient: &http.Client{
+			Timeout: requestTimeout,
+			Transport: &http.Transport{
+				TLSClientConfig: tlsConfig,
+			},
+		},
+	}, nil
+}
+
+// ReadKeystore reads the PKCS#12 keystore, and its password, stored in the secret name of namespace. The namespace of
+// the pod is used when namespace is empty.
+func (c *Client) ReadKeystore(ctx context.Context, namespace string, name string) (*Keystore, error) {
+	if namespace == "" {
+		namespace = c.namespace
+	}
+	if namespace == "" {
+		return nil, fmt.Errorf("%s: no namespace set for secret [%s] and the namespace of the pod is unknown", msgKubernetesError, name)
+	}
+
+	location := fmt.Sprintf("%s/api/v1/namespaces/%s/secrets/%s", c.address, url.PathEscape(namespace), url.PathEscape(name))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", msgKubernetesError, err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", msgKubernetesError, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError(resp, namespace, name)
+	}
+
+	// The values of the secret data are base64-encoded, which encoding/json decodes into byte slices
+	var secret struct {
+		Data map[string][]byte `json:"data"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&secret); err != nil {
+		return nil, fmt.Errorf("%s: unable to decode secret [%s/%s]: %w", msgKubernetesError, namespace, name, err)
+	}
+	keystore, ok := secret.Data[KeyKeystore]
+	if !ok || len(keystore) == 0 {
+		return nil, fmt.Errorf("%s: secret [%s/%s] has no %s key", msgKubernetesError, namespace, name, KeyKeystore)
+	}
+
+	return &Keystore{
+		Data:     keystore,
+		Password: strings.TrimSpace(string(secret.Data[KeyPassword])),
+	}, nil
+}
+
+// statusError reports an unexpected status code. The response body is a Kubernetes Status, never the secret itself.
+func statusError(resp *http.Response, namespace string, name string) error {
+	var details struct {
+		Message string `json:"message"`
+	}
+	_ = json.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&details)
+	return fmt.Errorf("%s: unable to read secret [%s/%s]: status %d %s", msgKubernetesError, namespace, name, resp.StatusCode,
+		details.Message)
+}
@@ -0,0 +1,105 @@
+package kubernetes
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+// fakeAPIServer serves the secrets of the Kubernetes API, keyed by namespace/name, to the bearer token
+func fakeAPIServer(t *testing.T, token string, secrets map[string]map[string][]byte) *Client {
+	t.Helper()
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Header.Get("Authorization") != "Bearer "+token {
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string]string{"kind": "Status", "message": "secrets is forbidden"})
+			return
+		}
+		key, found := strings.CutPrefix(r.URL.Path, "/api/v1/namespaces/")
+		key = strings.Replace(key, "/secrets/", "/", 1)
+		data, ok := secrets[key]
+		if !found || !ok {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"kind": "Status", "message": "secrets not found"})
+			return
+		}
+		// The values are base64-encoded, as by the Kubernetes API
+		json.NewEncoder(w).Encode(map[string]interface{}{"kind": "Secret", "data": data})
+	}))
+	t.Cleanup(server.Close)
+
+	return &Client{address: server.URL, token: token, namespace: "pod-namespace", httpClient: server.Client()}
+}
+
+func TestReadKeystore(t *testing.T) {
+	keystore := []byte{0x30, 0x82, 0x01, 0x00, 0xff}
+	client := fakeAPIServer(t, "sa-token", map[string]map[string][]byte{
+		"venafi/client-keystore":        {KeyKeystore: keystore, KeyPassword: []byte("secret\n")},
+		"pod-namespace/client-keystore": {KeyKeystore: keystore},
+		"venafi/no-keystore":            {KeyPassword: []byte("secret")},
+	})
+
+	tests := []struct {
+		name         string
+		client       *Client
+		namespace    string
+		secret       string
+		wantPassword string
+		wantErr      string
+	}{
+		{"found", client, "venafi", "client-keystore", "secret", ""},
+		{"namespace of the pod", client, "", "client-keystore", "", ""},
+		{"no keystore key", client, "venafi", "no-keystore", "", "secret [venafi/no-keystore] has no keystore.p12 key"},
+		{"not found", client, "venafi", "other", "", "status 404 secrets not found"},
+		{"forbidden", &Client{address: client.address, token: "other-token", httpClient: client.httpClient}, "venafi", "client-keystore", "",
+			"status 403 secrets is forbidden"},
+		{"namespace unknown", &Client{address: client.address, token: "sa-token", httpClient: client.httpClient}, "", "client-keystore", "",
+			"the namespace of the pod is unknown"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := test.client.ReadKeystore(context.Background(), test.namespace, test.secret)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Errorf("error = %v, want %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !bytes.Equal(got.Data, keystore) || got.Password != test.wantPassword {
+				t.Errorf("keystore = %x with password %q, want %x with %q", got.Data, got.Password, keystore, test.wantPassword)
+			}
+		})
+	}
+}
+
+func TestNewInClusterOutsideCluster(t *testing.T) {
+	t.Run("no service", func(t *testing.T) {
+		t.Setenv(envServiceHost, "")
+		t.Setenv(envServicePort, "")
+		if _, err := NewInCluster(); !errors.Is(err, ErrNotInCluster) {
+			t.Errorf("error = %v, want %v", err, ErrNotInCluster)
+		}
+	})
+
+	t.Run("no service account", func(t *testing.T) {
+		if _, err := os.Stat(serviceAccountToken); err == nil {
+			t.Skip("running in a Kubernetes pod")
+		}
+		t.Setenv(envServiceHost, "10.0.0.1")
+		t.Setenv(envServicePort, "443")
+		if _, err := NewInCluster(); !errors.Is(err, ErrNotInCluster) {
+			t.Errorf("error = %v, want %v", err, ErrNotInCluster)
+		}
+	})
+}
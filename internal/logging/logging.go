@@ -0,0 +1,124 @@
+// Package logging wraps tflog so that the resource log_level attribute can restrict what the provider emits,
+// regardless of TF_LOG
+package logging
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Level is the verbosity of the provider logs
+type Level int
+
+const (
+	// LevelNone emits nothing
+	LevelNone Level = iota
+	// LevelSummary emits the outcome of the operations (info, warning and error messages), without token metadata
+	LevelSummary
+	// LevelDebug emits everything, including attribute values and token metadata
+	LevelDebug
+)
+
+const (
+	nameNone    = "none"
+	nameSummary = "summary"
+	nameDebug   = "debug"
+
+	// DefaultLevel is used when no level was set in the context
+	DefaultLevel = LevelSummary
+)
+
+type contextKey struct{}
+
+// Names returns the accepted level names
+func Names() []string {
+	return []string{nameNone, nameSummary, nameDebug}
+}
+
+func (l Level) String() string {
+	switch l {
+	case LevelNone:
+		return nameNone
+	case LevelSummary:
+		return nameSummary
+	default:
+		return nameDebug
+	}
+}
+
+// ParseLevel returns the level named name
+func ParseLevel(name string) (Level, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case nameNone:
+		return LevelNone, nil
+	case nameSummary:
+		return LevelSummary, nil
+	case nameDebug:
+		return LevelDebug, nil
+	}
+	return DefaultLevel, fmt.Errorf("invalid log level [%s], must be one of: %s", name, strings.Join(Names(), ", "))
+}
+
+// WithLevel returns a copy of ctx in which the messages are filtered with level
+func WithLevel(ctx context.Context, level Level) context.Context {
+	return context.WithValue(ctx, contextKey{}, level)
+}
+
+// MaskValues returns a copy of ctx in which the given values are replaced with asterisks in the messages and fields
+// logged by the provider. Empty values are ignored.
+func MaskValues(ctx context.Context, values ...string) context.Context {
+	var masked []string
+	for _, value := range values {
+		if value != "" {
+			masked = append(masked, value)
+		}
+	}
+	if len(masked) == 0 {
+		return ctx
+	}
+
+	ctx = tflog.MaskMessageStrings(ctx, masked...)
+	return tflog.MaskAllFieldValuesStrings(ctx, masked...)
+}
+
+// LevelFrom returns the level set in ctx, or DefaultLevel
+func LevelFrom(ctx context.Context) Level {
+	level, ok := ctx.Value(contextKey{}).(Level)
+	if !ok {
+		return DefaultLevel
+	}
+	return level
+}
+
+func Trace(ctx context.Context, msg string, additionalFields ...map[string]interface{}) {
+	if LevelFrom(ctx) >= LevelDebug {
+		tflog.Trace(ctx, msg, additionalFields...)
+	}
+}
+
+func Debug(ctx context.Context, msg string, additionalFields ...map[string]interface{}) {
+	if LevelFrom(ctx) >= LevelDebug {
+		tflog.Debug(ctx, msg, additionalFields...)
+	}
+}
+
+func Info(ctx context.Context, msg string, additionalFields ...map[string]interface{}) {
+	if LevelFrom(ctx) >= LevelSummary {
+		tflog.Info(ctx, msg, additionalFields...)
+	}
+}
+
+func Warn(ctx context.Context, msg string, additionalFields ...map[string]interface{}) {
+	if LevelFrom(ctx) >= LevelSummary {
+		tflog.Warn(ctx, msg, additionalFields...)
+	}
+}
+
+func Error(ctx context.Context, msg string, additionalFields ...map[string]interface{}) {
+	if LevelFrom(ctx) >= LevelSummary {
+		tflog.Error(ctx, msg, additionalFields...)
+	}
+}
@@ -0,0 +1,77 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-log/tflogtest"
+)
+
+// logAll emits a message at every level of tflog and returns the output
+func logAll(ctx context.Context) string {
+	var output bytes.Buffer
+	ctx = tflogtest.RootLogger(ctx, &output)
+	Trace(ctx, "trace message")
+	Debug(ctx, "debug message")
+	Info(ctx, "info message")
+	Warn(ctx, "warn message")
+	Error(ctx, "error message")
+	return output.String()
+}
+
+func TestLevels(t *testing.T) {
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		want       []string
+		suppressed []string
+	}{
+		{"none", WithLevel(context.Background(), LevelNone), nil, []string{"trace", "debug", "info", "warn", "error"}},
+		{"summary", WithLevel(context.Background(), LevelSummary), []string{"info", "warn", "error"}, []string{"trace", "debug"}},
+		{"debug", WithLevel(context.Background(), LevelDebug), []string{"trace", "debug", "info", "warn", "error"}, nil},
+		{"default", context.Background(), []string{"info", "warn", "error"}, []string{"trace", "debug"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			output := logAll(test.ctx)
+			for _, level := range test.want {
+				if !strings.Contains(output, level+" message") {
+					t.Errorf("%s message not emitted", level)
+				}
+			}
+			for _, level := range test.suppressed {
+				if strings.Contains(output, level+" message") {
+					t.Errorf("%s message emitted", level)
+				}
+			}
+			if test.name == "none" && output != "" {
+				t.Errorf("output = %q, want nothing", output)
+			}
+		})
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for _, name := range Names() {
+		level, err := ParseLevel(" " + strings.ToUpper(name) + " ")
+		if err != nil || level.String() != name {
+			t.Errorf("%s parsed as %s, %v", name, level, err)
+		}
+	}
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Error("invalid level accepted")
+	}
+}
+
+func TestMaskValues(t *testing.T) {
+	var output bytes.Buffer
+	ctx := tflogtest.RootLogger(context.Background(), &output)
+	ctx = MaskValues(ctx, "s3cr3t", "")
+	Info(ctx, "password is s3cr3t", map[string]interface{}{"password": "s3cr3t"})
+
+	if strings.Contains(output.String(), "s3cr3t") || !strings.Contains(output.String(), "password is ***") {
+		t.Errorf("output = %q, want the value masked", output.String())
+	}
+}
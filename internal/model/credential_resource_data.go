@@ -5,15 +5,102 @@ import "github.com/hashicorp/terraform-plugin-framework/types"
 
 // CredentialResourceData represents a credential resource
 type CredentialResourceData struct {
-	URL            types.String `tfsdk:"url"`
-	Username       types.String `tfsdk:"username"`
-	Password       types.String `tfsdk:"password"`
-	P12Certificate types.String `tfsdk:"p12_cert_filename"`
-	P12Password    types.String `tfsdk:"p12_cert_password"`
-	AccessToken    types.String `tfsdk:"access_token"`
-	RefreshToken   types.String `tfsdk:"refresh_token"`
-	ClientID       types.String `tfsdk:"client_id"`
-	ExpirationDate types.Int64  `tfsdk:"expiration"`
-	TrustBundle    types.String `tfsdk:"trust_bundle"`
-	RefreshWindow  types.Int64  `tfsdk:"refresh_window"`
+	URL                    types.String `tfsdk:"url"`
+	Username               types.String `tfsdk:"username"`
+	Password               types.String `tfsdk:"password"`
+	P12Certificate         types.String `tfsdk:"p12_cert_filename"`
+	P12Password            types.String `tfsdk:"p12_cert_password"`
+	AccessToken            types.String `tfsdk:"access_token"`
+	RefreshToken           types.String `tfsdk:"refresh_token"`
+	ClientID               types.String `tfsdk:"client_id"`
+	ExpirationDate         types.Int64  `tfsdk:"expiration"`
+	TrustBundle            types.String `tfsdk:"trust_bundle"`
+	RefreshWindow          types.Int64  `tfsdk:"refresh_window"`
+	Rotated                types.Bool   `tfsdk:"rotated_on_last_apply"`
+	IssuedAt               types.Int64  `tfsdk:"issued_at"`
+	RefreshWindowPercent   types.Int64  `tfsdk:"refresh_window_percent"`
+	P12PasswordFromSidecar types.Bool   `tfsdk:"p12_password_from_sidecar"`
+	FallbackURL            types.String `tfsdk:"fallback_url"`
+	ActiveURL              types.String `tfsdk:"active_url"`
+	GrantID                types.String `tfsdk:"grant_id"`
+	TLSHandshakeTimeout    types.Int64  `tfsdk:"tls_handshake_timeout_seconds"`
+	ClientCertExpiration   types.Int64  `tfsdk:"client_cert_expiration"`
+	ValidateOnly           types.Bool   `tfsdk:"validate_only"`
+	Scope                  types.String `tfsdk:"scope"`
+	GrantedScopes          types.List   `tfsdk:"granted_scopes"`
+	ScopeSatisfied         types.Bool   `tfsdk:"scope_satisfied"`
+	RotateTrigger          types.String `tfsdk:"rotate_trigger"`
+	VCertVersion           types.String `tfsdk:"vcert_version"`
+	TPPVersion             types.String `tfsdk:"tpp_version"`
+	MaxTotalAttempts       types.Int64  `tfsdk:"max_total_attempts"`
+	VaultTokenPath         types.String `tfsdk:"vault_token_path"`
+	VaultWriteBack         types.Bool   `tfsdk:"vault_write_back"`
+	LogLevel               types.String `tfsdk:"log_level"`
+	OfflineAccess          types.Bool   `tfsdk:"offline_access"`
+	RefreshUntil           types.Int64  `tfsdk:"refresh_until"`
+	LastRefreshWarning     types.String `tfsdk:"last_refresh_warning"`
+	OAuthPathOverride      types.String `tfsdk:"oauth_path_override"`
+	DotenvOutputFile       types.String `tfsdk:"dotenv_output_file"`
+	DotenvIncludeURL       types.Bool   `tfsdk:"dotenv_include_url"`
+	DotenvRefreshToken     types.Bool   `tfsdk:"dotenv_include_refresh_token"`
+	MinGrantedLifetime     types.Int64  `tfsdk:"min_granted_lifetime_seconds"`
+	TokenStatus            types.Object `tfsdk:"token_status"`
+	P12PasswordCommand     types.String `tfsdk:"p12_password_command"`
+	PrunePreviousGrants    types.Bool   `tfsdk:"prune_previous_grants"`
+	TrustBundleSystemName  types.String `tfsdk:"trust_bundle_system_name"`
+	AuthAttempts           types.List   `tfsdk:"auth_attempts"`
+	MaxResponseBytes       types.Int64  `tfsdk:"max_response_bytes"`
+	StagedRotation         types.Bool   `tfsdk:"staged_rotation"`
+	CommitRotation         types.Bool   `tfsdk:"commit_rotation"`
+	PendingAccessToken     types.String `tfsdk:"pending_access_token"`
+	PendingRefreshToken    types.String `tfsdk:"pending_refresh_token"`
+	PendingExpiration      types.Int64  `tfsdk:"pending_expiration"`
+	PendingIssuedAt        types.Int64  `tfsdk:"pending_issued_at"`
+	TraceFile              types.String `tfsdk:"trace_file"`
+	ApplyMarginSeconds     types.Int64  `tfsdk:"apply_margin_seconds"`
+	ExpectedServerSANs     types.List   `tfsdk:"expected_server_sans"`
+	RotationCount          types.Int64  `tfsdk:"rotation_count"`
+	MaxIdleConns           types.Int64  `tfsdk:"max_idle_conns"`
+	IdleConnTimeout        types.Int64  `tfsdk:"idle_conn_timeout_seconds"`
+	TokenCacheFile         types.String `tfsdk:"token_cache_file"`
+	ExpirationFormat       types.String `tfsdk:"expiration_format"`
+	ExpirationFormatted    types.String `tfsdk:"expiration_formatted"`
+	NeverExpires           types.Bool   `tfsdk:"never_expires"`
+	RecoveryFile           types.String `tfsdk:"recovery_file"`
+	RotationPolicy         types.String `tfsdk:"rotation_policy"`
+	TokenIdentity          types.String `tfsdk:"token_identity"`
+	RequireClientCertTLS   types.Bool   `tfsdk:"require_client_cert_tls"`
+	EffectiveRefreshWindow types.Int64  `tfsdk:"effective_refresh_window_seconds"`
+	P12CertFilenames       types.List   `tfsdk:"p12_cert_filenames"`
+	ClientCertIssuer       types.String `tfsdk:"client_cert_issuer"`
+	MinRemainingForOp      types.Int64  `tfsdk:"min_remaining_for_operation_seconds"`
+	MetadataJSON           types.String `tfsdk:"metadata_json"`
+	VerifyRevocation       types.Bool   `tfsdk:"verify_revocation"`
+	BackgroundRefresh      types.Bool   `tfsdk:"background_refresh"`
+	K8sSecretName          types.String `tfsdk:"k8s_secret_name"`
+	K8sSecretNamespace     types.String `tfsdk:"k8s_secret_namespace"`
+	SelectedAuthMethod     types.String `tfsdk:"selected_auth_method"`
+	UsedFallbackMethod     types.Bool   `tfsdk:"used_fallback_method"`
+	TrustBundleSystemRoots types.Bool   `tfsdk:"trust_bundle_append_system_roots"`
+	InsecureSkipVerify     types.Bool   `tfsdk:"insecure_skip_verify"`
+	RotationSchedule       types.String `tfsdk:"rotation_schedule"`
+	ConfigSource           types.Map    `tfsdk:"config_source"`
+	BlueGreenRotation      types.Bool   `tfsdk:"blue_green_rotation"`
+	BlueGreenGraceSeconds  types.Int64  `tfsdk:"blue_green_grace_seconds"`
+	NextAccessToken        types.String `tfsdk:"next_access_token"`
+	NextRefreshToken       types.String `tfsdk:"next_refresh_token"`
+	NextExpiration         types.Int64  `tfsdk:"next_expiration"`
+	NextIssuedAt           types.Int64  `tfsdk:"next_issued_at"`
+	SupersededAccessToken  types.String `tfsdk:"superseded_access_token"`
+	SupersededRevokeAt     types.Int64  `tfsdk:"superseded_revoke_at"`
+	RefreshFailurePolicy   types.String `tfsdk:"refresh_failure_policy"`
+	RefreshDueAt           types.Int64  `tfsdk:"refresh_due_at"`
+	PlanCheckStrategy      types.String `tfsdk:"plan_check_strategy"`
+	ProxyTrustBundle       types.String `tfsdk:"proxy_trust_bundle"`
+	VerifyMaxRetries       types.Int64  `tfsdk:"verify_max_retries"`
+	RotateMaxRetries       types.Int64  `tfsdk:"rotate_max_retries"`
+	EffectiveURL           types.String `tfsdk:"effective_url"`
+	ExpectedTokenType      types.String `tfsdk:"expected_token_type"`
+	TokenType              types.String `tfsdk:"token_type"`
+	ResourceIdentity       types.String `tfsdk:"resource_identity"`
 }
@@ -0,0 +1,23 @@
+package model
+
+import (
+	"crypto/cipher"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// ProviderData represents the provider configuration, shared with the resources as defaults
+type ProviderData struct {
+	URL                   types.String `tfsdk:"url"`
+	TrustBundle           types.String `tfsdk:"trust_bundle"`
+	ClientID              types.String `tfsdk:"client_id"`
+	MaxConcurrentRequests types.Int64  `tfsdk:"max_concurrent_requests"`
+	StrictSensitive       types.Bool   `tfsdk:"strict_sensitive"`
+	StateEncryptionKey    types.String `tfsdk:"state_encryption_key"`
+
+	// Sources records whether the url, trust_bundle and client_id defaults come from the provider configuration or
+	// from its environment variables
+	Sources map[string]string `tfsdk:"-"`
+	// StateCipher encrypts the tokens saved to the state, nil when no state_encryption_key is set
+	StateCipher cipher.AEAD `tfsdk:"-"`
+}
@@ -0,0 +1,152 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/logging"
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/model"
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/vcertclient"
+)
+
+// defaultBlueGreenGraceSeconds is how long consumers have to move to the next token pair, and then to stop using the
+// superseded one, when blue_green_grace_seconds is not set
+const defaultBlueGreenGraceSeconds = 3600
+
+// errBlueGreenNoPrimary is returned when the next token pair cannot be provisioned as a grant of its own
+var errBlueGreenNoPrimary = errors.New("blue_green_rotation requires a client certificate or username/password: refreshing the token pair would not create a second grant")
+
+func blueGreenGrace(data *model.CredentialResourceData) time.Duration {
+	if data.BlueGreenGraceSeconds.IsNull() || data.BlueGreenGraceSeconds.IsUnknown() {
+		return defaultBlueGreenGraceSeconds * time.Second
+	}
+	return time.Duration(data.BlueGreenGraceSeconds.ValueInt64()) * time.Second
+}
+
+// blueGreenRotation rotates the token pair of data in two steps. The next token pair is provisioned first, as a second
+// grant, and exposed in the next_* attributes. It is promoted to the active one once consumers had the grace period to
+// move to it, or right away when the active access token expired. The superseded grant is revoked a grace period later.
+func blueGreenRotation(ctx context.Context, data *model.CredentialResourceData, expired bool, now time.Time, diags *diag.Diagnostics) error {
+	if data.NextAccessToken.IsNull() {
+		if err := provisionNextPair(ctx, data, now); err != nil {
+			return err
+		}
+		if !expired {
+			return nil
+		}
+	}
+
+	promoteAt := time.Unix(data.NextIssuedAt.ValueInt64(), 0).Add(blueGreenGrace(data))
+	if !expired && now.Before(promoteAt) {
+		logging.Info(ctx, fmt.Sprintf("next token pair provisioned, promoting it after %s", promoteAt.UTC().Format(time.RFC3339)))
+		return nil
+	}
+	promoteNextPair(ctx, data, now, diags)
+	return nil
+}
+
+// provisionNextPair requests the next token pair with the primary credential of data, leaving the active one untouched
+func provisionNextPair(ctx context.Context, data *model.CredentialResourceData, now time.Time) error {
+	if !hasKeystore(data) && (isBlank(data.Username) || isBlank(data.Password)) {
+		return fmt.Errorf("%s: %w", msgCredentialResourceError, errBlueGreenNoPrimary)
+	}
+
+	logging.Info(ctx, "provisioning the next token pair")
+	request := *data
+	request.RefreshToken = types.StringNull()
+	client := vcertclient.New(ctx, request)
+	clientResp, err := client.RequestNewTokenPair()
+	if err != nil {
+		return err
+	}
+	if err = normalizeTokenPair(clientResp); err != nil {
+		return err
+	}
+	if err = checkTokenType(ctx, data, clientResp); err != nil {
+		return err
+	}
+
+	data.NextAccessToken = types.StringValue(clientResp.AccessToken)
+	data.NextRefreshToken = stringOrNull(clientResp.RefreshToken)
+	data.NextExpiration = types.Int64Value(clientResp.Expires)
+	data.NextIssuedAt = types.Int64Value(now.Unix())
+	setAuthAttempts(data, client.AuthAttempts())
+	setSelectedAuthMethod(data, clientResp)
+	data.LastRefreshWarning = stringOrNull(clientResp.Warning)
+	return nil
+}
+
+// promoteNextPair makes the next token pair the active one. The active pair is superseded, and revoked once the grace
+// period ends. A previously superseded grant still waiting for its revocation is revoked right away.
+func promoteNextPair(ctx context.Context, data *model.CredentialResourceData, now time.Time, diags *diag.Diagnostics) {
+	logging.Info(ctx, "promoting the next token pair")
+	if !data.SupersededAccessToken.IsNull() {
+		revokeSupersededGrant(ctx, data, diags)
+	}
+
+	data.SupersededAccessToken = data.AccessToken
+	data.SupersededRevokeAt = types.Int64Value(now.Add(blueGreenGrace(data)).Unix())
+	data.AccessToken = data.NextAccessToken
+	data.RefreshToken = data.NextRefreshToken
+	data.ExpirationDate = data.NextExpiration
+	data.IssuedAt = data.NextIssuedAt
+	data.GrantID = types.StringNull()
+	data.TokenIdentity = types.StringNull()
+	// Now the active pair, it is only forgotten as the next one
+	discardNextPair(ctx, data, diags)
+	data.Rotated = types.BoolValue(true)
+	data.RotationCount = types.Int64Value(data.RotationCount.ValueInt64() + 1)
+}
+
+// revokeDueSupersededGrant revokes the grant superseded by the last promotion once its grace period ended
+func revokeDueSupersededGrant(ctx context.Context, data *model.CredentialResourceData, now time.Time, diags *diag.Diagnostics) {
+	if data.SupersededAccessToken.IsNull() || now.Unix() < data.SupersededRevokeAt.ValueInt64() {
+		return
+	}
+	revokeSupersededGrant(ctx, data, diags)
+}
+
+// revokeSupersededGrant revokes the grant superseded by the last promotion. It is forgotten even when the revocation
+// fails, since it cannot be used to get tokens once its access token expired anyway.
+func revokeSupersededGrant(ctx context.Context, data *model.CredentialResourceData, diags *diag.Diagnostics) {
+	logging.Info(ctx, "revoking the superseded grant")
+	superseded := *data
+	superseded.AccessToken = data.SupersededAccessToken
+	// The refresh token of data belongs to the active grant, it must not be used to revoke the superseded one
+	superseded.RefreshToken = types.StringNull()
+	if err := vcertclient.New(ctx, superseded).RevokeToken(); err != nil {
+		diags.AddAttributeWarning(path.Root(fSupersededAccessToken), msgCredentialResourceError,
+			fmt.Sprintf("unable to revoke the superseded grant, it remains valid until its access token expires: %s", err.Error()))
+	}
+	data.SupersededAccessToken = types.StringNull()
+	data.SupersededRevokeAt = types.Int64Null()
+}
+
+// discardNextPair revokes the next token pair, e.g. when a forced rotation replaces the active pair, and forgets it. A
+// next pair promoted to the active one is forgotten without being revoked.
+func discardNextPair(ctx context.Context, data *model.CredentialResourceData, diags *diag.Diagnostics) {
+	if data.NextAccessToken.IsNull() {
+		return
+	}
+	if !data.NextAccessToken.Equal(data.AccessToken) {
+		logging.Info(ctx, "revoking the next token pair")
+		next := *data
+		next.AccessToken = data.NextAccessToken
+		next.RefreshToken = data.NextRefreshToken
+		next.ExpirationDate = data.NextExpiration
+		if err := vcertclient.New(ctx, next).RevokeToken(); err != nil {
+			diags.AddAttributeWarning(path.Root(fNextAccessToken), msgCredentialResourceError,
+				fmt.Sprintf("unable to revoke the discarded next token pair, its grant remains valid until it expires: %s", err.Error()))
+		}
+	}
+	data.NextAccessToken = types.StringNull()
+	data.NextRefreshToken = types.StringNull()
+	data.NextExpiration = types.Int64Null()
+	data.NextIssuedAt = types.Int64Null()
+}
@@ -0,0 +1,179 @@
+package provider
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/model"
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/tpptest"
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/vcertclient"
+)
+
+// blueGreenCredential returns a credential of server holding an active token pair, with blue_green_rotation set
+func blueGreenCredential(t *testing.T, server *tpptest.Server) model.CredentialResourceData {
+	t.Helper()
+
+	data := serverCredential(server)
+	var diags diag.Diagnostics
+	if err := rotateToken(context.Background(), &data, &diags); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	data.BlueGreenRotation = types.BoolValue(true)
+	data.BlueGreenGraceSeconds = types.Int64Value(3600)
+	return data
+}
+
+// revoked reports whether the grant of token was revoked by server, failing the test when the token is unknown to it
+func revoked(t *testing.T, server *tpptest.Server, token types.String) bool {
+	t.Helper()
+
+	grant, ok := server.GrantOf(token.ValueString())
+	if !ok {
+		t.Fatalf("token %s not issued by TLSPDC", token)
+	}
+	return grant.Revoked
+}
+
+func TestBlueGreenPromoteAndRevoke(t *testing.T) {
+	server := tpptest.NewServer(t)
+	data := blueGreenCredential(t, server)
+	active := data.AccessToken
+	ctx := context.Background()
+	var diags diag.Diagnostics
+	now := time.Now()
+
+	// The next token pair is provisioned as a grant of its own, the active one is kept
+	if err := blueGreenRotation(ctx, &data, false, now, &diags); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	next := data.NextAccessToken
+	if next.IsNull() || !data.AccessToken.Equal(active) {
+		t.Fatalf("access_token = %s and next_access_token = %s, want the next pair provisioned next to the active one", data.AccessToken, next)
+	}
+	activeGrant, _ := server.GrantOf(active.ValueString())
+	if nextGrant, _ := server.GrantOf(next.ValueString()); nextGrant.ID == activeGrant.ID {
+		t.Error("next token pair issued from the grant of the active one")
+	}
+
+	// Consumers have the grace period to move to the next pair
+	if err := blueGreenRotation(ctx, &data, false, now.Add(30*time.Minute), &diags); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !data.AccessToken.Equal(active) || !data.NextAccessToken.Equal(next) {
+		t.Fatal("next token pair promoted before the grace period ended")
+	}
+
+	// Promoted once the grace period ended, the active pair being superseded
+	promotedAt := now.Add(61 * time.Minute)
+	if err := blueGreenRotation(ctx, &data, false, promotedAt, &diags); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !data.AccessToken.Equal(next) || !data.NextAccessToken.IsNull() || !data.NextRefreshToken.IsNull() || !data.NextIssuedAt.IsNull() {
+		t.Fatalf("access_token = %s and next_access_token = %s, want the next pair promoted", data.AccessToken, data.NextAccessToken)
+	}
+	if !data.SupersededAccessToken.Equal(active) || data.SupersededRevokeAt.ValueInt64() != promotedAt.Add(time.Hour).Unix() {
+		t.Errorf("superseded_access_token = %s revoked at %s, want the previous pair revoked a grace period later", data.SupersededAccessToken,
+			data.SupersededRevokeAt)
+	}
+	if revoked(t, server, next) || revoked(t, server, active) {
+		t.Fatal("grant revoked by the promotion")
+	}
+	if !data.Rotated.ValueBool() {
+		t.Error("rotated_on_last_apply not set by the promotion")
+	}
+
+	// The superseded grant is revoked once its grace period ended, never the promoted one
+	revokeDueSupersededGrant(ctx, &data, promotedAt.Add(30*time.Minute), &diags)
+	if revoked(t, server, active) || data.SupersededAccessToken.IsNull() {
+		t.Fatal("superseded grant revoked before the grace period ended")
+	}
+	revokeDueSupersededGrant(ctx, &data, promotedAt.Add(time.Hour), &diags)
+	if !revoked(t, server, active) || !data.SupersededAccessToken.IsNull() || !data.SupersededRevokeAt.IsNull() {
+		t.Error("superseded grant not revoked once the grace period ended")
+	}
+	if revoked(t, server, next) {
+		t.Error("promoted grant revoked")
+	}
+	if diags.HasError() || diags.WarningsCount() != 0 {
+		t.Errorf("unexpected diagnostics: %v", diags)
+	}
+}
+
+func TestBlueGreenRotation(t *testing.T) {
+	t.Run("expired", func(t *testing.T) {
+		// The next token pair is promoted right away
+		server := tpptest.NewServer(t)
+		data := blueGreenCredential(t, server)
+		active := data.AccessToken
+		var diags diag.Diagnostics
+
+		if err := blueGreenRotation(context.Background(), &data, true, time.Now(), &diags); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if data.AccessToken.Equal(active) || !data.NextAccessToken.IsNull() || !data.SupersededAccessToken.Equal(active) {
+			t.Errorf("access_token = %s, superseded_access_token = %s, want a new pair promoted", data.AccessToken, data.SupersededAccessToken)
+		}
+	})
+
+	t.Run("pending superseded grant", func(t *testing.T) {
+		// Revoked right away by the next promotion
+		server := tpptest.NewServer(t)
+		data := blueGreenCredential(t, server)
+		pending := server.IssueGrant(vcertclient.DefaultScope)
+		data.SupersededAccessToken = types.StringValue(pending.AccessToken)
+		data.SupersededRevokeAt = types.Int64Value(time.Now().Add(time.Hour).Unix())
+		active := data.AccessToken
+		var diags diag.Diagnostics
+
+		if err := blueGreenRotation(context.Background(), &data, true, time.Now(), &diags); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if !revoked(t, server, types.StringValue(pending.AccessToken)) {
+			t.Error("pending superseded grant not revoked")
+		}
+		if !data.SupersededAccessToken.Equal(active) || revoked(t, server, active) {
+			t.Errorf("superseded_access_token = %s, want the previous active pair, not revoked yet", data.SupersededAccessToken)
+		}
+	})
+
+	t.Run("no primary credential", func(t *testing.T) {
+		server := tpptest.NewServer(t)
+		data := blueGreenCredential(t, server)
+		data.Username = types.StringNull()
+		data.Password = types.StringNull()
+		grants := len(server.Grants())
+		var diags diag.Diagnostics
+
+		if err := blueGreenRotation(context.Background(), &data, false, time.Now(), &diags); err == nil {
+			t.Fatal("next token pair provisioned with the refresh token")
+		}
+		if len(server.Grants()) != grants || !data.NextAccessToken.IsNull() {
+			t.Error("next token pair provisioned")
+		}
+	})
+}
+
+func TestDiscardNextPair(t *testing.T) {
+	server := tpptest.NewServer(t)
+	data := blueGreenCredential(t, server)
+	var diags diag.Diagnostics
+	if err := blueGreenRotation(context.Background(), &data, false, time.Now(), &diags); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	next := data.NextAccessToken
+
+	discardNextPair(context.Background(), &data, &diags)
+	if !revoked(t, server, next) {
+		t.Error("discarded next token pair not revoked")
+	}
+	if !data.NextAccessToken.IsNull() || !data.NextRefreshToken.IsNull() || !data.NextExpiration.IsNull() || !data.NextIssuedAt.IsNull() {
+		t.Error("discarded next token pair kept")
+	}
+	if revoked(t, server, data.AccessToken) {
+		t.Error("active token pair revoked")
+	}
+}
@@ -0,0 +1,93 @@
+package provider
+
+import (
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/model"
+)
+
+// sources of the attributes reported in config_source
+const (
+	sourceResource = "resource"
+	sourceProvider = "provider"
+	sourceEnv      = "env"
+	sourceDefault  = "default"
+)
+
+// configSourceAttributes are the attributes whose value may come from elsewhere than the resource: the provider
+// configuration, the environment variables of the provider, or a default
+var configSourceAttributes = []string{fURL, fTrustBundle, fClientID, fRefreshWindow}
+
+// providerSource returns where the provider attribute set from value, or from one of the envs environment variables,
+// comes from, or an empty string when it is not set at all
+func providerSource(value types.String, envs ...string) string {
+	if !value.IsNull() && !value.IsUnknown() {
+		return sourceProvider
+	}
+	if _, ok := lookupEnv(envs...); ok {
+		return sourceEnv
+	}
+	return ""
+}
+
+// importConfigSources returns where the attributes of configSourceAttributes come from for an import ID whose values
+// are dataMap, before the provider defaults are applied to it
+func (r *CredentialResource) importConfigSources(dataMap map[string]string) map[string]string {
+	sources := make(map[string]string)
+	for _, name := range configSourceAttributes {
+		if _, ok := dataMap[name]; ok {
+			sources[name] = sourceResource
+		} else if source := r.providerSource(name); source != "" {
+			sources[name] = source
+		}
+	}
+	// Set by the import when missing from the import ID
+	for _, name := range []string{fClientID, fRefreshWindow} {
+		if _, ok := sources[name]; !ok {
+			sources[name] = sourceDefault
+		}
+	}
+	return sources
+}
+
+// providerSource returns where the provider default of the name attribute comes from, or an empty string when the
+// provider has none
+func (r *CredentialResource) providerSource(name string) string {
+	if r.providerData == nil {
+		return ""
+	}
+	return r.providerData.Sources[name]
+}
+
+// configSources returns the content of the config_source attribute of data
+func configSources(data *model.CredentialResourceData) map[string]string {
+	sources := make(map[string]string)
+	for name, value := range data.ConfigSource.Elements() {
+		if source, ok := value.(types.String); ok {
+			sources[name] = source.ValueString()
+		}
+	}
+	return sources
+}
+
+// setConfigSources sets the config_source attribute of data to sources
+func setConfigSources(data *model.CredentialResourceData, sources map[string]string) {
+	elements := make(map[string]attr.Value, len(sources))
+	for name, source := range sources {
+		elements[name] = types.StringValue(source)
+	}
+	data.ConfigSource = types.MapValueMust(types.StringType, elements)
+}
+
+// markConfiguredSources records the attributes of configSourceAttributes set in the configuration of the resource as
+// coming from it
+func markConfiguredSources(data *model.CredentialResourceData, configured map[string]bool) {
+	sources := configSources(data)
+	for _, name := range configSourceAttributes {
+		if configured[name] {
+			sources[name] = sourceResource
+		}
+	}
+	setConfigSources(data, sources)
+}
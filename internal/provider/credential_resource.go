@@ -4,36 +4,130 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"reflect"
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
 	"github.com/hashicorp/terraform-plugin-framework/types"
-	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
 
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/logging"
 	"github.com/terraform-providers/terraform-provider-venafi-token/internal/model"
 	"github.com/terraform-providers/terraform-provider-venafi-token/internal/vcertclient"
 )
 
 const (
 	// attributes of the resource
-	fURL            = "url"
-	fUsername       = "username"
-	fPassword       = "password"
-	fP12Cert        = "p12_cert_filename"
-	fP12Password    = "p12_cert_password"
-	fAccessToken    = "access_token"
-	fRefreshToken   = "refresh_token"
-	fClientID       = "client_id"
-	fExpirationDate = "expiration"
-	fTrustBundle    = "trust_bundle"
-	fRefreshWindow  = "refresh_window"
+	fURL                    = "url"
+	fUsername               = "username"
+	fPassword               = "password"
+	fP12Cert                = "p12_cert_filename"
+	fP12Password            = "p12_cert_password"
+	fAccessToken            = "access_token"
+	fRefreshToken           = "refresh_token"
+	fClientID               = "client_id"
+	fExpirationDate         = "expiration"
+	fTrustBundle            = "trust_bundle"
+	fRefreshWindow          = "refresh_window"
+	fRotated                = "rotated_on_last_apply"
+	fIssuedAt               = "issued_at"
+	fRefreshWindowPercent   = "refresh_window_percent"
+	fP12PasswordFromSidecar = "p12_password_from_sidecar"
+	fFallbackURL            = "fallback_url"
+	fActiveURL              = "active_url"
+	fGrantID                = "grant_id"
+	fTLSHandshakeTimeout    = "tls_handshake_timeout_seconds"
+	fClientCertExpiration   = "client_cert_expiration"
+	fValidateOnly           = "validate_only"
+	fScope                  = "scope"
+	fGrantedScopes          = "granted_scopes"
+	fScopeSatisfied         = "scope_satisfied"
+	fRotateTrigger          = "rotate_trigger"
+	fVCertVersion           = "vcert_version"
+	fTPPVersion             = "tpp_version"
+	fMaxTotalAttempts       = "max_total_attempts"
+	fVaultTokenPath         = "vault_token_path"
+	fVaultWriteBack         = "vault_write_back"
+	fLogLevel               = "log_level"
+	fOfflineAccess          = "offline_access"
+	fRefreshUntil           = "refresh_until"
+	fLastRefreshWarning     = "last_refresh_warning"
+	fOAuthPathOverride      = "oauth_path_override"
+	fDotenvOutputFile       = "dotenv_output_file"
+	fDotenvIncludeURL       = "dotenv_include_url"
+	fDotenvRefreshToken     = "dotenv_include_refresh_token"
+	fMinGrantedLifetime     = "min_granted_lifetime_seconds"
+	fTokenStatus            = "token_status"
+	fP12PasswordCommand     = "p12_password_command"
+	fPrunePreviousGrants    = "prune_previous_grants"
+	fTrustBundleSystemName  = "trust_bundle_system_name"
+	fAuthAttempts           = "auth_attempts"
+	fMaxResponseBytes       = "max_response_bytes"
+	fStagedRotation         = "staged_rotation"
+	fCommitRotation         = "commit_rotation"
+	fPendingAccessToken     = "pending_access_token"
+	fPendingRefreshToken    = "pending_refresh_token"
+	fPendingExpiration      = "pending_expiration"
+	fPendingIssuedAt        = "pending_issued_at"
+	fTraceFile              = "trace_file"
+	fApplyMarginSeconds     = "apply_margin_seconds"
+	fExpectedServerSANs     = "expected_server_sans"
+	fRotationCount          = "rotation_count"
+	fMaxIdleConns           = "max_idle_conns"
+	fIdleConnTimeout        = "idle_conn_timeout_seconds"
+	fTokenCacheFile         = "token_cache_file"
+	fExpirationFormat       = "expiration_format"
+	fExpirationFormatted    = "expiration_formatted"
+	fNeverExpires           = "never_expires"
+	fRecoveryFile           = "recovery_file"
+	fRotationPolicy         = "rotation_policy"
+	fTokenIdentity          = "token_identity"
+	fRequireClientCertTLS   = "require_client_cert_tls"
+	fEffectiveRefreshWindow = "effective_refresh_window_seconds"
+	fP12CertFilenames       = "p12_cert_filenames"
+	fClientCertIssuer       = "client_cert_issuer"
+	fMinRemainingForOp      = "min_remaining_for_operation_seconds"
+	fMetadataJSON           = "metadata_json"
+	fVerifyRevocation       = "verify_revocation"
+	fBackgroundRefresh      = "background_refresh"
+	fK8sSecretName          = "k8s_secret_name"
+	fK8sSecretNamespace     = "k8s_secret_namespace"
+	fSelectedAuthMethod     = "selected_auth_method"
+	fUsedFallbackMethod     = "used_fallback_method"
+	fTrustBundleSystemRoots = "trust_bundle_append_system_roots"
+	fInsecureSkipVerify     = "insecure_skip_verify"
+	fRotationSchedule       = "rotation_schedule"
+	fConfigSource           = "config_source"
+	fBlueGreenRotation      = "blue_green_rotation"
+	fBlueGreenGraceSeconds  = "blue_green_grace_seconds"
+	fNextAccessToken        = "next_access_token"
+	fNextRefreshToken       = "next_refresh_token"
+	fNextExpiration         = "next_expiration"
+	fNextIssuedAt           = "next_issued_at"
+	fSupersededAccessToken  = "superseded_access_token"
+	fSupersededRevokeAt     = "superseded_revoke_at"
+	fRefreshFailurePolicy   = "refresh_failure_policy"
+	fRefreshDueAt           = "refresh_due_at"
+	fPlanCheckStrategy      = "plan_check_strategy"
+	fProxyTrustBundle       = "proxy_trust_bundle"
+	fVerifyMaxRetries       = "verify_max_retries"
+	fRotateMaxRetries       = "rotate_max_retries"
+	fEffectiveURL           = "effective_url"
+	fExpectedTokenType      = "expected_token_type"
+	fTokenType              = "token_type"
+	fResourceIdentity       = "resource_identity"
 
 	// messages
 	msgCredentialResourceError = "credential resource error"
 	msgImportFail              = "failed to import certificate resource"
+	msgStateEncryptionError    = "state encryption error"
 
 	// default values
 	defaultClientID      = "hashicorp-terraform-by-venafi"
@@ -43,20 +137,39 @@ const (
 )
 
 var (
-	_ resource.Resource                = &CredentialResource{}
-	_ resource.ResourceWithImportState = &CredentialResource{}
+	_ resource.Resource                   = &CredentialResource{}
+	_ resource.ResourceWithImportState    = &CredentialResource{}
+	_ resource.ResourceWithValidateConfig = &CredentialResource{}
+	_ resource.ResourceWithConfigure      = &CredentialResource{}
+	_ resource.ResourceWithModifyPlan     = &CredentialResource{}
 )
 
 func NewCredentialResource() resource.Resource {
 	return &CredentialResource{}
 }
 
-type CredentialResource struct{}
+type CredentialResource struct {
+	providerData *model.ProviderData
+}
 
 func (r *CredentialResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
 	resp.TypeName = fmt.Sprintf("%s_%s", req.ProviderTypeName, resourceNameSuffix)
 }
 
+func (r *CredentialResource) Configure(_ context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Provider data is not available until the provider has been configured
+	if req.ProviderData == nil {
+		return
+	}
+
+	providerData, ok := req.ProviderData.(*model.ProviderData)
+	if !ok {
+		resp.Diagnostics.AddError(msgCredentialResourceError, fmt.Sprintf("unexpected provider data type: %T", req.ProviderData))
+		return
+	}
+	r.providerData = providerData
+}
+
 func (r *CredentialResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
 	resp.Schema = schema.Schema{
 		MarkdownDescription: "Venafi Credential Resource",
@@ -67,6 +180,19 @@ func (r *CredentialResource) Schema(_ context.Context, _ resource.SchemaRequest,
 				Optional:            true,
 				Computed:            true,
 			},
+			fFallbackURL: schema.StringAttribute{
+				MarkdownDescription: "Secondary Venafi TLSPDC URL to use when url cannot be reached. Authentication errors do not trigger a failover",
+				Optional:            true,
+				Computed:            true,
+			},
+			fActiveURL: schema.StringAttribute{
+				MarkdownDescription: "TLSPDC URL that served the last successful operation: either url or fallback_url",
+				Computed:            true,
+			},
+			fEffectiveURL: schema.StringAttribute{
+				MarkdownDescription: "Base URL the last successful operation actually sent its requests to, once active_url was normalized",
+				Computed:            true,
+			},
 			fUsername: schema.StringAttribute{
 				MarkdownDescription: "Username to authenticate to TLSPDC and request a new token",
 				Optional:            true,
@@ -90,7 +216,7 @@ func (r *CredentialResource) Schema(_ context.Context, _ resource.SchemaRequest,
 				Sensitive:           true,
 			},
 			fAccessToken: schema.StringAttribute{
-				MarkdownDescription: "Access token used for authorization to TLSPDC",
+				MarkdownDescription: "Access token used for authorization to TLSPDC. Encrypted in the state when the provider sets state_encryption_key",
 				Computed:            true,
 				Sensitive:           true,
 			},
@@ -120,210 +246,1907 @@ func (r *CredentialResource) Schema(_ context.Context, _ resource.SchemaRequest,
 				Optional:            true,
 				Computed:            true,
 			},
+			fTLSHandshakeTimeout: schema.Int64Attribute{
+				MarkdownDescription: "Maximum number of seconds to wait for the TLS handshake with TLSPDC to complete. Defaults to 10",
+				Optional:            true,
+				Computed:            true,
+			},
+			fMaxTotalAttempts: schema.Int64Attribute{
+				MarkdownDescription: "Maximum number of requests sent to TLSPDC to get a new token pair, shared by all configured authentication methods. Defaults to 3",
+				Optional:            true,
+				Computed:            true,
+			},
+			fVerifyMaxRetries: schema.Int64Attribute{
+				MarkdownDescription: "Number of times the verification of the access token is retried after a connection error. Defaults to 0",
+				Optional:            true,
+			},
+			fRotateMaxRetries: schema.Int64Attribute{
+				MarkdownDescription: "Number of times each authentication method is retried after a connection error when rotating the token pair, within max_total_attempts. Only requests that never reached TLSPDC are retried. Without it, retries are only bounded by max_total_attempts",
+				Optional:            true,
+			},
+			fMaxResponseBytes: schema.Int64Attribute{
+				MarkdownDescription: "Largest response body, in bytes, read from TLSPDC. Defaults to 1048576 (1 MiB)",
+				Optional:            true,
+				Computed:            true,
+			},
+			fRefreshWindowPercent: schema.Int64Attribute{
+				MarkdownDescription: "percentage (0-100) of the token lifetime remaining at which a token refresh should be done. Takes precedence over refresh_window once the token lifetime is known",
+				Optional:            true,
+				Computed:            true,
+			},
+			fClientCertExpiration: schema.Int64Attribute{
+				MarkdownDescription: "Expiration date of the PKCS#12 client certificate last used to authenticate, in epoch format",
+				Computed:            true,
+			},
+			fGrantID: schema.StringAttribute{
+				MarkdownDescription: "Grant identifier (JTI) of the access token, when exposed by TLSPDC. Null otherwise",
+				Computed:            true,
+			},
+			fIssuedAt: schema.Int64Attribute{
+				MarkdownDescription: "Date the access token was issued by the provider, in epoch format",
+				Computed:            true,
+			},
+			fP12PasswordFromSidecar: schema.BoolAttribute{
+				MarkdownDescription: "When p12_cert_password is not set, read the PKCS#12 password from a sidecar file named after p12_cert_filename with a `.pass` suffix",
+				Optional:            true,
+				Computed:            true,
+			},
+			fScope: schema.StringAttribute{
+				MarkdownDescription: "Scope requested when authenticating with a client certificate or username/password. Example: certificate:manage;configuration. Defaults to certificate:manage",
+				Optional:            true,
+				Computed:            true,
+			},
+			fGrantedScopes: schema.ListAttribute{
+				MarkdownDescription: "Scope entries granted to the access token, e.g. [\"certificate:manage\", \"configuration\"]",
+				ElementType:         types.StringType,
+				Computed:            true,
+			},
+			fScopeSatisfied: schema.BoolAttribute{
+				MarkdownDescription: "Whether every requested scope entry was granted to the access token",
+				Computed:            true,
+			},
+			fRotateTrigger: schema.StringAttribute{
+				MarkdownDescription: "Arbitrary value that forces a token rotation whenever it changes, e.g. when a downstream consumer reports the token as rejected",
+				Optional:            true,
+			},
+			fVCertVersion: schema.StringAttribute{
+				MarkdownDescription: "Version of the vcert SDK used by the provider",
+				Computed:            true,
+			},
+			fTPPVersion: schema.StringAttribute{
+				MarkdownDescription: "Version of the TLSPDC instance, retrieved when the token pair is rotated or, for tokens set on import, on the next refresh. Null when it cannot be retrieved",
+				Computed:            true,
+			},
+			fVaultTokenPath: schema.StringAttribute{
+				MarkdownDescription: "Vault KV path holding the access_token and refresh_token to use, e.g. secret/data/venafi/tpp. The Vault address and token are read from VAULT_ADDR and VAULT_TOKEN",
+				Optional:            true,
+			},
+			fVaultWriteBack: schema.BoolAttribute{
+				MarkdownDescription: "Write the token pair back to vault_token_path after a rotation. Defaults to false",
+				Optional:            true,
+				Computed:            true,
+			},
+			fLogLevel: schema.StringAttribute{
+				MarkdownDescription: "Verbosity of the provider logs for this resource, regardless of TF_LOG: none, summary (no token metadata) or debug. Defaults to summary",
+				Optional:            true,
+				Computed:            true,
+			},
+			fOfflineAccess: schema.BoolAttribute{
+				MarkdownDescription: "Request a long-lived refresh token by adding offline_access to the requested scope. When TLSPDC rejects it, a warning is reported and the token is requested without it. Defaults to false",
+				Optional:            true,
+				Computed:            true,
+			},
+			fRefreshUntil: schema.Int64Attribute{
+				MarkdownDescription: "Date until which the refresh token can be used, in epoch format. Null when not reported by TLSPDC",
+				Computed:            true,
+			},
+			fLastRefreshWarning: schema.StringAttribute{
+				MarkdownDescription: "Most recent non-fatal issue met during the last token rotation, e.g. why an authentication method was skipped. Null when there was none",
+				Computed:            true,
+			},
+			fOAuthPathOverride: schema.StringAttribute{
+				MarkdownDescription: "Base path of the OAuth endpoints, replacing the standard /vedauth, for proxies relocating them",
+				Optional:            true,
+			},
+			fDotenvOutputFile: schema.StringAttribute{
+				MarkdownDescription: "File to write the access token to, in dotenv format, after each rotation. The file is removed on destroy",
+				Optional:            true,
+			},
+			fDotenvIncludeURL: schema.BoolAttribute{
+				MarkdownDescription: "Also write the TLSPDC URL to dotenv_output_file. Defaults to false",
+				Optional:            true,
+				Computed:            true,
+			},
+			fDotenvRefreshToken: schema.BoolAttribute{
+				MarkdownDescription: "Also write the refresh token to dotenv_output_file. Defaults to false",
+				Optional:            true,
+				Computed:            true,
+			},
+			fMinGrantedLifetime: schema.Int64Attribute{
+				MarkdownDescription: "Minimum lifetime, in seconds, of a newly granted access token. A shorter-lived token is revoked and the rotation fails",
+				Optional:            true,
+			},
+			fTokenStatus: schema.SingleNestedAttribute{
+				MarkdownDescription: "Summary of the access token state, as of the last refresh",
+				Computed:            true,
+				Attributes: map[string]schema.Attribute{
+					fStatusValid: schema.BoolAttribute{
+						MarkdownDescription: "Whether the access token has not expired",
+						Computed:            true,
+					},
+					fStatusExpired: schema.BoolAttribute{
+						MarkdownDescription: "Whether the access token has expired",
+						Computed:            true,
+					},
+					fStatusDaysUntilExpiration: schema.Int64Attribute{
+						MarkdownDescription: "Number of whole days left before the access token expires",
+						Computed:            true,
+					},
+					fStatusWithinRefreshWindow: schema.BoolAttribute{
+						MarkdownDescription: "Whether the access token expiration falls within the refresh window",
+						Computed:            true,
+					},
+					fStatusIssuedAt: schema.Int64Attribute{
+						MarkdownDescription: "Date the access token was issued, in epoch format",
+						Computed:            true,
+					},
+					fStatusExpiration: schema.Int64Attribute{
+						MarkdownDescription: "Expiration date of the access token, in epoch format",
+						Computed:            true,
+					},
+				},
+			},
+			fP12PasswordCommand: schema.StringAttribute{
+				MarkdownDescription: "Command printing the PKCS#12 password on its standard output, e.g. a password manager helper. Conflicts with p12_cert_password",
+				Optional:            true,
+			},
+			fPrunePreviousGrants: schema.BoolAttribute{
+				MarkdownDescription: "Revoke the grant previously held by this resource once a client certificate or username/password got a new one. Only enable it when the token of this resource is not shared. Defaults to false",
+				Optional:            true,
+				Computed:            true,
+			},
+			fTrustBundleSystemName: schema.StringAttribute{
+				MarkdownDescription: "Subject common name, or full subject, of a CA certificate of the system trust store to trust when connecting to TLSPDC, in addition to trust_bundle",
+				Optional:            true,
+			},
+			fAuthAttempts: schema.ListNestedAttribute{
+				MarkdownDescription: "Requests sent by the authentication ladder during the last rotation, in order",
+				Computed:            true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						fAttemptMethod: schema.StringAttribute{
+							MarkdownDescription: "Authentication method: refresh token, client certificate or username-password",
+							Computed:            true,
+						},
+						fAttemptSucceeded: schema.BoolAttribute{
+							MarkdownDescription: "Whether the request got a token pair",
+							Computed:            true,
+						},
+						fAttemptError: schema.StringAttribute{
+							MarkdownDescription: "Failure reason, scrubbed of any credential. Null when the request succeeded",
+							Computed:            true,
+						},
+					},
+				},
+			},
+			fStagedRotation: schema.BoolAttribute{
+				MarkdownDescription: "Stage rotations: the new token pair is kept in the pending_* attributes, the active one being left untouched until commit_rotation is set. Defaults to false",
+				Optional:            true,
+				Computed:            true,
+			},
+			fCommitRotation: schema.BoolAttribute{
+				MarkdownDescription: "Promote the staged token pair to the active one on the next apply",
+				Optional:            true,
+			},
+			fPendingAccessToken: schema.StringAttribute{
+				MarkdownDescription: "Access token staged by a rotation, waiting for commit_rotation",
+				Computed:            true,
+				Sensitive:           true,
+			},
+			fPendingRefreshToken: schema.StringAttribute{
+				MarkdownDescription: "Refresh token staged by a rotation, waiting for commit_rotation",
+				Computed:            true,
+				Sensitive:           true,
+			},
+			fPendingExpiration: schema.Int64Attribute{
+				MarkdownDescription: "Expiration date of the staged access token, in epoch format",
+				Computed:            true,
+			},
+			fPendingIssuedAt: schema.Int64Attribute{
+				MarkdownDescription: "Date the staged token pair was issued, in epoch format. It becomes issued_at once committed",
+				Computed:            true,
+			},
+			fBlueGreenRotation: schema.BoolAttribute{
+				MarkdownDescription: "Rotate with overlapping grants: the next token pair is provisioned in the next_* attributes, promoted to the active one after blue_green_grace_seconds, and the superseded grant is revoked a grace period later. Defaults to false",
+				Optional:            true,
+				Computed:            true,
+			},
+			fBlueGreenGraceSeconds: schema.Int64Attribute{
+				MarkdownDescription: "Number of seconds consumers have to move to the next token pair before it is promoted, and then to stop using the superseded one before it is revoked. Defaults to 3600",
+				Optional:            true,
+			},
+			fNextAccessToken: schema.StringAttribute{
+				MarkdownDescription: "Access token provisioned by blue_green_rotation, waiting to be promoted",
+				Computed:            true,
+				Sensitive:           true,
+			},
+			fNextRefreshToken: schema.StringAttribute{
+				MarkdownDescription: "Refresh token provisioned by blue_green_rotation, waiting to be promoted",
+				Computed:            true,
+				Sensitive:           true,
+			},
+			fNextExpiration: schema.Int64Attribute{
+				MarkdownDescription: "Expiration date of the next access token, in epoch format",
+				Computed:            true,
+			},
+			fNextIssuedAt: schema.Int64Attribute{
+				MarkdownDescription: "Date the next token pair was provisioned, in epoch format",
+				Computed:            true,
+			},
+			fSupersededAccessToken: schema.StringAttribute{
+				MarkdownDescription: "Access token superseded by the last promotion of blue_green_rotation, revoked at superseded_revoke_at",
+				Computed:            true,
+				Sensitive:           true,
+			},
+			fSupersededRevokeAt: schema.Int64Attribute{
+				MarkdownDescription: "Date from which the superseded grant is revoked, in epoch format",
+				Computed:            true,
+			},
+			fTraceFile: schema.StringAttribute{
+				MarkdownDescription: "File to append a trace of every request sent to TLSPDC to: method, URL, status and timings. Headers and bodies are never written",
+				Optional:            true,
+			},
+			fApplyMarginSeconds: schema.Int64Attribute{
+				MarkdownDescription: "Number of seconds between plan and apply to account for: an update is planned when the access token enters its refresh window within that time, so that the rotation is decided again at apply",
+				Optional:            true,
+			},
+			fExpectedServerSANs: schema.ListAttribute{
+				MarkdownDescription: "DNS names or IP addresses expected in the subject alternative names of the TLSPDC certificate. The TLS handshake fails when none matches, in addition to the usual certificate verification",
+				ElementType:         types.StringType,
+				Optional:            true,
+			},
+			fMaxIdleConns: schema.Int64Attribute{
+				MarkdownDescription: "Maximum number of idle connections to TLSPDC kept open for the next operations of the provider process. Defaults to 0, closing connections after each request",
+				Optional:            true,
+			},
+			fIdleConnTimeout: schema.Int64Attribute{
+				MarkdownDescription: "Number of seconds an idle connection to TLSPDC is kept open when max_idle_conns is set. Defaults to 30",
+				Optional:            true,
+			},
+			fTokenCacheFile: schema.StringAttribute{
+				MarkdownDescription: "JSON file caching the token pair. A cached access token that is neither expired nor within the refresh window is used without contacting TLSPDC. The file is written after each rotation, or when missing",
+				Optional:            true,
+			},
+			fExpirationFormat: schema.StringAttribute{
+				MarkdownDescription: "Layout of expiration_formatted: epoch, epoch_ms, rfc3339 or a Go time layout such as 2006-01-02 15:04:05. Defaults to rfc3339",
+				Optional:            true,
+			},
+			fExpirationFormatted: schema.StringAttribute{
+				MarkdownDescription: "Expiration date of the access token, formatted according to expiration_format. Null when the token never expires",
+				Computed:            true,
+			},
+			fNeverExpires: schema.BoolAttribute{
+				MarkdownDescription: "Whether TLSPDC granted the access token without an expiration date, i.e. an expiration of 0. Such a token never enters the refresh window and is only rotated when forced, e.g. by rotate_trigger or rotation_schedule",
+				Computed:            true,
+			},
+			fRecoveryFile: schema.StringAttribute{
+				MarkdownDescription: "File recording each newly granted token pair until a later run finds it in the state. A pair granted by a run interrupted before saving the state is adopted when still valid, revoked otherwise",
+				Optional:            true,
+			},
+			fRotationPolicy: schema.StringAttribute{
+				MarkdownDescription: "Whether the refresh token is used to rotate the token pair: prefer_refresh, or always_primary to always authenticate with the client certificate or username/password. Defaults to prefer_refresh",
+				Optional:            true,
+				Computed:            true,
+			},
+			fRefreshFailurePolicy: schema.StringAttribute{
+				MarkdownDescription: "Whether a failed rotation of a token pair that is still valid fails the operation: fail, or keep_existing to keep the current token pair with a warning. Defaults to fail",
+				Optional:            true,
+				Computed:            true,
+			},
+			fExpectedTokenType: schema.StringAttribute{
+				MarkdownDescription: "Token type TLSPDC must grant, compared case-insensitively. A token pair of another type is revoked and the operation fails. Defaults to Bearer",
+				Optional:            true,
+				Computed:            true,
+			},
+			fTokenType: schema.StringAttribute{
+				MarkdownDescription: "Token type of the last token pair granted by TLSPDC, e.g. Bearer. Null when TLSPDC did not report it",
+				Computed:            true,
+			},
+			fPlanCheckStrategy: schema.StringAttribute{
+				MarkdownDescription: "How the access token is checked on refresh: online verifies it with TLSPDC, offline trusts its expiration date without contacting TLSPDC until it is due for rotation. Defaults to online",
+				Optional:            true,
+				Computed:            true,
+			},
+			fTokenIdentity: schema.StringAttribute{
+				MarkdownDescription: "Name of the TLSPDC identity the access token belongs to, e.g. the username it was granted to. Null when it could not be retrieved",
+				Computed:            true,
+			},
+			fRequireClientCertTLS: schema.BoolAttribute{
+				MarkdownDescription: "Present the client certificate of p12_cert_filename on every connection to TLSPDC, for deployments mandating mutual TLS. With username and password set, the certificate only authenticates the TLS connections and the token pair is requested with username/password. Defaults to false",
+				Optional:            true,
+				Computed:            true,
+			},
+			fEffectiveRefreshWindow: schema.Int64Attribute{
+				MarkdownDescription: "Refresh window, in seconds, the rotation of the access token is decided with, as resolved from refresh_window and refresh_window_percent",
+				Computed:            true,
+			},
+			fRefreshDueAt: schema.Int64Attribute{
+				MarkdownDescription: "Date the access token enters its refresh window, in epoch format: expiration minus effective_refresh_window_seconds",
+				Computed:            true,
+			},
+			fP12CertFilenames: schema.ListAttribute{
+				MarkdownDescription: "Further PKCS#12 keystores, in the same formats as p12_cert_filename and protected by the same password, to choose the client certificate from",
+				ElementType:         types.StringType,
+				Optional:            true,
+			},
+			fClientCertIssuer: schema.StringAttribute{
+				MarkdownDescription: "Issuer common name, or full issuer, of the client certificate to present among p12_cert_filename and p12_cert_filenames",
+				Optional:            true,
+			},
+			fMinRemainingForOp: schema.Int64Attribute{
+				MarkdownDescription: "Minimum number of seconds the access token must remain valid after each read or apply, for long-running downstream operations. A token with less time left is rotated, and the rotation fails when a fresh token cannot provide it",
+				Optional:            true,
+			},
+			fMetadataJSON: schema.StringAttribute{
+				MarkdownDescription: "JSON object of the non-sensitive token metadata: connector_type, client_id, expiration, issued_at, granted_scopes and token_fingerprint",
+				Computed:            true,
+			},
+			fVerifyRevocation: schema.BoolAttribute{
+				MarkdownDescription: "On destroy, check that TLSPDC rejects the revoked access token, asking again a few times while it is still accepted. A warning is reported when the revocation cannot be confirmed. Defaults to false",
+				Optional:            true,
+				Computed:            true,
+			},
+			fRotationSchedule: schema.StringAttribute{
+				MarkdownDescription: "Cron expression, in UTC, of the times the token pair is rotated at, e.g. `0 3 * * 1` for every Monday at 03:00. The rotation is planned by the first plan after a scheduled time passed since the access token was issued. Accepts 5 fields (minute, hour, day of month, month, day of week) and the @hourly, @daily, @weekly, @monthly and @yearly aliases",
+				Optional:            true,
+			},
+			fConfigSource: schema.MapAttribute{
+				MarkdownDescription: "Where the values of url, trust_bundle, client_id and refresh_window come from: `resource`, `provider`, `env` or `default`",
+				ElementType:         types.StringType,
+				Computed:            true,
+			},
+			fProxyTrustBundle: schema.StringAttribute{
+				MarkdownDescription: "CA certificates of a TLS-intercepting proxy, in the same formats as trust_bundle, trusted in addition to trust_bundle when TLSPDC is reached through the proxy of the HTTPS_PROXY environment variable",
+				Optional:            true,
+			},
+			fTrustBundleSystemRoots: schema.BoolAttribute{
+				MarkdownDescription: "Trust the system roots in addition to trust_bundle, e.g. when TLSPDC is reached through a proxy whose certificate is issued by a public CA. Defaults to false",
+				Optional:            true,
+				Computed:            true,
+			},
+			fInsecureSkipVerify: schema.BoolAttribute{
+				MarkdownDescription: "Do not verify the certificate of TLSPDC. Only meant for development environments: tokens may then be sent to an impostor. expected_server_sans is still checked. Defaults to false",
+				Optional:            true,
+				Computed:            true,
+			},
+			fSelectedAuthMethod: schema.StringAttribute{
+				MarkdownDescription: "Authentication method that got the token pair on the last rotation: `refresh token`, `client certificate` or `username-password`. Null until the provider rotates the token",
+				Computed:            true,
+			},
+			fUsedFallbackMethod: schema.BoolAttribute{
+				MarkdownDescription: "Whether the last rotation fell back to another authentication method after the preferred one failed, e.g. a client certificate after a refresh token rejected by TLSPDC. Null until the provider rotates the token",
+				Computed:            true,
+			},
+			fK8sSecretName: schema.StringAttribute{
+				MarkdownDescription: "Kubernetes secret holding the PKCS#12 keystore, under its keystore.p12 key, and its password, under its password key. Read with the service account of the pod when the provider runs in a Kubernetes cluster, in place of p12_cert_filename",
+				Optional:            true,
+			},
+			fK8sSecretNamespace: schema.StringAttribute{
+				MarkdownDescription: "Namespace of k8s_secret_name. Defaults to the namespace of the pod",
+				Optional:            true,
+			},
+			fBackgroundRefresh: schema.BoolAttribute{
+				MarkdownDescription: "Rotate the token pair in the background once it enters its refresh window, for as long as the provider process runs. The rotated pair is used by the next read of the resource in the same process. Requires a primary credential or a recovery_file. Defaults to false",
+				Optional:            true,
+				Computed:            true,
+			},
+			fValidateOnly: schema.BoolAttribute{
+				MarkdownDescription: "Only check that the credentials can obtain a token: every read requests a new token pair and revokes it right away. No token is kept in the state",
+				Optional:            true,
+				Computed:            true,
+			},
+			fRotated: schema.BoolAttribute{
+				MarkdownDescription: "Whether the last read or apply of the resource rotated the token pair",
+				Computed:            true,
+			},
+			fRotationCount: schema.Int64Attribute{
+				MarkdownDescription: "Number of times the provider rotated the token pair since the resource was imported",
+				Computed:            true,
+			},
+			fResourceIdentity: schema.StringAttribute{
+				MarkdownDescription: "Stable identifier of the resource, the SHA-256 digest of url, client_id and the identity of the primary credential. Set on import and never changed afterwards, by rotations or otherwise",
+				Computed:            true,
+			},
 		},
 	}
 }
 
-func (r *CredentialResource) Create(_ context.Context, _ resource.CreateRequest, resp *resource.CreateResponse) {
-	resp.Diagnostics.AddError(msgCredentialResourceError, "credential resource cannot be created, only imported.")
-}
-
-func (r *CredentialResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
-	tflog.Info(ctx, "reading credential resource")
+func (r *CredentialResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
 	var data model.CredentialResourceData
-	diags := req.State.Get(ctx, &data)
+	diags := req.Config.Get(ctx, &data)
 	resp.Diagnostics.Append(diags...)
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
-	// No access token, request a new pair right away
-	if data.AccessToken.IsNull() {
-		tflog.Info(ctx, "no access token, retrieving a new token pair")
-		err := rotateToken(ctx, &data)
-		if err != nil {
-			reportClientError(ctx, err, resp)
-			return
+	// Every problem is reported, so that a single run of terraform validate lists them all
+	for _, location := range []struct {
+		attribute string
+		value     types.String
+	}{
+		{fURL, data.URL},
+		{fFallbackURL, data.FallbackURL},
+	} {
+		if location.value.IsNull() || location.value.IsUnknown() {
+			continue
+		}
+		if err := vcertclient.ValidateURL(location.value.ValueString()); err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root(location.attribute), msgCredentialResourceError, err.Error())
 		}
-		resp.State.Set(ctx, data)
-		return
 	}
 
-	// Got access token, check expiration
-	client := vcertclient.New(ctx, data)
-	expired, err := client.VerifyTokenExpired()
-	if err != nil {
-		tflog.Error(ctx, fmt.Sprintf("client error: %s", err.Error()))
-		resp.Diagnostics.AddError("Client Error", fmt.Sprintf("Unable to verify token expiration, got error: %s", err))
-		return
+	validateCredentialsConfig(&data, &resp.Diagnostics)
+
+	if !data.RefreshWindow.IsNull() && !data.RefreshWindow.IsUnknown() && data.RefreshWindow.ValueInt64() < 0 {
+		resp.Diagnostics.AddAttributeError(path.Root(fRefreshWindow), msgCredentialResourceError,
+			fmt.Sprintf("%s must not be negative, got %d", fRefreshWindow, data.RefreshWindow.ValueInt64()))
 	}
 
-	// If token already expired, request new pair
-	if expired {
-		tflog.Info(ctx, "access token expired, retrieving a new token pair")
-		err = rotateToken(ctx, &data)
-		if err != nil {
-			reportClientError(ctx, err, resp)
-			return
+	if !data.RefreshWindowPercent.IsNull() && !data.RefreshWindowPercent.IsUnknown() {
+		if err := validateRefreshWindowPercent(data.RefreshWindowPercent.ValueInt64()); err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root(fRefreshWindowPercent), msgCredentialResourceError, err.Error())
+		}
+		if !data.RefreshWindow.IsNull() {
+			resp.Diagnostics.AddAttributeWarning(path.Root(fRefreshWindow), msgCredentialResourceError,
+				fmt.Sprintf("%s is ignored when %s is set and the token lifetime is known", fRefreshWindow, fRefreshWindowPercent))
 		}
-		resp.State.Set(ctx, data)
-		return
 	}
 
-	// Refresh window is in days, we need to convert it to seconds: n days * 24 hours * 60 minutes * 60 seconds
-	refreshWindowSeconds := data.RefreshWindow.ValueInt64() * 24 * 60 * 60
-	// If token not expired, check expiration date is on refresh window. If so, request new pair
-	if data.ExpirationDate.ValueInt64()-refreshWindowSeconds < time.Now().Unix() {
-		tflog.Info(ctx, "access token expiration within refresh window, retrieving a new token pair")
-		err = rotateToken(ctx, &data)
-		if err != nil {
-			reportClientError(ctx, err, resp)
-			return
+	if !data.LogLevel.IsNull() && !data.LogLevel.IsUnknown() {
+		if _, err := logging.ParseLevel(data.LogLevel.ValueString()); err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root(fLogLevel), msgCredentialResourceError, err.Error())
 		}
+	}
 
-		resp.State.Set(ctx, data)
-		return
+	if !data.OAuthPathOverride.IsNull() && !data.OAuthPathOverride.IsUnknown() {
+		if err := vcertclient.ValidateOAuthPath(data.OAuthPathOverride.ValueString()); err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root(fOAuthPathOverride), msgCredentialResourceError, err.Error())
+		}
 	}
 
-	// Token is valid, nothing to do here
-	tflog.Info(ctx, "access token valid")
-}
+	if !data.MinGrantedLifetime.IsNull() && !data.MinGrantedLifetime.IsUnknown() && data.MinGrantedLifetime.ValueInt64() < 0 {
+		resp.Diagnostics.AddAttributeError(path.Root(fMinGrantedLifetime), msgCredentialResourceError,
+			fmt.Sprintf("%s must not be negative, got %d", fMinGrantedLifetime, data.MinGrantedLifetime.ValueInt64()))
+	}
 
-func (r *CredentialResource) Update(ctx context.Context, _ resource.UpdateRequest, _ *resource.UpdateResponse) {
-	tflog.Info(ctx, "updating credential resource")
-}
+	if (!data.P12CertFilenames.IsNull() || !data.ClientCertIssuer.IsNull()) && data.P12Certificate.IsNull() && data.K8sSecretName.IsNull() {
+		resp.Diagnostics.AddAttributeError(path.Root(fP12CertFilenames), msgCredentialResourceError,
+			fmt.Sprintf("%s and %s require %s or %s", fP12CertFilenames, fClientCertIssuer, fP12Cert, fK8sSecretName))
+	}
 
-func (r *CredentialResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
-	tflog.Info(ctx, "deleting credential resource")
-	var state model.CredentialResourceData
+	if data.RequireClientCertTLS.ValueBool() && data.P12Certificate.IsNull() && data.K8sSecretName.IsNull() {
+		resp.Diagnostics.AddAttributeError(path.Root(fRequireClientCertTLS), msgCredentialResourceError,
+			fmt.Sprintf("%s requires %s or %s", fRequireClientCertTLS, fP12Cert, fK8sSecretName))
+	}
 
-	diags := req.State.Get(ctx, &state)
-	resp.Diagnostics.Append(diags...)
-	if resp.Diagnostics.HasError() {
-		return
+	if data.BlueGreenRotation.ValueBool() {
+		for _, conflicting := range []struct {
+			name string
+			set  bool
+		}{
+			{fStagedRotation, data.StagedRotation.ValueBool()},
+			{fValidateOnly, data.ValidateOnly.ValueBool()},
+		} {
+			if conflicting.set {
+				resp.Diagnostics.AddAttributeError(path.Root(fBlueGreenRotation), msgCredentialResourceError,
+					fmt.Sprintf("%s and %s are conflicting, set only one of them", fBlueGreenRotation, conflicting.name))
+			}
+		}
+	}
+	if !data.BlueGreenGraceSeconds.IsNull() && !data.BlueGreenGraceSeconds.IsUnknown() && data.BlueGreenGraceSeconds.ValueInt64() < 0 {
+		resp.Diagnostics.AddAttributeError(path.Root(fBlueGreenGraceSeconds), msgCredentialResourceError,
+			fmt.Sprintf("%s must not be negative, got %d", fBlueGreenGraceSeconds, data.BlueGreenGraceSeconds.ValueInt64()))
 	}
 
-	client := vcertclient.New(ctx, state)
-	err := client.RevokeToken()
+	if !data.RotationSchedule.IsNull() && !data.RotationSchedule.IsUnknown() {
+		if _, err := parseRotationSchedule(data.RotationSchedule.ValueString()); err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root(fRotationSchedule), msgCredentialResourceError, err.Error())
+		}
+	}
+
+	if data.InsecureSkipVerify.ValueBool() {
+		resp.Diagnostics.AddAttributeWarning(path.Root(fInsecureSkipVerify), msgCredentialResourceError,
+			fmt.Sprintf("%s disables the verification of the TLSPDC certificate, tokens may be sent to an impostor. Only use it in development environments", fInsecureSkipVerify))
+	}
+	if data.TrustBundleSystemRoots.ValueBool() && data.TrustBundle.IsNull() && data.TrustBundleSystemName.IsNull() {
+		resp.Diagnostics.AddAttributeWarning(path.Root(fTrustBundleSystemRoots), msgCredentialResourceError,
+			fmt.Sprintf("%s has no effect without %s or %s, the system roots are already used", fTrustBundleSystemRoots, fTrustBundle, fTrustBundleSystemName))
+	}
+
+	if !data.K8sSecretName.IsNull() && !data.P12Certificate.IsNull() {
+		resp.Diagnostics.AddAttributeError(path.Root(fK8sSecretName), msgCredentialResourceError,
+			fmt.Sprintf("%s and %s are conflicting, set only one of them", fK8sSecretName, fP12Cert))
+	}
+	if !data.K8sSecretNamespace.IsNull() && data.K8sSecretName.IsNull() {
+		resp.Diagnostics.AddAttributeError(path.Root(fK8sSecretNamespace), msgCredentialResourceError,
+			fmt.Sprintf("%s requires %s", fK8sSecretNamespace, fK8sSecretName))
+	}
+
+	if !data.RotationPolicy.IsNull() && !data.RotationPolicy.IsUnknown() {
+		if err := vcertclient.ValidateRotationPolicy(data.RotationPolicy.ValueString()); err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root(fRotationPolicy), msgCredentialResourceError, err.Error())
+		}
+	}
+	if !data.PlanCheckStrategy.IsNull() && !data.PlanCheckStrategy.IsUnknown() {
+		if err := validatePlanCheckStrategy(data.PlanCheckStrategy.ValueString()); err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root(fPlanCheckStrategy), msgCredentialResourceError, err.Error())
+		}
+	}
+	if !data.RefreshFailurePolicy.IsNull() && !data.RefreshFailurePolicy.IsUnknown() {
+		if err := validateRefreshFailurePolicy(data.RefreshFailurePolicy.ValueString()); err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root(fRefreshFailurePolicy), msgCredentialResourceError, err.Error())
+		}
+	}
+	if !data.ExpectedTokenType.IsNull() && !data.ExpectedTokenType.IsUnknown() {
+		if err := validateExpectedTokenType(data.ExpectedTokenType.ValueString()); err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root(fExpectedTokenType), msgCredentialResourceError, err.Error())
+		}
+	}
+
+	if !data.ExpirationFormat.IsNull() && !data.ExpirationFormat.IsUnknown() {
+		if err := validateExpirationFormat(data.ExpirationFormat.ValueString()); err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root(fExpirationFormat), msgCredentialResourceError, err.Error())
+		}
+	}
+
+	if !data.ApplyMarginSeconds.IsNull() && !data.ApplyMarginSeconds.IsUnknown() && data.ApplyMarginSeconds.ValueInt64() < 0 {
+		resp.Diagnostics.AddAttributeError(path.Root(fApplyMarginSeconds), msgCredentialResourceError,
+			fmt.Sprintf("%s must not be negative, got %d", fApplyMarginSeconds, data.ApplyMarginSeconds.ValueInt64()))
+	}
+
+	if !data.MaxIdleConns.IsNull() && !data.MaxIdleConns.IsUnknown() && data.MaxIdleConns.ValueInt64() < 0 {
+		resp.Diagnostics.AddAttributeError(path.Root(fMaxIdleConns), msgCredentialResourceError,
+			fmt.Sprintf("%s must not be negative, got %d", fMaxIdleConns, data.MaxIdleConns.ValueInt64()))
+	}
+
+	if !data.IdleConnTimeout.IsNull() && !data.IdleConnTimeout.IsUnknown() && data.IdleConnTimeout.ValueInt64() < 1 {
+		resp.Diagnostics.AddAttributeError(path.Root(fIdleConnTimeout), msgCredentialResourceError,
+			fmt.Sprintf("%s must be at least 1, got %d", fIdleConnTimeout, data.IdleConnTimeout.ValueInt64()))
+	}
+
+	if !data.MinRemainingForOp.IsNull() && !data.MinRemainingForOp.IsUnknown() && data.MinRemainingForOp.ValueInt64() < 0 {
+		resp.Diagnostics.AddAttributeError(path.Root(fMinRemainingForOp), msgCredentialResourceError,
+			fmt.Sprintf("%s must not be negative, got %d", fMinRemainingForOp, data.MinRemainingForOp.ValueInt64()))
+	}
+
+	if !data.MaxResponseBytes.IsNull() && !data.MaxResponseBytes.IsUnknown() && data.MaxResponseBytes.ValueInt64() < 1 {
+		resp.Diagnostics.AddAttributeError(path.Root(fMaxResponseBytes), msgCredentialResourceError,
+			fmt.Sprintf("%s must be at least 1, got %d", fMaxResponseBytes, data.MaxResponseBytes.ValueInt64()))
+	}
+
+	if !data.MaxTotalAttempts.IsNull() && !data.MaxTotalAttempts.IsUnknown() {
+		if err := validateMaxTotalAttempts(data.MaxTotalAttempts.ValueInt64()); err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root(fMaxTotalAttempts), msgCredentialResourceError, err.Error())
+		}
+	}
+	for _, retries := range []struct {
+		name  string
+		value types.Int64
+	}{
+		{fVerifyMaxRetries, data.VerifyMaxRetries},
+		{fRotateMaxRetries, data.RotateMaxRetries},
+	} {
+		if retries.value.IsNull() || retries.value.IsUnknown() {
+			continue
+		}
+		if err := validateMaxRetries(retries.name, retries.value.ValueInt64()); err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root(retries.name), msgCredentialResourceError, err.Error())
+		}
+	}
+}
+
+// validateCredentialsConfig reports the credentials of the configuration that cannot be used together, or without
+// their counterpart. Values not known yet are not checked.
+func validateCredentialsConfig(data *model.CredentialResourceData, diags *diag.Diagnostics) {
+	for _, value := range []types.String{data.Username, data.Password, data.P12Certificate, data.P12Password, data.P12PasswordCommand, data.RefreshToken, data.K8sSecretName} {
+		if value.IsUnknown() {
+			return
+		}
+	}
+	if data.P12PasswordFromSidecar.IsUnknown() || data.RotationPolicy.IsUnknown() {
+		return
+	}
+
+	hasUsername, hasPassword := !isBlank(data.Username), !isBlank(data.Password)
+	if hasUsername && !hasPassword {
+		diags.AddAttributeWarning(path.Root(fPassword), msgCredentialResourceError,
+			fmt.Sprintf("%s is required with %s, unless it was set on import", fPassword, fUsername))
+	}
+	if hasPassword && !hasUsername {
+		diags.AddAttributeWarning(path.Root(fUsername), msgCredentialResourceError,
+			fmt.Sprintf("%s is required with %s, unless it was set on import", fUsername, fPassword))
+	}
+
+	hasP12 := !isBlank(data.P12Certificate)
+	hasP12Password, hasP12Command := !isBlank(data.P12Password), !isBlank(data.P12PasswordCommand)
+	if hasP12 && !hasP12Password && !hasP12Command && !data.P12PasswordFromSidecar.ValueBool() {
+		diags.AddAttributeWarning(path.Root(fP12Password), msgCredentialResourceError,
+			fmt.Sprintf("%s requires %s, %s or %s, unless it was set on import", fP12Cert, fP12Password, fP12PasswordCommand, fP12PasswordFromSidecar))
+	}
+	if hasP12Password && hasP12Command {
+		diags.AddAttributeError(path.Root(fP12PasswordCommand), msgCredentialResourceError,
+			fmt.Sprintf("%s and %s are conflicting, set only one of them", fP12Password, fP12PasswordCommand))
+	}
+	if (hasP12Password || hasP12Command) && !hasP12 {
+		diags.AddAttributeWarning(path.Root(fP12Cert), msgCredentialResourceError,
+			fmt.Sprintf("%s is required with %s or %s, unless it was set on import", fP12Cert, fP12Password, fP12PasswordCommand))
+	}
+
+	if data.RotationPolicy.ValueString() == vcertclient.RotationPolicyAlwaysPrimary && !hasKeystore(data) && !hasUsername {
+		diags.AddAttributeWarning(path.Root(fRotationPolicy), msgCredentialResourceError,
+			fmt.Sprintf("%s %s requires a primary credential, %s or %s and %s, unless it was set on import", fRotationPolicy,
+				vcertclient.RotationPolicyAlwaysPrimary, fP12Cert, fUsername, fPassword))
+	}
+}
+
+func (r *CredentialResource) Create(_ context.Context, _ resource.CreateRequest, resp *resource.CreateResponse) {
+	resp.Diagnostics.AddError(msgCredentialResourceError, "credential resource cannot be created, only imported.")
+}
+
+func (r *CredentialResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	// Nothing to do on creation. On destruction, the token pair is only revoked by Delete: planning a rotation would
+	// waste a grant right before its revocation.
+	if req.State.Raw.IsNull() || req.Plan.Raw.IsNull() {
+		return
+	}
+
+	var plan, state, config model.CredentialResourceData
+	diags := req.Plan.Get(ctx, &plan)
+	resp.Diagnostics.Append(diags...)
+	diags = req.State.Get(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+	diags = req.Config.Get(ctx, &config)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx = r.logContext(ctx, &plan)
+	now := time.Now()
+	reason := rotationReason(&state, &plan, now)
+	if reason == "" {
+		if !refreshDueBeforeApply(&state, &plan, now) {
+			return
+		}
+		// The update decides again whether to rotate, with the time of the apply
+		logging.Info(ctx, "access token may enter its refresh window before apply, planning an update")
+	} else {
+		logging.Info(ctx, fmt.Sprintf("token pair will be rotated: %s", reason))
+	}
+	// Any computed attribute may change with the update, not only the token pair: the status, the grant, the scopes or
+	// the url answering are only known once it is applied
+	configured := configuredAttributes(config)
+	for name, attribute := range req.Plan.Schema.GetAttributes() {
+		// Values set in the configuration cannot be changed by the plan
+		if !attribute.IsComputed() || configured[name] {
+			continue
+		}
+		attributeType := attribute.GetType()
+		unknown, err := attributeType.ValueFromTerraform(ctx, tftypes.NewValue(attributeType.TerraformType(ctx), tftypes.UnknownValue))
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root(name), "Unable To Plan Update", err.Error())
+			continue
+		}
+		diags = resp.Plan.SetAttribute(ctx, path.Root(name), unknown)
+		resp.Diagnostics.Append(diags...)
+	}
+}
+
+func (r *CredentialResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data model.CredentialResourceData
+	diags := req.State.Get(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	stored := data
+	if err := r.decryptTokens(&data); err != nil {
+		resp.Diagnostics.AddError(msgStateEncryptionError, err.Error())
+		return
+	}
+	ctx = r.logContext(ctx, &data)
+	logging.Info(ctx, "reading credential resource")
+
+	r.applyProviderDefaultsToData(&data)
+	loadFromVault(ctx, &data, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	adopted := recoverInterruptedRotation(ctx, &data, &resp.Diagnostics)
+	previousToken := data.AccessToken
+	refreshCredential(ctx, &data, "", &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if adopted {
+		data.Rotated = types.BoolValue(true)
+	}
+	storeInVault(ctx, &data, &resp.Diagnostics)
+	storeInTokenCache(ctx, &data, &resp.Diagnostics)
+	writeDotenvFile(ctx, &data, &resp.Diagnostics)
+	scheduleBackgroundRefresh(ctx, previousToken, &data)
+
+	if err := r.encryptTokens(&data, &stored, nil); err != nil {
+		resp.Diagnostics.AddError(msgStateEncryptionError, err.Error())
+		return
+	}
+	diags = resp.State.Set(ctx, data)
+	resp.Diagnostics.Append(diags...)
+}
+
+func (r *CredentialResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan, state, config model.CredentialResourceData
+
+	diags := req.Plan.Get(ctx, &plan)
+	resp.Diagnostics.Append(diags...)
+	diags = req.State.Get(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+	diags = req.Config.Get(ctx, &config)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	planned := plan
+	for _, decrypted := range []*model.CredentialResourceData{&plan, &state} {
+		if err := r.decryptTokens(decrypted); err != nil {
+			resp.Diagnostics.AddError(msgStateEncryptionError, err.Error())
+			return
+		}
+	}
+	data := mergePlan(state, plan)
+	ctx = r.logContext(ctx, &data)
+	logging.Info(ctx, "updating credential resource")
+	configured := configuredAttributes(config)
+	markConfiguredSources(&data, configured)
+	r.applyProviderDefaultsToData(&data)
+	loadFromVault(ctx, &data, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	adopted := recoverInterruptedRotation(ctx, &data, &resp.Diagnostics)
+	previousToken := data.AccessToken
+	reason := rotationReason(&state, &data, time.Now())
+	replaceGrant := authCategoryChanged(&state, &data)
+	if urlChanged(&state, &data) || replaceGrant {
+		dropStaleRefreshToken(ctx, &data, reason)
+		// A staged, next or superseded token pair was obtained with the previous url or credential
+		discardStaleGrants(ctx, &data, state, &resp.Diagnostics)
+	}
+	refreshCredential(ctx, &data, reason, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if adopted {
+		data.Rotated = types.BoolValue(true)
+	}
+	// The grant obtained with the previous kind of credential is replaced, not kept alongside the new one
+	if replaceGrant && !data.ValidateOnly.ValueBool() && !data.PrunePreviousGrants.ValueBool() {
+		prunePreviousGrant(ctx, &state, &resp.Diagnostics)
+	}
+	storeInVault(ctx, &data, &resp.Diagnostics)
+	storeInTokenCache(ctx, &data, &resp.Diagnostics)
+	writeDotenvFile(ctx, &data, &resp.Diagnostics)
+	scheduleBackgroundRefresh(ctx, previousToken, &data)
+
+	if err := r.encryptTokens(&data, &planned, configured); err != nil {
+		resp.Diagnostics.AddError(msgStateEncryptionError, err.Error())
+		return
+	}
+	diags = resp.State.Set(ctx, data)
+	resp.Diagnostics.Append(diags...)
+}
+
+func (r *CredentialResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var state model.CredentialResourceData
+
+	diags := req.State.Get(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if err := r.decryptTokens(&state); err != nil {
+		resp.Diagnostics.AddError(msgStateEncryptionError, err.Error())
+		return
+	}
+	ctx = r.logContext(ctx, &state)
+	logging.Info(ctx, "deleting credential resource")
+	// Destroying never rotates the token pair. A pair already rotated in the background is revoked rather than adopted.
+	if pair, ok := backgroundRefreshes.take(state.AccessToken); ok {
+		revokeBackgroundPair(ctx, &state, pair, &resp.Diagnostics)
+	}
+	removeDotenvFile(ctx, &state, &resp.Diagnostics)
+	discardStagedPair(ctx, &state, &resp.Diagnostics)
+	discardNextPair(ctx, &state, &resp.Diagnostics)
+	if !state.SupersededAccessToken.IsNull() {
+		revokeSupersededGrant(ctx, &state, &resp.Diagnostics)
+	}
+
+	// Nothing to revoke, i.e. in validate_only mode
+	if state.AccessToken.IsNull() {
+		resp.State.RemoveResource(ctx)
+		logging.Info(ctx, "no access token to revoke")
+		return
+	}
+
+	client := vcertclient.New(ctx, state)
+	err := client.RevokeToken()
 	if err != nil {
 		resp.Diagnostics.AddError("Client Error", fmt.Sprintf("Unable to delete credential resource: %s", err.Error()))
 		return
 	}
+	if state.VerifyRevocation.ValueBool() && !client.ConfirmRevocation() {
+		resp.Diagnostics.AddAttributeWarning(path.Root(fVerifyRevocation), msgCredentialResourceError,
+			"The access token was revoked but TLSPDC still accepts it, or could not be asked. Its grant may still be active, check it in TLSPDC.")
+	}
+
+	if !state.RecoveryFile.IsNull() {
+		removeRecoveryFile(ctx, state.RecoveryFile.ValueString(), &resp.Diagnostics)
+	}
 
 	resp.State.RemoveResource(ctx)
-	tflog.Info(ctx, "successfully revoked access token")
+	logging.Info(ctx, "successfully revoked access token")
 }
 
 func (r *CredentialResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
-	tflog.Info(ctx, "importing credential resource")
-	id := req.ID
+	logging.Info(ctx, "importing credential resource")
+	data, ok := r.importData(ctx, req.ID, &resp.Diagnostics)
+	if !ok {
+		return
+	}
+
+	if err := r.encryptTokens(&data, nil, nil); err != nil {
+		resp.Diagnostics.AddError(msgStateEncryptionError, err.Error())
+		return
+	}
+	diags := resp.State.Set(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+}
 
+// importData returns the resource data of the import ID id, with the provider defaults applied, without contacting
+// TLSPDC. The second value is false when id cannot be imported, the reason being added to diags.
+func (r *CredentialResource) importData(ctx context.Context, id string, diags *diag.Diagnostics) (model.CredentialResourceData, bool) {
 	dataMap, err := getValuesMap(ctx, id)
 	if err != nil {
 		details := fmt.Sprintf("%s: %s", msgImportFail, err.Error())
-		resp.Diagnostics.AddError(msgCredentialResourceError, details)
-		return
+		diags.AddError(msgCredentialResourceError, details)
+		return model.CredentialResourceData{}, false
 	}
-	tflog.Debug(ctx, fmt.Sprintf("field map: %v", dataMap))
-
 	data := model.CredentialResourceData{}
 
+	logLevel := logging.DefaultLevel
+	if val, ok := dataMap[fLogLevel]; ok {
+		logLevel, err = logging.ParseLevel(val)
+		if err != nil {
+			details := fmt.Sprintf("%s: %s", msgImportFail, err.Error())
+			diags.AddError(msgCredentialResourceError, details)
+			return data, false
+		}
+	}
+	data.LogLevel = types.StringValue(logLevel.String())
+	ctx = logging.WithLevel(ctx, logLevel)
+
+	sources := r.importConfigSources(dataMap)
+	r.applyProviderDefaults(ctx, dataMap)
+	applyVcertCredentials(ctx, dataMap)
+	if r.strictSensitive() {
+		ctx = logging.MaskValues(ctx, dataMap[fURL], dataMap[fFallbackURL], dataMap[fClientID], dataMap[fUsername],
+			dataMap[fPassword], dataMap[fP12Password], dataMap[fAccessToken], dataMap[fRefreshToken])
+	}
+
 	msg := "saving attribute to terraform state: [%s]=%s"
+	// The value of a secret is never logged
+	secretMsg := "saving attribute to terraform state: [%s]"
 	if val, ok := dataMap[fURL]; ok {
-		tflog.Info(ctx, fmt.Sprintf(msg, fURL, val))
+		logging.Info(ctx, fmt.Sprintf(msg, fURL, val))
 		data.URL = types.StringValue(val)
 	}
+	if val, ok := dataMap[fFallbackURL]; ok {
+		logging.Info(ctx, fmt.Sprintf(msg, fFallbackURL, val))
+		data.FallbackURL = types.StringValue(val)
+	}
 	if val, ok := dataMap[fUsername]; ok {
-		tflog.Info(ctx, fmt.Sprintf(msg, fUsername, val))
-		data.Username = types.StringValue(val)
+		logging.Info(ctx, fmt.Sprintf(msg, fUsername, val))
+		data.Username = stringOrNull(val)
 	}
 	if val, ok := dataMap[fPassword]; ok {
-		tflog.Info(ctx, fmt.Sprintf(msg, fPassword, val))
-		data.Password = types.StringValue(val)
+		logging.Info(ctx, fmt.Sprintf(secretMsg, fPassword))
+		data.Password = stringOrNull(val)
 	}
 	if val, ok := dataMap[fP12Cert]; ok {
-		tflog.Info(ctx, fmt.Sprintf(msg, fP12Cert, val))
-		data.P12Certificate = types.StringValue(val)
+		logging.Info(ctx, fmt.Sprintf(msg, fP12Cert, val))
+		data.P12Certificate = stringOrNull(val)
 	}
 	if val, ok := dataMap[fP12Password]; ok {
-		tflog.Info(ctx, fmt.Sprintf(msg, fP12Password, val))
-		data.P12Password = types.StringValue(val)
+		logging.Info(ctx, fmt.Sprintf(secretMsg, fP12Password))
+		data.P12Password = stringOrNull(val)
+	}
+	if val, ok := dataMap[fP12PasswordCommand]; ok {
+		logging.Info(ctx, fmt.Sprintf(msg, fP12PasswordCommand, val))
+		data.P12PasswordCommand = stringOrNull(val)
 	}
 	if val, ok := dataMap[fAccessToken]; ok {
-		tflog.Info(ctx, fmt.Sprintf(msg, fAccessToken, val))
-		data.AccessToken = types.StringValue(val)
+		logging.Info(ctx, fmt.Sprintf(secretMsg, fAccessToken))
+		data.AccessToken = stringOrNull(val)
 	}
 	if val, ok := dataMap[fRefreshToken]; ok {
-		tflog.Info(ctx, fmt.Sprintf(msg, fRefreshToken, val))
-		data.RefreshToken = types.StringValue(val)
+		logging.Info(ctx, fmt.Sprintf(secretMsg, fRefreshToken))
+		data.RefreshToken = stringOrNull(val)
+	}
+	if val, ok := dataMap[fScope]; ok {
+		logging.Info(ctx, fmt.Sprintf(msg, fScope, val))
+		data.Scope = types.StringValue(val)
 	}
 	if val, ok := dataMap[fTrustBundle]; ok {
-		tflog.Info(ctx, fmt.Sprintf(msg, fTrustBundle, val))
+		// Fail fast on a broken trust bundle instead of waiting for the first request to TLSPDC
+		if _, err := vcertclient.LoadTrustBundle(val); err != nil {
+			diags.AddAttributeError(path.Root(fTrustBundle), msgCredentialResourceError, fmt.Sprintf("%s: %s", msgImportFail, err.Error()))
+			return data, false
+		}
+		logging.Info(ctx, fmt.Sprintf(msg, fTrustBundle, val))
 		data.TrustBundle = types.StringValue(val)
 	}
+	if val, ok := dataMap[fProxyTrustBundle]; ok {
+		if _, err := vcertclient.LoadTrustBundle(val); err != nil {
+			diags.AddAttributeError(path.Root(fProxyTrustBundle), msgCredentialResourceError, fmt.Sprintf("%s: %s", msgImportFail, err.Error()))
+			return data, false
+		}
+		logging.Info(ctx, fmt.Sprintf(msg, fProxyTrustBundle, val))
+		data.ProxyTrustBundle = types.StringValue(val)
+	}
+	if val, ok := dataMap[fTrustBundleSystemName]; ok {
+		if _, err := vcertclient.LoadSystemTrustBundle(val); err != nil {
+			diags.AddAttributeError(path.Root(fTrustBundleSystemName), msgCredentialResourceError, fmt.Sprintf("%s: %s", msgImportFail, err.Error()))
+			return data, false
+		}
+		logging.Info(ctx, fmt.Sprintf(msg, fTrustBundleSystemName, val))
+		data.TrustBundleSystemName = types.StringValue(val)
+	}
 
 	clientID := defaultClientID
 	if val, ok := dataMap[fClientID]; ok {
 		clientID = val
 	}
-	tflog.Info(ctx, fmt.Sprintf(msg, fClientID, clientID))
+	logging.Info(ctx, fmt.Sprintf(msg, fClientID, clientID))
 	data.ClientID = types.StringValue(clientID)
 
-	refreshWindow := defaultRefreshWindow
-	if val, ok := dataMap[fRefreshWindow]; ok {
-		valInt, err := strconv.Atoi(val)
-		if err != nil {
+	// Numbers, null when not set unless they have a default
+	for _, field := range []struct {
+		name     string
+		value    *types.Int64
+		fallback types.Int64
+		validate func(int64) error
+	}{
+		{fRefreshWindow, &data.RefreshWindow, types.Int64Value(defaultRefreshWindow), nil},
+		{fRefreshWindowPercent, &data.RefreshWindowPercent, types.Int64Null(), validateRefreshWindowPercent},
+		{fExpirationDate, &data.ExpirationDate, types.Int64Null(), nil},
+		{fApplyMarginSeconds, &data.ApplyMarginSeconds, types.Int64Null(), nil},
+		{fBlueGreenGraceSeconds, &data.BlueGreenGraceSeconds, types.Int64Null(), nil},
+		{fMaxIdleConns, &data.MaxIdleConns, types.Int64Null(), nil},
+		{fIdleConnTimeout, &data.IdleConnTimeout, types.Int64Null(), nil},
+		{fMinRemainingForOp, &data.MinRemainingForOp, types.Int64Null(), nil},
+		{fMinGrantedLifetime, &data.MinGrantedLifetime, types.Int64Null(), nil},
+		{fTLSHandshakeTimeout, &data.TLSHandshakeTimeout, types.Int64Null(), nil},
+		{fMaxResponseBytes, &data.MaxResponseBytes, types.Int64Value(vcertclient.DefaultMaxResponseBytes), validateMaxResponseBytes},
+		{fMaxTotalAttempts, &data.MaxTotalAttempts, types.Int64Value(vcertclient.DefaultMaxTotalAttempts), validateMaxTotalAttempts},
+		{fVerifyMaxRetries, &data.VerifyMaxRetries, types.Int64Null(), func(retries int64) error { return validateMaxRetries(fVerifyMaxRetries, retries) }},
+		{fRotateMaxRetries, &data.RotateMaxRetries, types.Int64Null(), func(retries int64) error { return validateMaxRetries(fRotateMaxRetries, retries) }},
+	} {
+		value := field.fallback
+		if val, ok := dataMap[field.name]; ok {
+			valInt, err := strconv.ParseInt(val, 10, 64)
+			if err == nil && field.validate != nil {
+				err = field.validate(valInt)
+			}
+			if err != nil {
+				details := fmt.Sprintf("%s: %s", msgImportFail, err.Error())
+				diags.AddError(msgCredentialResourceError, details)
+				return data, false
+			}
+			value = types.Int64Value(valInt)
+		}
+		if !value.IsNull() {
+			logging.Info(ctx, fmt.Sprintf(msg, field.name, strconv.FormatInt(value.ValueInt64(), 10)))
+		}
+		*field.value = value
+	}
+
+	if val, ok := dataMap[fOAuthPathOverride]; ok {
+		if err = vcertclient.ValidateOAuthPath(val); err != nil {
+			details := fmt.Sprintf("%s: %s", msgImportFail, err.Error())
+			diags.AddError(msgCredentialResourceError, details)
+			return data, false
+		}
+		logging.Info(ctx, fmt.Sprintf(msg, fOAuthPathOverride, val))
+		data.OAuthPathOverride = types.StringValue(val)
+	}
+
+	data.P12CertFilenames = types.ListNull(types.StringType)
+	if val, ok := dataMap[fP12CertFilenames]; ok {
+		// Since the import string is comma-separated, the keystores are separated by semicolons
+		elements := make([]attr.Value, 0)
+		for _, location := range strings.Split(val, ";") {
+			if location = strings.TrimSpace(location); location != "" {
+				elements = append(elements, types.StringValue(location))
+			}
+		}
+		logging.Debug(ctx, fmt.Sprintf(msg, fP12CertFilenames, val))
+		data.P12CertFilenames = types.ListValueMust(types.StringType, elements)
+	}
+
+	if val, ok := dataMap[fClientCertIssuer]; ok {
+		logging.Info(ctx, fmt.Sprintf(msg, fClientCertIssuer, val))
+		data.ClientCertIssuer = types.StringValue(val)
+	}
+
+	data.ExpectedServerSANs = types.ListNull(types.StringType)
+	if val, ok := dataMap[fExpectedServerSANs]; ok {
+		// Since the import string is comma-separated, the expected SANs are separated by semicolons
+		elements := make([]attr.Value, 0)
+		for _, san := range strings.Split(val, ";") {
+			if san = strings.TrimSpace(san); san != "" {
+				elements = append(elements, types.StringValue(san))
+			}
+		}
+		logging.Info(ctx, fmt.Sprintf(msg, fExpectedServerSANs, val))
+		data.ExpectedServerSANs = types.ListValueMust(types.StringType, elements)
+	}
+
+	if val, ok := dataMap[fTokenCacheFile]; ok {
+		logging.Info(ctx, fmt.Sprintf(msg, fTokenCacheFile, val))
+		data.TokenCacheFile = types.StringValue(val)
+	}
+
+	if val, ok := dataMap[fExpirationFormat]; ok {
+		if err = validateExpirationFormat(val); err != nil {
 			details := fmt.Sprintf("%s: %s", msgImportFail, err.Error())
-			resp.Diagnostics.AddError(msgCredentialResourceError, details)
+			diags.AddError(msgCredentialResourceError, details)
+			return data, false
+		}
+		logging.Info(ctx, fmt.Sprintf(msg, fExpirationFormat, val))
+		data.ExpirationFormat = types.StringValue(val)
+	}
+
+	if val, ok := dataMap[fRecoveryFile]; ok {
+		logging.Info(ctx, fmt.Sprintf(msg, fRecoveryFile, val))
+		data.RecoveryFile = types.StringValue(val)
+	}
+
+	rotationPolicy := vcertclient.RotationPolicyPreferRefresh
+	if val, ok := dataMap[fRotationPolicy]; ok {
+		if err = vcertclient.ValidateRotationPolicy(val); err != nil {
+			details := fmt.Sprintf("%s: %s", msgImportFail, err.Error())
+			diags.AddError(msgCredentialResourceError, details)
+			return data, false
+		}
+		rotationPolicy = val
+	}
+	logging.Info(ctx, fmt.Sprintf(msg, fRotationPolicy, rotationPolicy))
+	data.RotationPolicy = types.StringValue(rotationPolicy)
+
+	refreshFailurePolicy := refreshFailureFail
+	if val, ok := dataMap[fRefreshFailurePolicy]; ok {
+		if err = validateRefreshFailurePolicy(val); err != nil {
+			details := fmt.Sprintf("%s: %s", msgImportFail, err.Error())
+			diags.AddError(msgCredentialResourceError, details)
+			return data, false
+		}
+		refreshFailurePolicy = val
+	}
+	logging.Info(ctx, fmt.Sprintf(msg, fRefreshFailurePolicy, refreshFailurePolicy))
+	data.RefreshFailurePolicy = types.StringValue(refreshFailurePolicy)
+
+	planCheckStrategy := planCheckOnline
+	if val, ok := dataMap[fPlanCheckStrategy]; ok {
+		if err = validatePlanCheckStrategy(val); err != nil {
+			details := fmt.Sprintf("%s: %s", msgImportFail, err.Error())
+			diags.AddError(msgCredentialResourceError, details)
+			return data, false
+		}
+		planCheckStrategy = val
+	}
+	logging.Info(ctx, fmt.Sprintf(msg, fPlanCheckStrategy, planCheckStrategy))
+	data.PlanCheckStrategy = types.StringValue(planCheckStrategy)
+
+	expectedTokenType := defaultExpectedTokenType
+	if val, ok := dataMap[fExpectedTokenType]; ok {
+		if err = validateExpectedTokenType(val); err != nil {
+			details := fmt.Sprintf("%s: %s", msgImportFail, err.Error())
+			diags.AddError(msgCredentialResourceError, details)
+			return data, false
+		}
+		expectedTokenType = val
+	}
+	logging.Info(ctx, fmt.Sprintf(msg, fExpectedTokenType, expectedTokenType))
+	data.ExpectedTokenType = types.StringValue(expectedTokenType)
+	data.TokenType = types.StringNull()
+
+	if val, ok := dataMap[fK8sSecretName]; ok {
+		logging.Info(ctx, fmt.Sprintf(msg, fK8sSecretName, val))
+		data.K8sSecretName = stringOrNull(val)
+	}
+	if val, ok := dataMap[fK8sSecretNamespace]; ok {
+		logging.Info(ctx, fmt.Sprintf(msg, fK8sSecretNamespace, val))
+		data.K8sSecretNamespace = types.StringValue(val)
+	}
+	if val, ok := dataMap[fRotationSchedule]; ok {
+		if _, err := parseRotationSchedule(val); err != nil {
+			details := fmt.Sprintf("%s: %s", msgImportFail, err.Error())
+			diags.AddError(msgCredentialResourceError, details)
+			return data, false
+		}
+		logging.Info(ctx, fmt.Sprintf(msg, fRotationSchedule, val))
+		data.RotationSchedule = types.StringValue(val)
+	}
+	if val, ok := dataMap[fTraceFile]; ok {
+		logging.Info(ctx, fmt.Sprintf(msg, fTraceFile, val))
+		data.TraceFile = types.StringValue(val)
+	}
+
+	// Flags, false when not set
+	for _, field := range []struct {
+		name  string
+		value *types.Bool
+	}{
+		{fP12PasswordFromSidecar, &data.P12PasswordFromSidecar},
+		{fVaultWriteBack, &data.VaultWriteBack},
+		{fDotenvIncludeURL, &data.DotenvIncludeURL},
+		{fDotenvRefreshToken, &data.DotenvRefreshToken},
+		{fStagedRotation, &data.StagedRotation},
+		{fBlueGreenRotation, &data.BlueGreenRotation},
+		{fPrunePreviousGrants, &data.PrunePreviousGrants},
+		{fVerifyRevocation, &data.VerifyRevocation},
+		{fBackgroundRefresh, &data.BackgroundRefresh},
+		{fTrustBundleSystemRoots, &data.TrustBundleSystemRoots},
+		{fInsecureSkipVerify, &data.InsecureSkipVerify},
+		{fRequireClientCertTLS, &data.RequireClientCertTLS},
+		{fOfflineAccess, &data.OfflineAccess},
+		{fValidateOnly, &data.ValidateOnly},
+	} {
+		enabled := false
+		if val, ok := dataMap[field.name]; ok {
+			valBool, err := strconv.ParseBool(val)
+			if err != nil {
+				details := fmt.Sprintf("%s: %s", msgImportFail, err.Error())
+				diags.AddError(msgCredentialResourceError, details)
+				return data, false
+			}
+			enabled = valBool
+		}
+		logging.Info(ctx, fmt.Sprintf(msg, field.name, strconv.FormatBool(enabled)))
+		*field.value = types.BoolValue(enabled)
+	}
+
+	if val, ok := dataMap[fVaultTokenPath]; ok {
+		logging.Info(ctx, fmt.Sprintf(msg, fVaultTokenPath, val))
+		data.VaultTokenPath = types.StringValue(val)
+	}
+
+	if val, ok := dataMap[fDotenvOutputFile]; ok {
+		logging.Info(ctx, fmt.Sprintf(msg, fDotenvOutputFile, val))
+		data.DotenvOutputFile = types.StringValue(val)
+	}
+
+	if missing := missingImportAttributes(&data); len(missing) > 0 {
+		details := fmt.Sprintf("%s: the import string does not allow any operation, missing %s", msgImportFail, strings.Join(missing, "; "))
+		diags.AddError(msgCredentialResourceError, details)
+		return data, false
+	}
+
+	data.Rotated = types.BoolValue(false)
+	data.RotationCount = types.Int64Value(0)
+	setResourceIdentity(&data)
+	setExpirationFormatted(&data)
+	setNeverExpires(&data)
+	setEffectiveRefreshWindow(&data)
+	data.GrantedScopes = types.ListNull(types.StringType)
+	setMetadataJSON(&data)
+	data.TokenStatus = types.ObjectNull(tokenStatusAttributeTypes)
+	data.AuthAttempts = types.ListNull(authAttemptType)
+	data.SelectedAuthMethod = types.StringNull()
+	data.UsedFallbackMethod = types.BoolNull()
+	setConfigSources(&data, sources)
+	data.VCertVersion = stringOrNull(vcertclient.SDKVersion())
+
+	return data, true
+}
+
+// applyProviderDefaults fills the values missing from the import ID with the ones from the provider configuration or
+// its environment variables
+func (r *CredentialResource) applyProviderDefaults(ctx context.Context, dataMap map[string]string) {
+	if r.providerData == nil {
+		return
+	}
+
+	defaults := map[string]types.String{
+		fURL:         r.providerData.URL,
+		fTrustBundle: r.providerData.TrustBundle,
+		fClientID:    r.providerData.ClientID,
+	}
+	for key, value := range defaults {
+		if _, ok := dataMap[key]; ok || value.IsNull() {
+			continue
+		}
+		logging.Info(ctx, fmt.Sprintf("attribute [%s] not found in import ID, using provider default", key))
+		dataMap[key] = value.ValueString()
+	}
+}
+
+// applyVcertCredentials fills an import ID holding no token nor credential with the ones of the environment variables
+// of the vcert CLI: VCERT_TOKEN as access token, VCERT_USER and VCERT_PASSWORD as username and password
+func applyVcertCredentials(ctx context.Context, dataMap map[string]string) {
+	for _, key := range []string{fAccessToken, fRefreshToken, fVaultTokenPath, fP12Cert, fK8sSecretName, fUsername, fPassword} {
+		if _, ok := dataMap[key]; ok {
 			return
 		}
-		refreshWindow = valInt
 	}
-	tflog.Info(ctx, fmt.Sprintf(msg, fRefreshWindow, fmt.Sprintf("%d", refreshWindow)))
-	data.RefreshWindow = types.Int64Value(int64(refreshWindow))
 
-	tflog.Debug(ctx, fmt.Sprintf("data struct: %v", data))
-	diags := resp.State.Set(ctx, &data)
-	resp.Diagnostics.Append(diags...)
-	if resp.Diagnostics.HasError() {
+	for key, env := range map[string]string{fAccessToken: envVcertToken, fUsername: envVcertUser, fPassword: envVcertPassword} {
+		if value, ok := lookupEnv(env); ok {
+			logging.Info(ctx, fmt.Sprintf("attribute [%s] not found in import ID, using the %s environment variable", key, env))
+			dataMap[key] = value
+		}
+	}
+}
+
+// applyProviderDefaultsToData sets the connection attributes missing from data with the configuration of the provider
+// instance (or alias) the resource belongs to, recording their source in config_source
+func (r *CredentialResource) applyProviderDefaultsToData(data *model.CredentialResourceData) {
+	if r.providerData == nil {
 		return
 	}
+
+	sources := configSources(data)
+	defaults := []struct {
+		name  string
+		value *types.String
+		def   types.String
+	}{
+		{fURL, &data.URL, r.providerData.URL},
+		{fTrustBundle, &data.TrustBundle, r.providerData.TrustBundle},
+		{fClientID, &data.ClientID, r.providerData.ClientID},
+	}
+	for _, d := range defaults {
+		if !d.value.IsNull() || d.def.IsNull() {
+			continue
+		}
+		*d.value = d.def
+		sources[d.name] = r.providerSource(d.name)
+	}
+	setConfigSources(data, sources)
+}
+
+// missingImportAttributes lists what the imported attributes lack to verify or rotate a token: a url, plus a token or
+// a primary credential. An empty list is returned when the import is sufficient.
+func missingImportAttributes(data *model.CredentialResourceData) []string {
+	var missing []string
+	if data.URL.IsNull() || data.URL.ValueString() == "" {
+		missing = append(missing, fmt.Sprintf("%s (or the provider url)", fURL))
+	}
+
+	hasToken := !data.AccessToken.IsNull() || !data.RefreshToken.IsNull() || !data.VaultTokenPath.IsNull()
+	hasP12 := !data.K8sSecretName.IsNull() || (!data.P12Certificate.IsNull() &&
+		(!data.P12Password.IsNull() || !data.P12PasswordCommand.IsNull() || data.P12PasswordFromSidecar.ValueBool()))
+	hasUser := !data.Username.IsNull() && !data.Password.IsNull()
+	if hasToken || hasP12 || hasUser {
+		return missing
+	}
+
+	switch {
+	case !data.P12Certificate.IsNull():
+		missing = append(missing, fmt.Sprintf("%s, %s or %s to go with %s", fP12Password, fP12PasswordCommand, fP12PasswordFromSidecar, fP12Cert))
+	case !data.Username.IsNull():
+		missing = append(missing, fmt.Sprintf("%s to go with %s", fPassword, fUsername))
+	case !data.Password.IsNull():
+		missing = append(missing, fmt.Sprintf("%s to go with %s", fUsername, fPassword))
+	default:
+		missing = append(missing, fmt.Sprintf("a token (%s, %s or %s) or a primary credential (%s with its password, or %s and %s)",
+			fAccessToken, fRefreshToken, fVaultTokenPath, fP12Cert, fUsername, fPassword))
+	}
+	return missing
+}
+
+// import string separators
+const (
+	importSeparator      = ","
+	importQuerySeparator = "&"
+	// importSeparatorHint starts an import string choosing its own separator, the character following the hint
+	importSeparatorHint = "sep="
+)
+
+// importSeparatorOf returns the separator of the attributes of the import string and the attributes themselves. The
+// separator is a comma, unless the string starts with a separator hint (sep=; followed by the attributes separated by
+// semicolons) or is in URL query form (key=value&key2=value2, without any comma).
+func importSeparatorOf(values string) (separator string, attributes string) {
+	if strings.HasPrefix(values, importSeparatorHint) {
+		hinted := strings.TrimPrefix(values, importSeparatorHint)
+		if separator, size := utf8.DecodeRuneInString(hinted); size > 0 && separator != '=' {
+			return string(separator), hinted[size:]
+		}
+	}
+
+	if strings.Contains(values, importQuerySeparator) && !strings.Contains(values, importSeparator) {
+		queryForm := true
+		for _, item := range strings.Split(values, importQuerySeparator) {
+			if !strings.Contains(item, "=") {
+				queryForm = false
+				break
+			}
+		}
+		if queryForm {
+			return importQuerySeparator, values
+		}
+	}
+	return importSeparator, values
 }
 
 func getValuesMap(ctx context.Context, values string) (map[string]string, error) {
+	if strings.HasPrefix(values, importTokenPrefix) {
+		return tokenValuesMap(ctx, strings.TrimPrefix(values, importTokenPrefix), time.Now())
+	}
 
 	dict := make(map[string]string)
 
-	list := strings.Split(values, ",")
+	separator, attributes := importSeparatorOf(values)
+	if separator != importSeparator {
+		logging.Debug(ctx, fmt.Sprintf("import string separator: %s", separator))
+	}
+	list := strings.Split(attributes, separator)
 	for _, item := range list {
 		key, value, found := strings.Cut(item, "=")
 		if !found {
 			msg := fmt.Sprintf("no separator found on value: %s", item)
-			tflog.Info(ctx, msg)
+			logging.Info(ctx, msg)
 			return nil, errors.New(msg)
 		}
-		tflog.Debug(ctx, fmt.Sprintf("credential field found: %s = %s", key, value))
+		logging.Debug(ctx, fmt.Sprintf("credential field found: %s", key))
 		dict[key] = value
 	}
 
 	return dict, nil
 }
 
-func rotateToken(ctx context.Context, data *model.CredentialResourceData) error {
+// refreshCredential verifies the access token held by data and rotates the token pair when it is missing, expired or
+// within the refresh window, or right away when forceReason is set. The rotated_on_last_apply attribute is always reset
+// to reflect the outcome.
+func refreshCredential(ctx context.Context, data *model.CredentialResourceData, forceReason string, diags *diag.Diagnostics) {
+	data.Rotated = types.BoolValue(false)
+	data.VCertVersion = stringOrNull(vcertclient.SDKVersion())
+	setResourceIdentity(data)
+	defer warnClientCertificateExpiration(data, diags)
+	defer warnUnsupportedServerVersion(data, diags)
+	defer func() { setTokenStatus(data, time.Now()) }()
+	defer setExpirationFormatted(data)
+	defer setNeverExpires(data)
+	defer setEffectiveRefreshWindow(data)
+	defer setMetadataJSON(data)
+
+	if data.ValidateOnly.ValueBool() {
+		validateCredentials(ctx, data, diags)
+		return
+	}
+
+	revokeDueSupersededGrant(ctx, data, time.Now(), diags)
+
+	if forceReason != "" {
+		logging.Info(ctx, fmt.Sprintf("%s, retrieving a new token pair", forceReason))
+		// The next token pair was provisioned for the pair being replaced
+		discardNextPair(ctx, data, diags)
+		err := rotateToken(ctx, data, diags)
+		if err != nil {
+			reportClientError(ctx, err, diags)
+		}
+		return
+	}
+
+	if adoptBackgroundRotation(ctx, data) {
+		return
+	}
+
+	// A cached token still valid is trusted as is, e.g. to plan offline
+	if useTokenCache(ctx, data, time.Now()) {
+		return
+	}
+	if trustedOffline(ctx, data, time.Now()) {
+		return
+	}
+
+	// No access token, request a new pair right away
+	if data.AccessToken.IsNull() {
+		logging.Info(ctx, "no access token, retrieving a new token pair")
+		err := rotateToken(ctx, data, diags)
+		if err != nil {
+			reportClientError(ctx, err, diags)
+		}
+		return
+	}
+
+	// Got access token, check expiration
+	client := vcertclient.New(ctx, *data)
+	validity, err := client.VerifyToken()
+	if err != nil {
+		if errors.Is(err, vcertclient.ErrServerCertificateUntrusted) || errors.Is(err, vcertclient.ErrClientCertificateExpired) {
+			reportClientError(ctx, err, diags)
+			return
+		}
+		logging.Error(ctx, fmt.Sprintf("client error: %s", err.Error()))
+		diags.AddError("Client Error", fmt.Sprintf("Unable to verify token expiration, got error: %s", err))
+		return
+	}
+
+	data.ActiveURL = types.StringValue(client.ActiveURL())
+	setEffectiveURL(data, client)
+	warnServerNotices(client.Notices(), diags)
+
+	expired := validity != vcertclient.TokenValid
+	// The expiration date is authoritative when the token cannot be introspected, e.g. when it was set on import
+	if validity == vcertclient.TokenUnknown && neverExpires(data) {
+		logging.Warn(ctx, "unable to introspect access token, relying on its lack of expiration date")
+		expired = false
+	} else if validity == vcertclient.TokenUnknown && !data.ExpirationDate.IsNull() && data.ExpirationDate.ValueInt64() > time.Now().Unix() {
+		logging.Warn(ctx, fmt.Sprintf("unable to introspect access token, relying on its expiration date %s",
+			time.Unix(data.ExpirationDate.ValueInt64(), 0).UTC().Format(time.RFC3339)))
+		expired = false
+	}
+
+	// The grant was revoked in TLSPDC, its refresh token is no longer valid either
+	if validity == vcertclient.TokenRevoked {
+		expiresOn := "it expires on " + time.Unix(data.ExpirationDate.ValueInt64(), 0).UTC().Format(time.RFC3339)
+		if neverExpires(data) {
+			expiresOn = "it never expires"
+		}
+		logging.Warn(ctx, fmt.Sprintf("access token rejected by TLSPDC although %s, retrieving a new token pair", expiresOn))
+		diags.AddWarning(msgCredentialResourceError,
+			fmt.Sprintf("The access token was rejected by TLSPDC although %s, it was likely revoked out-of-band. Retrieving a new token pair", expiresOn))
+		dropStaleRefreshToken(ctx, data, "access token revoked")
+		err = rotateToken(ctx, data, diags)
+		if err != nil {
+			reportClientError(ctx, err, diags)
+		}
+		return
+	}
+
+	// If token already expired, request new pair
+	if expired {
+		logging.Info(ctx, "access token expired, retrieving a new token pair")
+		err = rotateDueToken(ctx, data, true, diags)
+		if err != nil {
+			reportClientError(ctx, err, diags)
+		}
+		return
+	}
+
+	// If token not expired, check expiration date is on refresh window. If so, request new pair
+	now := time.Now()
+	// Only the refresh window rotation is skipped on clock skew, the other checks still apply
+	skewed := false
+	if justEnteredRefreshWindow(data, now) {
+		if serverTime, ok := client.ServerTime(); ok && !withinRefreshWindow(data, serverTime) {
+			skew := now.Sub(serverTime).Round(time.Second)
+			logging.Warn(ctx, fmt.Sprintf("local clock is %s ahead of TLSPDC, access token not within refresh window yet according to TLSPDC", skew))
+			diags.AddWarning(msgCredentialResourceError,
+				fmt.Sprintf("Rotation skipped: the local clock is %s ahead of TLSPDC, which does not consider the access token within its refresh window yet", skew))
+			skewed = true
+		}
+	}
+	if withinRefreshWindow(data, now) && !skewed {
+		logging.Info(ctx, "access token expiration within refresh window, retrieving a new token pair")
+		err = rotateDueToken(ctx, data, false, diags)
+		if err != nil {
+			reportRotationFailure(ctx, data, err, diags)
+		}
+		return
+	}
+
+	if lacksOperationHeadroom(data, now) {
+		logging.Info(ctx, fmt.Sprintf("access token expires in less than %s, retrieving a new token pair", fMinRemainingForOp))
+		err = rotateDueToken(ctx, data, false, diags)
+		if err != nil {
+			reportRotationFailure(ctx, data, err, diags)
+		}
+		return
+	}
+
+	// The version is otherwise retrieved on rotation, query it once for tokens set on import
+	if data.TPPVersion.IsNull() {
+		version, _ := client.ServerDetails(data.AccessToken.ValueString())
+		data.TPPVersion = stringOrNull(version)
+	}
+
+	// Token is valid, nothing to do here
+	logging.Info(ctx, "access token valid")
+}
+
+// validateCredentials requests a new token pair to confirm the credentials work, then revokes it immediately. The
+// minted tokens are never stored in data.
+func validateCredentials(ctx context.Context, data *model.CredentialResourceData, diags *diag.Diagnostics) {
+	logging.Info(ctx, "validate only mode, requesting a token pair to validate the credentials")
+	// The refresh tokens are left out, validating must not rotate the grant they belong to
+	credentials := *data
+	credentials.RefreshToken = types.StringNull()
+	credentials.PendingRefreshToken = types.StringNull()
+	client := vcertclient.New(ctx, credentials)
+	clientResp, err := client.RequestNewTokenPair()
+	if err != nil {
+		logging.Error(ctx, fmt.Sprintf("client error: %s", err.Error()))
+		diags.AddError("Client Error", fmt.Sprintf("Unable to validate credentials, got error: %s", err.Error()))
+		return
+	}
+	warnServerNotices(clientResp.Notices, diags)
+
+	// The minted grant is revoked with its own access token, its expiration telling a revoked token from an expired one
+	minted := credentials
+	minted.AccessToken = types.StringValue(clientResp.AccessToken)
+	minted.RefreshToken = types.StringNull()
+	minted.ExpirationDate = types.Int64Value(clientResp.Expires)
+	inMemoryGrants.hold(minted)
+	err = vcertclient.New(ctx, minted).RevokeToken()
+	if err != nil {
+		logging.Error(ctx, fmt.Sprintf("client error: %s", err.Error()))
+		diags.AddError("Client Error", fmt.Sprintf("Credentials are valid but the token used to validate them could not be revoked: %s", err.Error()))
+		return
+	}
+
+	inMemoryGrants.release(minted.AccessToken)
+	data.ActiveURL = types.StringValue(client.ActiveURL())
+	setEffectiveURL(data, client)
+	logging.Info(ctx, "credentials validated, token revoked")
+}
+
+// rotateDueToken rotates the token pair of data, which expired or is due for rotation, with blue_green_rotation when
+// enabled
+func rotateDueToken(ctx context.Context, data *model.CredentialResourceData, expired bool, diags *diag.Diagnostics) error {
+	if data.BlueGreenRotation.ValueBool() {
+		return blueGreenRotation(ctx, data, expired, time.Now(), diags)
+	}
+	return rotateToken(ctx, data, diags)
+}
+
+func rotateToken(ctx context.Context, data *model.CredentialResourceData, diags *diag.Diagnostics) error {
+	if !data.PendingAccessToken.IsNull() {
+		if data.CommitRotation.ValueBool() {
+			commitStagedRotation(ctx, data)
+			return nil
+		}
+		if data.StagedRotation.ValueBool() {
+			logging.Info(ctx, fmt.Sprintf("token pair already staged, waiting for %s", fCommitRotation))
+			return nil
+		}
+	}
+
+	previous := *data
 	client := vcertclient.New(ctx, *data)
 	clientResp, err := client.RequestNewTokenPair()
 	if err != nil {
 		return err
 	}
+	if err = normalizeTokenPair(clientResp); err != nil {
+		return err
+	}
+
+	for _, requirement := range []struct {
+		attribute string
+		minimum   types.Int64
+	}{
+		{fMinGrantedLifetime, data.MinGrantedLifetime},
+		{fMinRemainingForOp, data.MinRemainingForOp},
+	} {
+		// A token that never expires satisfies any lifetime
+		if requirement.minimum.IsNull() || clientResp.Expires <= 0 {
+			continue
+		}
+		lifetime := clientResp.Expires - time.Now().Unix()
+		if minimum := requirement.minimum.ValueInt64(); lifetime < minimum {
+			revokeRejectedPair(ctx, data, clientResp, "short-lived")
+			return fmt.Errorf("TLSPDC granted a token valid for %d seconds, less than the %d seconds required by %s; check the token validity of the API integration [%s]",
+				lifetime, minimum, requirement.attribute, data.ClientID.ValueString())
+		}
+	}
+
+	if err = checkTokenType(ctx, data, clientResp); err != nil {
+		return err
+	}
+
+	warnServerNotices(clientResp.Notices, diags)
+	staged := data.StagedRotation.ValueBool() && !data.CommitRotation.ValueBool()
+	writeRecoveryFile(ctx, data, clientResp, staged, diags)
+
+	if staged {
+		logging.Info(ctx, fmt.Sprintf("staging new token pair until %s is set", fCommitRotation))
+		data.PendingAccessToken = types.StringValue(clientResp.AccessToken)
+		data.PendingRefreshToken = types.StringValue(clientResp.RefreshToken)
+		data.PendingExpiration = types.Int64Value(clientResp.Expires)
+		data.PendingIssuedAt = types.Int64Value(time.Now().Unix())
+		setAuthAttempts(data, client.AuthAttempts())
+		setSelectedAuthMethod(data, clientResp)
+		data.LastRefreshWarning = stringOrNull(clientResp.Warning)
+		return nil
+	}
 
 	data.AccessToken = types.StringValue(clientResp.AccessToken)
 	data.ExpirationDate = types.Int64Value(clientResp.Expires)
 	data.RefreshToken = types.StringValue(clientResp.RefreshToken)
+	data.IssuedAt = types.Int64Value(time.Now().Unix())
+	data.ActiveURL = types.StringValue(client.ActiveURL())
+	setEffectiveURL(data, client)
+	if expiration := client.ClientCertificateExpiration(); !expiration.IsZero() {
+		data.ClientCertExpiration = types.Int64Value(expiration.Unix())
+	}
+	data.GrantID = stringOrNull(clientResp.GrantID)
+	setAuthAttempts(data, client.AuthAttempts())
+	setSelectedAuthMethod(data, clientResp)
+	data.LastRefreshWarning = stringOrNull(clientResp.Warning)
+	if clientResp.OfflineAccessDenied {
+		diags.AddAttributeWarning(path.Root(fOfflineAccess), msgCredentialResourceError,
+			"offline access is not supported by TLSPDC, the token pair was requested without it")
+	}
+	data.RefreshUntil = types.Int64Null()
+	if clientResp.RefreshUntil > 0 {
+		data.RefreshUntil = types.Int64Value(clientResp.RefreshUntil)
+	}
+	version, identity := client.ServerDetails(clientResp.AccessToken)
+	data.TPPVersion = stringOrNull(version)
+	data.TokenIdentity = stringOrNull(identity)
+	setGrantedScopes(data, clientResp.Scope)
+	data.Rotated = types.BoolValue(true)
+	data.RotationCount = types.Int64Value(data.RotationCount.ValueInt64() + 1)
+
+	if data.PrunePreviousGrants.ValueBool() && clientResp.Method != vcertclient.MethodRefreshToken {
+		prunePreviousGrant(ctx, &previous, diags)
+	}
 
 	return nil
 }
 
-func reportClientError(ctx context.Context, err error, resp *resource.ReadResponse) {
-	tflog.Error(ctx, fmt.Sprintf("client error: %s", err.Error()))
-	resp.Diagnostics.AddError("Client Error", fmt.Sprintf("Unable to rotate token, got error: %s", err.Error()))
+// commitStagedRotation promotes the staged token pair to the active one
+func commitStagedRotation(ctx context.Context, data *model.CredentialResourceData) {
+	logging.Info(ctx, "committing staged token pair")
+	data.AccessToken = data.PendingAccessToken
+	data.RefreshToken = data.PendingRefreshToken
+	data.ExpirationDate = data.PendingExpiration
+	data.IssuedAt = data.PendingIssuedAt
+	data.TokenIdentity = types.StringNull()
+	data.PendingAccessToken = types.StringNull()
+	data.PendingRefreshToken = types.StringNull()
+	data.PendingExpiration = types.Int64Null()
+	data.PendingIssuedAt = types.Int64Null()
+	data.Rotated = types.BoolValue(true)
+	data.RotationCount = types.Int64Value(data.RotationCount.ValueInt64() + 1)
+}
+
+// discardStagedPair revokes the staged token pair, e.g. when it was obtained with a url or credential no longer used
+func discardStagedPair(ctx context.Context, data *model.CredentialResourceData, diags *diag.Diagnostics) {
+	if data.PendingAccessToken.IsNull() {
+		return
+	}
+	logging.Info(ctx, "revoking the staged token pair")
+	staged := *data
+	staged.AccessToken = data.PendingAccessToken
+	staged.RefreshToken = data.PendingRefreshToken
+	staged.ExpirationDate = data.PendingExpiration
+	if err := vcertclient.New(ctx, staged).RevokeToken(); err != nil {
+		diags.AddAttributeWarning(path.Root(fPendingAccessToken), msgCredentialResourceError,
+			fmt.Sprintf("unable to revoke the discarded staged token pair, its grant remains valid until it expires: %s", err.Error()))
+	}
+	data.PendingAccessToken = types.StringNull()
+	data.PendingRefreshToken = types.StringNull()
+	data.PendingExpiration = types.Int64Null()
+	data.PendingIssuedAt = types.Int64Null()
+}
+
+// discardStaleGrants revokes the staged, next and superseded token pairs of data and forgets them. They are revoked
+// with the url and credential of previous, the ones they were obtained with before data changed them.
+func discardStaleGrants(ctx context.Context, data *model.CredentialResourceData, previous model.CredentialResourceData, diags *diag.Diagnostics) {
+	stale := previous
+	stale.PendingAccessToken, stale.PendingRefreshToken, stale.PendingExpiration, stale.PendingIssuedAt = data.PendingAccessToken, data.PendingRefreshToken, data.PendingExpiration, data.PendingIssuedAt
+	stale.NextAccessToken, stale.NextRefreshToken, stale.NextExpiration, stale.NextIssuedAt = data.NextAccessToken, data.NextRefreshToken, data.NextExpiration, data.NextIssuedAt
+	stale.SupersededAccessToken, stale.SupersededRevokeAt = data.SupersededAccessToken, data.SupersededRevokeAt
+	discardStagedPair(ctx, &stale, diags)
+	discardNextPair(ctx, &stale, diags)
+	if !stale.SupersededAccessToken.IsNull() {
+		revokeSupersededGrant(ctx, &stale, diags)
+	}
+
+	data.PendingAccessToken, data.PendingRefreshToken, data.PendingExpiration, data.PendingIssuedAt = stale.PendingAccessToken, stale.PendingRefreshToken, stale.PendingExpiration, stale.PendingIssuedAt
+	data.NextAccessToken, data.NextRefreshToken, data.NextExpiration, data.NextIssuedAt = stale.NextAccessToken, stale.NextRefreshToken, stale.NextExpiration, stale.NextIssuedAt
+	data.SupersededAccessToken, data.SupersededRevokeAt = stale.SupersededAccessToken, stale.SupersededRevokeAt
+}
+
+// prunePreviousGrant revokes the grant held by previous, the data of the resource before a primary credential got a new
+// grant. Tokens shared through Vault are left alone since other consumers may still rely on them.
+func prunePreviousGrant(ctx context.Context, previous *model.CredentialResourceData, diags *diag.Diagnostics) {
+	if previous.AccessToken.IsNull() {
+		return
+	}
+	if !previous.VaultTokenPath.IsNull() {
+		logging.Info(ctx, fmt.Sprintf("previous grant is shared through %s, not pruning it", fVaultTokenPath))
+		return
+	}
+
+	logging.Info(ctx, "revoking previous grant")
+	if err := vcertclient.New(ctx, *previous).RevokeToken(); err != nil {
+		diags.AddAttributeWarning(path.Root(fPrunePreviousGrants), msgCredentialResourceError,
+			fmt.Sprintf("unable to revoke the previous grant: %s", err.Error()))
+	}
+}
+
+// setGrantedScopes stores the scope granted to the access token and whether it covers the requested scope. Both are
+// null when the granted scope is unknown.
+func setGrantedScopes(data *model.CredentialResourceData, grantedScope string) {
+	if grantedScope == "" {
+		data.GrantedScopes = types.ListNull(types.StringType)
+		data.ScopeSatisfied = types.BoolNull()
+		return
+	}
+
+	granted := parseScope(grantedScope)
+	elements := make([]attr.Value, 0, len(granted))
+	for _, entry := range granted {
+		elements = append(elements, types.StringValue(entry))
+	}
+	data.GrantedScopes = types.ListValueMust(types.StringType, elements)
+
+	requestedScope := defaultScope
+	if !data.Scope.IsNull() && data.Scope.ValueString() != "" {
+		requestedScope = data.Scope.ValueString()
+	}
+	data.ScopeSatisfied = types.BoolValue(scopeSatisfied(parseScope(requestedScope), granted))
+}
+
+// warnClientCertificateExpiration adds a warning when the client certificate used to authenticate is about to expire,
+// as token rotation will stop working once it does
+func warnClientCertificateExpiration(data *model.CredentialResourceData, diags *diag.Diagnostics) {
+	if data.ClientCertExpiration.IsNull() || data.ClientCertExpiration.IsUnknown() {
+		return
+	}
+
+	expiration := time.Unix(data.ClientCertExpiration.ValueInt64(), 0)
+	if time.Until(expiration) < vcertclient.ClientCertificateExpirationWarning {
+		diags.AddAttributeWarning(path.Root(fClientCertExpiration), "Client certificate expiring",
+			fmt.Sprintf("The client certificate used to authenticate to TLSPDC expires on %s. Renew it before then to keep rotating tokens.", expiration.UTC().Format(time.RFC3339)))
+	}
+}
+
+// setEffectiveURL records the URL the last request of client was actually sent to. The previous value is kept when no
+// request was sent, e.g. when the access token was verified recently.
+func setEffectiveURL(data *model.CredentialResourceData, client *vcertclient.Client) {
+	if effectiveURL := client.EffectiveURL(); effectiveURL != "" {
+		data.EffectiveURL = types.StringValue(effectiveURL)
+	}
+}
+
+// warnServerNotices adds a warning for each non-fatal notice returned by TLSPDC, e.g. the password of the service
+// account about to expire, which would break the next rotations
+func warnServerNotices(notices []string, diags *diag.Diagnostics) {
+	for _, notice := range notices {
+		diags.AddWarning("TLSPDC Notice", fmt.Sprintf("TLSPDC returned the following notice: %s", notice))
+	}
+}
+
+// mergePlan returns a copy of state where every attribute with a known value in plan takes the planned value. Computed
+// attributes not set in the configuration are unknown in the plan and keep their stored value.
+func mergePlan(state, plan model.CredentialResourceData) model.CredentialResourceData {
+	merged := state
+	mergedValue := reflect.ValueOf(&merged).Elem()
+	planValue := reflect.ValueOf(plan)
+	for i := 0; i < planValue.NumField(); i++ {
+		value, ok := planValue.Field(i).Interface().(attr.Value)
+		if !ok || value.IsUnknown() {
+			continue
+		}
+		mergedValue.Field(i).Set(planValue.Field(i))
+	}
+	return merged
+}
+
+// logContext returns a copy of ctx filtering the provider logs with the log_level of data, and masking its
+// identifying attributes when the provider is in strict_sensitive mode. An invalid log_level is ignored.
+func (r *CredentialResource) logContext(ctx context.Context, data *model.CredentialResourceData) context.Context {
+	if !data.LogLevel.IsNull() && !data.LogLevel.IsUnknown() {
+		if level, err := logging.ParseLevel(data.LogLevel.ValueString()); err == nil {
+			ctx = logging.WithLevel(ctx, level)
+		}
+	}
+
+	if r.strictSensitive() {
+		ctx = logging.MaskValues(ctx, data.URL.ValueString(), data.FallbackURL.ValueString(), data.ClientID.ValueString(),
+			data.Username.ValueString(), data.Password.ValueString(), data.P12Password.ValueString(),
+			data.AccessToken.ValueString(), data.RefreshToken.ValueString())
+	}
+	return ctx
+}
+
+// strictSensitive reports whether the provider is configured to redact identifying attributes from the logs
+func (r *CredentialResource) strictSensitive() bool {
+	return r.providerData != nil && r.providerData.StrictSensitive.ValueBool()
+}
+
+// validateMaxResponseBytes checks that a response body can be read at all
+func validateMaxResponseBytes(maxBytes int64) error {
+	if maxBytes < 1 {
+		return fmt.Errorf("%s must be at least 1, got %d", fMaxResponseBytes, maxBytes)
+	}
+	return nil
+}
+
+// validateMaxTotalAttempts checks that at least one request can be sent to TLSPDC
+func validateMaxTotalAttempts(attempts int64) error {
+	if attempts < 1 {
+		return fmt.Errorf("%s must be at least 1, got %d", fMaxTotalAttempts, attempts)
+	}
+	return nil
+}
+
+// validateMaxRetries checks that the retries of the name attribute are not negative
+func validateMaxRetries(name string, retries int64) error {
+	if retries < 0 {
+		return fmt.Errorf("%s must not be negative, got %d", name, retries)
+	}
+	return nil
+}
+
+// stringOrNull returns a null string for empty values
+func stringOrNull(value string) types.String {
+	if value == "" {
+		return types.StringNull()
+	}
+	return types.StringValue(value)
+}
+
+// configuredAttributes returns the names of the attributes with a value in config
+func configuredAttributes(config model.CredentialResourceData) map[string]bool {
+	configured := make(map[string]bool)
+	configValue := reflect.ValueOf(config)
+	configType := configValue.Type()
+	for i := 0; i < configValue.NumField(); i++ {
+		value, ok := configValue.Field(i).Interface().(attr.Value)
+		if ok && !value.IsNull() {
+			configured[configType.Field(i).Tag.Get("tfsdk")] = true
+		}
+	}
+	return configured
+}
+
+func reportClientError(ctx context.Context, err error, diags *diag.Diagnostics) {
+	logging.Error(ctx, fmt.Sprintf("client error: %s", err.Error()))
+	switch {
+	case errors.Is(err, vcertclient.ErrNoAuthMethod):
+		diags.AddError("Credential Cannot Be Refreshed",
+			"The token pair must be rotated but the resource holds no refresh token, client certificate or username/password to request a new one. "+
+				"Re-import the resource with a primary credential (p12_cert_filename and its password, or username and password) so that it can be refreshed again.")
+	case errors.Is(err, vcertclient.ErrClientCertificateExpired):
+		diags.AddAttributeError(path.Root(fP12Cert), "Client Certificate Expired",
+			fmt.Sprintf("The client certificate of the PKCS#12 keystore cannot be used to authenticate to TLSPDC, the failure is not caused by TLSPDC "+
+				"or its certificate. Renew the client certificate and re-import the resource with the new keystore, or fix the clock of the host "+
+				"when the certificate should be valid. Got error: %s", err.Error()))
+	case errors.Is(err, vcertclient.ErrCredentialsRejected):
+		diags.AddError("Credential Cannot Be Refreshed",
+			fmt.Sprintf("The token pair must be rotated but every authentication method of the resource was rejected, e.g. the refresh token expired. "+
+				"Retrying will not help: re-import the resource with a valid refresh token or a primary credential (p12_cert_filename and its password, or username and password). Got error: %s", err.Error()))
+	case errors.Is(err, vcertclient.ErrMaintenanceMode):
+		diags.AddError("TLSPDC In Maintenance",
+			fmt.Sprintf("TLSPDC is in maintenance mode; retry later. The request was not retried, nor another authentication method tried, "+
+				"since neither helps until the maintenance ends. Got error: %s", err.Error()))
+	case errors.Is(err, vcertclient.ErrServerCertificateUntrusted):
+		diags.AddError("TLSPDC Certificate Not Trusted",
+			fmt.Sprintf("The certificate presented by TLSPDC could not be verified. Check that trust_bundle (or trust_bundle_system_name) holds the CA "+
+				"that issued it and that url matches one of its names. When TLSPDC is reached through a proxy whose certificate is issued by a public CA, "+
+				"set %s; when the proxy intercepts TLS with its own CA, set %s. As a last resort, in development environments only, %s disables the verification. Got error: %s",
+				fTrustBundleSystemRoots, fProxyTrustBundle, fInsecureSkipVerify, err.Error()))
+	default:
+		diags.AddError("Client Error", fmt.Sprintf("Unable to rotate token, got error: %s", err.Error()))
+	}
 }
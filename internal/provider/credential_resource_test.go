@@ -0,0 +1,1522 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/hashicorp/terraform-plugin-log/tflogtest"
+
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/logging"
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/model"
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/tpptest"
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/vcertclient"
+)
+
+// newResource returns a credential resource configured by the provider data, nil when the provider is not configured
+func newResource(t *testing.T, providerData *model.ProviderData) *CredentialResource {
+	t.Helper()
+
+	r := NewCredentialResource().(*CredentialResource)
+	var resp resource.ConfigureResponse
+	r.Configure(context.Background(), resource.ConfigureRequest{ProviderData: providerData}, &resp)
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("unable to configure the resource: %v", resp.Diagnostics)
+	}
+	return r
+}
+
+// importState imports id with the ImportState of r, as Terraform does, failing the test when the import is rejected
+func importState(t *testing.T, r *CredentialResource, id string) tfsdk.State {
+	t.Helper()
+	ctx := context.Background()
+
+	var schemaResp resource.SchemaResponse
+	r.Schema(ctx, resource.SchemaRequest{}, &schemaResp)
+	resp := resource.ImportStateResponse{
+		State: tfsdk.State{Schema: schemaResp.Schema, Raw: tftypes.NewValue(schemaResp.Schema.Type().TerraformType(ctx), nil)},
+	}
+	r.ImportState(ctx, resource.ImportStateRequest{ID: id}, &resp)
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("import of [%s] rejected: %v", id, resp.Diagnostics)
+	}
+	return resp.State
+}
+
+// readState refreshes state with the Read of r, failing the test on error
+func readState(t *testing.T, r *CredentialResource, state tfsdk.State) tfsdk.State {
+	t.Helper()
+
+	resp := resource.ReadResponse{State: state}
+	r.Read(context.Background(), resource.ReadRequest{State: state}, &resp)
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("read failed: %v", resp.Diagnostics)
+	}
+	return resp.State
+}
+
+// stateData returns the resource data held by state
+func stateData(t *testing.T, state tfsdk.State) model.CredentialResourceData {
+	t.Helper()
+
+	var data model.CredentialResourceData
+	if diags := state.Get(context.Background(), &data); diags.HasError() {
+		t.Fatalf("invalid state: %v", diags)
+	}
+	return data
+}
+
+// importID imports id with the given provider data, failing the test when the import is rejected
+func importID(t *testing.T, providerData *model.ProviderData, id string) model.CredentialResourceData {
+	t.Helper()
+
+	r := &CredentialResource{providerData: providerData}
+	var diags diag.Diagnostics
+	data, ok := r.importData(context.Background(), id, &diags)
+	if !ok || diags.HasError() {
+		t.Fatalf("import of [%s] rejected: %v", id, diags)
+	}
+	return data
+}
+
+func TestImportProviderDefaults(t *testing.T) {
+	ca := tpptest.NewCA(t, "Test CA")
+	t.Setenv(envURL, "")
+	t.Setenv(envVcertURL, "")
+	t.Setenv(envTrustBundle, ca.PEM)
+	t.Setenv(envClientID, "")
+	t.Setenv(envVcertClientID, "")
+	providerData := configureProvider(t, map[string]string{fURL: "https://tpp.venafi.example", fClientID: "provider-client"})
+
+	t.Run("partial ID", func(t *testing.T) {
+		data := importID(t, providerData, "access_token=access,refresh_token=refresh")
+
+		if data.AccessToken.ValueString() != "access" || data.RefreshToken.ValueString() != "refresh" {
+			t.Errorf("tokens = %s, %s", data.AccessToken, data.RefreshToken)
+		}
+		if data.URL.ValueString() != "https://tpp.venafi.example" {
+			t.Errorf("url = %s, want the provider url", data.URL)
+		}
+		if data.TrustBundle.ValueString() != ca.PEM {
+			t.Errorf("trust_bundle = %s, want the environment trust bundle", data.TrustBundle)
+		}
+		if data.ClientID.ValueString() != "provider-client" {
+			t.Errorf("client_id = %s, want the provider client_id", data.ClientID)
+		}
+		sources := configSources(&data)
+		for name, want := range map[string]string{fURL: sourceProvider, fTrustBundle: sourceEnv, fClientID: sourceProvider, fRefreshWindow: sourceDefault} {
+			if sources[name] != want {
+				t.Errorf("config_source[%s] = %q, want %q", name, sources[name], want)
+			}
+		}
+	})
+
+	t.Run("ID values take precedence", func(t *testing.T) {
+		data := importID(t, providerData, "url=https://other.venafi.example,client_id=own-client,access_token=access")
+
+		if data.URL.ValueString() != "https://other.venafi.example" {
+			t.Errorf("url = %s, want the one of the import ID", data.URL)
+		}
+		if data.ClientID.ValueString() != "own-client" {
+			t.Errorf("client_id = %s, want the one of the import ID", data.ClientID)
+		}
+		if sources := configSources(&data); sources[fURL] != sourceResource || sources[fClientID] != sourceResource {
+			t.Errorf("config_source = %v, want the resource for url and client_id", sources)
+		}
+	})
+
+	t.Run("no provider url", func(t *testing.T) {
+		r := &CredentialResource{}
+		var diags diag.Diagnostics
+		if _, ok := r.importData(context.Background(), "access_token=access", &diags); ok || !diags.HasError() {
+			t.Error("import accepted without any url")
+		}
+	})
+}
+
+func TestConfigSourceMixedConfig(t *testing.T) {
+	server := tpptest.NewServer(t)
+	t.Setenv(envURL, "")
+	t.Setenv(envVcertURL, "")
+	t.Setenv(envTrustBundle, "")
+	t.Setenv(envVcertTrustBundle, server.TrustBundle())
+	t.Setenv(envClientID, "")
+	t.Setenv(envVcertClientID, "")
+	r := newResource(t, configureProvider(t, map[string]string{fURL: server.URL}))
+
+	// checkSources fails the test when the config_source of state is not want
+	checkSources := func(t *testing.T, state tfsdk.State, want map[string]string) {
+		t.Helper()
+		data := stateData(t, state)
+		if sources := configSources(&data); !reflect.DeepEqual(sources, want) {
+			t.Errorf("config_source = %v, want %v", sources, want)
+		}
+	}
+
+	id := fmt.Sprintf("%s,%s=20", serverImportID(server), fRefreshWindow)
+	state := readState(t, r, importState(t, r, id))
+	checkSources(t, state, map[string]string{fURL: sourceProvider, fTrustBundle: sourceEnv, fClientID: sourceDefault, fRefreshWindow: sourceResource})
+
+	// Setting an attribute in the configuration of the resource leaves the source of the others as is
+	data := stateData(t, state)
+	data.RotateTrigger = types.StringValue("1")
+	plan, config := planUpdate(t, r, state, data, fUsername, fPassword, fRefreshWindow, fClientID, fRotateTrigger)
+	state = applyUpdate(t, r, state, plan, config)
+	checkSources(t, state, map[string]string{fURL: sourceProvider, fTrustBundle: sourceEnv, fClientID: sourceResource, fRefreshWindow: sourceResource})
+}
+
+func TestImportTruncatedTrustBundle(t *testing.T) {
+	ca := tpptest.NewCA(t, "Test CA")
+	truncated := filepath.Join(t.TempDir(), "bundle.pem")
+	if err := os.WriteFile(truncated, []byte(ca.PEM[:len(ca.PEM)/2]), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, attribute := range []string{fTrustBundle, fProxyTrustBundle} {
+		t.Run(attribute, func(t *testing.T) {
+			r := &CredentialResource{}
+			var diags diag.Diagnostics
+			id := fmt.Sprintf("url=https://tpp.venafi.example,access_token=access,%s=%s", attribute, truncated)
+			if _, ok := r.importData(context.Background(), id, &diags); ok {
+				t.Fatal("truncated trust bundle imported")
+			}
+			if len(diags) != 1 || !strings.Contains(diags[0].Detail(), "truncated") {
+				t.Fatalf("diagnostics = %v, want one error", diags)
+			}
+			if withPath, ok := diags[0].(diag.DiagnosticWithPath); !ok || !withPath.Path().Equal(path.Root(attribute)) {
+				t.Errorf("error not scoped to %s: %v", attribute, diags[0])
+			}
+		})
+	}
+}
+
+func TestWarnClientCertificateExpiration(t *testing.T) {
+	for _, test := range []struct {
+		name       string
+		expiration types.Int64
+		want       bool
+	}{
+		{"no client certificate", types.Int64Null(), false},
+		{"close to expiring", types.Int64Value(time.Now().Add(10 * 24 * time.Hour).Unix()), true},
+		{"far from expiring", types.Int64Value(time.Now().Add(90 * 24 * time.Hour).Unix()), false},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var diags diag.Diagnostics
+			warnClientCertificateExpiration(&model.CredentialResourceData{ClientCertExpiration: test.expiration}, &diags)
+			if got := diags.WarningsCount() == 1; got != test.want {
+				t.Errorf("warned = %t, want %t: %v", got, test.want, diags)
+			}
+		})
+	}
+}
+
+// serverCredential returns the attributes of a credential authenticating to server with username/password
+func serverCredential(server *tpptest.Server) model.CredentialResourceData {
+	return model.CredentialResourceData{
+		URL:            types.StringValue(server.URL),
+		TrustBundle:    types.StringValue(server.TrustBundle()),
+		ClientID:       types.StringValue(defaultClientID),
+		Username:       types.StringValue(server.Username),
+		Password:       types.StringValue(server.Password),
+		RefreshWindow:  types.Int64Value(defaultRefreshWindow),
+		RotationPolicy: types.StringValue(vcertclient.RotationPolicyPreferRefresh),
+	}
+}
+
+func TestValidateCredentials(t *testing.T) {
+	server := tpptest.NewServer(t)
+
+	t.Run("minted token revoked", func(t *testing.T) {
+		existing := server.IssueGrant(vcertclient.DefaultScope)
+		data := serverCredential(server)
+		data.ValidateOnly = types.BoolValue(true)
+		data.AccessToken = types.StringValue(existing.AccessToken)
+		data.RefreshToken = types.StringValue(existing.RefreshToken)
+		grants := len(server.Grants())
+
+		var diags diag.Diagnostics
+		validateCredentials(context.Background(), &data, &diags)
+		if diags.HasError() {
+			t.Fatalf("unexpected error: %v", diags)
+		}
+
+		all := server.Grants()
+		if len(all) != grants+1 {
+			t.Fatalf("%d grant(s) minted, want 1", len(all)-grants)
+		}
+		if minted := all[len(all)-1]; !minted.Revoked || minted.Method != tpptest.PathAuthorizeOAuth {
+			t.Errorf("minted grant = %+v, want a revoked username-password grant", minted)
+		}
+		// The token pair of the state belongs to another grant, which must be left untouched
+		if grant, _ := server.GrantOf(existing.AccessToken); grant.Revoked || grant.Refreshed != 0 {
+			t.Errorf("existing grant = %+v, want it neither refreshed nor revoked", grant)
+		}
+		if data.AccessToken.ValueString() != existing.AccessToken || data.RefreshToken.ValueString() != existing.RefreshToken {
+			t.Error("minted token pair persisted")
+		}
+		inMemoryGrants.mu.Lock()
+		defer inMemoryGrants.mu.Unlock()
+		if _, held := inMemoryGrants.grants[accessTokenFingerprint(types.StringValue(all[len(all)-1].AccessToken))]; held {
+			t.Error("revoked grant still held in memory")
+		}
+	})
+
+	t.Run("credentials rejected", func(t *testing.T) {
+		data := serverCredential(server)
+		data.ValidateOnly = types.BoolValue(true)
+		data.Password = types.StringValue("wrong")
+		grants := len(server.Grants())
+
+		var diags diag.Diagnostics
+		validateCredentials(context.Background(), &data, &diags)
+		if !diags.HasError() {
+			t.Error("rejected credentials validated")
+		}
+		if len(server.Grants()) != grants {
+			t.Error("grant minted with rejected credentials")
+		}
+	})
+}
+
+func TestProviderAliases(t *testing.T) {
+	t.Setenv(envURL, "")
+	t.Setenv(envVcertURL, "")
+	t.Setenv(envTrustBundle, "")
+	t.Setenv(envVcertTrustBundle, "")
+	t.Setenv(envClientID, "")
+	t.Setenv(envVcertClientID, "")
+
+	// Two aliases of the provider, each configured for its own TLSPDC
+	servers := []*tpptest.Server{tpptest.NewServer(t), tpptest.NewServer(t)}
+	var states []tfsdk.State
+	for i, server := range servers {
+		providerData := configureProvider(t, map[string]string{
+			fURL:         server.URL,
+			fTrustBundle: server.TrustBundle(),
+			fClientID:    fmt.Sprintf("client-%d", i),
+		})
+		r := newResource(t, providerData)
+		states = append(states, readState(t, r, importState(t, r, "username=tppadmin,password=password")))
+	}
+
+	for i, server := range servers {
+		data := stateData(t, states[i])
+		grant, ok := server.GrantOf(data.AccessToken.ValueString())
+		if !ok {
+			t.Fatalf("alias %d: token not issued by its TLSPDC", i)
+		}
+		if _, ok = servers[1-i].GrantOf(data.AccessToken.ValueString()); ok {
+			t.Errorf("alias %d: token issued by the TLSPDC of the other alias", i)
+		}
+		if want := fmt.Sprintf("client-%d", i); grant.ClientID != want || data.ClientID.ValueString() != want {
+			t.Errorf("alias %d: client_id = %s, granted to %s, want %s", i, data.ClientID, grant.ClientID, want)
+		}
+		if data.URL.ValueString() != server.URL || data.ActiveURL.ValueString() != server.URL {
+			t.Errorf("alias %d: url = %s, active_url = %s, want %s", i, data.URL, data.ActiveURL, server.URL)
+		}
+		if sources := configSources(&data); sources[fURL] != sourceProvider || sources[fTrustBundle] != sourceProvider {
+			t.Errorf("alias %d: config_source = %v, want the provider", i, sources)
+		}
+	}
+}
+
+// importServerState imports a username/password credential of server with a provider configured for it, and returns
+// the resource with its state after a refresh
+func importServerState(t *testing.T, server *tpptest.Server) (*CredentialResource, tfsdk.State) {
+	t.Helper()
+	t.Setenv(envURL, "")
+	t.Setenv(envVcertURL, "")
+	t.Setenv(envTrustBundle, "")
+	t.Setenv(envVcertTrustBundle, "")
+	t.Setenv(envClientID, "")
+	t.Setenv(envVcertClientID, "")
+
+	r := newResource(t, configureProvider(t, map[string]string{fURL: server.URL, fTrustBundle: server.TrustBundle()}))
+	return r, readState(t, r, importState(t, r, serverImportID(server)))
+}
+
+// serverImportID returns the import ID of a username/password credential of server
+func serverImportID(server *tpptest.Server) string {
+	return fmt.Sprintf("%s=%s,%s=%s", fUsername, server.Username, fPassword, server.Password)
+}
+
+// planUpdate plans the update of state to plan with the ModifyPlan of r, the attributes named by configured being
+// the only ones set in the configuration
+func planUpdate(t *testing.T, r *CredentialResource, state tfsdk.State, plan model.CredentialResourceData, configured ...string) (tfsdk.Plan, tfsdk.Config) {
+	t.Helper()
+	ctx := context.Background()
+
+	proposed := tfsdk.State{Schema: state.Schema}
+	if diags := proposed.Set(ctx, plan); diags.HasError() {
+		t.Fatalf("invalid plan: %v", diags)
+	}
+	var values map[string]tftypes.Value
+	if err := proposed.Raw.As(&values); err != nil {
+		t.Fatalf("invalid plan: %s", err)
+	}
+	objectType := state.Schema.Type().TerraformType(ctx).(tftypes.Object)
+	configValues := make(map[string]tftypes.Value, len(values))
+	for name := range values {
+		configValues[name] = tftypes.NewValue(objectType.AttributeTypes[name], nil)
+	}
+	for _, name := range configured {
+		configValues[name] = values[name]
+	}
+	config := tfsdk.Config{Schema: state.Schema, Raw: tftypes.NewValue(objectType, configValues)}
+
+	req := resource.ModifyPlanRequest{State: state, Config: config, Plan: tfsdk.Plan{Schema: state.Schema, Raw: proposed.Raw}}
+	resp := resource.ModifyPlanResponse{Plan: req.Plan}
+	r.ModifyPlan(ctx, req, &resp)
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("plan failed: %v", resp.Diagnostics)
+	}
+	return resp.Plan, config
+}
+
+// applyUpdate applies plan with the Update of r and, as Terraform does, fails the test when the new state differs
+// from a value known in the plan
+func applyUpdate(t *testing.T, r *CredentialResource, state tfsdk.State, plan tfsdk.Plan, config tfsdk.Config) tfsdk.State {
+	t.Helper()
+
+	resp := resource.UpdateResponse{State: tfsdk.State{Schema: plan.Schema, Raw: plan.Raw}}
+	r.Update(context.Background(), resource.UpdateRequest{State: state, Plan: plan, Config: config}, &resp)
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("apply failed: %v", resp.Diagnostics)
+	}
+
+	var planned, applied map[string]tftypes.Value
+	if err := plan.Raw.As(&planned); err != nil {
+		t.Fatalf("invalid plan: %s", err)
+	}
+	if err := resp.State.Raw.As(&applied); err != nil {
+		t.Fatalf("invalid state: %s", err)
+	}
+	for name, value := range planned {
+		if value.IsFullyKnown() && !value.Equal(applied[name]) {
+			t.Errorf("inconsistent result after apply: %s planned %s, got %s", name, value, applied[name])
+		}
+	}
+	return resp.State
+}
+
+// unknownAttributes returns the names of the attributes unknown in plan
+func unknownAttributes(t *testing.T, plan tfsdk.Plan) map[string]bool {
+	t.Helper()
+
+	var values map[string]tftypes.Value
+	if err := plan.Raw.As(&values); err != nil {
+		t.Fatalf("invalid plan: %s", err)
+	}
+	unknown := make(map[string]bool)
+	for name, value := range values {
+		if !value.IsKnown() {
+			unknown[name] = true
+		}
+	}
+	return unknown
+}
+
+func TestModifyPlanRotateTrigger(t *testing.T) {
+	server := tpptest.NewServer(t)
+	configured := []string{fUsername, fPassword, fRotateTrigger}
+
+	t.Run("changed", func(t *testing.T) {
+		r, state := importServerState(t, server)
+		previous := stateData(t, state)
+		data := previous
+		data.RotateTrigger = types.StringValue("rejected-1")
+
+		plan, config := planUpdate(t, r, state, data, configured...)
+		unknown := unknownAttributes(t, plan)
+		for name, attribute := range plan.Schema.GetAttributes() {
+			isConfigured := name == fUsername || name == fPassword || name == fRotateTrigger
+			if want := attribute.IsComputed() && !isConfigured; unknown[name] != want {
+				t.Errorf("%s unknown = %t, want %t", name, unknown[name], want)
+			}
+		}
+
+		applied := stateData(t, applyUpdate(t, r, state, plan, config))
+		if applied.AccessToken.Equal(previous.AccessToken) || !applied.Rotated.ValueBool() {
+			t.Errorf("access_token = %s, rotated_on_last_apply = %s, want a rotation", applied.AccessToken, applied.Rotated)
+		}
+		if applied.RotateTrigger.ValueString() != "rejected-1" {
+			t.Errorf("rotate_trigger = %s, want it stored", applied.RotateTrigger)
+		}
+	})
+
+	t.Run("unchanged", func(t *testing.T) {
+		r, state := importServerState(t, server)
+		data := stateData(t, state)
+
+		// Terraform does not apply a plan without changes
+		plan, _ := planUpdate(t, r, state, data, configured...)
+		if unknown := unknownAttributes(t, plan); len(unknown) != 0 {
+			t.Errorf("unknown attributes = %v, want none", unknown)
+		}
+		if !plan.Raw.Equal(state.Raw) {
+			t.Error("plan differs from the state")
+		}
+	})
+}
+
+func TestVersions(t *testing.T) {
+	t.Run("retrieved", func(t *testing.T) {
+		server := tpptest.NewServer(t)
+		_, state := importServerState(t, server)
+
+		data := stateData(t, state)
+		if data.VCertVersion.IsNull() || data.VCertVersion.ValueString() != vcertclient.SDKVersion() {
+			t.Errorf("vcert_version = %s, want %q", data.VCertVersion, vcertclient.SDKVersion())
+		}
+		if data.TPPVersion.ValueString() != server.Version {
+			t.Errorf("tpp_version = %s, want %s", data.TPPVersion, server.Version)
+		}
+	})
+
+	t.Run("server version unavailable", func(t *testing.T) {
+		server := tpptest.NewServer(t)
+		server.Handle(tpptest.PathSystemVersion, func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		})
+		_, state := importServerState(t, server)
+
+		data := stateData(t, state)
+		if data.VCertVersion.IsNull() {
+			t.Error("vcert_version not set")
+		}
+		if !data.TPPVersion.IsNull() {
+			t.Errorf("tpp_version = %s, want null", data.TPPVersion)
+		}
+	})
+}
+
+func TestRefreshWindowReachedBeforeApply(t *testing.T) {
+	const margin = 600
+	tests := []struct {
+		name string
+		// untilWindow is the number of seconds before the access token enters its refresh window at plan time
+		untilWindow int64
+		// elapsed is the number of seconds between plan and apply
+		elapsed     int64
+		wantPlanned bool
+		wantRotated bool
+	}{
+		{"entered before apply", 300, 400, true, true},
+		{"not entered yet at apply", 300, 60, true, false},
+		{"beyond the margin", 1200, 0, false, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := tpptest.NewServer(t)
+			r, state := importServerState(t, server)
+			data := stateData(t, state)
+			data.ApplyMarginSeconds = types.Int64Value(margin)
+			data.ExpirationDate = types.Int64Value(time.Now().Unix() + refreshWindowSeconds(&data) + test.untilWindow)
+			state = setStateData(t, state, data)
+
+			plan, config := planUpdate(t, r, state, data, fUsername, fPassword, fApplyMarginSeconds)
+			if planned := !plan.Raw.Equal(state.Raw); planned != test.wantPlanned {
+				t.Fatalf("update planned = %t, want %t", planned, test.wantPlanned)
+			}
+			if !test.wantPlanned {
+				return
+			}
+
+			// Moving the expiration closer stands for the time elapsed until the plan is applied
+			elapsed := data
+			elapsed.ExpirationDate = types.Int64Value(data.ExpirationDate.ValueInt64() - test.elapsed)
+			applied := stateData(t, applyUpdate(t, r, setStateData(t, state, elapsed), plan, config))
+			if rotated := !applied.AccessToken.Equal(data.AccessToken); rotated != test.wantRotated {
+				t.Errorf("rotated = %t, want %t", rotated, test.wantRotated)
+			}
+			if applied.Rotated.ValueBool() != test.wantRotated {
+				t.Errorf("rotated_on_last_apply = %s, want %t", applied.Rotated, test.wantRotated)
+			}
+		})
+	}
+}
+
+func TestTokenIdentity(t *testing.T) {
+	t.Run("username/password", func(t *testing.T) {
+		server := tpptest.NewServer(t)
+		_, state := importServerState(t, server)
+
+		if data := stateData(t, state); data.TokenIdentity.ValueString() != server.Username {
+			t.Errorf("token_identity = %s, want the username %s", data.TokenIdentity, server.Username)
+		}
+	})
+
+	t.Run("introspection unavailable", func(t *testing.T) {
+		server := tpptest.NewServer(t)
+		server.Handle(tpptest.PathIdentitySelf, func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		})
+		_, state := importServerState(t, server)
+
+		if data := stateData(t, state); !data.TokenIdentity.IsNull() {
+			t.Errorf("token_identity = %s, want null", data.TokenIdentity)
+		}
+	})
+}
+
+func TestImportLogs(t *testing.T) {
+	t.Setenv(envURL, "")
+	t.Setenv(envVcertURL, "")
+	t.Setenv(envTrustBundle, "")
+	t.Setenv(envVcertTrustBundle, "")
+	secrets := []string{"s3cr3t-password", "s3cr3t-p12", "s3cr3t-access", "s3cr3t-refresh"}
+	id := fmt.Sprintf("url=https://tpp.venafi.example,username=tppadmin,password=%s,p12_cert_password=%s,access_token=%s,refresh_token=%s",
+		secrets[0], secrets[1], secrets[2], secrets[3])
+
+	for _, level := range []string{"", "none", "summary", "debug"} {
+		name := level
+		if name == "" {
+			name = "default"
+		}
+		t.Run(name, func(t *testing.T) {
+			var output bytes.Buffer
+			ctx := tflogtest.RootLogger(context.Background(), &output)
+			importID := id
+			if level != "" {
+				importID += ",log_level=" + level
+			}
+
+			r := &CredentialResource{providerData: configureProvider(t, nil)}
+			var diags diag.Diagnostics
+			if _, ok := r.importData(ctx, importID, &diags); !ok {
+				t.Fatalf("import rejected: %v", diags)
+			}
+			logs := output.String()
+			for _, secret := range secrets {
+				if strings.Contains(logs, secret) {
+					t.Errorf("%s logged", secret)
+				}
+			}
+			if level == "none" && logs != "" {
+				t.Errorf("logs = %q, want nothing", logs)
+			}
+			if level != "none" && !strings.Contains(logs, "saving attribute to terraform state: [password]") {
+				t.Errorf("logs = %q, want the saved attributes", logs)
+			}
+		})
+	}
+}
+
+func TestStrictSensitiveRedaction(t *testing.T) {
+	t.Setenv(envURL, "")
+	t.Setenv(envVcertURL, "")
+	t.Setenv(envTrustBundle, "")
+	t.Setenv(envVcertTrustBundle, "")
+	values := map[string]string{
+		fURL:          "https://tpp.s3cr3t.example",
+		fFallbackURL:  "https://dr.s3cr3t.example",
+		fClientID:     "s3cr3t-client",
+		fUsername:     "s3cr3t-user",
+		fPassword:     "s3cr3t-password",
+		fP12Password:  "s3cr3t-p12",
+		fAccessToken:  "s3cr3t-access",
+		fRefreshToken: "s3cr3t-refresh",
+	}
+	providerData := configureProvider(t, nil)
+	providerData.StrictSensitive = types.BoolValue(true)
+	r := &CredentialResource{providerData: providerData}
+
+	checkRedacted := func(t *testing.T, logs string) {
+		t.Helper()
+		for name, value := range values {
+			if strings.Contains(logs, value) {
+				t.Errorf("%s logged: %s", name, logs)
+			}
+		}
+		if !strings.Contains(logs, "***") {
+			t.Errorf("logs = %q, want masked values", logs)
+		}
+	}
+
+	t.Run("import", func(t *testing.T) {
+		var output bytes.Buffer
+		ctx := tflogtest.RootLogger(context.Background(), &output)
+		var fields []string
+		for name, value := range values {
+			fields = append(fields, name+"="+value)
+		}
+		var diags diag.Diagnostics
+		if _, ok := r.importData(ctx, strings.Join(fields, ",")+",log_level=debug", &diags); !ok {
+			t.Fatalf("import rejected: %v", diags)
+		}
+		checkRedacted(t, output.String())
+	})
+
+	t.Run("operations", func(t *testing.T) {
+		var output bytes.Buffer
+		ctx := tflogtest.RootLogger(context.Background(), &output)
+		data := model.CredentialResourceData{
+			URL:          types.StringValue(values[fURL]),
+			FallbackURL:  types.StringValue(values[fFallbackURL]),
+			ClientID:     types.StringValue(values[fClientID]),
+			Username:     types.StringValue(values[fUsername]),
+			Password:     types.StringValue(values[fPassword]),
+			P12Password:  types.StringValue(values[fP12Password]),
+			AccessToken:  types.StringValue(values[fAccessToken]),
+			RefreshToken: types.StringValue(values[fRefreshToken]),
+			LogLevel:     types.StringValue("debug"),
+		}
+		ctx = r.logContext(ctx, &data)
+
+		// e.g. an error of TLSPDC echoing the request
+		var echoed []string
+		for _, value := range values {
+			echoed = append(echoed, value)
+		}
+		logging.Error(ctx, "request rejected: "+strings.Join(echoed, " "))
+		logging.Debug(ctx, "request sent", map[string]interface{}{"request": strings.Join(echoed, " ")})
+		checkRedacted(t, output.String())
+	})
+}
+
+func TestRevokedOutOfBand(t *testing.T) {
+	server := tpptest.NewServer(t)
+	r, state := importServerState(t, server)
+	data := stateData(t, state)
+	revoked, _ := server.GrantOf(data.AccessToken.ValueString())
+	server.Revoke(data.AccessToken.ValueString())
+
+	// The token is far from its refresh window, only the revocation leads to the rotation
+	resp := resource.ReadResponse{State: state}
+	r.Read(context.Background(), resource.ReadRequest{State: state}, &resp)
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("read failed: %v", resp.Diagnostics)
+	}
+	read := stateData(t, resp.State)
+	current, ok := server.GrantOf(read.AccessToken.ValueString())
+	if !ok || current.ID == revoked.ID || current.Revoked {
+		t.Errorf("access token of grant %d, want a new grant", current.ID)
+	}
+	if server.Grants()[revoked.ID-1].Refreshed != 0 {
+		t.Error("refresh token of the revoked grant used")
+	}
+	warned := false
+	for _, warning := range resp.Diagnostics.Warnings() {
+		warned = warned || strings.Contains(warning.Detail(), "it was likely revoked out-of-band")
+	}
+	if !warned {
+		t.Errorf("diagnostics = %v, want the revocation reported", resp.Diagnostics)
+	}
+}
+
+func TestRotationCount(t *testing.T) {
+	server := tpptest.NewServer(t)
+	r, state := importServerState(t, server)
+
+	// The import holds no token, the first read rotates
+	if count := stateData(t, state).RotationCount; count.ValueInt64() != 1 {
+		t.Fatalf("rotation_count = %s after the first read, want 1", count)
+	}
+	state = readState(t, r, state)
+	if count := stateData(t, state).RotationCount; count.ValueInt64() != 1 {
+		t.Errorf("rotation_count = %s after a read without rotation, want 1", count)
+	}
+
+	for i, trigger := range []string{"first", "second"} {
+		data := stateData(t, state)
+		data.RotateTrigger = types.StringValue(trigger)
+		plan, config := planUpdate(t, r, state, data, fUsername, fPassword, fRotateTrigger)
+		state = applyUpdate(t, r, state, plan, config)
+		if count := stateData(t, state).RotationCount; count.ValueInt64() != int64(i+2) {
+			t.Errorf("rotation_count = %s after rotation %s, want %d", count, trigger, i+2)
+		}
+	}
+}
+
+func TestUnrecoverableCredential(t *testing.T) {
+	tests := []struct {
+		name string
+		// refreshToken is the refresh token left, none when empty
+		refreshToken string
+		unreachable  bool
+		wantSummary  string
+		wantDetail   string
+	}{
+		{"no auth method", "", false, "Credential Cannot Be Refreshed", "holds no refresh token, client certificate or username/password"},
+		{"refresh token rejected", "expired", false, "Credential Cannot Be Refreshed", "every authentication method of the resource was rejected"},
+		// Not unrecoverable, retrying may succeed
+		{"unreachable", "expired", true, "Client Error", "Unable to rotate token"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := tpptest.NewServer(t)
+			grant := server.IssueGrant(vcertclient.DefaultScope)
+			server.Expire(grant.AccessToken)
+			data := serverCredential(server)
+			data.Username, data.Password = types.StringNull(), types.StringNull()
+			data.AccessToken = types.StringValue(grant.AccessToken)
+			data.ExpirationDate = types.Int64Value(time.Now().Add(-time.Second).Unix())
+			data.RefreshToken = stringOrNull(test.refreshToken)
+			data.VerifyMaxRetries = types.Int64Value(0)
+			data.RotateMaxRetries = types.Int64Value(0)
+			if test.unreachable {
+				data.URL = types.StringValue(tpptest.UnreachableURL(t))
+			}
+
+			var diags diag.Diagnostics
+			refreshCredential(context.Background(), &data, "", &diags)
+			errs := diags.Errors()
+			if len(errs) != 1 || errs[0].Summary() != test.wantSummary || !strings.Contains(errs[0].Detail(), test.wantDetail) {
+				t.Fatalf("diagnostics = %v, want %s: %s", diags, test.wantSummary, test.wantDetail)
+			}
+			if reimport := strings.Contains(errs[0].Detail(), "re-import the resource") || strings.Contains(errs[0].Detail(), "Re-import the resource"); reimport == test.unreachable {
+				t.Errorf("re-import suggested = %t, want %t", reimport, !test.unreachable)
+			}
+		})
+	}
+}
+
+func TestLastRefreshWarning(t *testing.T) {
+	server := tpptest.NewServer(t)
+	r, _ := importServerState(t, server)
+
+	// The refresh token of the import is unknown to TLSPDC, the username/password is used instead
+	state := readState(t, r, importState(t, r, fmt.Sprintf("%s,%s=expired", serverImportID(server), fRefreshToken)))
+	data := stateData(t, state)
+	if !strings.Contains(data.LastRefreshWarning.ValueString(), vcertclient.MethodRefreshToken+" failed") {
+		t.Errorf("last_refresh_warning = %s, want the failure of the refresh token", data.LastRefreshWarning)
+	}
+	if !data.UsedFallbackMethod.ValueBool() {
+		t.Errorf("used_fallback_method = %s, want true", data.UsedFallbackMethod)
+	}
+
+	// Cleared by the next rotation, with the refresh token obtained
+	data.RotateTrigger = types.StringValue("rotate")
+	plan, config := planUpdate(t, r, state, data, fUsername, fPassword, fRotateTrigger)
+	data = stateData(t, applyUpdate(t, r, state, plan, config))
+	if data.SelectedAuthMethod.ValueString() != vcertclient.MethodRefreshToken || !data.LastRefreshWarning.IsNull() {
+		t.Errorf("selected_auth_method = %s, last_refresh_warning = %s, want the refresh token without warning",
+			data.SelectedAuthMethod, data.LastRefreshWarning)
+	}
+}
+
+func TestMinGrantedLifetime(t *testing.T) {
+	server := tpptest.NewServer(t)
+	server.TokenLifetime = 5 * time.Minute
+
+	t.Run("short-lived token", func(t *testing.T) {
+		data := serverCredential(server)
+		data.MinGrantedLifetime = types.Int64Value(3600)
+
+		var diags diag.Diagnostics
+		err := rotateToken(context.Background(), &data, &diags)
+		if err == nil || !strings.Contains(err.Error(), "less than the 3600 seconds required by "+fMinGrantedLifetime) {
+			t.Fatalf("error = %v, want the token lifetime rejected", err)
+		}
+		if !data.AccessToken.IsNull() {
+			t.Errorf("access_token = %s, want the short-lived token not stored", data.AccessToken)
+		}
+		grants := server.Grants()
+		if last := grants[len(grants)-1]; !last.Revoked {
+			t.Errorf("grant = %+v, want the short-lived token revoked", last)
+		}
+	})
+
+	t.Run("long enough", func(t *testing.T) {
+		data := serverCredential(server)
+		data.MinGrantedLifetime = types.Int64Value(60)
+
+		var diags diag.Diagnostics
+		if err := rotateToken(context.Background(), &data, &diags); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if data.AccessToken.IsNull() {
+			t.Error("access token not stored")
+		}
+	})
+}
+
+func TestMinRemainingForOperation(t *testing.T) {
+	const day = 24 * time.Hour
+	// grantedToken returns a credential of server holding a token valid for 45 days
+	grantedToken := func(t *testing.T, server *tpptest.Server) model.CredentialResourceData {
+		t.Helper()
+
+		server.TokenLifetime = 45 * day
+		data := serverCredential(server)
+		var diags diag.Diagnostics
+		if err := rotateToken(context.Background(), &data, &diags); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		server.TokenLifetime = tpptest.DefaultTokenLifetime
+		return data
+	}
+
+	t.Run("sufficient headroom", func(t *testing.T) {
+		server := tpptest.NewServer(t)
+		data := grantedToken(t, server)
+		accessToken := data.AccessToken
+		data.MinRemainingForOp = types.Int64Value(int64((10 * day).Seconds()))
+
+		var diags diag.Diagnostics
+		refreshCredential(context.Background(), &data, "", &diags)
+		if diags.HasError() {
+			t.Fatalf("refresh failed: %v", diags)
+		}
+		if !data.AccessToken.Equal(accessToken) || data.Rotated.ValueBool() {
+			t.Errorf("access_token = %s, want the token kept", data.AccessToken)
+		}
+	})
+
+	t.Run("insufficient headroom", func(t *testing.T) {
+		// The token is out of the refresh window, but expires before the end of the operation
+		server := tpptest.NewServer(t)
+		data := grantedToken(t, server)
+		accessToken := data.AccessToken
+		minimum := int64((60 * day).Seconds())
+		data.MinRemainingForOp = types.Int64Value(minimum)
+
+		var diags diag.Diagnostics
+		refreshCredential(context.Background(), &data, "", &diags)
+		if diags.HasError() {
+			t.Fatalf("refresh failed: %v", diags)
+		}
+		if data.AccessToken.Equal(accessToken) || !data.Rotated.ValueBool() {
+			t.Fatalf("access_token = %s, want a token rotated for headroom", data.AccessToken)
+		}
+		if remaining := data.ExpirationDate.ValueInt64() - time.Now().Unix(); remaining < minimum {
+			t.Errorf("token valid for %d seconds, want at least %d", remaining, minimum)
+		}
+	})
+
+	t.Run("headroom out of reach", func(t *testing.T) {
+		// Even a fresh token expires before the end of the operation
+		server := tpptest.NewServer(t)
+		data := grantedToken(t, server)
+		data.MinRemainingForOp = types.Int64Value(int64((120 * day).Seconds()))
+
+		var diags diag.Diagnostics
+		refreshCredential(context.Background(), &data, "", &diags)
+		if !diags.HasError() || !strings.Contains(diags.Errors()[0].Detail(), "required by "+fMinRemainingForOp) {
+			t.Fatalf("diagnostics = %v, want the headroom reported unreachable", diags)
+		}
+		grants := server.Grants()
+		if last := grants[len(grants)-1]; !last.Revoked {
+			t.Errorf("grant = %+v, want the fresh token revoked", last)
+		}
+	})
+}
+
+func TestEffectiveURL(t *testing.T) {
+	server := tpptest.NewServer(t)
+	r, state := importServerState(t, server)
+	if effectiveURL := stateData(t, state).EffectiveURL; effectiveURL.ValueString() != server.URL+"/vedsdk" {
+		t.Errorf("effective_url = %s, want the WebSDK of %s", effectiveURL, server.URL)
+	}
+
+	// Kept when TLSPDC cannot be reached
+	data := stateData(t, state)
+	data.URL = types.StringValue(tpptest.UnreachableURL(t))
+	resp := readWithDiagnostics(t, r, setStateData(t, state, data))
+	if effectiveURL := stateData(t, resp.State).EffectiveURL; effectiveURL.ValueString() != server.URL+"/vedsdk" {
+		t.Errorf("effective_url = %s, want the last one answering", effectiveURL)
+	}
+}
+
+func TestUntrustedServerCertificate(t *testing.T) {
+	server := tpptest.NewServer(t)
+	data := serverCredential(server)
+	// The trust bundle does not hold the CA of the server certificate
+	data.TrustBundle = types.StringValue(tpptest.NewCA(t, "Other CA").PEM)
+
+	var diags diag.Diagnostics
+	refreshCredential(context.Background(), &data, "", &diags)
+	if len(diags.Errors()) != 1 || diags.Errors()[0].Summary() != "TLSPDC Certificate Not Trusted" {
+		t.Fatalf("diagnostics = %v, want the certificate reported untrusted", diags)
+	}
+	detail := diags.Errors()[0].Detail()
+	for _, hint := range []string{fTrustBundle, fTrustBundleSystemRoots, fInsecureSkipVerify, "x509"} {
+		if !strings.Contains(detail, hint) {
+			t.Errorf("error = %s, want %s mentioned", detail, hint)
+		}
+	}
+}
+
+func TestExpiredClientCertificate(t *testing.T) {
+	server := tpptest.NewServer(t)
+	notAfter := time.Now().Add(-24 * time.Hour).Truncate(time.Second)
+	cert := tpptest.NewCA(t, "Client CA").Issue(t, "client", tpptest.CertificateOptions{NotBefore: notAfter.Add(-365 * 24 * time.Hour), NotAfter: notAfter})
+	data := model.CredentialResourceData{
+		URL:            types.StringValue(server.URL),
+		TrustBundle:    types.StringValue(server.TrustBundle()),
+		ClientID:       types.StringValue(defaultClientID),
+		P12Certificate: types.StringValue(tpptest.PKCS12File(t, cert, nil, "secret")),
+		P12Password:    types.StringValue("secret"),
+		RefreshWindow:  types.Int64Value(defaultRefreshWindow),
+	}
+
+	var diags diag.Diagnostics
+	refreshCredential(context.Background(), &data, "", &diags)
+	if len(diags.Errors()) != 1 || diags.Errors()[0].Summary() != "Client Certificate Expired" {
+		t.Fatalf("diagnostics = %v, want the client certificate reported expired", diags)
+	}
+	if withPath, ok := diags.Errors()[0].(diag.DiagnosticWithPath); !ok || !withPath.Path().Equal(path.Root(fP12Cert)) {
+		t.Errorf("error not scoped to %s: %v", fP12Cert, diags.Errors()[0])
+	}
+	if detail, expiry := diags.Errors()[0].Detail(), notAfter.UTC().Format(time.RFC3339); !strings.Contains(detail, expiry) {
+		t.Errorf("error = %s, want the expiry date %s", detail, expiry)
+	}
+	if server.Requests(tpptest.PathAuthorizeCertificate) != 0 {
+		t.Error("expired client certificate sent to TLSPDC")
+	}
+}
+
+// setStateData returns a copy of state holding data
+func setStateData(t *testing.T, state tfsdk.State, data model.CredentialResourceData) tfsdk.State {
+	t.Helper()
+
+	updated := tfsdk.State{Schema: state.Schema}
+	if diags := updated.Set(context.Background(), data); diags.HasError() {
+		t.Fatalf("invalid state: %v", diags)
+	}
+	return updated
+}
+
+// holdStaleGrants gives data a staged, a next and a superseded token pair, each a grant of server, and returns them
+func holdStaleGrants(server *tpptest.Server, data *model.CredentialResourceData) []tpptest.Grant {
+	staged, next, superseded := server.IssueGrant(vcertclient.DefaultScope), server.IssueGrant(vcertclient.DefaultScope), server.IssueGrant(vcertclient.DefaultScope)
+	data.PendingAccessToken = types.StringValue(staged.AccessToken)
+	data.PendingRefreshToken = types.StringValue(staged.RefreshToken)
+	data.PendingExpiration = types.Int64Value(staged.ExpiresAt.Unix())
+	data.PendingIssuedAt = types.Int64Value(staged.IssuedAt.Unix())
+	data.NextAccessToken = types.StringValue(next.AccessToken)
+	data.NextRefreshToken = types.StringValue(next.RefreshToken)
+	data.NextExpiration = types.Int64Value(next.ExpiresAt.Unix())
+	data.NextIssuedAt = types.Int64Value(next.IssuedAt.Unix())
+	data.SupersededAccessToken = types.StringValue(superseded.AccessToken)
+	data.SupersededRevokeAt = types.Int64Value(time.Now().Add(time.Hour).Unix())
+	return []tpptest.Grant{staged, next, superseded}
+}
+
+// checkStaleGrantsDiscarded fails the test when a grant of holdStaleGrants is still valid on server or held by data
+func checkStaleGrantsDiscarded(t *testing.T, server *tpptest.Server, grants []tpptest.Grant, data model.CredentialResourceData) {
+	t.Helper()
+
+	for i, kind := range []string{"staged", "next", "superseded"} {
+		if revoked := server.Grants()[grants[i].ID-1]; !revoked.Revoked {
+			t.Errorf("%s grant not revoked", kind)
+		}
+	}
+	for name, value := range map[string]attr.Value{
+		fPendingAccessToken: data.PendingAccessToken, fPendingRefreshToken: data.PendingRefreshToken, fPendingExpiration: data.PendingExpiration, fPendingIssuedAt: data.PendingIssuedAt,
+		fNextAccessToken: data.NextAccessToken, fNextRefreshToken: data.NextRefreshToken, fNextExpiration: data.NextExpiration, fNextIssuedAt: data.NextIssuedAt,
+		fSupersededAccessToken: data.SupersededAccessToken, fSupersededRevokeAt: data.SupersededRevokeAt,
+	} {
+		if !value.IsNull() {
+			t.Errorf("%s = %s, want null", name, value)
+		}
+	}
+}
+
+func TestURLChangeDiscardsStaleGrants(t *testing.T) {
+	previous, moved := tpptest.NewServer(t), tpptest.NewServer(t)
+	r, state := importServerState(t, previous)
+	data := stateData(t, state)
+	grants := holdStaleGrants(previous, &data)
+	state = setStateData(t, state, data)
+
+	data.URL = types.StringValue(moved.URL)
+	data.TrustBundle = types.StringValue(previous.TrustBundle() + moved.TrustBundle())
+	plan, config := planUpdate(t, r, state, data, fURL, fTrustBundle, fUsername, fPassword)
+	applied := stateData(t, applyUpdate(t, r, state, plan, config))
+
+	if _, ok := moved.GrantOf(applied.AccessToken.ValueString()); !ok {
+		t.Errorf("access token not issued by the new url")
+	}
+	checkStaleGrantsDiscarded(t, previous, grants, applied)
+}
+
+func TestAuthCategoryChangeReplacesGrant(t *testing.T) {
+	server := tpptest.NewServer(t)
+	r, state := importServerState(t, server)
+	data := stateData(t, state)
+	previous, _ := server.GrantOf(data.AccessToken.ValueString())
+	grants := holdStaleGrants(server, &data)
+	state = setStateData(t, state, data)
+
+	// Switch from username/password to a client certificate
+	data.Username = types.StringNull()
+	data.Password = types.StringNull()
+	data.P12Certificate = types.StringValue(tpptest.PKCS12File(t, server.CA.Issue(t, "client", tpptest.CertificateOptions{}), nil, "secret"))
+	data.P12Password = types.StringValue("secret")
+	plan, config := planUpdate(t, r, state, data, fP12Cert, fP12Password)
+	if unknown := unknownAttributes(t, plan); !unknown[fAccessToken] || !unknown[fRefreshToken] {
+		t.Errorf("unknown attributes = %v, want the token pair", unknown)
+	}
+	applied := stateData(t, applyUpdate(t, r, state, plan, config))
+
+	current, ok := server.GrantOf(applied.AccessToken.ValueString())
+	if !ok || current.Method != tpptest.PathAuthorizeCertificate {
+		t.Errorf("access token granted by %q, want %s", current.Method, tpptest.PathAuthorizeCertificate)
+	}
+	if !server.Grants()[previous.ID-1].Revoked {
+		t.Error("grant of the username/password not revoked")
+	}
+	checkStaleGrantsDiscarded(t, server, grants, applied)
+}
+
+func TestStagedRotationIssuedAt(t *testing.T) {
+	server := tpptest.NewServer(t)
+	r, state := importServerState(t, server)
+	active := stateData(t, state)
+
+	// Stage a rotation
+	data := active
+	data.StagedRotation = types.BoolValue(true)
+	data.RotateTrigger = types.StringValue("stage")
+	before := time.Now().Unix()
+	plan, config := planUpdate(t, r, state, data, fUsername, fPassword, fStagedRotation, fRotateTrigger)
+	state = applyUpdate(t, r, state, plan, config)
+	staged := stateData(t, state)
+	if !staged.AccessToken.Equal(active.AccessToken) || !staged.IssuedAt.Equal(active.IssuedAt) {
+		t.Errorf("access_token = %s, issued_at = %s, want the active pair untouched", staged.AccessToken, staged.IssuedAt)
+	}
+	if staged.PendingAccessToken.IsNull() || staged.PendingIssuedAt.ValueInt64() < before || staged.PendingIssuedAt.ValueInt64() > time.Now().Unix() {
+		t.Fatalf("pending_access_token = %s, pending_issued_at = %s, want the staged pair", staged.PendingAccessToken, staged.PendingIssuedAt)
+	}
+
+	// Commit it
+	data = staged
+	data.CommitRotation = types.BoolValue(true)
+	plan, config = planUpdate(t, r, state, data, fUsername, fPassword, fStagedRotation, fRotateTrigger, fCommitRotation)
+	committed := stateData(t, applyUpdate(t, r, state, plan, config))
+	if !committed.AccessToken.Equal(staged.PendingAccessToken) {
+		t.Errorf("access_token = %s, want the staged one", committed.AccessToken)
+	}
+	if !committed.IssuedAt.Equal(staged.PendingIssuedAt) {
+		t.Errorf("issued_at = %s, want the date the staged pair was issued %s", committed.IssuedAt, staged.PendingIssuedAt)
+	}
+	if !committed.PendingAccessToken.IsNull() || !committed.PendingIssuedAt.IsNull() {
+		t.Errorf("pending_access_token = %s, pending_issued_at = %s, want null", committed.PendingAccessToken, committed.PendingIssuedAt)
+	}
+}
+
+func TestImportVcertCredentials(t *testing.T) {
+	t.Setenv(envVcertToken, "vcert-access")
+	t.Setenv(envVcertUser, "vcert-user")
+	t.Setenv(envVcertPassword, "vcert-password")
+	providerData := &model.ProviderData{URL: types.StringValue("https://tpp.venafi.example")}
+
+	t.Run("no credential in the import ID", func(t *testing.T) {
+		data := importID(t, providerData, "client_id=own-client")
+		if data.AccessToken.ValueString() != "vcert-access" || data.Username.ValueString() != "vcert-user" || data.Password.ValueString() != "vcert-password" {
+			t.Errorf("access_token = %s, username = %s, want the vcert environment variables", data.AccessToken, data.Username)
+		}
+	})
+
+	t.Run("credential in the import ID", func(t *testing.T) {
+		// The environment variables are not mixed with the credentials of the import ID
+		data := importID(t, providerData, "refresh_token=refresh")
+		if !data.AccessToken.IsNull() || !data.Username.IsNull() || !data.Password.IsNull() {
+			t.Errorf("access_token = %s, username = %s, want none", data.AccessToken, data.Username)
+		}
+	})
+}
+
+func TestImportInsufficientAttributes(t *testing.T) {
+	t.Setenv(envURL, "")
+	t.Setenv(envVcertURL, "")
+	t.Setenv(envTrustBundle, "")
+	t.Setenv(envVcertTrustBundle, "")
+	r := &CredentialResource{providerData: configureProvider(t, nil)}
+
+	tests := []struct {
+		name string
+		id   string
+		// want are the missing attributes reported, nil when the import is sufficient
+		want []string
+	}{
+		{"access token without url", "access_token=access", []string{"url (or the provider url)"}},
+		{"url only", "url=https://tpp.venafi.example", []string{"a token (access_token, refresh_token or vault_token_path)"}},
+		{"nothing usable", "client_id=app", []string{"url (or the provider url)", "a token"}},
+		{"username without password", "url=https://tpp.venafi.example,username=tppadmin", []string{"password to go with username"}},
+		{"password without username", "url=https://tpp.venafi.example,password=password", []string{"username to go with password"}},
+		{"keystore without password", "url=https://tpp.venafi.example,p12_cert_filename=client.p12",
+			[]string{"p12_cert_password, p12_password_command or p12_password_from_sidecar to go with p12_cert_filename"}},
+		{"blank password", "url=https://tpp.venafi.example,username=tppadmin,password=", []string{"password to go with username"}},
+		{"blank tokens", "url=https://tpp.venafi.example,access_token=,refresh_token=", []string{"a token"}},
+		{"access token", "url=https://tpp.venafi.example,access_token=access", nil},
+		{"username and password", "url=https://tpp.venafi.example,username=tppadmin,password=password", nil},
+		{"keystore and password", "url=https://tpp.venafi.example,p12_cert_filename=client.p12,p12_cert_password=secret", nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var diags diag.Diagnostics
+			_, ok := r.importData(context.Background(), test.id, &diags)
+			if test.want == nil {
+				if !ok || diags.HasError() {
+					t.Errorf("import rejected: %v", diags)
+				}
+				return
+			}
+			if ok || !diags.HasError() {
+				t.Fatal("insufficient import accepted")
+			}
+			detail := diags.Errors()[0].Detail()
+			for _, missing := range test.want {
+				if !strings.Contains(detail, missing) {
+					t.Errorf("error = %s, want %q reported missing", detail, missing)
+				}
+			}
+		})
+	}
+}
+
+func TestImportSeparators(t *testing.T) {
+	tests := []struct {
+		name string
+		id   string
+		want map[string]string
+	}{
+		{"comma", "url=https://tpp.venafi.example,username=tppadmin,password=secret",
+			map[string]string{"url": "https://tpp.venafi.example", "username": "tppadmin", "password": "secret"}},
+		{"semicolon hint", "sep=;url=https://tpp.venafi.example;username=tppadmin;password=se,cr=et",
+			map[string]string{"url": "https://tpp.venafi.example", "username": "tppadmin", "password": "se,cr=et"}},
+		{"pipe hint", "sep=|url=https://tpp.venafi.example|password=a;b",
+			map[string]string{"url": "https://tpp.venafi.example", "password": "a;b"}},
+		{"query form", "url=https://tpp.venafi.example&username=tppadmin&password=secret",
+			map[string]string{"url": "https://tpp.venafi.example", "username": "tppadmin", "password": "secret"}},
+		// A comma keeps the default separator, an ampersand then being part of the value
+		{"ampersand in a value", "url=https://tpp.venafi.example,password=a&b",
+			map[string]string{"url": "https://tpp.venafi.example", "password": "a&b"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			values, err := getValuesMap(context.Background(), test.id)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !reflect.DeepEqual(values, test.want) {
+				t.Errorf("values = %v, want %v", values, test.want)
+			}
+		})
+	}
+
+	t.Run("imported", func(t *testing.T) {
+		providerData := configureProvider(t, nil)
+		data := importID(t, providerData, "sep=;url=https://tpp.venafi.example;username=tppadmin;password=se,cret")
+		if data.URL.ValueString() != "https://tpp.venafi.example" || data.Username.ValueString() != "tppadmin" || data.Password.ValueString() != "se,cret" {
+			t.Errorf("url = %s, username = %s, password = %s", data.URL, data.Username, data.Password)
+		}
+		data = importID(t, providerData, "url=https://tpp.venafi.example&access_token=access")
+		if data.URL.ValueString() != "https://tpp.venafi.example" || data.AccessToken.ValueString() != "access" {
+			t.Errorf("url = %s, access_token = %s", data.URL, data.AccessToken)
+		}
+	})
+}
+
+// validateConfig validates a configuration setting attributes only, with the ValidateConfig of the credential resource
+func validateConfig(t *testing.T, attributes map[string]interface{}) diag.Diagnostics {
+	t.Helper()
+	ctx := context.Background()
+
+	r := NewCredentialResource().(*CredentialResource)
+	var schemaResp resource.SchemaResponse
+	r.Schema(ctx, resource.SchemaRequest{}, &schemaResp)
+	objectType := schemaResp.Schema.Type().TerraformType(ctx).(tftypes.Object)
+	values := make(map[string]tftypes.Value, len(objectType.AttributeTypes))
+	for name, attributeType := range objectType.AttributeTypes {
+		values[name] = tftypes.NewValue(attributeType, attributes[name])
+	}
+
+	var resp resource.ValidateConfigResponse
+	r.ValidateConfig(ctx, resource.ValidateConfigRequest{
+		Config: tfsdk.Config{Schema: schemaResp.Schema, Raw: tftypes.NewValue(objectType, values)},
+	}, &resp)
+	return resp.Diagnostics
+}
+
+func TestValidateConfigReportsEveryProblem(t *testing.T) {
+	diags := validateConfig(t, map[string]interface{}{
+		fURL:                  "ftp://tpp.venafi.example",
+		fUsername:             "tppadmin",
+		fP12Password:          "secret",
+		fP12PasswordCommand:   "pass show venafi",
+		fRefreshWindow:        -1,
+		fRefreshWindowPercent: 150,
+	})
+
+	for _, want := range []struct {
+		attribute string
+		error     bool
+		detail    string
+	}{
+		{fURL, true, "unsupported scheme"},
+		{fPassword, false, "password is required with username"},
+		{fP12PasswordCommand, true, "conflicting"},
+		{fP12Cert, false, "p12_cert_filename is required"},
+		{fRefreshWindow, true, "must not be negative"},
+		{fRefreshWindowPercent, true, ""},
+	} {
+		found := false
+		for _, d := range diags {
+			withPath, ok := d.(diag.DiagnosticWithPath)
+			if ok && withPath.Path().Equal(path.Root(want.attribute)) && (d.Severity() == diag.SeverityError) == want.error &&
+				strings.Contains(d.Detail(), want.detail) {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("no diagnostic for %s with %q in %v", want.attribute, want.detail, diags)
+		}
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		diags := validateConfig(t, map[string]interface{}{
+			fURL:      "https://tpp.venafi.example/vedsdk",
+			fUsername: "tppadmin",
+			fPassword: "password",
+		})
+		if len(diags) != 0 {
+			t.Errorf("diagnostics = %v, want none", diags)
+		}
+	})
+}
+
+func TestImportedExpiration(t *testing.T) {
+	const day = 24 * time.Hour
+	server := tpptest.NewServer(t)
+	r, _ := importServerState(t, server)
+	// The access tokens cannot be introspected
+	server.Handle(tpptest.PathVerifyToken, func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	tests := []struct {
+		name        string
+		expiresIn   time.Duration
+		wantRotated bool
+	}{
+		{"outside refresh window", 60 * day, false},
+		{"within refresh window", 10 * day, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			grant := server.IssueGrant(vcertclient.DefaultScope)
+			expiration := time.Now().Add(test.expiresIn).Unix()
+			id := fmt.Sprintf("%s=%s,%s=%s,%s=%d", fAccessToken, grant.AccessToken, fRefreshToken, grant.RefreshToken, fExpirationDate, expiration)
+
+			data := stateData(t, readState(t, r, importState(t, r, id)))
+			if rotated := data.AccessToken.ValueString() != grant.AccessToken; rotated != test.wantRotated {
+				t.Fatalf("rotated = %t, want %t", rotated, test.wantRotated)
+			}
+			if test.wantRotated {
+				return
+			}
+			if data.ExpirationDate.ValueInt64() != expiration {
+				t.Errorf("expiration = %s, want the imported %d", data.ExpirationDate, expiration)
+			}
+			if want := expiration - int64(defaultRefreshWindow)*int64(day/time.Second); data.RefreshDueAt.ValueInt64() != want {
+				t.Errorf("refresh_due_at = %s, want %d", data.RefreshDueAt, want)
+			}
+		})
+	}
+}
+
+func TestClockSkew(t *testing.T) {
+	tests := []struct {
+		name        string
+		clockOffset time.Duration
+		wantRotated bool
+	}{
+		{"in sync", 0, true},
+		// The local clock is an hour ahead of TLSPDC
+		{"local clock ahead", -time.Hour, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := tpptest.NewServer(t)
+			r, state := importServerState(t, server)
+			server.ClockOffset = test.clockOffset
+
+			// The refresh window started five minutes ago according to the local clock
+			data := stateData(t, state)
+			data.ExpirationDate = types.Int64Value(time.Now().Add(-5*time.Minute).Unix() + refreshWindowSeconds(&data))
+			state = setStateData(t, state, data)
+
+			resp := resource.ReadResponse{State: state}
+			r.Read(context.Background(), resource.ReadRequest{State: state}, &resp)
+			if resp.Diagnostics.HasError() {
+				t.Fatalf("read failed: %v", resp.Diagnostics)
+			}
+			read := stateData(t, resp.State)
+			if rotated := !read.AccessToken.Equal(data.AccessToken); rotated != test.wantRotated {
+				t.Errorf("rotated = %t, want %t", rotated, test.wantRotated)
+			}
+			skipped := false
+			for _, warning := range resp.Diagnostics.Warnings() {
+				// The Date header has a precision of a second
+				skipped = skipped || strings.HasPrefix(warning.Detail(), "Rotation skipped: the local clock is 1h0m")
+			}
+			if skipped == test.wantRotated {
+				t.Errorf("rotation skipped warning = %t, want %t: %v", skipped, !test.wantRotated, resp.Diagnostics)
+			}
+		})
+	}
+}
+
+func TestPrunePreviousGrants(t *testing.T) {
+	tests := []struct {
+		name         string
+		prune        bool
+		refreshToken bool
+		vaultPath    string
+		wantRevoked  bool
+	}{
+		{"enabled", true, false, "", true},
+		{"disabled", false, false, "", false},
+		// The refresh token extends the same grant
+		{"refresh token", true, true, "", false},
+		{"shared through vault", true, false, "secret/data/venafi/tpp", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := tpptest.NewServer(t)
+			_, state := importServerState(t, server)
+			// A grant of the same identity held by another resource
+			other := server.IssueGrant(defaultScope)
+
+			data := stateData(t, state)
+			previous, _ := server.GrantOf(data.AccessToken.ValueString())
+			data.PrunePreviousGrants = types.BoolValue(test.prune)
+			data.VaultTokenPath = stringOrNull(test.vaultPath)
+			if !test.refreshToken {
+				data.RefreshToken = types.StringNull()
+			}
+
+			var diags diag.Diagnostics
+			if err := rotateToken(context.Background(), &data, &diags); err != nil || diags.HasError() {
+				t.Fatalf("rotation failed: %v, %v", err, diags)
+			}
+			if data.AccessToken.ValueString() == previous.AccessToken {
+				t.Fatal("access token not rotated")
+			}
+
+			grants := server.Grants()
+			if revoked := grants[previous.ID-1].Revoked; revoked != test.wantRevoked {
+				t.Errorf("previous grant revoked = %t, want %t", revoked, test.wantRevoked)
+			}
+			if grants[other.ID-1].Revoked {
+				t.Error("grant of another resource revoked")
+			}
+			if current, _ := server.GrantOf(data.AccessToken.ValueString()); current.Revoked {
+				t.Error("new grant revoked")
+			}
+		})
+	}
+}
+
+func TestDestroyNeverRotates(t *testing.T) {
+	// destroy plans and applies the destruction of state with r, failing the test on error
+	destroy := func(t *testing.T, r *CredentialResource, state tfsdk.State) {
+		t.Helper()
+		ctx := context.Background()
+
+		plan := tfsdk.Plan{Schema: state.Schema, Raw: tftypes.NewValue(state.Schema.Type().TerraformType(ctx), nil)}
+		planResp := resource.ModifyPlanResponse{Plan: plan}
+		r.ModifyPlan(ctx, resource.ModifyPlanRequest{State: state, Plan: plan, Config: tfsdk.Config{Schema: state.Schema, Raw: plan.Raw}}, &planResp)
+		if planResp.Diagnostics.HasError() || !planResp.Plan.Raw.IsNull() {
+			t.Fatalf("destroy plan = %s, diagnostics %v, want the null plan kept", planResp.Plan.Raw, planResp.Diagnostics)
+		}
+		resp := resource.DeleteResponse{State: state}
+		r.Delete(ctx, resource.DeleteRequest{State: state}, &resp)
+		if resp.Diagnostics.HasError() {
+			t.Fatalf("destroy failed: %v", resp.Diagnostics)
+		}
+	}
+	// requested returns the number of requests granting a token pair received by server
+	requested := func(server *tpptest.Server) int {
+		return server.Requests(tpptest.PathAuthorizeOAuth) + server.Requests(tpptest.PathRefreshToken)
+	}
+
+	t.Run("expired", func(t *testing.T) {
+		server := tpptest.NewServer(t)
+		r, state := importServerState(t, server)
+		data := stateData(t, state)
+		data.ExpirationDate = types.Int64Value(time.Now().Add(-time.Hour).Unix())
+		state = setStateData(t, state, data)
+		requests, grants := requested(server), len(server.Grants())
+
+		destroy(t, r, state)
+		if requested(server) != requests || len(server.Grants()) != grants {
+			t.Error("token pair rotated on destroy")
+		}
+		if !revoked(t, server, data.AccessToken) {
+			t.Error("token pair not revoked on destroy")
+		}
+	})
+
+	t.Run("rotated in the background", func(t *testing.T) {
+		// The pair rotated before the destroy is revoked too, rather than adopted
+		server := tpptest.NewServer(t)
+		r, state := importServerState(t, server)
+		data := stateData(t, state)
+		refresher := newBackgroundRefresher()
+		previous := backgroundRefreshes
+		backgroundRefreshes = refresher
+		t.Cleanup(func() {
+			refresher.stop()
+			backgroundRefreshes = previous
+		})
+		rotated := server.IssueGrant(vcertclient.DefaultScope)
+		refresher.rotated[accessTokenFingerprint(data.AccessToken)] = recoveredPair{URL: server.URL, AccessToken: rotated.AccessToken,
+			RefreshToken: rotated.RefreshToken, Expiration: rotated.ExpiresAt.Unix()}
+		requests := requested(server)
+
+		destroy(t, r, state)
+		if requested(server) != requests {
+			t.Error("token pair rotated on destroy")
+		}
+		if !revoked(t, server, data.AccessToken) || !revoked(t, server, types.StringValue(rotated.AccessToken)) {
+			t.Error("token pairs not revoked on destroy")
+		}
+	})
+}
+
+func TestMaintenanceModeDiagnostic(t *testing.T) {
+	server := tpptest.NewServer(t)
+	r, state := importServerState(t, server)
+	server.Expire(stateData(t, state).AccessToken.ValueString())
+	// requested returns the number of requests granting a token pair received by server
+	requested := func() int {
+		return server.Requests(tpptest.PathRefreshToken) + server.Requests(tpptest.PathAuthorizeOAuth)
+	}
+	requests := requested()
+	for _, path := range []string{tpptest.PathRefreshToken, tpptest.PathAuthorizeOAuth} {
+		server.Handle(path, func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("Content-Type", "text/html")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte("<html><body>TLSPDC is under maintenance</body></html>"))
+		})
+	}
+
+	resp := resource.ReadResponse{State: state}
+	r.Read(context.Background(), resource.ReadRequest{State: state}, &resp)
+	errs := resp.Diagnostics.Errors()
+	if len(errs) != 1 || errs[0].Summary() != "TLSPDC In Maintenance" || !strings.Contains(errs[0].Detail(), "retry later") {
+		t.Fatalf("diagnostics = %v, want TLSPDC reported in maintenance", resp.Diagnostics)
+	}
+	// Neither retried nor falling back to username and password
+	if requested()-requests != 1 {
+		t.Errorf("%d token request(s), want a single one", requested()-requests)
+	}
+}
@@ -0,0 +1,304 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/model"
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/vcertclient"
+)
+
+const (
+	describeNameSuffix = "describe"
+
+	fImportID                  = "import_id"
+	fConnectorType             = "connector_type"
+	fTrustBundleCertCount      = "trust_bundle_cert_count"
+	fProxyTrustBundleCertCount = "proxy_trust_bundle_cert_count"
+	fAuthMethods               = "auth_methods"
+	fSettings                  = "settings"
+	fDescribeJSON              = "json"
+
+	msgDescribeDataSourceError = "describe data source error"
+
+	// describeSensitive replaces the value of the sensitive settings
+	describeSensitive = "(sensitive)"
+	// describeSet replaces the value of the settings holding files or their content, e.g. keystores
+	describeSet = "(set)"
+)
+
+// describeContentAttributes are the settings holding a file or its content, only reported as set
+var describeContentAttributes = map[string]bool{
+	fTrustBundle:      true,
+	fProxyTrustBundle: true,
+	fP12Cert:          true,
+	fP12CertFilenames: true,
+}
+
+var (
+	_ datasource.DataSource              = &DescribeDataSource{}
+	_ datasource.DataSourceWithConfigure = &DescribeDataSource{}
+)
+
+func NewDescribeDataSource() datasource.DataSource {
+	return &DescribeDataSource{}
+}
+
+// DescribeDataSource reports the settings a venafi-token_credential resource resolves from its import string and the
+// provider configuration, for support tickets. It never contacts TLSPDC nor reports any secret.
+type DescribeDataSource struct {
+	providerData *model.ProviderData
+}
+
+func (d *DescribeDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = fmt.Sprintf("%s_%s", req.ProviderTypeName, describeNameSuffix)
+}
+
+func (d *DescribeDataSource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Provider data is not available until the provider has been configured
+	if req.ProviderData == nil {
+		return
+	}
+
+	providerData, ok := req.ProviderData.(*model.ProviderData)
+	if !ok {
+		resp.Diagnostics.AddError(msgDescribeDataSourceError, fmt.Sprintf("unexpected provider data type: %T", req.ProviderData))
+		return
+	}
+	d.providerData = providerData
+}
+
+func (d *DescribeDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Sanitized dump of the settings resolved for a venafi-token_credential resource, for support tickets. TLSPDC is not contacted and secrets are never reported",
+
+		Attributes: map[string]schema.Attribute{
+			fImportID: schema.StringAttribute{
+				MarkdownDescription: "Import string of the credential resource to describe",
+				Required:            true,
+				Sensitive:           true,
+			},
+			fConnectorType: schema.StringAttribute{
+				MarkdownDescription: "Type of the vcert connector",
+				Computed:            true,
+			},
+			fEffectiveURL: schema.StringAttribute{
+				MarkdownDescription: "Base URL the requests are sent to, once url was normalized",
+				Computed:            true,
+			},
+			fTrustBundleCertCount: schema.Int64Attribute{
+				MarkdownDescription: "Number of certificates trusted for TLSPDC, from trust_bundle and trust_bundle_system_name. 0 when the system roots are used",
+				Computed:            true,
+			},
+			fProxyTrustBundleCertCount: schema.Int64Attribute{
+				MarkdownDescription: "Number of certificates of proxy_trust_bundle",
+				Computed:            true,
+			},
+			fAuthMethods: schema.ListAttribute{
+				MarkdownDescription: "Authentication methods tried to get a new token pair, in order",
+				ElementType:         types.StringType,
+				Computed:            true,
+			},
+			fConfigSource: schema.MapAttribute{
+				MarkdownDescription: "Where the values of url, trust_bundle, client_id and refresh_window come from: `resource`, `provider`, `env` or `default`",
+				ElementType:         types.StringType,
+				Computed:            true,
+			},
+			fSettings: schema.MapAttribute{
+				MarkdownDescription: "Every argument of the resource that is set, by name. Sensitive values are replaced with `(sensitive)`, and files or their content with `(set)`",
+				ElementType:         types.StringType,
+				Computed:            true,
+			},
+			fVCertVersion: schema.StringAttribute{
+				MarkdownDescription: "Version of the vcert SDK used by the provider",
+				Computed:            true,
+			},
+			fDescribeJSON: schema.StringAttribute{
+				MarkdownDescription: "JSON object holding all the above, to attach to support tickets",
+				Computed:            true,
+			},
+		},
+	}
+}
+
+// describeData is the content of the describe data source
+type describeData struct {
+	ImportID                  types.String `tfsdk:"import_id"`
+	ConnectorType             types.String `tfsdk:"connector_type"`
+	EffectiveURL              types.String `tfsdk:"effective_url"`
+	TrustBundleCertCount      types.Int64  `tfsdk:"trust_bundle_cert_count"`
+	ProxyTrustBundleCertCount types.Int64  `tfsdk:"proxy_trust_bundle_cert_count"`
+	AuthMethods               types.List   `tfsdk:"auth_methods"`
+	ConfigSource              types.Map    `tfsdk:"config_source"`
+	Settings                  types.Map    `tfsdk:"settings"`
+	VCertVersion              types.String `tfsdk:"vcert_version"`
+	JSON                      types.String `tfsdk:"json"`
+}
+
+// describeJSON is the content of the json attribute. It must never hold a secret.
+type describeJSON struct {
+	ConnectorType             string            `json:"connector_type"`
+	EffectiveURL              string            `json:"effective_url"`
+	TrustBundleCertCount      int               `json:"trust_bundle_cert_count"`
+	ProxyTrustBundleCertCount int               `json:"proxy_trust_bundle_cert_count"`
+	AuthMethods               []string          `json:"auth_methods"`
+	ConfigSource              map[string]string `json:"config_source"`
+	Settings                  map[string]string `json:"settings"`
+	VCertVersion              string            `json:"vcert_version"`
+}
+
+func (d *DescribeDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data describeData
+	diags := req.Config.Get(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	credential := &CredentialResource{providerData: d.providerData}
+	credentialData, ok := credential.importData(ctx, data.ImportID.ValueString(), &resp.Diagnostics)
+	if !ok {
+		return
+	}
+
+	description := describeJSON{
+		ConnectorType: connectorTypeTPP,
+		AuthMethods:   vcertclient.New(ctx, credentialData).AuthMethods(),
+		ConfigSource:  configSources(&credentialData),
+		Settings:      describeSettings(ctx, credentialData),
+		VCertVersion:  vcertclient.SDKVersion(),
+	}
+	if description.AuthMethods == nil {
+		description.AuthMethods = []string{}
+	}
+	var err error
+	if description.EffectiveURL, err = vcertclient.NormalizedURL(credentialData.URL.ValueString()); err != nil {
+		resp.Diagnostics.AddError(msgDescribeDataSourceError, err.Error())
+		return
+	}
+	if description.TrustBundleCertCount, description.ProxyTrustBundleCertCount, err = trustBundleCertCounts(credentialData); err != nil {
+		resp.Diagnostics.AddError(msgDescribeDataSourceError, err.Error())
+		return
+	}
+
+	content, err := json.Marshal(description)
+	if err != nil {
+		resp.Diagnostics.AddError(msgDescribeDataSourceError, err.Error())
+		return
+	}
+
+	data.ConnectorType = types.StringValue(description.ConnectorType)
+	data.EffectiveURL = types.StringValue(description.EffectiveURL)
+	data.TrustBundleCertCount = types.Int64Value(int64(description.TrustBundleCertCount))
+	data.ProxyTrustBundleCertCount = types.Int64Value(int64(description.ProxyTrustBundleCertCount))
+	data.AuthMethods, diags = types.ListValueFrom(ctx, types.StringType, description.AuthMethods)
+	resp.Diagnostics.Append(diags...)
+	data.ConfigSource = credentialData.ConfigSource
+	data.Settings, diags = types.MapValueFrom(ctx, types.StringType, description.Settings)
+	resp.Diagnostics.Append(diags...)
+	data.VCertVersion = types.StringValue(description.VCertVersion)
+	data.JSON = types.StringValue(string(content))
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	diags = resp.State.Set(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+}
+
+// describeSettings returns the arguments of the credential resource set in data, by name, with the sensitive values
+// and the content of the files redacted
+func describeSettings(ctx context.Context, data model.CredentialResourceData) map[string]string {
+	var schemaResp resource.SchemaResponse
+	(&CredentialResource{}).Schema(ctx, resource.SchemaRequest{}, &schemaResp)
+
+	settings := make(map[string]string)
+	dataValue := reflect.ValueOf(data)
+	dataType := dataValue.Type()
+	for i := 0; i < dataValue.NumField(); i++ {
+		name := dataType.Field(i).Tag.Get("tfsdk")
+		attribute, ok := schemaResp.Schema.Attributes[name]
+		if !ok || !attribute.IsOptional() {
+			continue
+		}
+		value, ok := dataValue.Field(i).Interface().(attr.Value)
+		if !ok || value.IsNull() || value.IsUnknown() {
+			continue
+		}
+		switch {
+		case attribute.IsSensitive():
+			settings[name] = describeSensitive
+		case describeContentAttributes[name]:
+			settings[name] = describeSet
+		default:
+			settings[name] = describeValue(value)
+		}
+	}
+	return settings
+}
+
+// describeValue returns value as a string, the elements of lists being separated by semicolons as in the import string
+func describeValue(value attr.Value) string {
+	switch v := value.(type) {
+	case types.String:
+		return v.ValueString()
+	case types.Int64:
+		return strconv.FormatInt(v.ValueInt64(), 10)
+	case types.Bool:
+		return strconv.FormatBool(v.ValueBool())
+	case types.List:
+		var elements []string
+		for _, element := range v.Elements() {
+			elements = append(elements, describeValue(element))
+		}
+		return strings.Join(elements, ";")
+	case types.Map:
+		var elements []string
+		for key, element := range v.Elements() {
+			elements = append(elements, fmt.Sprintf("%s=%s", key, describeValue(element)))
+		}
+		sort.Strings(elements)
+		return strings.Join(elements, ";")
+	default:
+		return value.String()
+	}
+}
+
+// trustBundleCertCounts returns the number of certificates trusted for TLSPDC and for the proxy, loaded as for the
+// connections to TLSPDC
+func trustBundleCertCounts(data model.CredentialResourceData) (int, int, error) {
+	trusted, proxy := 0, 0
+	if !data.TrustBundle.IsNull() {
+		bundle, err := vcertclient.LoadTrustBundle(data.TrustBundle.ValueString())
+		if err != nil {
+			return 0, 0, err
+		}
+		trusted += vcertclient.TrustBundleCertificateCount(bundle)
+	}
+	if !data.TrustBundleSystemName.IsNull() {
+		bundle, err := vcertclient.LoadSystemTrustBundle(data.TrustBundleSystemName.ValueString())
+		if err != nil {
+			return 0, 0, err
+		}
+		trusted += vcertclient.TrustBundleCertificateCount(bundle)
+	}
+	if !data.ProxyTrustBundle.IsNull() {
+		bundle, err := vcertclient.LoadTrustBundle(data.ProxyTrustBundle.ValueString())
+		if err != nil {
+			return 0, 0, err
+		}
+		proxy = vcertclient.TrustBundleCertificateCount(bundle)
+	}
+	return trusted, proxy, nil
+}
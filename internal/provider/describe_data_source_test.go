@@ -0,0 +1,138 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/model"
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/tpptest"
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/vcertclient"
+)
+
+// describe reads the describe data source of importID with the given provider data, failing the test on error
+func describe(t *testing.T, providerData *model.ProviderData, importID string) (describeData, tfsdk.State) {
+	t.Helper()
+	ctx := context.Background()
+
+	d := &DescribeDataSource{providerData: providerData}
+	var schemaResp datasource.SchemaResponse
+	d.Schema(ctx, datasource.SchemaRequest{}, &schemaResp)
+	objectType := schemaResp.Schema.Type().TerraformType(ctx).(tftypes.Object)
+	values := make(map[string]tftypes.Value, len(objectType.AttributeTypes))
+	for name, attributeType := range objectType.AttributeTypes {
+		values[name] = tftypes.NewValue(attributeType, nil)
+	}
+	values[fImportID] = tftypes.NewValue(tftypes.String, importID)
+	config := tfsdk.Config{Schema: schemaResp.Schema, Raw: tftypes.NewValue(objectType, values)}
+
+	resp := datasource.ReadResponse{State: tfsdk.State{Schema: schemaResp.Schema, Raw: tftypes.NewValue(objectType, nil)}}
+	d.Read(ctx, datasource.ReadRequest{Config: config}, &resp)
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("describe of [%s] failed: %v", importID, resp.Diagnostics)
+	}
+	var data describeData
+	if diags := resp.State.Get(ctx, &data); diags.HasError() {
+		t.Fatalf("invalid state: %v", diags)
+	}
+	return data, resp.State
+}
+
+func TestDescribe(t *testing.T) {
+	server := tpptest.NewServer(t)
+	// Not to be found in the names of the authentication methods
+	server.Password = "tpp-s3cr3t"
+	grant := server.IssueGrant(vcertclient.DefaultScope)
+	proxyCA := tpptest.NewCA(t, "Proxy CA")
+	keystore := tpptest.PKCS12File(t, tpptest.NewCA(t, "Client CA").Issue(t, "client", tpptest.CertificateOptions{}), nil, "p12-s3cr3t")
+	t.Setenv(envURL, "")
+	t.Setenv(envVcertURL, "")
+	t.Setenv(envTrustBundle, "")
+	t.Setenv(envVcertTrustBundle, "")
+	t.Setenv(envClientID, "")
+	t.Setenv(envVcertClientID, "")
+	providerData := configureProvider(t, map[string]string{fURL: server.URL, fTrustBundle: server.TrustBundle() + proxyCA.PEM})
+
+	importID := fmt.Sprintf("%s,%s=%s,%s=%s,%s=%s,%s=%s,%s=%s", serverImportID(server), fAccessToken, grant.AccessToken, fRefreshToken,
+		grant.RefreshToken, fP12Cert, keystore, fP12Password, "p12-s3cr3t", fClientID, "support-client")
+	data, state := describe(t, providerData, importID)
+
+	t.Run("no secret", func(t *testing.T) {
+		secrets := map[string]string{
+			fPassword:     server.Password,
+			fAccessToken:  grant.AccessToken,
+			fRefreshToken: grant.RefreshToken,
+			fP12Password:  "p12-s3cr3t",
+			fTrustBundle:  strings.Split(server.TrustBundle(), "\n")[1],
+		}
+		// Every attribute but import_id, which is sensitive
+		var values map[string]tftypes.Value
+		if err := state.Raw.As(&values); err != nil {
+			t.Fatal(err)
+		}
+		delete(values, fImportID)
+		for attribute, value := range values {
+			for name, secret := range secrets {
+				if strings.Contains(value.String(), secret) {
+					t.Errorf("%s found in %s: %s", name, attribute, value)
+				}
+			}
+		}
+	})
+
+	t.Run("key settings", func(t *testing.T) {
+		if data.ConnectorType.ValueString() != connectorTypeTPP {
+			t.Errorf("connector_type = %s, want %s", data.ConnectorType, connectorTypeTPP)
+		}
+		if data.EffectiveURL.ValueString() != server.URL+"/vedsdk" {
+			t.Errorf("effective_url = %s, want the WebSDK of %s", data.EffectiveURL, server.URL)
+		}
+		if data.TrustBundleCertCount.ValueInt64() != 2 {
+			t.Errorf("trust_bundle_cert_count = %s, want 2", data.TrustBundleCertCount)
+		}
+		var methods []string
+		data.AuthMethods.ElementsAs(context.Background(), &methods, false)
+		if want := []string{vcertclient.MethodRefreshToken, vcertclient.MethodClientCertificate, vcertclient.MethodUsernamePassword}; strings.Join(methods, ",") != strings.Join(want, ",") {
+			t.Errorf("auth_methods = %v, want %v", methods, want)
+		}
+		for name, want := range map[string]string{fURL: sourceProvider, fTrustBundle: sourceProvider, fClientID: sourceResource, fRefreshWindow: sourceDefault} {
+			if source := data.ConfigSource.Elements()[name]; source == nil || source.String() != fmt.Sprintf("%q", want) {
+				t.Errorf("config_source[%s] = %v, want %q", name, source, want)
+			}
+		}
+		for name, want := range map[string]string{
+			fUsername:     fmt.Sprintf("%q", server.Username),
+			fClientID:     `"support-client"`,
+			fPassword:     fmt.Sprintf("%q", describeSensitive),
+			fRefreshToken: fmt.Sprintf("%q", describeSensitive),
+			fP12Cert:      fmt.Sprintf("%q", describeSet),
+			fTrustBundle:  fmt.Sprintf("%q", describeSet),
+		} {
+			if setting := data.Settings.Elements()[name]; setting == nil || setting.String() != want {
+				t.Errorf("settings[%s] = %v, want %s", name, setting, want)
+			}
+		}
+		if data.VCertVersion.ValueString() == "" {
+			t.Error("vcert_version not set")
+		}
+		for _, key := range []string{fConnectorType, fEffectiveURL, fTrustBundleCertCount, fAuthMethods, fConfigSource, fSettings, fVCertVersion} {
+			if !strings.Contains(data.JSON.ValueString(), `"`+key+`"`) {
+				t.Errorf("json = %s, want %s", data.JSON, key)
+			}
+		}
+	})
+
+	t.Run("TLSPDC not contacted", func(t *testing.T) {
+		for _, path := range []string{tpptest.PathAuthorizeOAuth, tpptest.PathAuthorizeCertificate, tpptest.PathRefreshToken, tpptest.PathVerifyToken,
+			tpptest.PathRevokeToken, tpptest.PathIdentitySelf, tpptest.PathSystemVersion} {
+			if requests := server.Requests(path); requests != 0 {
+				t.Errorf("%d request(s) to %s", requests, path)
+			}
+		}
+	})
+}
@@ -0,0 +1,110 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/logging"
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/model"
+)
+
+const (
+	// variables written to the dotenv file
+	dotenvAccessToken  = "VENAFI_ACCESS_TOKEN"
+	dotenvRefreshToken = "VENAFI_REFRESH_TOKEN"
+	dotenvURL          = "VENAFI_URL"
+
+	dotenvFileMode = 0600
+)
+
+// writeDotenvFile writes the access token of data to dotenv_output_file after a rotation, or when the file does not
+// exist yet
+func writeDotenvFile(ctx context.Context, data *model.CredentialResourceData, diags *diag.Diagnostics) {
+	if data.DotenvOutputFile.IsNull() || data.AccessToken.IsNull() {
+		return
+	}
+
+	location := data.DotenvOutputFile.ValueString()
+	if _, err := os.Stat(location); err == nil && !data.Rotated.ValueBool() {
+		return
+	}
+
+	var content strings.Builder
+	writeDotenvVariable(&content, dotenvAccessToken, data.AccessToken.ValueString())
+	if data.DotenvRefreshToken.ValueBool() && !data.RefreshToken.IsNull() {
+		writeDotenvVariable(&content, dotenvRefreshToken, data.RefreshToken.ValueString())
+	}
+	if data.DotenvIncludeURL.ValueBool() && !data.URL.IsNull() {
+		writeDotenvVariable(&content, dotenvURL, data.URL.ValueString())
+	}
+
+	logging.Info(ctx, fmt.Sprintf("writing access token to dotenv file [%s]", location))
+	if err := writeFileAtomically(location, []byte(content.String()), dotenvFileMode); err != nil {
+		diags.AddAttributeWarning(path.Root(fDotenvOutputFile), msgCredentialResourceError,
+			fmt.Sprintf("unable to write dotenv file [%s]: %s", location, err.Error()))
+	}
+}
+
+// removeDotenvFile deletes dotenv_output_file, if any
+func removeDotenvFile(ctx context.Context, data *model.CredentialResourceData, diags *diag.Diagnostics) {
+	if data.DotenvOutputFile.IsNull() {
+		return
+	}
+
+	location := data.DotenvOutputFile.ValueString()
+	logging.Info(ctx, fmt.Sprintf("removing dotenv file [%s]", location))
+	if err := os.Remove(location); err != nil && !errors.Is(err, os.ErrNotExist) {
+		diags.AddAttributeWarning(path.Root(fDotenvOutputFile), msgCredentialResourceError,
+			fmt.Sprintf("unable to remove dotenv file [%s]: %s", location, err.Error()))
+	}
+}
+
+// writeDotenvVariable appends name=value to content. The value is double-quoted, escaping the characters interpreted
+// by dotenv parsers and shells.
+func writeDotenvVariable(content *strings.Builder, name string, value string) {
+	escaped := strings.NewReplacer(
+		`\`, `\\`,
+		`"`, `\"`,
+		`$`, `\$`,
+		"`", "\\`",
+		"\n", `\n`,
+		"\r", `\r`,
+	).Replace(value)
+	content.WriteString(fmt.Sprintf("%s=\"%s\"\n", name, escaped))
+}
+
+// writeFileAtomically writes data to a temporary file next to location, then renames it, so that readers never see a
+// partially written file
+func writeFileAtomically(location string, data []byte, mode os.FileMode) error {
+	file, err := os.CreateTemp(filepath.Dir(location), "."+filepath.Base(location)+".tmp*")
+	if err != nil {
+		return err
+	}
+	tmpLocation := file.Name()
+	defer os.Remove(tmpLocation)
+
+	if err = file.Chmod(mode); err != nil {
+		file.Close()
+		return err
+	}
+	if _, err = file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err = file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err = file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpLocation, location)
+}
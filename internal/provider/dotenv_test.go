@@ -0,0 +1,65 @@
+package provider
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/model"
+)
+
+func TestDotenvFile(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("no shell to parse the dotenv file with")
+	}
+
+	location := filepath.Join(t.TempDir(), "venafi.env")
+	data := model.CredentialResourceData{
+		URL:                types.StringValue("https://tpp.venafi.example/vedsdk"),
+		AccessToken:        types.StringValue(`access"$HOME` + "`id`" + `\token`),
+		RefreshToken:       types.StringValue("refresh token with spaces"),
+		DotenvOutputFile:   types.StringValue(location),
+		DotenvRefreshToken: types.BoolValue(true),
+		DotenvIncludeURL:   types.BoolValue(true),
+		Rotated:            types.BoolValue(true),
+	}
+
+	var diags diag.Diagnostics
+	writeDotenvFile(context.Background(), &data, &diags)
+	if diags.HasError() || diags.WarningsCount() != 0 {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	info, err := os.Stat(location)
+	if err != nil {
+		t.Fatalf("dotenv file not written: %s", err)
+	}
+	if info.Mode().Perm() != dotenvFileMode {
+		t.Errorf("mode = %s, want %s", info.Mode().Perm(), os.FileMode(dotenvFileMode))
+	}
+
+	// The values read by a shell sourcing the file are the ones of the state
+	for name, want := range map[string]string{
+		dotenvAccessToken:  data.AccessToken.ValueString(),
+		dotenvRefreshToken: data.RefreshToken.ValueString(),
+		dotenvURL:          data.URL.ValueString(),
+	} {
+		output, err := exec.Command(sh, "-c", `. "$1" && printf %s "$`+name+`"`, "sh", location).Output()
+		if err != nil {
+			t.Fatalf("invalid dotenv file: %s", err)
+		}
+		if string(output) != want {
+			t.Errorf("%s = %q, want %q", name, output, want)
+		}
+	}
+
+	removeDotenvFile(context.Background(), &data, &diags)
+	if _, err = os.Stat(location); !os.IsNotExist(err) {
+		t.Errorf("dotenv file not removed: %v", err)
+	}
+}
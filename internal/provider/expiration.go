@@ -0,0 +1,85 @@
+package provider
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/model"
+)
+
+// named formats of expiration_format. Any other value is a Go time layout.
+const (
+	expirationFormatEpoch   = "epoch"
+	expirationFormatEpochMs = "epoch_ms"
+	expirationFormatRFC3339 = "rfc3339"
+
+	defaultExpirationFormat = expirationFormatRFC3339
+)
+
+// layoutCheckTime is formatted then parsed back to check a Go time layout
+var layoutCheckTime = time.Date(2031, time.November, 23, 17, 45, 59, 0, time.UTC)
+
+// validateExpirationFormat checks format is a named format or a Go time layout holding at least one layout element,
+// e.g. 2006-01-02
+func validateExpirationFormat(format string) error {
+	switch format {
+	case expirationFormatEpoch, expirationFormatEpochMs, expirationFormatRFC3339:
+		return nil
+	}
+
+	formatted := layoutCheckTime.Format(format)
+	if formatted == format {
+		return fmt.Errorf("%s must be %s, %s, %s or a Go time layout such as 2006-01-02T15:04:05Z07:00, got [%s]",
+			fExpirationFormat, expirationFormatEpoch, expirationFormatEpochMs, expirationFormatRFC3339, format)
+	}
+	if _, err := time.Parse(format, formatted); err != nil {
+		return fmt.Errorf("%s [%s] is not a valid Go time layout: %s", fExpirationFormat, format, err.Error())
+	}
+	return nil
+}
+
+// formatExpiration formats the expiration date, in epoch format, according to format. Go layouts are applied in UTC.
+func formatExpiration(format string, expiration int64) string {
+	switch format {
+	case expirationFormatEpoch:
+		return strconv.FormatInt(expiration, 10)
+	case expirationFormatEpochMs:
+		return strconv.FormatInt(expiration*1000, 10)
+	case expirationFormatRFC3339:
+		return time.Unix(expiration, 0).UTC().Format(time.RFC3339)
+	}
+	return time.Unix(expiration, 0).UTC().Format(format)
+}
+
+// neverExpires reports whether TLSPDC granted the access token of data without an expiration date, returned as 0
+// when the API integration sets no token validity. Such a token is only rotated when forced or scheduled.
+func neverExpires(data *model.CredentialResourceData) bool {
+	return !data.ExpirationDate.IsNull() && !data.ExpirationDate.IsUnknown() && data.ExpirationDate.ValueInt64() <= 0
+}
+
+// setNeverExpires sets never_expires from the expiration date of data, null when the date is not known
+func setNeverExpires(data *model.CredentialResourceData) {
+	if data.AccessToken.IsNull() || data.ExpirationDate.IsNull() || data.ExpirationDate.IsUnknown() {
+		data.NeverExpires = types.BoolNull()
+		return
+	}
+	data.NeverExpires = types.BoolValue(neverExpires(data))
+}
+
+// setExpirationFormatted sets expiration_formatted from the expiration date of data, null when the date is not known
+// or when the token never expires
+func setExpirationFormatted(data *model.CredentialResourceData) {
+	if data.ExpirationDate.IsNull() || data.ExpirationDate.IsUnknown() || neverExpires(data) {
+		data.ExpirationFormatted = types.StringNull()
+		return
+	}
+
+	format := defaultExpirationFormat
+	if !data.ExpirationFormat.IsNull() && data.ExpirationFormat.ValueString() != "" {
+		format = data.ExpirationFormat.ValueString()
+	}
+	data.ExpirationFormatted = types.StringValue(formatExpiration(format, data.ExpirationDate.ValueInt64()))
+}
@@ -0,0 +1,120 @@
+package provider
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/model"
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/tpptest"
+)
+
+func TestExpirationFormatted(t *testing.T) {
+	expiration := time.Date(2026, time.March, 4, 5, 6, 7, 0, time.UTC).Unix()
+
+	tests := []struct {
+		name       string
+		format     types.String
+		expiration types.Int64
+		want       types.String
+	}{
+		{"default", types.StringNull(), types.Int64Value(expiration), types.StringValue("2026-03-04T05:06:07Z")},
+		{"epoch", types.StringValue("epoch"), types.Int64Value(expiration), types.StringValue("1772600767")},
+		{"epoch_ms", types.StringValue("epoch_ms"), types.Int64Value(expiration), types.StringValue("1772600767000")},
+		{"rfc3339", types.StringValue("rfc3339"), types.Int64Value(expiration), types.StringValue("2026-03-04T05:06:07Z")},
+		{"Go layout", types.StringValue("02/01/2006 15:04"), types.Int64Value(expiration), types.StringValue("04/03/2026 05:06")},
+		{"never expires", types.StringValue("epoch"), types.Int64Value(0), types.StringNull()},
+		{"unknown expiration", types.StringValue("epoch"), types.Int64Null(), types.StringNull()},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data := &model.CredentialResourceData{ExpirationFormat: test.format, ExpirationDate: test.expiration}
+			setExpirationFormatted(data)
+			if !data.ExpirationFormatted.Equal(test.want) {
+				t.Errorf("expiration_formatted = %s, want %s", data.ExpirationFormatted, test.want)
+			}
+		})
+	}
+}
+
+func TestValidateExpirationFormat(t *testing.T) {
+	for _, format := range []string{"epoch", "epoch_ms", "rfc3339", "2006-01-02", time.RFC1123, "Jan _2 15:04:05"} {
+		if err := validateExpirationFormat(format); err != nil {
+			t.Errorf("%s rejected: %s", format, err)
+		}
+	}
+	// Layouts without any element format every date the same
+	for _, format := range []string{"", "iso", "YYYY-MM-DD", "EPOCH"} {
+		if err := validateExpirationFormat(format); err == nil {
+			t.Errorf("%q accepted", format)
+		}
+	}
+}
+
+func TestNeverExpires(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name       string
+		expiration types.Int64
+		want       types.Bool
+		wantDue    bool
+	}{
+		{"zero expiration", types.Int64Value(0), types.BoolValue(true), false},
+		{"negative expiration", types.Int64Value(-1), types.BoolValue(true), false},
+		{"expired", types.Int64Value(now.Add(-time.Hour).Unix()), types.BoolValue(false), true},
+		{"within the refresh window", types.Int64Value(now.Add(10 * 24 * time.Hour).Unix()), types.BoolValue(false), true},
+		{"not due", types.Int64Value(now.Add(60 * 24 * time.Hour).Unix()), types.BoolValue(false), false},
+		{"unknown expiration", types.Int64Null(), types.BoolNull(), true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data := &model.CredentialResourceData{AccessToken: types.StringValue("access"), ExpirationDate: test.expiration,
+				RefreshWindow: types.Int64Value(30), MinRemainingForOp: types.Int64Value(3600)}
+			setNeverExpires(data)
+			if !data.NeverExpires.Equal(test.want) {
+				t.Errorf("never_expires = %s, want %s", data.NeverExpires, test.want)
+			}
+			if due := withinRefreshWindow(data, now); due != test.wantDue {
+				t.Errorf("within the refresh window = %t, want %t", due, test.wantDue)
+			}
+			if neverExpires(data) && lacksOperationHeadroom(data, now) {
+				t.Error("token that never expires lacking headroom")
+			}
+		})
+	}
+}
+
+func TestZeroExpiryResponse(t *testing.T) {
+	server := tpptest.NewServer(t)
+	server.NeverExpires = true
+	r, state := importServerState(t, server)
+	data := stateData(t, state)
+	if !data.NeverExpires.ValueBool() || data.ExpirationDate.ValueInt64() != 0 {
+		t.Fatalf("never_expires = %s with expiration_date = %s, want the token granted without expiration", data.NeverExpires, data.ExpirationDate)
+	}
+	if !data.ExpirationFormatted.IsNull() || !data.RefreshDueAt.IsNull() {
+		t.Errorf("expiration_formatted = %s and refresh_due_at = %s, want both null", data.ExpirationFormatted, data.RefreshDueAt)
+	}
+	configured := []string{fUsername, fPassword, fRotateTrigger}
+
+	// Never due for an expiry-driven rotation
+	grants := len(server.Grants())
+	state = readState(t, r, state)
+	plan, _ := planUpdate(t, r, state, stateData(t, state), configured...)
+	if unknownAttributes(t, plan)[fAccessToken] || len(server.Grants()) != grants {
+		t.Fatal("token that never expires rotated")
+	}
+
+	// Rotated when forced
+	forced := stateData(t, state)
+	forced.RotateTrigger = types.StringValue("1")
+	plan, config := planUpdate(t, r, state, forced, configured...)
+	if !unknownAttributes(t, plan)[fAccessToken] {
+		t.Fatal("forced rotation not planned")
+	}
+	rotated := stateData(t, applyUpdate(t, r, state, plan, config))
+	if rotated.AccessToken.Equal(data.AccessToken) || !rotated.NeverExpires.ValueBool() {
+		t.Errorf("access_token = %s with never_expires = %s, want a new token that never expires", rotated.AccessToken, rotated.NeverExpires)
+	}
+}
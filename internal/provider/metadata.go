@@ -0,0 +1,92 @@
+package provider
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/model"
+)
+
+// connectorTypeTPP is the connector type reported in metadata_json, the only one supported by the provider
+const connectorTypeTPP = "TPP"
+
+// tokenMetadata is the content of metadata_json. It must never hold a token or any other secret.
+type tokenMetadata struct {
+	ConnectorType    string   `json:"connector_type"`
+	ClientID         string   `json:"client_id,omitempty"`
+	Expiration       *int64   `json:"expiration"`
+	IssuedAt         *int64   `json:"issued_at"`
+	GrantedScopes    []string `json:"granted_scopes"`
+	TokenFingerprint string   `json:"token_fingerprint,omitempty"`
+}
+
+// setMetadataJSON sets metadata_json from the non-sensitive attributes of data
+func setMetadataJSON(data *model.CredentialResourceData) {
+	metadata := tokenMetadata{
+		ConnectorType:    connectorTypeTPP,
+		ClientID:         data.ClientID.ValueString(),
+		Expiration:       int64OrNil(data.ExpirationDate),
+		IssuedAt:         int64OrNil(data.IssuedAt),
+		GrantedScopes:    []string{},
+		TokenFingerprint: accessTokenFingerprint(data.AccessToken),
+	}
+	for _, element := range data.GrantedScopes.Elements() {
+		if scope, ok := element.(types.String); ok {
+			metadata.GrantedScopes = append(metadata.GrantedScopes, scope.ValueString())
+		}
+	}
+
+	content, err := json.Marshal(metadata)
+	if err != nil {
+		data.MetadataJSON = types.StringNull()
+		return
+	}
+	data.MetadataJSON = types.StringValue(string(content))
+}
+
+// setResourceIdentity sets resource_identity, unless it is already known: it must not change once set, so that the
+// resource can be correlated across rotations. Resources imported before it existed get it on their next refresh.
+func setResourceIdentity(data *model.CredentialResourceData) {
+	if !data.ResourceIdentity.IsNull() && !data.ResourceIdentity.IsUnknown() {
+		return
+	}
+	digest := sha256.Sum256([]byte(strings.Join([]string{data.URL.ValueString(), data.ClientID.ValueString(), credentialIdentity(data)}, "\x00")))
+	data.ResourceIdentity = types.StringValue(hex.EncodeToString(digest[:]))
+}
+
+// credentialIdentity returns what identifies the primary credential of data: the username, the Kubernetes secret or
+// the PKCS#12 keystore, in that order. Resources holding tokens only share the same identity.
+func credentialIdentity(data *model.CredentialResourceData) string {
+	switch {
+	case !isBlank(data.Username):
+		return "username:" + data.Username.ValueString()
+	case !isBlank(data.K8sSecretName):
+		return "k8s_secret:" + data.K8sSecretNamespace.ValueString() + "/" + data.K8sSecretName.ValueString()
+	case !isBlank(data.P12Certificate):
+		return "p12_cert:" + data.P12Certificate.ValueString()
+	default:
+		return "token"
+	}
+}
+
+// accessTokenFingerprint returns the SHA-256 digest of the access token, identifying it without disclosing it. Empty
+// when there is no access token.
+func accessTokenFingerprint(token types.String) string {
+	if token.IsNull() || token.IsUnknown() || token.ValueString() == "" {
+		return ""
+	}
+	digest := sha256.Sum256([]byte(token.ValueString()))
+	return "sha256:" + hex.EncodeToString(digest[:])
+}
+
+func int64OrNil(value types.Int64) *int64 {
+	if value.IsNull() || value.IsUnknown() {
+		return nil
+	}
+	result := value.ValueInt64()
+	return &result
+}
@@ -0,0 +1,144 @@
+package provider
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/model"
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/tpptest"
+)
+
+func TestMetadataJSON(t *testing.T) {
+	t.Run("read", func(t *testing.T) {
+		server := tpptest.NewServer(t)
+		_, state := importServerState(t, server)
+		data := stateData(t, state)
+
+		var metadata map[string]interface{}
+		if err := json.Unmarshal([]byte(data.MetadataJSON.ValueString()), &metadata); err != nil {
+			t.Fatalf("invalid metadata_json %s: %s", data.MetadataJSON, err)
+		}
+		for _, key := range []string{"connector_type", "client_id", "expiration", "issued_at", "granted_scopes", "token_fingerprint"} {
+			if _, ok := metadata[key]; !ok {
+				t.Errorf("metadata_json = %s, want %s", data.MetadataJSON, key)
+			}
+		}
+		if expiration, _ := metadata["expiration"].(float64); int64(expiration) != data.ExpirationDate.ValueInt64() {
+			t.Errorf("expiration = %v, want %s", metadata["expiration"], data.ExpirationDate)
+		}
+		if metadata["token_fingerprint"] != accessTokenFingerprint(data.AccessToken) {
+			t.Errorf("token_fingerprint = %v, want the one of the access token", metadata["token_fingerprint"])
+		}
+
+		// No secret, in full or in part
+		for name, secret := range map[string]string{
+			fAccessToken:  data.AccessToken.ValueString(),
+			fRefreshToken: data.RefreshToken.ValueString(),
+			fPassword:     data.Password.ValueString(),
+		} {
+			if secret == "" {
+				t.Fatalf("%s not set", name)
+			}
+			if strings.Contains(data.MetadataJSON.ValueString(), secret) || strings.Contains(data.MetadataJSON.ValueString(), secret[:len(secret)/2]) {
+				t.Errorf("%s found in metadata_json: %s", name, data.MetadataJSON)
+			}
+		}
+	})
+
+	t.Run("no token", func(t *testing.T) {
+		data := &model.CredentialResourceData{
+			ClientID:       types.StringValue(defaultClientID),
+			ExpirationDate: types.Int64Null(),
+			GrantedScopes:  types.ListNull(types.StringType),
+		}
+		setMetadataJSON(data)
+		want := `{"connector_type":"TPP","client_id":"` + defaultClientID + `","expiration":null,"issued_at":null,"granted_scopes":[]}`
+		if data.MetadataJSON.ValueString() != want {
+			t.Errorf("metadata_json = %s, want %s", data.MetadataJSON, want)
+		}
+	})
+}
+
+func TestResourceIdentity(t *testing.T) {
+	identity := func(data model.CredentialResourceData) string {
+		setResourceIdentity(&data)
+		return data.ResourceIdentity.ValueString()
+	}
+	base := model.CredentialResourceData{
+		URL:          types.StringValue("https://tpp.venafi.example"),
+		ClientID:     types.StringValue("hashicorp-terraform-by-venafi"),
+		Username:     types.StringValue("tppadmin"),
+		AccessToken:  types.StringValue("access"),
+		RefreshToken: types.StringValue("refresh"),
+	}
+	want := identity(base)
+
+	t.Run("independent of the tokens", func(t *testing.T) {
+		data := base
+		data.AccessToken = types.StringValue("other-access")
+		data.RefreshToken = types.StringNull()
+		data.ExpirationDate = types.Int64Value(1772600767)
+		if got := identity(data); got != want {
+			t.Errorf("resource_identity = %s, want %s", got, want)
+		}
+	})
+
+	for name, change := range map[string]func(*model.CredentialResourceData){
+		"url":       func(data *model.CredentialResourceData) { data.URL = types.StringValue("https://tpp2.venafi.example") },
+		"client_id": func(data *model.CredentialResourceData) { data.ClientID = types.StringValue("other-client") },
+		"username":  func(data *model.CredentialResourceData) { data.Username = types.StringValue("other") },
+		"credential": func(data *model.CredentialResourceData) {
+			data.Username = types.StringNull()
+			data.P12Certificate = types.StringValue("/etc/venafi/client.p12")
+		},
+	} {
+		t.Run("different "+name, func(t *testing.T) {
+			data := base
+			change(&data)
+			if identity(data) == want {
+				t.Errorf("resource_identity unchanged by another %s", name)
+			}
+		})
+	}
+
+	t.Run("kept once set", func(t *testing.T) {
+		data := base
+		data.ResourceIdentity = types.StringValue(want)
+		data.URL = types.StringValue("https://tpp2.venafi.example")
+		if got := identity(data); got != want {
+			t.Errorf("resource_identity = %s, want %s kept", got, want)
+		}
+	})
+}
+
+func TestResourceIdentityAcrossRotations(t *testing.T) {
+	server := tpptest.NewServer(t)
+	r, state := importServerState(t, server)
+	identity := stateData(t, state).ResourceIdentity
+	if identity.IsNull() || identity.ValueString() == "" {
+		t.Fatal("resource_identity not set on import")
+	}
+	configured := []string{fUsername, fPassword, fRotateTrigger}
+
+	for i, trigger := range []string{"1", "2"} {
+		data := stateData(t, state)
+		previous := data.AccessToken
+		data.RotateTrigger = types.StringValue(trigger)
+		plan, config := planUpdate(t, r, state, data, configured...)
+		state = applyUpdate(t, r, state, plan, config)
+		rotated := stateData(t, state)
+		if rotated.AccessToken.Equal(previous) {
+			t.Fatalf("rotation %d: token pair not rotated", i+1)
+		}
+		if !rotated.ResourceIdentity.Equal(identity) {
+			t.Errorf("rotation %d: resource_identity = %s, want %s", i+1, rotated.ResourceIdentity, identity)
+		}
+	}
+
+	if again := stateData(t, readState(t, r, state)); !again.ResourceIdentity.Equal(identity) {
+		t.Errorf("resource_identity = %s after a refresh, want %s", again.ResourceIdentity, identity)
+	}
+}
@@ -2,11 +2,41 @@ package provider
 
 import (
 	"context"
+	"fmt"
+	"os"
 
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/provider"
 	"github.com/hashicorp/terraform-plugin-framework/provider/schema"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/model"
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/vcertclient"
+)
+
+const (
+	// environment variables used as fallback for the provider attributes
+	envURL         = "VENAFI_URL"
+	envTrustBundle = "VENAFI_TRUST_BUNDLE"
+	envClientID    = "VENAFI_CLIENT_ID"
+
+	// envStateEncryptionKey is the environment variable used as fallback for state_encryption_key
+	envStateEncryptionKey = "VENAFI_STATE_ENCRYPTION_KEY"
+
+	// environment variables of the vcert CLI, used when the VENAFI_* ones are not set
+	envVcertURL         = "VCERT_URL"
+	envVcertTrustBundle = "VCERT_TRUST_BUNDLE"
+	envVcertClientID    = "VCERT_CLIENT_ID"
+	envVcertToken       = "VCERT_TOKEN"
+	envVcertUser        = "VCERT_USER"
+	envVcertPassword    = "VCERT_PASSWORD"
+
+	fMaxConcurrentRequests = "max_concurrent_requests"
+	fStrictSensitive       = "strict_sensitive"
+	fStateEncryptionKey    = "state_encryption_key"
 )
 
 var _ provider.Provider = &VenafiTokenProvider{}
@@ -24,15 +54,81 @@ func (p *VenafiTokenProvider) Metadata(_ context.Context, _ provider.MetadataReq
 func (p *VenafiTokenProvider) Schema(_ context.Context, _ provider.SchemaRequest, resp *provider.SchemaResponse) {
 	resp.Schema = schema.Schema{
 		MarkdownDescription: "This is for refreshing Venafi tokens for use with venafi-provider.",
+
+		Attributes: map[string]schema.Attribute{
+			fURL: schema.StringAttribute{
+				MarkdownDescription: "Default Venafi TLSPDC URL used by resources that do not specify one. Can also be set with the `" + envURL + "` environment variable, or the `" + envVcertURL + "` one of the vcert CLI",
+				Optional:            true,
+			},
+			fTrustBundle: schema.StringAttribute{
+				MarkdownDescription: "Default trust bundle file used by resources that do not specify one. Can also be set with the `" + envTrustBundle + "` environment variable, or the `" + envVcertTrustBundle + "` one of the vcert CLI",
+				Optional:            true,
+			},
+			fClientID: schema.StringAttribute{
+				MarkdownDescription: "Default application that will be using the tokens. Can also be set with the `" + envClientID + "` environment variable, or the `" + envVcertClientID + "` one of the vcert CLI",
+				Optional:            true,
+			},
+			fMaxConcurrentRequests: schema.Int64Attribute{
+				MarkdownDescription: "Maximum number of requests sent at the same time to a TLSPDC host, across all the resources using this provider configuration. Defaults to 8",
+				Optional:            true,
+			},
+			fStrictSensitive: schema.BoolAttribute{
+				MarkdownDescription: "Redact the url, client_id and username of the resources from the provider logs. Defaults to false",
+				Optional:            true,
+			},
+			fStateEncryptionKey: schema.StringAttribute{
+				MarkdownDescription: "Base64-encoded AES key of 16, 24 or 32 bytes encrypting the tokens saved to the state with AES-GCM. They are decrypted in memory when the resources use them. Can also be set with the `" + envStateEncryptionKey + "` environment variable",
+				Optional:            true,
+				Sensitive:           true,
+			},
+		},
 	}
 }
 
-func (p *VenafiTokenProvider) Configure(_ context.Context, _ provider.ConfigureRequest, _ *provider.ConfigureResponse) {
+func (p *VenafiTokenProvider) Configure(ctx context.Context, req provider.ConfigureRequest, resp *provider.ConfigureResponse) {
+	tflog.Info(ctx, "configuring venafi-token provider")
+	var data model.ProviderData
+	diags := req.Config.Get(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	data.Sources = map[string]string{
+		fURL:         providerSource(data.URL, envURL, envVcertURL),
+		fTrustBundle: providerSource(data.TrustBundle, envTrustBundle, envVcertTrustBundle),
+		fClientID:    providerSource(data.ClientID, envClientID, envVcertClientID),
+	}
+	data.URL = valueOrEnv(data.URL, envURL, envVcertURL)
+	data.TrustBundle = valueOrEnv(data.TrustBundle, envTrustBundle, envVcertTrustBundle)
+	data.ClientID = valueOrEnv(data.ClientID, envClientID, envVcertClientID)
+
+	if !data.MaxConcurrentRequests.IsNull() {
+		if data.MaxConcurrentRequests.ValueInt64() < 1 {
+			resp.Diagnostics.AddAttributeError(path.Root(fMaxConcurrentRequests), "provider configuration error",
+				fmt.Sprintf("%s must be at least 1, got %d", fMaxConcurrentRequests, data.MaxConcurrentRequests.ValueInt64()))
+			return
+		}
+		vcertclient.SetMaxConcurrentRequests(int(data.MaxConcurrentRequests.ValueInt64()))
+	}
+
+	if key := valueOrEnv(data.StateEncryptionKey, envStateEncryptionKey); !key.IsNull() {
+		stateCipher, err := newStateCipher(key.ValueString())
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root(fStateEncryptionKey), "provider configuration error", err.Error())
+			return
+		}
+		data.StateCipher = stateCipher
+	}
 
+	resp.ResourceData = &data
+	resp.DataSourceData = &data
 }
 
 func (p *VenafiTokenProvider) DataSources(_ context.Context) []func() datasource.DataSource {
-	return []func() datasource.DataSource{}
+	return []func() datasource.DataSource{
+		NewDescribeDataSource,
+	}
 }
 
 func (p *VenafiTokenProvider) Resources(_ context.Context) []func() resource.Resource {
@@ -40,3 +136,25 @@ func (p *VenafiTokenProvider) Resources(_ context.Context) []func() resource.Res
 		NewCredentialResource,
 	}
 }
+
+// valueOrEnv returns value when it is set in the configuration. Otherwise, it returns the content of the first
+// environment variable of envs that is set, or null if none is
+func valueOrEnv(value types.String, envs ...string) types.String {
+	if !value.IsNull() && !value.IsUnknown() {
+		return value
+	}
+	if envValue, ok := lookupEnv(envs...); ok {
+		return types.StringValue(envValue)
+	}
+	return types.StringNull()
+}
+
+// lookupEnv returns the value of the first environment variable of envs that is set to a non-empty value
+func lookupEnv(envs ...string) (string, bool) {
+	for _, env := range envs {
+		if envValue, ok := os.LookupEnv(env); ok && envValue != "" {
+			return envValue, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,99 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/provider"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/model"
+)
+
+// configureProvider configures the provider with the given attributes, the others being null, and returns the data
+// shared with its resources
+func configureProvider(t *testing.T, attributes map[string]string) *model.ProviderData {
+	t.Helper()
+	ctx := context.Background()
+
+	p := New()
+	var schemaResp provider.SchemaResponse
+	p.Schema(ctx, provider.SchemaRequest{}, &schemaResp)
+
+	objectType := schemaResp.Schema.Type().TerraformType(ctx).(tftypes.Object)
+	values := make(map[string]tftypes.Value, len(objectType.AttributeTypes))
+	for name, attributeType := range objectType.AttributeTypes {
+		values[name] = tftypes.NewValue(attributeType, nil)
+		if value, ok := attributes[name]; ok {
+			values[name] = tftypes.NewValue(attributeType, value)
+		}
+	}
+
+	var resp provider.ConfigureResponse
+	p.Configure(ctx, provider.ConfigureRequest{
+		Config: tfsdk.Config{Schema: schemaResp.Schema, Raw: tftypes.NewValue(objectType, values)},
+	}, &resp)
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("unable to configure the provider: %v", resp.Diagnostics)
+	}
+	return resp.ResourceData.(*model.ProviderData)
+}
+
+func TestConfigureDefaults(t *testing.T) {
+	t.Setenv(envURL, "")
+	t.Setenv(envVcertURL, "https://vcert.venafi.example")
+	t.Setenv(envClientID, "env-client")
+	t.Setenv(envTrustBundle, "")
+	t.Setenv(envVcertTrustBundle, "")
+
+	data := configureProvider(t, map[string]string{fClientID: "configured-client"})
+
+	if data.URL.ValueString() != "https://vcert.venafi.example" || data.Sources[fURL] != sourceEnv {
+		t.Errorf("url = %s from %q, want the vcert environment variable", data.URL, data.Sources[fURL])
+	}
+	if data.ClientID.ValueString() != "configured-client" || data.Sources[fClientID] != sourceProvider {
+		t.Errorf("client_id = %s from %q, want the provider configuration", data.ClientID, data.Sources[fClientID])
+	}
+	if !data.TrustBundle.IsNull() || data.Sources[fTrustBundle] != "" {
+		t.Errorf("trust_bundle = %s from %q, want none", data.TrustBundle, data.Sources[fTrustBundle])
+	}
+}
+
+func TestVcertEnvironmentFallbacks(t *testing.T) {
+	tests := []struct {
+		name     string
+		attr     string
+		env      string
+		vcertEnv string
+	}{
+		{"url", fURL, envURL, envVcertURL},
+		{"trust bundle", fTrustBundle, envTrustBundle, envVcertTrustBundle},
+		{"client id", fClientID, envClientID, envVcertClientID},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for _, env := range []string{envURL, envVcertURL, envTrustBundle, envVcertTrustBundle, envClientID, envVcertClientID} {
+				t.Setenv(env, "")
+			}
+			// value returns the attribute of data under test
+			value := func(data *model.ProviderData) string {
+				return map[string]string{fURL: data.URL.ValueString(), fTrustBundle: data.TrustBundle.ValueString(), fClientID: data.ClientID.ValueString()}[test.attr]
+			}
+
+			t.Setenv(test.vcertEnv, "vcert-value")
+			if data := configureProvider(t, nil); value(data) != "vcert-value" || data.Sources[test.attr] != sourceEnv {
+				t.Errorf("%s = %q from %q, want the %s environment variable", test.attr, value(data), data.Sources[test.attr], test.vcertEnv)
+			}
+
+			// The VENAFI_* environment variables take precedence, and the provider configuration over both
+			t.Setenv(test.env, "venafi-value")
+			if data := configureProvider(t, nil); value(data) != "venafi-value" {
+				t.Errorf("%s = %q, want the %s environment variable", test.attr, value(data), test.env)
+			}
+			if data := configureProvider(t, map[string]string{test.attr: "configured-value"}); value(data) != "configured-value" || data.Sources[test.attr] != sourceProvider {
+				t.Errorf("%s = %q from %q, want the provider configuration", test.attr, value(data), data.Sources[test.attr])
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/logging"
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/model"
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/vcertclient"
+)
+
+const recoveryFileMode = 0600
+
+// recoveredPair is the content of recovery_file: the last token pair granted to the resource, kept until a later run
+// finds it in the state
+type recoveredPair struct {
+	URL          string `json:"url"`
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	Expiration   int64  `json:"expiration"`
+	IssuedAt     int64  `json:"issued_at"`
+	// Staged is set when the pair was staged by staged_rotation rather than made active
+	Staged bool `json:"staged,omitempty"`
+}
+
+// writeRecoveryFile records a newly granted token pair to recovery_file before it is stored in data, so that the grant
+// can be recovered when Terraform is interrupted before saving the state
+func writeRecoveryFile(ctx context.Context, data *model.CredentialResourceData, resp *vcertclient.RefreshTokenResponse, staged bool, diags *diag.Diagnostics) {
+	if data.RecoveryFile.IsNull() {
+		return
+	}
+
+	location := data.RecoveryFile.ValueString()
+	content, err := json.Marshal(recoveredPair{
+		URL:          data.URL.ValueString(),
+		AccessToken:  resp.AccessToken,
+		RefreshToken: resp.RefreshToken,
+		Expiration:   resp.Expires,
+		IssuedAt:     time.Now().Unix(),
+		Staged:       staged,
+	})
+	if err == nil {
+		logging.Info(ctx, fmt.Sprintf("recording granted token pair to recovery file [%s]", location))
+		err = writeFileAtomically(location, content, recoveryFileMode)
+	}
+	if err != nil {
+		diags.AddAttributeWarning(path.Root(fRecoveryFile), msgCredentialResourceError,
+			fmt.Sprintf("unable to write recovery file [%s]: %s", location, err.Error()))
+	}
+}
+
+// recoverInterruptedRotation looks for a token pair left in recovery_file by a previous run. The pair is forgotten
+// when the state holds it, i.e. the previous run completed. Otherwise that run was interrupted before saving the state:
+// the pair is adopted when still valid, since the rotation may have ended the validity of the pair found in the state,
+// and revoked otherwise. It reports whether the pair was adopted.
+func recoverInterruptedRotation(ctx context.Context, data *model.CredentialResourceData, diags *diag.Diagnostics) bool {
+	if data.RecoveryFile.IsNull() {
+		return false
+	}
+
+	location := data.RecoveryFile.ValueString()
+	content, err := os.ReadFile(location)
+	if errors.Is(err, os.ErrNotExist) {
+		return false
+	}
+	if err != nil {
+		diags.AddAttributeWarning(path.Root(fRecoveryFile), msgCredentialResourceError,
+			fmt.Sprintf("unable to read recovery file [%s]: %s", location, err.Error()))
+		return false
+	}
+
+	var pair recoveredPair
+	if err = json.Unmarshal(content, &pair); err != nil || pair.AccessToken == "" {
+		logging.Warn(ctx, fmt.Sprintf("ignoring unreadable recovery file [%s]", location))
+		removeRecoveryFile(ctx, location, diags)
+		return false
+	}
+
+	if pair.AccessToken == data.AccessToken.ValueString() || pair.AccessToken == data.PendingAccessToken.ValueString() {
+		logging.Info(ctx, "token pair of recovery file found in the state, previous run completed")
+		removeRecoveryFile(ctx, location, diags)
+		return false
+	}
+
+	if pair.URL == data.URL.ValueString() && (pair.Expiration <= 0 || pair.Expiration > time.Now().Unix()) {
+		logging.Warn(ctx, fmt.Sprintf("adopting token pair of recovery file [%s] left by an interrupted run", location))
+		diags.AddAttributeWarning(path.Root(fRecoveryFile), msgCredentialResourceError,
+			"A previous run was interrupted after a new token pair was granted but before the state was saved. That token pair was adopted.")
+		adoptRecoveredPair(data, pair)
+		removeRecoveryFile(ctx, location, diags)
+		return true
+	}
+
+	logging.Warn(ctx, fmt.Sprintf("revoking token pair of recovery file [%s] left by an interrupted run", location))
+	orphaned := *data
+	orphaned.URL = types.StringValue(pair.URL)
+	orphaned.FallbackURL = types.StringNull()
+	orphaned.AccessToken = types.StringValue(pair.AccessToken)
+	orphaned.RefreshToken = stringOrNull(pair.RefreshToken)
+	orphaned.ExpirationDate = types.Int64Value(pair.Expiration)
+	if err = vcertclient.New(ctx, orphaned).RevokeToken(); err != nil {
+		// Kept for the next run to try again
+		diags.AddAttributeWarning(path.Root(fRecoveryFile), msgCredentialResourceError,
+			fmt.Sprintf("unable to revoke the grant left by an interrupted run: %s", err.Error()))
+		return false
+	}
+	removeRecoveryFile(ctx, location, diags)
+	return false
+}
+
+// adoptRecoveredPair stores pair to data as if the interrupted run had completed
+func adoptRecoveredPair(data *model.CredentialResourceData, pair recoveredPair) {
+	if pair.Staged {
+		data.PendingAccessToken = types.StringValue(pair.AccessToken)
+		data.PendingRefreshToken = stringOrNull(pair.RefreshToken)
+		data.PendingExpiration = types.Int64Value(pair.Expiration)
+		data.PendingIssuedAt = types.Int64Value(pair.IssuedAt)
+		return
+	}
+
+	data.AccessToken = types.StringValue(pair.AccessToken)
+	data.RefreshToken = stringOrNull(pair.RefreshToken)
+	data.ExpirationDate = types.Int64Value(pair.Expiration)
+	data.IssuedAt = types.Int64Value(pair.IssuedAt)
+	data.RotationCount = types.Int64Value(data.RotationCount.ValueInt64() + 1)
+}
+
+// removeRecoveryFile deletes the recovery file at location, if any
+func removeRecoveryFile(ctx context.Context, location string, diags *diag.Diagnostics) {
+	logging.Info(ctx, fmt.Sprintf("removing recovery file [%s]", location))
+	if err := os.Remove(location); err != nil && !errors.Is(err, os.ErrNotExist) {
+		diags.AddAttributeWarning(path.Root(fRecoveryFile), msgCredentialResourceError,
+			fmt.Sprintf("unable to remove recovery file [%s]: %s", location, err.Error()))
+	}
+}
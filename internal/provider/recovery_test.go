@@ -0,0 +1,132 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/model"
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/tpptest"
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/vcertclient"
+)
+
+// recoveryState imports a credential of server recording its token pairs to a recovery file, and returns the resource
+// with its state and the location of the file
+func recoveryState(t *testing.T, server *tpptest.Server) (*CredentialResource, tfsdk.State, string) {
+	t.Helper()
+
+	r, state := importServerState(t, server)
+	location := filepath.Join(t.TempDir(), "recovery.json")
+	data := stateData(t, state)
+	data.RecoveryFile = types.StringValue(location)
+	return r, setStateData(t, state, data), location
+}
+
+// readWithDiagnostics refreshes state with the Read of r, failing the test on error, and returns the response
+func readWithDiagnostics(t *testing.T, r *CredentialResource, state tfsdk.State) resource.ReadResponse {
+	t.Helper()
+
+	resp := resource.ReadResponse{State: state}
+	r.Read(context.Background(), resource.ReadRequest{State: state}, &resp)
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("read failed: %v", resp.Diagnostics)
+	}
+	return resp
+}
+
+func TestRecoveryFile(t *testing.T) {
+	configured := []string{fUsername, fPassword, fRotateTrigger, fRecoveryFile}
+
+	t.Run("crash before saving the state", func(t *testing.T) {
+		server := tpptest.NewServer(t)
+		r, state, location := recoveryState(t, server)
+		data := stateData(t, state)
+		data.RotateTrigger = types.StringValue("rotate")
+		plan, config := planUpdate(t, r, state, data, configured...)
+		// The state of the apply is lost, as when Terraform is interrupted
+		minted := stateData(t, applyUpdate(t, r, state, plan, config))
+		if _, err := os.Stat(location); err != nil {
+			t.Fatalf("recovery file not written: %s", err)
+		}
+
+		grants := len(server.Grants())
+		resp := readWithDiagnostics(t, r, state)
+		recovered := stateData(t, resp.State)
+		if !recovered.AccessToken.Equal(minted.AccessToken) || !recovered.RefreshToken.Equal(minted.RefreshToken) {
+			t.Errorf("access_token = %s, want the token pair granted by the interrupted run", recovered.AccessToken)
+		}
+		if len(resp.Diagnostics.Warnings()) == 0 {
+			t.Error("adoption not reported")
+		}
+		if _, err := os.Stat(location); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("recovery file left after adoption: %v", err)
+		}
+		// The token pair was adopted, no other is granted
+		if issued := len(server.Grants()) - grants; issued != 0 || server.Requests(tpptest.PathRefreshToken) != 1 {
+			t.Errorf("%d grants issued, %d refreshes, want the adopted pair only", issued, server.Requests(tpptest.PathRefreshToken))
+		}
+	})
+
+	t.Run("completed run", func(t *testing.T) {
+		server := tpptest.NewServer(t)
+		r, state, location := recoveryState(t, server)
+		data := stateData(t, state)
+		data.RotateTrigger = types.StringValue("rotate")
+		plan, config := planUpdate(t, r, state, data, configured...)
+		state = applyUpdate(t, r, state, plan, config)
+		saved := stateData(t, state)
+
+		resp := readWithDiagnostics(t, r, state)
+		if read := stateData(t, resp.State); !read.AccessToken.Equal(saved.AccessToken) || len(resp.Diagnostics.Warnings()) != 0 {
+			t.Errorf("access_token = %s, diagnostics = %v, want the saved pair kept", read.AccessToken, resp.Diagnostics)
+		}
+		if _, err := os.Stat(location); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("recovery file left once found in the state: %v", err)
+		}
+	})
+
+	t.Run("orphaned grant expired", func(t *testing.T) {
+		server := tpptest.NewServer(t)
+		r, state, location := recoveryState(t, server)
+		orphaned := server.IssueGrant(vcertclient.DefaultScope)
+		content, _ := json.Marshal(recoveredPair{
+			URL:          server.URL,
+			AccessToken:  orphaned.AccessToken,
+			RefreshToken: orphaned.RefreshToken,
+			Expiration:   time.Now().Add(-time.Minute).Unix(),
+			IssuedAt:     time.Now().Add(-time.Hour).Unix(),
+		})
+		if err := os.WriteFile(location, content, recoveryFileMode); err != nil {
+			t.Fatal(err)
+		}
+
+		resp := readWithDiagnostics(t, r, state)
+		if read := stateData(t, resp.State); !read.AccessToken.Equal(stateData(t, state).AccessToken) {
+			t.Errorf("access_token = %s, want the pair of the state kept", read.AccessToken)
+		}
+		if !server.Grants()[orphaned.ID-1].Revoked {
+			t.Error("orphaned grant not revoked")
+		}
+		if _, err := os.Stat(location); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("recovery file left after revocation: %v", err)
+		}
+	})
+
+	t.Run("staged pair", func(t *testing.T) {
+		issuedAt := time.Now().Add(-time.Minute).Unix()
+		var data model.CredentialResourceData
+		adoptRecoveredPair(&data, recoveredPair{AccessToken: "staged-access", RefreshToken: "staged-refresh", Expiration: 1, IssuedAt: issuedAt, Staged: true})
+		if data.PendingAccessToken.ValueString() != "staged-access" || data.PendingIssuedAt.ValueInt64() != issuedAt || !data.AccessToken.IsNull() {
+			t.Errorf("pending_access_token = %s, pending_issued_at = %s, access_token = %s, want the pair staged",
+				data.PendingAccessToken, data.PendingIssuedAt, data.AccessToken)
+		}
+	})
+}
@@ -0,0 +1,255 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/logging"
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/model"
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/vcertclient"
+)
+
+// backgroundRefreshRetryDelay is how long the refresher waits before trying again a rotation that failed
+const backgroundRefreshRetryDelay = time.Minute
+
+// backgroundRefresher rotates, within the lifetime of the provider process, the token pairs of the resources with
+// background_refresh set once they enter their refresh window. The rotated pairs are kept in memory, keyed by the
+// fingerprint of the access token they replace, until the resource is refreshed again.
+type backgroundRefresher struct {
+	mu      sync.Mutex
+	stopped bool
+	wg      sync.WaitGroup
+	// scheduled holds the pending rotations, keyed by the fingerprint of the access token of the resource
+	scheduled map[string]*scheduledRefresh
+	// rotated holds the latest pair rotated in the background, keyed by the same fingerprint
+	rotated map[string]recoveredPair
+}
+
+type scheduledRefresh struct {
+	timer  *time.Timer
+	cancel context.CancelFunc
+}
+
+// backgroundRefreshes is the refresher shared by all the resources of the provider process
+var backgroundRefreshes = newBackgroundRefresher()
+
+func newBackgroundRefresher() *backgroundRefresher {
+	return &backgroundRefresher{
+		scheduled: make(map[string]*scheduledRefresh),
+		rotated:   make(map[string]recoveredPair),
+	}
+}
+
+// backgroundRefreshAt returns when the token pair of data must be rotated: at the start of its refresh window, or
+// earlier when min_remaining_for_operation_seconds requires it
+func backgroundRefreshAt(data *model.CredentialResourceData) time.Time {
+	due := data.ExpirationDate.ValueInt64() - refreshWindowSeconds(data)
+	if !data.MinRemainingForOp.IsNull() {
+		due = min(due, data.ExpirationDate.ValueInt64()-data.MinRemainingForOp.ValueInt64())
+	}
+	return time.Unix(due, 0)
+}
+
+// schedule plans the background rotation of the token pair of data. Nothing is scheduled without background_refresh,
+// or when the rotation would consume the refresh token of the state with no recovery_file to hand the new pair over.
+// A token that never expires is never due, hence never scheduled.
+func (r *backgroundRefresher) schedule(ctx context.Context, data model.CredentialResourceData) {
+	if !data.BackgroundRefresh.ValueBool() || data.AccessToken.IsNull() || data.ExpirationDate.IsNull() || neverExpires(&data) {
+		return
+	}
+	if data.StagedRotation.ValueBool() || data.ValidateOnly.ValueBool() || data.BlueGreenRotation.ValueBool() {
+		logging.Info(ctx, fmt.Sprintf("%s ignored with %s, %s or %s", fBackgroundRefresh, fStagedRotation, fValidateOnly, fBlueGreenRotation))
+		return
+	}
+	hasPrimary := hasKeystore(&data) || (!isBlank(data.Username) && !isBlank(data.Password))
+	if !hasPrimary && data.RecoveryFile.IsNull() {
+		logging.Warn(ctx, fmt.Sprintf("%s requires a primary credential or a %s, the refresh token of the state would be consumed", fBackgroundRefresh, fRecoveryFile))
+		return
+	}
+
+	key := accessTokenFingerprint(data.AccessToken)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stopped {
+		return
+	}
+	if _, ok := r.scheduled[key]; ok {
+		return
+	}
+	r.scheduleLocked(ctx, key, data, time.Until(backgroundRefreshAt(&data)))
+}
+
+// scheduleLocked starts the timer of the rotation of data after delay. r.mu must be held.
+func (r *backgroundRefresher) scheduleLocked(ctx context.Context, key string, data model.CredentialResourceData, delay time.Duration) {
+	// The rotation outlives the request that scheduled it, it is only cancelled by stop
+	refreshCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	logging.Info(ctx, fmt.Sprintf("background rotation of the token pair scheduled in %s", delay.Round(time.Second)))
+	r.scheduled[key] = &scheduledRefresh{
+		cancel: cancel,
+		timer: time.AfterFunc(max(delay, 0), func() {
+			// Added under the lock, so that stop waits for the rotation once it started
+			r.mu.Lock()
+			if r.stopped {
+				r.mu.Unlock()
+				return
+			}
+			r.wg.Add(1)
+			r.mu.Unlock()
+			defer r.wg.Done()
+			r.refresh(refreshCtx, key, data)
+		}),
+	}
+}
+
+// refresh rotates the token pair of data, then schedules the rotation of the new pair
+func (r *backgroundRefresher) refresh(ctx context.Context, key string, data model.CredentialResourceData) {
+	logging.Info(ctx, "rotating the token pair in the background")
+	pair, err := backgroundRotation(ctx, data)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.scheduled[key]; r.stopped || !ok {
+		// Stopped, or forgotten while rotating
+		return
+	}
+	r.cancelLocked(key)
+	if err != nil {
+		logging.Warn(ctx, fmt.Sprintf("background rotation failed, trying again in %s: %s", backgroundRefreshRetryDelay, err.Error()))
+		r.scheduleLocked(ctx, key, data, backgroundRefreshRetryDelay)
+		return
+	}
+
+	r.rotated[key] = pair
+	data.AccessToken = types.StringValue(pair.AccessToken)
+	data.RefreshToken = stringOrNull(pair.RefreshToken)
+	data.ExpirationDate = types.Int64Value(pair.Expiration)
+	data.IssuedAt = types.Int64Value(pair.IssuedAt)
+	// Without recovery_file, the pair is lost when the process exits before a read adopts it
+	if data.RecoveryFile.IsNull() {
+		inMemoryGrants.hold(data)
+	}
+	if neverExpires(&data) {
+		logging.Info(ctx, "the new access token never expires, no further background rotation scheduled")
+		return
+	}
+	r.scheduleLocked(ctx, key, data, time.Until(backgroundRefreshAt(&data)))
+}
+
+// backgroundRotation requests a new token pair for data. A primary credential is preferred so that the refresh token
+// held by the state stays valid. Otherwise, the new pair is recorded to recovery_file for the next run to adopt it.
+func backgroundRotation(ctx context.Context, data model.CredentialResourceData) (recoveredPair, error) {
+	if hasKeystore(&data) || (!isBlank(data.Username) && !isBlank(data.Password)) {
+		data.RefreshToken = types.StringNull()
+	}
+	clientResp, err := vcertclient.New(ctx, data).RequestNewTokenPair()
+	if err != nil {
+		return recoveredPair{}, err
+	}
+	if err = normalizeTokenPair(clientResp); err != nil {
+		return recoveredPair{}, err
+	}
+	if err = checkTokenType(ctx, &data, clientResp); err != nil {
+		return recoveredPair{}, err
+	}
+
+	var diags diag.Diagnostics
+	writeRecoveryFile(ctx, &data, clientResp, false, &diags)
+	for _, d := range diags {
+		logging.Warn(ctx, d.Detail())
+	}
+	return recoveredPair{
+		URL:          data.URL.ValueString(),
+		AccessToken:  clientResp.AccessToken,
+		RefreshToken: clientResp.RefreshToken,
+		Expiration:   clientResp.Expires,
+		IssuedAt:     time.Now().Unix(),
+	}, nil
+}
+
+// take returns the latest pair rotated in the background to replace accessToken, and forgets about it
+func (r *backgroundRefresher) take(accessToken types.String) (recoveredPair, bool) {
+	key := accessTokenFingerprint(accessToken)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	pair, ok := r.rotated[key]
+	delete(r.rotated, key)
+	r.cancelLocked(key)
+	return pair, ok
+}
+
+// forget cancels the background rotation of accessToken, e.g. once it was replaced by a rotation of the resource
+func (r *backgroundRefresher) forget(accessToken types.String) {
+	key := accessTokenFingerprint(accessToken)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.rotated, key)
+	r.cancelLocked(key)
+}
+
+// cancelLocked stops the scheduled rotation of key, if any. r.mu must be held.
+func (r *backgroundRefresher) cancelLocked(key string) {
+	if scheduled, ok := r.scheduled[key]; ok {
+		scheduled.timer.Stop()
+		scheduled.cancel()
+		delete(r.scheduled, key)
+	}
+}
+
+// stop cancels the scheduled rotations and waits for the ones in progress
+func (r *backgroundRefresher) stop() {
+	r.mu.Lock()
+	r.stopped = true
+	for key := range r.scheduled {
+		r.cancelLocked(key)
+	}
+	r.mu.Unlock()
+	r.wg.Wait()
+}
+
+// adoptBackgroundRotation replaces the token pair of data with the one rotated in the background, if any. It reports
+// whether a pair was adopted.
+func adoptBackgroundRotation(ctx context.Context, data *model.CredentialResourceData) bool {
+	if !data.BackgroundRefresh.ValueBool() || data.AccessToken.IsNull() {
+		return false
+	}
+	pair, ok := backgroundRefreshes.take(data.AccessToken)
+	if !ok || pair.URL != data.URL.ValueString() || (pair.Expiration > 0 && pair.Expiration <= time.Now().Unix()) {
+		return false
+	}
+
+	logging.Info(ctx, "using the token pair rotated in the background")
+	inMemoryGrants.release(types.StringValue(pair.AccessToken))
+	adoptRecoveredPair(data, pair)
+	data.Rotated = types.BoolValue(true)
+	return true
+}
+
+// revokeBackgroundPair revokes pair, rotated in the background for the token pair of data, e.g. when the resource is
+// destroyed before a read adopted it. A failure is reported as a warning, the grant remaining valid until it expires.
+func revokeBackgroundPair(ctx context.Context, data *model.CredentialResourceData, pair recoveredPair, diags *diag.Diagnostics) {
+	rotated := *data
+	rotated.AccessToken = types.StringValue(pair.AccessToken)
+	rotated.RefreshToken = stringOrNull(pair.RefreshToken)
+	inMemoryGrants.release(rotated.AccessToken)
+	logging.Info(ctx, "revoking the token pair rotated in the background")
+	if err := vcertclient.New(ctx, rotated).RevokeToken(); err != nil {
+		logging.Warn(ctx, fmt.Sprintf("unable to revoke the token pair rotated in the background: %s", err.Error()))
+		diags.AddAttributeWarning(path.Root(fBackgroundRefresh), msgCredentialResourceError,
+			fmt.Sprintf("The token pair rotated in the background could not be revoked, it remains valid until it expires: %s", err.Error()))
+	}
+}
+
+// scheduleBackgroundRefresh plans the background rotation of the token pair of data once its read or update completed.
+// The rotation planned for previousToken is cancelled when the token pair changed.
+func scheduleBackgroundRefresh(ctx context.Context, previousToken types.String, data *model.CredentialResourceData) {
+	if !previousToken.IsNull() && !previousToken.Equal(data.AccessToken) {
+		backgroundRefreshes.forget(previousToken)
+	}
+	backgroundRefreshes.schedule(ctx, *data)
+}
@@ -0,0 +1,240 @@
+package provider
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/model"
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/tpptest"
+)
+
+// backgroundCredential returns a credential of server holding a token pair, with background_refresh set
+func backgroundCredential(t *testing.T, server *tpptest.Server) model.CredentialResourceData {
+	t.Helper()
+
+	data := serverCredential(server)
+	var diags diag.Diagnostics
+	if err := rotateToken(context.Background(), &data, &diags); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	data.BackgroundRefresh = types.BoolValue(true)
+	return data
+}
+
+// waitBackgroundRotation waits for r to rotate the token pair of key, failing the test when it does not in time
+func waitBackgroundRotation(t *testing.T, r *backgroundRefresher, key string) recoveredPair {
+	t.Helper()
+
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		r.mu.Lock()
+		pair, ok := r.rotated[key]
+		r.mu.Unlock()
+		if ok {
+			return pair
+		}
+	}
+	t.Fatal("token pair not rotated in the background")
+	return recoveredPair{}
+}
+
+func TestBackgroundRefreshAt(t *testing.T) {
+	const day = 24 * 60 * 60
+	expiration := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC).Unix()
+
+	tests := []struct {
+		name         string
+		minRemaining types.Int64
+		want         int64
+	}{
+		{"refresh window", types.Int64Null(), expiration - 30*day},
+		{"operation headroom within the window", types.Int64Value(10 * day), expiration - 30*day},
+		{"operation headroom beyond the window", types.Int64Value(45 * day), expiration - 45*day},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data := &model.CredentialResourceData{
+				ExpirationDate:    types.Int64Value(expiration),
+				RefreshWindow:     types.Int64Value(30),
+				MinRemainingForOp: test.minRemaining,
+			}
+			if got := backgroundRefreshAt(data); got.Unix() != test.want {
+				t.Errorf("refresh at = %s, want %s", got.UTC(), time.Unix(test.want, 0).UTC())
+			}
+		})
+	}
+}
+
+func TestBackgroundRefresherSchedule(t *testing.T) {
+	server := tpptest.NewServer(t)
+
+	tests := []struct {
+		name string
+		// prepare alters the credential scheduled
+		prepare func(data *model.CredentialResourceData)
+		want    bool
+	}{
+		{"scheduled", func(*model.CredentialResourceData) {}, true},
+		{"disabled", func(data *model.CredentialResourceData) { data.BackgroundRefresh = types.BoolValue(false) }, false},
+		{"no token", func(data *model.CredentialResourceData) { data.AccessToken = types.StringNull() }, false},
+		{"never expires", func(data *model.CredentialResourceData) { data.ExpirationDate = types.Int64Value(0) }, false},
+		{"staged rotation", func(data *model.CredentialResourceData) { data.StagedRotation = types.BoolValue(true) }, false},
+		{"refresh token only", func(data *model.CredentialResourceData) {
+			data.Username = types.StringNull()
+			data.Password = types.StringNull()
+		}, false},
+		{"refresh token with a recovery file", func(data *model.CredentialResourceData) {
+			data.Username = types.StringNull()
+			data.Password = types.StringNull()
+			data.RecoveryFile = types.StringValue("recovery.json")
+		}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			refresher := newBackgroundRefresher()
+			t.Cleanup(refresher.stop)
+			data := backgroundCredential(t, server)
+			test.prepare(&data)
+
+			refresher.schedule(context.Background(), data)
+			refresher.mu.Lock()
+			_, scheduled := refresher.scheduled[accessTokenFingerprint(data.AccessToken)]
+			refresher.mu.Unlock()
+			if scheduled != test.want {
+				t.Errorf("scheduled = %t, want %t", scheduled, test.want)
+			}
+		})
+	}
+
+	t.Run("scheduled once", func(t *testing.T) {
+		refresher := newBackgroundRefresher()
+		t.Cleanup(refresher.stop)
+		data := backgroundCredential(t, server)
+
+		refresher.schedule(context.Background(), data)
+		first := refresher.scheduled[accessTokenFingerprint(data.AccessToken)]
+		refresher.schedule(context.Background(), data)
+		if len(refresher.scheduled) != 1 || refresher.scheduled[accessTokenFingerprint(data.AccessToken)] != first {
+			t.Error("rotation scheduled twice for the same token")
+		}
+	})
+}
+
+func TestBackgroundRefresh(t *testing.T) {
+	t.Run("due", func(t *testing.T) {
+		server := tpptest.NewServer(t)
+		refresher := newBackgroundRefresher()
+		t.Cleanup(refresher.stop)
+		// The token is already within its refresh window, unlike the ones rotated in the background
+		server.TokenLifetime = 10 * 24 * time.Hour
+		data := backgroundCredential(t, server)
+		server.TokenLifetime = tpptest.DefaultTokenLifetime
+		key := accessTokenFingerprint(data.AccessToken)
+
+		refresher.schedule(context.Background(), data)
+		pair := waitBackgroundRotation(t, refresher, key)
+		t.Cleanup(func() { inMemoryGrants.release(types.StringValue(pair.AccessToken)) })
+		if _, ok := server.GrantOf(pair.AccessToken); !ok || pair.AccessToken == data.AccessToken.ValueString() {
+			t.Fatalf("access_token = %s, want a new one retrieved from TLSPDC", pair.AccessToken)
+		}
+		// The refresh token of the state is not consumed when a primary credential is set
+		if server.Requests(tpptest.PathRefreshToken) != 0 {
+			t.Error("refresh token of the state used")
+		}
+
+		// The rotation of the new pair is scheduled in turn
+		refresher.mu.Lock()
+		_, scheduled := refresher.scheduled[key]
+		refresher.mu.Unlock()
+		if !scheduled {
+			t.Error("rotation of the new pair not scheduled")
+		}
+
+		taken, ok := refresher.take(data.AccessToken)
+		if !ok || taken.AccessToken != pair.AccessToken {
+			t.Errorf("taken = %+v, want the pair rotated in the background", taken)
+		}
+		if _, ok = refresher.take(data.AccessToken); ok {
+			t.Error("pair taken twice")
+		}
+	})
+
+	t.Run("not due", func(t *testing.T) {
+		server := tpptest.NewServer(t)
+		refresher := newBackgroundRefresher()
+		t.Cleanup(refresher.stop)
+		data := backgroundCredential(t, server)
+		grants := len(server.Grants())
+
+		refresher.schedule(context.Background(), data)
+		time.Sleep(50 * time.Millisecond)
+		if len(server.Grants()) != grants || len(refresher.rotated) != 0 {
+			t.Error("token pair rotated before its refresh window")
+		}
+	})
+
+	t.Run("failure retried", func(t *testing.T) {
+		server := tpptest.NewServer(t)
+		refresher := newBackgroundRefresher()
+		t.Cleanup(refresher.stop)
+		data := backgroundCredential(t, server)
+		data.URL = types.StringValue(tpptest.UnreachableURL(t))
+		key := accessTokenFingerprint(data.AccessToken)
+
+		refresher.refresh(context.Background(), key, data)
+		refresher.mu.Lock()
+		_, rotated := refresher.rotated[key]
+		_, scheduled := refresher.scheduled[key]
+		refresher.mu.Unlock()
+		if rotated {
+			t.Error("pair recorded for a failed rotation")
+		}
+		// Only the rotations still wanted are retried
+		if scheduled {
+			t.Error("rotation retried although not scheduled")
+		}
+
+		refresher.mu.Lock()
+		refresher.scheduleLocked(context.Background(), key, data, time.Hour)
+		refresher.mu.Unlock()
+		refresher.refresh(context.Background(), key, data)
+		refresher.mu.Lock()
+		_, scheduled = refresher.scheduled[key]
+		refresher.mu.Unlock()
+		if !scheduled {
+			t.Error("failed rotation not scheduled again")
+		}
+	})
+
+	t.Run("forgotten", func(t *testing.T) {
+		server := tpptest.NewServer(t)
+		refresher := newBackgroundRefresher()
+		t.Cleanup(refresher.stop)
+		data := backgroundCredential(t, server)
+
+		refresher.schedule(context.Background(), data)
+		refresher.forget(data.AccessToken)
+		if len(refresher.scheduled) != 0 {
+			t.Error("rotation still scheduled once forgotten")
+		}
+	})
+
+	t.Run("stopped", func(t *testing.T) {
+		server := tpptest.NewServer(t)
+		refresher := newBackgroundRefresher()
+		data := backgroundCredential(t, server)
+		refresher.schedule(context.Background(), data)
+
+		refresher.stop()
+		if len(refresher.scheduled) != 0 {
+			t.Error("rotation still scheduled once stopped")
+		}
+		refresher.schedule(context.Background(), data)
+		if len(refresher.scheduled) != 0 {
+			t.Error("rotation scheduled once stopped")
+		}
+	})
+}
@@ -0,0 +1,226 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/logging"
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/model"
+)
+
+// rotationReason returns why the planned changes require a token rotation at now, or an empty string when they do not
+func rotationReason(state, plan *model.CredentialResourceData, now time.Time) string {
+	if urlChanged(state, plan) {
+		return fmt.Sprintf("%s changed", fURL)
+	}
+	if plan.CommitRotation.ValueBool() && !state.PendingAccessToken.IsNull() {
+		return fmt.Sprintf("%s set", fCommitRotation)
+	}
+	if authCategoryChanged(state, plan) {
+		return fmt.Sprintf("authentication method changed from %s to %s", authCategory(state), authCategory(plan))
+	}
+	if plan.RotateTrigger.IsUnknown() || !plan.RotateTrigger.Equal(state.RotateTrigger) {
+		return fmt.Sprintf("%s changed", fRotateTrigger)
+	}
+	if scheduledRotationDue(state, plan, now) {
+		return fmt.Sprintf("%s reached", fRotationSchedule)
+	}
+	return ""
+}
+
+// urlChanged reports whether the plan moves the credential to another TLSPDC URL. The tokens issued by the previous
+// one are not valid there.
+func urlChanged(state, plan *model.CredentialResourceData) bool {
+	return !plan.URL.IsUnknown() && !plan.URL.IsNull() && !plan.URL.Equal(state.URL)
+}
+
+// authentication method categories, from the primary credential of the resource
+const (
+	authCategoryClientCertificate = "client certificate"
+	authCategoryUsernamePassword  = "username/password"
+	authCategoryToken             = "token only"
+)
+
+// authCategory returns the category of the primary credential of data, or an empty string when it is not known yet
+func authCategory(data *model.CredentialResourceData) string {
+	if data.P12Certificate.IsUnknown() || data.K8sSecretName.IsUnknown() || data.Username.IsUnknown() {
+		return ""
+	}
+	if hasKeystore(data) {
+		return authCategoryClientCertificate
+	}
+	if !isBlank(data.Username) {
+		return authCategoryUsernamePassword
+	}
+	return authCategoryToken
+}
+
+// authCategoryChanged reports whether the plan switches the resource to another kind of primary credential, e.g. from
+// username/password to a client certificate. The grant obtained with the previous credential is then replaced.
+func authCategoryChanged(state, plan *model.CredentialResourceData) bool {
+	stateCategory, planCategory := authCategory(state), authCategory(plan)
+	return stateCategory != "" && planCategory != "" && planCategory != authCategoryToken && stateCategory != planCategory
+}
+
+// isBlank reports whether a credential is not provided: null, or set to an empty string
+func isBlank(value types.String) bool {
+	return value.IsNull() || value.ValueString() == ""
+}
+
+// hasKeystore reports whether data sets a PKCS#12 keystore, in a file or in a Kubernetes secret
+func hasKeystore(data *model.CredentialResourceData) bool {
+	return !isBlank(data.P12Certificate) || !isBlank(data.K8sSecretName)
+}
+
+// dropStaleRefreshToken removes the refresh token obtained before reason, e.g. a url change, so that a primary
+// credential is used to authenticate. The refresh token is kept when there is no primary credential to fall back to.
+func dropStaleRefreshToken(ctx context.Context, data *model.CredentialResourceData, reason string) {
+	if isBlank(data.RefreshToken) {
+		return
+	}
+	if !hasKeystore(data) && (isBlank(data.Username) || isBlank(data.Password)) {
+		logging.Warn(ctx, fmt.Sprintf("%s but no primary credential is set, trying the previous refresh token", reason))
+		return
+	}
+
+	logging.Info(ctx, fmt.Sprintf("%s, authenticating with the primary credential", reason))
+	data.RefreshToken = types.StringNull()
+}
+
+// refreshWindowSeconds returns the refresh window, in seconds, used to decide whether a token must be rotated. When a
+// percentage is set and the token lifetime is known, the window is that percentage of the lifetime. Otherwise, the
+// refresh window in days is used.
+func refreshWindowSeconds(data *model.CredentialResourceData) int64 {
+	if !data.RefreshWindowPercent.IsNull() && !data.IssuedAt.IsNull() {
+		lifetime := data.ExpirationDate.ValueInt64() - data.IssuedAt.ValueInt64()
+		if lifetime > 0 {
+			return lifetime * data.RefreshWindowPercent.ValueInt64() / 100
+		}
+	}
+
+	// Refresh window is in days, we need to convert it to seconds: n days * 24 hours * 60 minutes * 60 seconds
+	return data.RefreshWindow.ValueInt64() * 24 * 60 * 60
+}
+
+// setEffectiveRefreshWindow sets effective_refresh_window_seconds to the refresh window the rotation of data is decided
+// with, and refresh_due_at to the date the access token enters it. Both are null when no token is kept, and the date
+// is null as well while the expiration of the token is unknown or when the token never expires.
+func setEffectiveRefreshWindow(data *model.CredentialResourceData) {
+	if data.AccessToken.IsNull() || data.ValidateOnly.ValueBool() {
+		data.EffectiveRefreshWindow = types.Int64Null()
+		data.RefreshDueAt = types.Int64Null()
+		return
+	}
+	window := refreshWindowSeconds(data)
+	data.EffectiveRefreshWindow = types.Int64Value(window)
+	data.RefreshDueAt = types.Int64Null()
+	if !data.ExpirationDate.IsNull() && !neverExpires(data) {
+		data.RefreshDueAt = types.Int64Value(data.ExpirationDate.ValueInt64() - window)
+	}
+}
+
+// withinRefreshWindow reports whether the access token expiration date falls within the refresh window at the given
+// time. A token that never expires never enters it.
+func withinRefreshWindow(data *model.CredentialResourceData, now time.Time) bool {
+	if neverExpires(data) {
+		return false
+	}
+	return data.ExpirationDate.ValueInt64()-refreshWindowSeconds(data) < now.Unix()
+}
+
+// lacksOperationHeadroom reports whether the access token expires within min_remaining_for_operation_seconds at the
+// given time
+func lacksOperationHeadroom(data *model.CredentialResourceData, now time.Time) bool {
+	if data.MinRemainingForOp.IsNull() || neverExpires(data) {
+		return false
+	}
+	return data.ExpirationDate.ValueInt64()-now.Unix() < data.MinRemainingForOp.ValueInt64()
+}
+
+// clockSkewCheckPeriod is how long after the start of the refresh window, according to the local clock, the decision is
+// checked against the clock of TLSPDC
+const clockSkewCheckPeriod = 15 * time.Minute
+
+// justEnteredRefreshWindow reports whether the refresh window started less than clockSkewCheckPeriod ago, in which case
+// a local clock running ahead could be the only reason to rotate
+func justEnteredRefreshWindow(data *model.CredentialResourceData, now time.Time) bool {
+	windowStart := time.Unix(data.ExpirationDate.ValueInt64()-refreshWindowSeconds(data), 0)
+	return withinRefreshWindow(data, now) && now.Sub(windowStart) < clockSkewCheckPeriod
+}
+
+// refreshDueBeforeApply reports whether the access token of state enters its refresh window within the apply margin of
+// plan, i.e. whether a token valid at plan time could be due for rotation by the time the plan is applied
+func refreshDueBeforeApply(state, plan *model.CredentialResourceData, now time.Time) bool {
+	margin := plan.ApplyMarginSeconds.ValueInt64()
+	if margin <= 0 || plan.ValidateOnly.ValueBool() || state.AccessToken.IsNull() || state.ExpirationDate.IsNull() {
+		return false
+	}
+	return withinRefreshWindow(state, now.Add(time.Duration(margin)*time.Second))
+}
+
+// refresh failure policies: whether a failed rotation of a token pair that is still valid fails the operation
+const (
+	refreshFailureFail         = "fail"
+	refreshFailureKeepExisting = "keep_existing"
+)
+
+func validateRefreshFailurePolicy(policy string) error {
+	if policy != refreshFailureFail && policy != refreshFailureKeepExisting {
+		return fmt.Errorf("%s must be %s or %s, got [%s]", fRefreshFailurePolicy, refreshFailureFail, refreshFailureKeepExisting, policy)
+	}
+	return nil
+}
+
+// reportRotationFailure reports the failure to rotate a token pair that is still valid: as an error, or as a warning
+// leaving the token pair in place with the keep_existing refresh_failure_policy
+func reportRotationFailure(ctx context.Context, data *model.CredentialResourceData, err error, diags *diag.Diagnostics) {
+	if data.RefreshFailurePolicy.ValueString() != refreshFailureKeepExisting {
+		reportClientError(ctx, err, diags)
+		return
+	}
+
+	expiresOn := time.Unix(data.ExpirationDate.ValueInt64(), 0).UTC().Format(time.RFC3339)
+	logging.Warn(ctx, fmt.Sprintf("unable to rotate the token pair, keeping the access token valid until %s: %s", expiresOn, err.Error()))
+	diags.AddAttributeWarning(path.Root(fRefreshFailurePolicy), "Token Rotation Failed",
+		fmt.Sprintf("The token pair could not be rotated and the current one is kept, as set by %s %s. The access token remains valid until %s, "+
+			"the rotation is tried again on the next read or apply. Got error: %s", fRefreshFailurePolicy, refreshFailureKeepExisting, expiresOn, err.Error()))
+	data.LastRefreshWarning = types.StringValue(fmt.Sprintf("rotation failed, current token pair kept: %s", err.Error()))
+}
+
+// plan check strategies: how the access token is checked on refresh, before deciding whether to rotate it
+const (
+	planCheckOnline  = "online"
+	planCheckOffline = "offline"
+)
+
+func validatePlanCheckStrategy(strategy string) error {
+	if strategy != planCheckOnline && strategy != planCheckOffline {
+		return fmt.Errorf("%s must be %s or %s, got [%s]", fPlanCheckStrategy, planCheckOnline, planCheckOffline, strategy)
+	}
+	return nil
+}
+
+// trustedOffline reports whether the access token of data is trusted without contacting TLSPDC, as set by the offline
+// plan_check_strategy: its expiration date is known and it is neither expired nor due for rotation at now
+func trustedOffline(ctx context.Context, data *model.CredentialResourceData, now time.Time) bool {
+	if data.PlanCheckStrategy.ValueString() != planCheckOffline || data.AccessToken.IsNull() || data.ExpirationDate.IsNull() {
+		return false
+	}
+	if withinRefreshWindow(data, now) || lacksOperationHeadroom(data, now) {
+		return false
+	}
+	logging.Info(ctx, fmt.Sprintf("%s %s, access token trusted from its expiration date without verification", fPlanCheckStrategy, planCheckOffline))
+	return true
+}
+
+func validateRefreshWindowPercent(percent int64) error {
+	if percent < 0 || percent > 100 {
+		return fmt.Errorf("%s must be between 0 and 100, got %d", fRefreshWindowPercent, percent)
+	}
+	return nil
+}
@@ -0,0 +1,284 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/model"
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/tpptest"
+)
+
+func TestRefreshWindowPercent(t *testing.T) {
+	const day = 24 * 60 * 60
+	issuedAt := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	// A token valid for 100 days, with a refresh window of 30 days unless a percentage applies
+	expiration := issuedAt.Add(100 * day * time.Second)
+
+	tests := []struct {
+		name    string
+		percent types.Int64
+		// now is the number of days after issuedAt
+		now        int64
+		wantWindow int64
+		wantWithin bool
+	}{
+		{"not set", types.Int64Null(), 65, 30 * day, false},
+		{"not set, within the days", types.Int64Null(), 71, 30 * day, true},
+		{"zero", types.Int64Value(0), 99, 0, false},
+		{"20 percent", types.Int64Value(20), 79, 20 * day, false},
+		{"20 percent, within", types.Int64Value(20), 81, 20 * day, true},
+		{"50 percent", types.Int64Value(50), 51, 50 * day, true},
+		{"100 percent", types.Int64Value(100), 1, 100 * day, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data := &model.CredentialResourceData{
+				AccessToken:          types.StringValue("access"),
+				ExpirationDate:       types.Int64Value(expiration.Unix()),
+				IssuedAt:             types.Int64Value(issuedAt.Unix()),
+				RefreshWindow:        types.Int64Value(30),
+				RefreshWindowPercent: test.percent,
+			}
+			if got := refreshWindowSeconds(data); got != test.wantWindow {
+				t.Errorf("refresh window = %d, want %d", got, test.wantWindow)
+			}
+			now := issuedAt.Add(time.Duration(test.now) * day * time.Second)
+			if got := withinRefreshWindow(data, now); got != test.wantWithin {
+				t.Errorf("within refresh window = %t, want %t", got, test.wantWithin)
+			}
+
+			setEffectiveRefreshWindow(data)
+			if data.EffectiveRefreshWindow.ValueInt64() != test.wantWindow {
+				t.Errorf("effective_refresh_window_seconds = %s, want %d", data.EffectiveRefreshWindow, test.wantWindow)
+			}
+			if want := expiration.Unix() - test.wantWindow; data.RefreshDueAt.ValueInt64() != want {
+				t.Errorf("refresh_due_at = %s, want %d", data.RefreshDueAt, want)
+			}
+		})
+	}
+
+	t.Run("unknown lifetime", func(t *testing.T) {
+		// The percentage cannot apply without issued_at, the refresh window in days is used instead
+		data := &model.CredentialResourceData{
+			ExpirationDate:       types.Int64Value(expiration.Unix()),
+			RefreshWindow:        types.Int64Value(30),
+			RefreshWindowPercent: types.Int64Value(20),
+		}
+		if got := refreshWindowSeconds(data); got != 30*day {
+			t.Errorf("refresh window = %d, want %d", got, 30*day)
+		}
+	})
+}
+
+func TestEffectiveRefreshWindow(t *testing.T) {
+	const day = 24 * 60 * 60
+	issuedAt := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC).Unix()
+	expiration := issuedAt + 100*day
+
+	tests := []struct {
+		name string
+		data model.CredentialResourceData
+		// wantWindow and wantDue are the expected values, -1 when null
+		wantWindow int64
+		wantDue    int64
+	}{
+		{"days", model.CredentialResourceData{
+			AccessToken: types.StringValue("access"), ExpirationDate: types.Int64Value(expiration), RefreshWindow: types.Int64Value(7),
+		}, 7 * day, expiration - 7*day},
+		{"percent", model.CredentialResourceData{
+			AccessToken: types.StringValue("access"), ExpirationDate: types.Int64Value(expiration), IssuedAt: types.Int64Value(issuedAt),
+			RefreshWindow: types.Int64Value(7), RefreshWindowPercent: types.Int64Value(25),
+		}, 25 * day, expiration - 25*day},
+		{"percent without issued_at", model.CredentialResourceData{
+			AccessToken: types.StringValue("access"), ExpirationDate: types.Int64Value(expiration), RefreshWindow: types.Int64Value(7),
+			RefreshWindowPercent: types.Int64Value(25),
+		}, 7 * day, expiration - 7*day},
+		{"unknown expiration", model.CredentialResourceData{
+			AccessToken: types.StringValue("access"), ExpirationDate: types.Int64Null(), RefreshWindow: types.Int64Value(7),
+		}, 7 * day, -1},
+		{"never expires", model.CredentialResourceData{
+			AccessToken: types.StringValue("access"), ExpirationDate: types.Int64Value(0), RefreshWindow: types.Int64Value(7),
+		}, 7 * day, -1},
+		{"no token", model.CredentialResourceData{
+			AccessToken: types.StringNull(), ExpirationDate: types.Int64Value(expiration), RefreshWindow: types.Int64Value(7),
+		}, -1, -1},
+		{"validate only", model.CredentialResourceData{
+			AccessToken: types.StringValue("access"), ExpirationDate: types.Int64Value(expiration), RefreshWindow: types.Int64Value(7),
+			ValidateOnly: types.BoolValue(true),
+		}, -1, -1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data := test.data
+			setEffectiveRefreshWindow(&data)
+			if want := int64Attribute(test.wantWindow); !data.EffectiveRefreshWindow.Equal(want) {
+				t.Errorf("effective_refresh_window_seconds = %s, want %s", data.EffectiveRefreshWindow, want)
+			}
+			if want := int64Attribute(test.wantDue); !data.RefreshDueAt.Equal(want) {
+				t.Errorf("refresh_due_at = %s, want %s", data.RefreshDueAt, want)
+			}
+		})
+	}
+
+	t.Run("read", func(t *testing.T) {
+		_, state := importServerState(t, tpptest.NewServer(t))
+		data := stateData(t, state)
+		if want := int64(defaultRefreshWindow * day); data.EffectiveRefreshWindow.ValueInt64() != want {
+			t.Errorf("effective_refresh_window_seconds = %s, want %d", data.EffectiveRefreshWindow, want)
+		}
+	})
+}
+
+// int64Attribute returns value as an attribute value, null when it is -1
+func int64Attribute(value int64) types.Int64 {
+	if value == -1 {
+		return types.Int64Null()
+	}
+	return types.Int64Value(value)
+}
+
+func TestValidateRefreshWindowPercent(t *testing.T) {
+	for _, percent := range []int64{0, 1, 20, 100} {
+		if err := validateRefreshWindowPercent(percent); err != nil {
+			t.Errorf("%d rejected: %s", percent, err)
+		}
+	}
+	for _, percent := range []int64{-1, 101} {
+		if err := validateRefreshWindowPercent(percent); err == nil {
+			t.Errorf("%d accepted", percent)
+		}
+	}
+}
+
+func TestAuthCategory(t *testing.T) {
+	tests := []struct {
+		name     string
+		keystore types.String
+		username types.String
+		want     string
+	}{
+		{"client certificate", types.StringValue("client.p12"), types.StringValue("tppadmin"), authCategoryClientCertificate},
+		{"username/password", types.StringNull(), types.StringValue("tppadmin"), authCategoryUsernamePassword},
+		{"blank keystore", types.StringValue(""), types.StringValue("tppadmin"), authCategoryUsernamePassword},
+		{"blank username", types.StringNull(), types.StringValue(""), authCategoryToken},
+		{"token only", types.StringNull(), types.StringNull(), authCategoryToken},
+		{"unknown", types.StringUnknown(), types.StringNull(), ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data := &model.CredentialResourceData{P12Certificate: test.keystore, Username: test.username}
+			if got := authCategory(data); got != test.want {
+				t.Errorf("category = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestRefreshFailurePolicy(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy types.String
+		// expired makes the access token expire before the rotation
+		expired   bool
+		wantError bool
+	}{
+		{"default", types.StringNull(), false, true},
+		{"fail", types.StringValue(refreshFailureFail), false, true},
+		{"keep existing", types.StringValue(refreshFailureKeepExisting), false, false},
+		{"keep existing an expired token", types.StringValue(refreshFailureKeepExisting), true, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := tpptest.NewServer(t)
+			// The token pair is within its refresh window
+			server.TokenLifetime = 10 * 24 * time.Hour
+			data := serverCredential(server)
+			var diags diag.Diagnostics
+			if err := rotateToken(context.Background(), &data, &diags); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			data.RefreshFailurePolicy = test.policy
+			if test.expired {
+				server.Expire(data.AccessToken.ValueString())
+			}
+			active := data.AccessToken
+			for _, path := range []string{tpptest.PathAuthorizeOAuth, tpptest.PathRefreshToken} {
+				server.Handle(path, func(w http.ResponseWriter, _ *http.Request) {
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusBadRequest)
+					json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant", "error_description": "rotation failed"})
+				})
+			}
+
+			diags = nil
+			refreshCredential(context.Background(), &data, "", &diags)
+			if diags.HasError() != test.wantError {
+				t.Fatalf("diagnostics = %v, want error %t", diags, test.wantError)
+			}
+			if test.wantError {
+				return
+			}
+			if len(diags.Warnings()) != 1 || diags.Warnings()[0].Summary() != "Token Rotation Failed" {
+				t.Errorf("diagnostics = %v, want the failed rotation reported as a warning", diags)
+			}
+			if !data.AccessToken.Equal(active) {
+				t.Errorf("access_token = %s, want the current one kept", data.AccessToken)
+			}
+			if !strings.HasPrefix(data.LastRefreshWarning.ValueString(), "rotation failed, current token pair kept") {
+				t.Errorf("last_refresh_warning = %s, want the failed rotation", data.LastRefreshWarning)
+			}
+		})
+	}
+}
+
+func TestPlanCheckStrategy(t *testing.T) {
+	// requests returns the number of requests received by server
+	requests := func(server *tpptest.Server) int {
+		count := 0
+		for _, path := range []string{tpptest.PathAuthorizeOAuth, tpptest.PathAuthorizeCertificate, tpptest.PathRefreshToken, tpptest.PathVerifyToken,
+			tpptest.PathRevokeToken, tpptest.PathIdentitySelf, tpptest.PathSystemVersion} {
+			count += server.Requests(path)
+		}
+		return count
+	}
+
+	tests := []struct {
+		name     string
+		strategy string
+		// lifetime is the one of the token pair read
+		lifetime     time.Duration
+		wantRequests bool
+		wantRotated  bool
+	}{
+		{"online", planCheckOnline, tpptest.DefaultTokenLifetime, true, false},
+		{"offline", planCheckOffline, tpptest.DefaultTokenLifetime, false, false},
+		{"offline within the refresh window", planCheckOffline, 10 * 24 * time.Hour, true, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := tpptest.NewServer(t)
+			server.TokenLifetime = test.lifetime
+			r, state := importServerState(t, server)
+			server.TokenLifetime = tpptest.DefaultTokenLifetime
+			data := stateData(t, state)
+			data.PlanCheckStrategy = types.StringValue(test.strategy)
+			state = setStateData(t, state, data)
+			before := requests(server)
+
+			state = readState(t, r, state)
+			if sent := requests(server) != before; sent != test.wantRequests {
+				t.Errorf("requests sent to TLSPDC = %t, want %t", sent, test.wantRequests)
+			}
+			if rotated := !stateData(t, state).AccessToken.Equal(data.AccessToken); rotated != test.wantRotated {
+				t.Errorf("rotated = %t, want %t", rotated, test.wantRotated)
+			}
+		})
+	}
+}
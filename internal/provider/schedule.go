@@ -0,0 +1,187 @@
+package provider
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/model"
+)
+
+// scheduleSearchLimit bounds the search of the next occurrence of a schedule, e.g. for schedules that never match like
+// February 30th
+const scheduleSearchLimit = 5 * 366 * 24 * time.Hour
+
+// scheduleAliases are the predefined schedules, as understood by cron
+var scheduleAliases = map[string]string{
+	"@hourly":   "0 * * * *",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@weekly":   "0 0 * * 0",
+	"@monthly":  "0 0 1 * *",
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+}
+
+// rotationSchedule is a parsed rotation_schedule: the minutes, hours, days of month, months and days of week it
+// matches, as bit sets
+type rotationSchedule struct {
+	minutes, hours, daysOfMonth, months, daysOfWeek uint64
+	// anyDayOfMonth and anyDayOfWeek are set when the field is *. As with cron, when both day fields are restricted,
+	// a day matching either of them matches.
+	anyDayOfMonth, anyDayOfWeek bool
+}
+
+// scheduleFields are the fields of a cron expression, in order, with their bounds
+var scheduleFields = []struct {
+	name     string
+	min, max int
+}{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+// parseRotationSchedule parses a 5-field cron expression (minute, hour, day of month, month, day of week), or one of
+// the @hourly, @daily, @weekly, @monthly and @yearly aliases. Fields accept *, single values, ranges (1-5), lists
+// (1,15) and steps (*/15, 0-30/10). Sunday is 0 or 7.
+func parseRotationSchedule(expression string) (*rotationSchedule, error) {
+	expression = strings.TrimSpace(expression)
+	if alias, ok := scheduleAliases[strings.ToLower(expression)]; ok {
+		expression = alias
+	}
+	fields := strings.Fields(expression)
+	if len(fields) != len(scheduleFields) {
+		return nil, fmt.Errorf("%s must have %d fields (minute, hour, day of month, month, day of week), got %q",
+			fRotationSchedule, len(scheduleFields), expression)
+	}
+
+	sets := make([]uint64, len(fields))
+	for i, field := range fields {
+		set, err := parseScheduleField(field, scheduleFields[i].min, scheduleFields[i].max)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid %s %q: %w", fRotationSchedule, scheduleFields[i].name, field, err)
+		}
+		sets[i] = set
+	}
+
+	// Sunday is both 0 and 7
+	daysOfWeek := sets[4]
+	if daysOfWeek&(1<<7) != 0 {
+		daysOfWeek |= 1
+	}
+	return &rotationSchedule{
+		minutes:       sets[0],
+		hours:         sets[1],
+		daysOfMonth:   sets[2],
+		months:        sets[3],
+		daysOfWeek:    daysOfWeek,
+		anyDayOfMonth: fields[2] == "*",
+		anyDayOfWeek:  fields[4] == "*",
+	}, nil
+}
+
+// parseScheduleField returns the set of values matched by a field, within [min, max]
+func parseScheduleField(field string, min, max int) (uint64, error) {
+	var set uint64
+	for _, item := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(item, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepPart); err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step %q", stepPart)
+			}
+		}
+
+		low, high := min, max
+		if rangePart != "*" {
+			lowPart, highPart, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if low, err = strconv.Atoi(lowPart); err != nil {
+				return 0, fmt.Errorf("invalid value %q", lowPart)
+			}
+			high = low
+			if isRange {
+				if high, err = strconv.Atoi(highPart); err != nil {
+					return 0, fmt.Errorf("invalid value %q", highPart)
+				}
+			} else if hasStep {
+				// 5/15 stands for 5-max/15
+				high = max
+			}
+		}
+		if low > high {
+			return 0, fmt.Errorf("range %d-%d is reversed", low, high)
+		}
+		if low < min || high > max {
+			return 0, fmt.Errorf("values must be between %d and %d", min, max)
+		}
+		for value := low; value <= high; value += step {
+			set |= 1 << value
+		}
+	}
+	return set, nil
+}
+
+func (s *rotationSchedule) matchesDay(t time.Time) bool {
+	dayOfMonth := s.daysOfMonth&(1<<t.Day()) != 0
+	dayOfWeek := s.daysOfWeek&(1<<int(t.Weekday())) != 0
+	switch {
+	case s.anyDayOfMonth && s.anyDayOfWeek:
+		return true
+	case s.anyDayOfMonth:
+		return dayOfWeek
+	case s.anyDayOfWeek:
+		return dayOfMonth
+	default:
+		return dayOfMonth || dayOfWeek
+	}
+}
+
+// next returns the first time matched by the schedule strictly after after, in UTC. The zero time is returned when
+// the schedule matches nothing within scheduleSearchLimit.
+func (s *rotationSchedule) next(after time.Time) time.Time {
+	after = after.UTC()
+	limit := after.Add(scheduleSearchLimit)
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	for t.Before(limit) {
+		if s.months&(1<<int(t.Month())) == 0 || !s.matchesDay(t) {
+			// Jump to the start of the next day
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if s.hours&(1<<t.Hour()) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, time.UTC)
+			continue
+		}
+		if s.minutes&(1<<t.Minute()) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// scheduledRotationDue reports whether a time of rotation_schedule passed between the issuance of the access token of
+// state and now. A token whose issuance date is unknown, e.g. set on import, is due right away: the schedule then
+// applies from the issuance of its replacement.
+func scheduledRotationDue(state, plan *model.CredentialResourceData, now time.Time) bool {
+	if plan.RotationSchedule.IsNull() || plan.RotationSchedule.IsUnknown() || plan.ValidateOnly.ValueBool() {
+		return false
+	}
+	schedule, err := parseRotationSchedule(plan.RotationSchedule.ValueString())
+	if err != nil {
+		return false
+	}
+	if state.IssuedAt.IsNull() {
+		// Unless the schedule matches nothing, the token would never be rotated otherwise
+		return !schedule.next(now).IsZero()
+	}
+	scheduled := schedule.next(time.Unix(state.IssuedAt.ValueInt64(), 0))
+	return !scheduled.IsZero() && !scheduled.After(now)
+}
@@ -0,0 +1,80 @@
+package provider
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/model"
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/tpptest"
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/vcertclient"
+)
+
+func TestScheduledRotationDue(t *testing.T) {
+	// Monday 2026-03-02 at 03:00 UTC is a scheduled time
+	const weekly = "0 3 * * 1"
+	monday := time.Date(2026, time.March, 2, 3, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		schedule     types.String
+		issuedAt     types.Int64
+		validateOnly bool
+		now          time.Time
+		want         bool
+	}{
+		{"crossed", types.StringValue(weekly), types.Int64Value(monday.Add(-time.Hour).Unix()), false, monday.Add(time.Minute), true},
+		{"at the scheduled time", types.StringValue(weekly), types.Int64Value(monday.Add(-time.Hour).Unix()), false, monday, true},
+		{"crossed several times", types.StringValue(weekly), types.Int64Value(monday.Add(-30 * 24 * time.Hour).Unix()), false, monday.Add(time.Hour), true},
+		{"not yet due", types.StringValue(weekly), types.Int64Value(monday.Add(time.Minute).Unix()), false, monday.Add(6 * 24 * time.Hour), false},
+		{"issued at the scheduled time", types.StringValue(weekly), types.Int64Value(monday.Unix()), false, monday.Add(time.Hour), false},
+		{"issuance unknown", types.StringValue(weekly), types.Int64Null(), false, monday.Add(time.Hour), true},
+		{"issuance unknown, schedule matching nothing", types.StringValue("0 3 30 2 *"), types.Int64Null(), false, monday, false},
+		{"no schedule", types.StringNull(), types.Int64Value(monday.Add(-30 * 24 * time.Hour).Unix()), false, monday, false},
+		{"schedule not known yet", types.StringUnknown(), types.Int64Null(), false, monday, false},
+		{"invalid schedule", types.StringValue("every monday"), types.Int64Null(), false, monday, false},
+		{"validate only", types.StringValue(weekly), types.Int64Null(), true, monday, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			state := &model.CredentialResourceData{IssuedAt: test.issuedAt}
+			plan := &model.CredentialResourceData{RotationSchedule: test.schedule, ValidateOnly: types.BoolValue(test.validateOnly)}
+			if got := scheduledRotationDue(state, plan, test.now); got != test.want {
+				t.Errorf("due = %t, want %t", got, test.want)
+			}
+		})
+	}
+}
+
+func TestRotationScheduleAfterImport(t *testing.T) {
+	server := tpptest.NewServer(t)
+	r, _ := importServerState(t, server)
+	grant := server.IssueGrant(vcertclient.DefaultScope)
+	id := fmt.Sprintf("%s,%s=%s,%s=%s,%s=%d", serverImportID(server), fAccessToken, grant.AccessToken, fRefreshToken, grant.RefreshToken,
+		fExpirationDate, grant.ExpiresAt.Unix())
+	state := readState(t, r, importState(t, r, id))
+	configured := []string{fUsername, fPassword, fRotationSchedule}
+	if issuedAt := stateData(t, state).IssuedAt; !issuedAt.IsNull() {
+		t.Fatalf("issued_at = %s, want the issuance of the imported token unknown", issuedAt)
+	}
+
+	data := stateData(t, state)
+	data.RotationSchedule = types.StringValue("0 3 1 1 *")
+	plan, config := planUpdate(t, r, state, data, configured...)
+	if !unknownAttributes(t, plan)[fAccessToken] {
+		t.Fatal("rotation of the imported token not planned")
+	}
+	state = applyUpdate(t, r, state, plan, config)
+	rotated := stateData(t, state)
+	if rotated.IssuedAt.IsNull() {
+		t.Fatal("issued_at not set by the rotation")
+	}
+
+	// The schedule applies from the issuance of the new token
+	plan, _ = planUpdate(t, r, state, rotated, configured...)
+	if unknownAttributes(t, plan)[fAccessToken] {
+		t.Error("rotation planned again before the next scheduled time")
+	}
+}
@@ -0,0 +1,47 @@
+package provider
+
+import (
+	"strings"
+
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/vcertclient"
+)
+
+// defaultScope is the scope requested by vcert when none is set
+const defaultScope = vcertclient.DefaultScope
+
+// parseScope expands a TLSPDC scope string into individual entries. For example,
+// "certificate:manage,revoke;configuration" becomes "certificate:manage", "certificate:revoke" and "configuration".
+func parseScope(scope string) []string {
+	var entries []string
+	for _, restriction := range strings.Split(scope, ";") {
+		restriction = strings.TrimSpace(restriction)
+		if restriction == "" {
+			continue
+		}
+
+		name, privileges, found := strings.Cut(restriction, ":")
+		if !found {
+			entries = append(entries, name)
+			continue
+		}
+		for _, privilege := range strings.Split(privileges, ",") {
+			entries = append(entries, name+":"+strings.TrimSpace(privilege))
+		}
+	}
+	return entries
+}
+
+// scopeSatisfied reports whether every requested scope entry was granted
+func scopeSatisfied(requested, granted []string) bool {
+	grantedSet := make(map[string]bool, len(granted))
+	for _, entry := range granted {
+		grantedSet[entry] = true
+	}
+
+	for _, entry := range requested {
+		if !grantedSet[entry] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,51 @@
+package provider
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/model"
+)
+
+func TestParseScope(t *testing.T) {
+	got := parseScope(" certificate:manage, revoke;configuration;; ssh:approve")
+	want := []string{"certificate:manage", "certificate:revoke", "configuration", "ssh:approve"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("entries = %v, want %v", got, want)
+	}
+}
+
+func TestSetGrantedScopes(t *testing.T) {
+	tests := []struct {
+		name      string
+		requested string
+		granted   string
+		want      types.Bool
+	}{
+		{"full", "certificate:manage,revoke;configuration", "certificate:manage,revoke;configuration", types.BoolValue(true)},
+		{"partial", "certificate:manage,revoke;configuration", "certificate:manage;configuration", types.BoolValue(false)},
+		{"superset", "certificate:manage", "certificate:discover,manage,revoke;configuration:manage", types.BoolValue(true)},
+		{"default scope", "", "certificate:manage", types.BoolValue(true)},
+		{"unknown grant", "certificate:manage", "", types.BoolNull()},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data := &model.CredentialResourceData{Scope: types.StringValue(test.requested)}
+			setGrantedScopes(data, test.granted)
+			if !data.ScopeSatisfied.Equal(test.want) {
+				t.Errorf("scope_satisfied = %s, want %s", data.ScopeSatisfied, test.want)
+			}
+			if test.granted == "" {
+				if !data.GrantedScopes.IsNull() {
+					t.Errorf("granted_scopes = %s, want null", data.GrantedScopes)
+				}
+				return
+			}
+			if len(data.GrantedScopes.Elements()) != len(parseScope(test.granted)) {
+				t.Errorf("granted_scopes = %s, want the entries of %s", data.GrantedScopes, test.granted)
+			}
+		})
+	}
+}
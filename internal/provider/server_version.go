@@ -0,0 +1,121 @@
+package provider
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/model"
+)
+
+// serverVersionRequirement is a feature of the provider that only works from a given TLSPDC version
+type serverVersionRequirement struct {
+	feature string
+	// enabled reports whether the configuration relies on the feature
+	enabled func(data *model.CredentialResourceData) bool
+	// minimums are the lowest supported versions of each release line, e.g. 20.2.2 and 20.3.3. A release line not
+	// listed is supported when it is more recent than every listed one.
+	minimums []string
+}
+
+// serverVersionRequirements are the minimum TLSPDC versions documented by vcert for the operations of the provider
+var serverVersionRequirements = []serverVersionRequirement{
+	{
+		feature:  "token authentication",
+		enabled:  func(*model.CredentialResourceData) bool { return true },
+		minimums: []string{"20.1"},
+	},
+	{
+		feature:  "token verification",
+		enabled:  func(data *model.CredentialResourceData) bool { return !data.ValidateOnly.ValueBool() },
+		minimums: []string{"20.2.2", "20.3.3"},
+	},
+	{
+		feature: "client certificate authentication",
+		enabled: func(data *model.CredentialResourceData) bool {
+			return hasKeystore(data) || !data.P12CertFilenames.IsNull()
+		},
+		minimums: []string{"20.2.2", "20.3.3"},
+	},
+}
+
+// warnUnsupportedServerVersion adds a warning for every enabled feature the TLSPDC version recorded in data does not
+// support. Nothing is reported when the version is not known.
+func warnUnsupportedServerVersion(data *model.CredentialResourceData, diags *diag.Diagnostics) {
+	if data.TPPVersion.IsNull() || data.TPPVersion.IsUnknown() {
+		return
+	}
+	version, ok := parseServerVersion(data.TPPVersion.ValueString())
+	if !ok {
+		return
+	}
+
+	for _, requirement := range serverVersionRequirements {
+		if !requirement.enabled(data) || supportsVersion(version, requirement.minimums) {
+			continue
+		}
+		diags.AddAttributeWarning(path.Root(fTPPVersion), "Unsupported TLSPDC version",
+			fmt.Sprintf("TLSPDC %s does not support %s, which requires version %s or later. Upgrade TLSPDC to avoid unexpected failures.",
+				data.TPPVersion.ValueString(), requirement.feature, strings.Join(requirement.minimums, " or ")))
+	}
+}
+
+// supportsVersion reports whether version satisfies the minimum of its release line in minimums
+func supportsVersion(version []int, minimums []string) bool {
+	newest := true
+	for _, minimum := range minimums {
+		required, ok := parseServerVersion(minimum)
+		if !ok {
+			continue
+		}
+		if sameReleaseLine(version, required) {
+			return compareVersions(version, required) >= 0
+		}
+		if compareVersions(version, required) < 0 {
+			newest = false
+		}
+	}
+	return newest
+}
+
+// sameReleaseLine reports whether both versions share their major and minor numbers
+func sameReleaseLine(a, b []int) bool {
+	return len(a) >= 2 && len(b) >= 2 && a[0] == b[0] && a[1] == b[1]
+}
+
+// compareVersions returns -1, 0 or 1 when a is lower than, equal to or greater than b. Missing components count as 0.
+func compareVersions(a, b []int) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var x, y int
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// parseServerVersion splits a dotted TLSPDC version, e.g. 22.4.0.5038, into its numbers
+func parseServerVersion(version string) ([]int, bool) {
+	parts := strings.Split(strings.TrimSpace(version), ".")
+	numbers := make([]int, 0, len(parts))
+	for _, part := range parts {
+		number, err := strconv.Atoi(part)
+		if err != nil || number < 0 {
+			return nil, false
+		}
+		numbers = append(numbers, number)
+	}
+	return numbers, len(numbers) >= 2
+}
@@ -0,0 +1,67 @@
+package provider
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/model"
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/tpptest"
+)
+
+func TestWarnUnsupportedServerVersion(t *testing.T) {
+	tests := []struct {
+		name     string
+		version  types.String
+		keystore bool
+		// want are the features reported unsupported
+		want []string
+	}{
+		{"unknown version", types.StringNull(), true, nil},
+		{"unparsable version", types.StringValue("latest"), true, nil},
+		{"too old for tokens", types.StringValue("19.4.0.1234"), false, []string{"token authentication", "token verification"}},
+		{"old release line", types.StringValue("20.2.1"), false, []string{"token verification"}},
+		{"old release line with a keystore", types.StringValue("20.2.1"), true, []string{"token verification", "client certificate authentication"}},
+		{"release line minimum", types.StringValue("20.3.3"), true, nil},
+		{"recent", types.StringValue("24.1.0.2345"), true, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data := &model.CredentialResourceData{TPPVersion: test.version, P12CertFilenames: types.ListNull(types.StringType)}
+			if test.keystore {
+				data.P12Certificate = types.StringValue("client.p12")
+			}
+
+			var diags diag.Diagnostics
+			warnUnsupportedServerVersion(data, &diags)
+			if diags.HasError() || diags.WarningsCount() != len(test.want) {
+				t.Fatalf("diagnostics = %v, want %d warning(s)", diags, len(test.want))
+			}
+			for i, feature := range test.want {
+				if detail := diags.Warnings()[i].Detail(); !strings.Contains(detail, "does not support "+feature) {
+					t.Errorf("warning = %s, want %s reported", detail, feature)
+				}
+			}
+		})
+	}
+}
+
+func TestOldServerVersion(t *testing.T) {
+	server := tpptest.NewServer(t)
+	server.Version = "20.2.1.1234"
+	r, state := importServerState(t, server)
+
+	resp := readWithDiagnostics(t, r, state)
+	if warnings := resp.Diagnostics.Warnings(); len(warnings) != 1 || !strings.Contains(warnings[0].Detail(), "TLSPDC 20.2.1.1234 does not support token verification") {
+		t.Errorf("diagnostics = %v, want the unsupported version reported", resp.Diagnostics)
+	}
+
+	// The version is kept in the state, it is not retrieved again until the next rotation
+	requests := server.Requests(tpptest.PathSystemVersion)
+	readWithDiagnostics(t, r, resp.State)
+	if server.Requests(tpptest.PathSystemVersion) != requests {
+		t.Error("version retrieved again")
+	}
+}
@@ -0,0 +1,66 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/logging"
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/model"
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/vcertclient"
+)
+
+// heldGrants records the grants only held in the memory of the provider process: tokens minted by validate_only until
+// they are revoked, and pairs rotated in the background without recovery_file until a read adopts them. They would
+// stay active in TLSPDC, with nobody able to revoke them, once the process exits.
+type heldGrants struct {
+	mu sync.Mutex
+	// grants holds the credential data able to revoke each grant, keyed by the fingerprint of its access token
+	grants map[string]model.CredentialResourceData
+}
+
+// inMemoryGrants is the registry of the grants held by the provider process
+var inMemoryGrants = &heldGrants{grants: make(map[string]model.CredentialResourceData)}
+
+// shutdownOnce ensures the provider process is only shut down once, whether Terraform stopped it or it got a signal
+var shutdownOnce sync.Once
+
+// Shutdown stops the background refreshes of the provider process, waits for the rotations in progress to end, then
+// revokes, best-effort, the grants only held in memory
+func Shutdown() {
+	shutdownOnce.Do(func() {
+		backgroundRefreshes.stop()
+		inMemoryGrants.revokeAll(context.Background())
+	})
+}
+
+// hold records that the grant of the access token of data is only held in memory
+func (g *heldGrants) hold(data model.CredentialResourceData) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.grants[accessTokenFingerprint(data.AccessToken)] = data
+}
+
+// release records that the grant of accessToken was revoked or saved, e.g. to the state
+func (g *heldGrants) release(accessToken types.String) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.grants, accessTokenFingerprint(accessToken))
+}
+
+// revokeAll revokes the grants still held. Failures are only logged, the process is exiting.
+func (g *heldGrants) revokeAll(ctx context.Context) {
+	g.mu.Lock()
+	grants := g.grants
+	g.grants = make(map[string]model.CredentialResourceData)
+	g.mu.Unlock()
+
+	for _, data := range grants {
+		logging.Info(ctx, "revoking a grant only held in memory before exiting")
+		if err := vcertclient.New(ctx, data).RevokeToken(); err != nil {
+			logging.Warn(ctx, fmt.Sprintf("unable to revoke a grant only held in memory, it remains valid until it expires: %s", err.Error()))
+		}
+	}
+}
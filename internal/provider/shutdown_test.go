@@ -0,0 +1,123 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/model"
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/tpptest"
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/vcertclient"
+)
+
+// heldCredential returns a credential of server holding a grant of its own
+func heldCredential(server *tpptest.Server) model.CredentialResourceData {
+	data := serverCredential(server)
+	grant := server.IssueGrant(vcertclient.DefaultScope)
+	data.AccessToken = types.StringValue(grant.AccessToken)
+	data.RefreshToken = types.StringValue(grant.RefreshToken)
+	data.ExpirationDate = types.Int64Value(grant.ExpiresAt.Unix())
+	return data
+}
+
+func TestHeldGrants(t *testing.T) {
+	server := tpptest.NewServer(t)
+	held := &heldGrants{grants: make(map[string]model.CredentialResourceData)}
+	kept := heldCredential(server)
+	released := heldCredential(server)
+	unreachable := heldCredential(server)
+	unreachable.URL = types.StringValue(tpptest.UnreachableURL(t))
+	unreachable.MaxTotalAttempts = types.Int64Value(1)
+	held.hold(kept)
+	held.hold(released)
+	held.hold(unreachable)
+	held.release(released.AccessToken)
+
+	// A grant failing to be revoked does not prevent the others from being revoked
+	held.revokeAll(context.Background())
+	if !revoked(t, server, kept.AccessToken) {
+		t.Error("held grant not revoked")
+	}
+	if revoked(t, server, released.AccessToken) {
+		t.Error("released grant revoked")
+	}
+	if len(held.grants) != 0 {
+		t.Errorf("%d grant(s) still held", len(held.grants))
+	}
+
+	requests := server.Requests(tpptest.PathRevokeToken)
+	held.revokeAll(context.Background())
+	if server.Requests(tpptest.PathRevokeToken) != requests {
+		t.Error("grant revoked twice")
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	server := tpptest.NewServer(t)
+	held := &heldGrants{grants: make(map[string]model.CredentialResourceData)}
+	refresher := newBackgroundRefresher()
+	previousGrants, previousRefreshes := inMemoryGrants, backgroundRefreshes
+	inMemoryGrants, backgroundRefreshes, shutdownOnce = held, refresher, sync.Once{}
+	t.Cleanup(func() {
+		inMemoryGrants, backgroundRefreshes, shutdownOnce = previousGrants, previousRefreshes, sync.Once{}
+	})
+	data := heldCredential(server)
+	held.hold(data)
+	refresher.schedule(context.Background(), backgroundCredential(t, server))
+
+	// Whether Terraform stopped the process or it got a signal, it is shut down once
+	Shutdown()
+	Shutdown()
+	if !revoked(t, server, data.AccessToken) || server.Requests(tpptest.PathRevokeToken) != 1 {
+		t.Errorf("%d revocation(s), want the held grant revoked once", server.Requests(tpptest.PathRevokeToken))
+	}
+	if !refresher.stopped || len(refresher.scheduled) != 0 {
+		t.Error("background refreshes not stopped")
+	}
+}
+
+func TestValidateOnlyHeldGrant(t *testing.T) {
+	// heldAfterValidation validates the credentials of server and returns the grants held in memory afterwards
+	heldAfterValidation := func(t *testing.T, server *tpptest.Server) []model.CredentialResourceData {
+		held := &heldGrants{grants: make(map[string]model.CredentialResourceData)}
+		previous := inMemoryGrants
+		inMemoryGrants = held
+		t.Cleanup(func() { inMemoryGrants = previous })
+
+		data := serverCredential(server)
+		data.ValidateOnly = types.BoolValue(true)
+		var diags diag.Diagnostics
+		validateCredentials(context.Background(), &data, &diags)
+		var grants []model.CredentialResourceData
+		for _, grant := range held.grants {
+			grants = append(grants, grant)
+		}
+		return grants
+	}
+
+	t.Run("revoked", func(t *testing.T) {
+		server := tpptest.NewServer(t)
+		if grants := heldAfterValidation(t, server); len(grants) != 0 {
+			t.Errorf("%d grant(s) held, want the revoked one released", len(grants))
+		}
+	})
+
+	t.Run("not revoked", func(t *testing.T) {
+		// Held until the provider process shuts down
+		server := tpptest.NewServer(t)
+		server.Handle(tpptest.PathRevokeToken, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		})
+		grants := heldAfterValidation(t, server)
+		if len(grants) != 1 {
+			t.Fatalf("%d grant(s) held, want the minted one", len(grants))
+		}
+		if _, ok := server.GrantOf(grants[0].AccessToken.ValueString()); !ok {
+			t.Errorf("held access_token = %s, want the token minted by the validation", grants[0].AccessToken)
+		}
+	})
+}
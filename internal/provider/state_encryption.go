@@ -0,0 +1,145 @@
+package provider
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/model"
+)
+
+// encryptedTokenPrefix marks the tokens saved to the state encrypted with state_encryption_key, followed by the
+// base64-encoded nonce and ciphertext
+const encryptedTokenPrefix = "enc:v1:"
+
+// errNoStateEncryptionKey is returned when the state holds encrypted tokens but the provider has no key to decrypt them
+var errNoStateEncryptionKey = errors.New("the state holds tokens encrypted with state_encryption_key, which is not set in the provider configuration")
+
+// newStateCipher returns the AES-GCM cipher of key, a base64-encoded AES key of 16, 24 or 32 bytes
+func newStateCipher(key string) (cipher.AEAD, error) {
+	rawKey, err := base64.StdEncoding.DecodeString(strings.TrimSpace(key))
+	if err != nil {
+		return nil, fmt.Errorf("%s must be base64-encoded: %w", fStateEncryptionKey, err)
+	}
+	block, err := aes.NewCipher(rawKey)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be an AES key of 16, 24 or 32 bytes, got %d bytes", fStateEncryptionKey, len(rawKey))
+	}
+	return cipher.NewGCM(block)
+}
+
+// encryptedTokens returns the token attributes of data encrypted in the state, by name
+func encryptedTokens(data *model.CredentialResourceData) []struct {
+	name  string
+	value *types.String
+} {
+	return []struct {
+		name  string
+		value *types.String
+	}{
+		{fAccessToken, &data.AccessToken},
+		{fRefreshToken, &data.RefreshToken},
+		{fPendingAccessToken, &data.PendingAccessToken},
+		{fPendingRefreshToken, &data.PendingRefreshToken},
+		{fNextAccessToken, &data.NextAccessToken},
+		{fNextRefreshToken, &data.NextRefreshToken},
+		{fSupersededAccessToken, &data.SupersededAccessToken},
+	}
+}
+
+// encryptToken encrypts token, binding the ciphertext to the name of its attribute so that it cannot be moved to
+// another one
+func encryptToken(aead cipher.AEAD, name, token string) (string, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("unable to generate a nonce to encrypt %s: %w", name, err)
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(token), []byte(name))
+	return encryptedTokenPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// decryptToken returns the plaintext of the token encrypted by encryptToken
+func decryptToken(aead cipher.AEAD, name, value string) (string, error) {
+	if aead == nil {
+		return "", errNoStateEncryptionKey
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedTokenPrefix))
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", fmt.Errorf("%s is not a valid encrypted token", name)
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	token, err := aead.Open(nil, nonce, ciphertext, []byte(name))
+	if err != nil {
+		return "", fmt.Errorf("unable to decrypt %s, state_encryption_key differs from the key it was encrypted with: %w", name, err)
+	}
+	return string(token), nil
+}
+
+func isEncryptedToken(value types.String) bool {
+	return !value.IsNull() && !value.IsUnknown() && strings.HasPrefix(value.ValueString(), encryptedTokenPrefix)
+}
+
+// decryptTokens replaces the encrypted tokens of data with their plaintext, for the operations of the resource. Tokens
+// saved in plaintext, e.g. before state_encryption_key was set, are left as is.
+func (r *CredentialResource) decryptTokens(data *model.CredentialResourceData) error {
+	for _, token := range encryptedTokens(data) {
+		if !isEncryptedToken(*token.value) {
+			continue
+		}
+		plaintext, err := decryptToken(r.stateCipher(), token.name, token.value.ValueString())
+		if err != nil {
+			return err
+		}
+		*token.value = types.StringValue(plaintext)
+	}
+	return nil
+}
+
+// encryptTokens encrypts the tokens of data before it is saved to the state, when state_encryption_key is set. Tokens
+// unchanged since previous, the data the operation started from, keep their ciphertext so that the state does not
+// change on every run. The configured attributes are left as is, Terraform expecting the configured value.
+func (r *CredentialResource) encryptTokens(data, previous *model.CredentialResourceData, configured map[string]bool) error {
+	aead := r.stateCipher()
+	if aead == nil {
+		return nil
+	}
+
+	var previousTokens map[string]types.String
+	if previous != nil {
+		previousTokens = make(map[string]types.String)
+		for _, token := range encryptedTokens(previous) {
+			previousTokens[token.name] = *token.value
+		}
+	}
+	for _, token := range encryptedTokens(data) {
+		if token.value.IsNull() || token.value.IsUnknown() || configured[token.name] || isEncryptedToken(*token.value) {
+			continue
+		}
+		if sealed, ok := previousTokens[token.name]; ok && isEncryptedToken(sealed) {
+			if plaintext, err := decryptToken(aead, token.name, sealed.ValueString()); err == nil && plaintext == token.value.ValueString() {
+				*token.value = sealed
+				continue
+			}
+		}
+		sealed, err := encryptToken(aead, token.name, token.value.ValueString())
+		if err != nil {
+			return err
+		}
+		*token.value = types.StringValue(sealed)
+	}
+	return nil
+}
+
+// stateCipher returns the cipher of the state_encryption_key of the provider, nil when it is not set
+func (r *CredentialResource) stateCipher() cipher.AEAD {
+	if r.providerData == nil {
+		return nil
+	}
+	return r.providerData.StateCipher
+}
@@ -0,0 +1,145 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/tpptest"
+)
+
+// testStateKey returns a base64-encoded AES key of size bytes, all set to b
+func testStateKey(size int, b byte) string {
+	return base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{b}, size))
+}
+
+func TestNewStateCipher(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		wantErr string
+	}{
+		{"AES-128", testStateKey(16, 1), ""},
+		{"AES-192", testStateKey(24, 1), ""},
+		{"AES-256 with a trailing newline", testStateKey(32, 1) + "\n", ""},
+		{"not base64", "not a key!", "must be base64-encoded"},
+		{"wrong size", testStateKey(20, 1), "must be an AES key of 16, 24 or 32 bytes, got 20 bytes"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := newStateCipher(test.key)
+			if test.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("error = %v, want %q", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestEncryptToken(t *testing.T) {
+	aead, err := newStateCipher(testStateKey(32, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := encryptToken(aead, fAccessToken, "access")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !strings.HasPrefix(sealed, encryptedTokenPrefix) || strings.Contains(sealed, "access") {
+		t.Fatalf("encrypted token = %s", sealed)
+	}
+
+	t.Run("round trip", func(t *testing.T) {
+		if token, err := decryptToken(aead, fAccessToken, sealed); err != nil || token != "access" {
+			t.Errorf("token = %q, %v, want %q", token, err, "access")
+		}
+		// A random nonce is used for each encryption
+		if other, _ := encryptToken(aead, fAccessToken, "access"); other == sealed {
+			t.Error("same ciphertext for two encryptions")
+		}
+	})
+
+	t.Run("wrong key", func(t *testing.T) {
+		other, err := newStateCipher(testStateKey(32, 2))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = decryptToken(other, fAccessToken, sealed); err == nil || !strings.Contains(err.Error(), "differs from the key it was encrypted with") {
+			t.Errorf("error = %v, want the key reported wrong", err)
+		}
+	})
+
+	t.Run("moved to another attribute", func(t *testing.T) {
+		if _, err := decryptToken(aead, fRefreshToken, sealed); err == nil {
+			t.Error("access token decrypted as the refresh token")
+		}
+	})
+
+	t.Run("no key", func(t *testing.T) {
+		if _, err := decryptToken(nil, fAccessToken, sealed); !errors.Is(err, errNoStateEncryptionKey) {
+			t.Errorf("error = %v, want %v", err, errNoStateEncryptionKey)
+		}
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		if _, err := decryptToken(aead, fAccessToken, encryptedTokenPrefix+"AAAA"); err == nil || !strings.Contains(err.Error(), "not a valid encrypted token") {
+			t.Errorf("error = %v, want the token reported invalid", err)
+		}
+	})
+}
+
+func TestStateEncryption(t *testing.T) {
+	server := tpptest.NewServer(t)
+	t.Setenv(envStateEncryptionKey, "")
+	// encryptedResource returns a resource of server whose provider encrypts the state with key
+	encryptedResource := func(key string) *CredentialResource {
+		attributes := map[string]string{fURL: server.URL, fTrustBundle: server.TrustBundle()}
+		if key != "" {
+			attributes[fStateEncryptionKey] = key
+		}
+		return newResource(t, configureProvider(t, attributes))
+	}
+	key := testStateKey(32, 1)
+	r := encryptedResource(key)
+	state := readState(t, r, importState(t, r, serverImportID(server)))
+
+	data := stateData(t, state)
+	for name, token := range map[string]string{fAccessToken: data.AccessToken.ValueString(), fRefreshToken: data.RefreshToken.ValueString()} {
+		if !strings.HasPrefix(token, encryptedTokenPrefix) {
+			t.Errorf("%s = %s, want it encrypted in the state", name, token)
+		}
+	}
+	decrypted := data
+	if err := r.decryptTokens(&decrypted); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, ok := server.GrantOf(decrypted.AccessToken.ValueString()); !ok {
+		t.Errorf("decrypted access_token = %s, want a token of TLSPDC", decrypted.AccessToken)
+	}
+
+	t.Run("unchanged token keeps its ciphertext", func(t *testing.T) {
+		if again := stateData(t, readState(t, r, state)); !again.AccessToken.Equal(data.AccessToken) {
+			t.Errorf("access_token = %s, want the ciphertext %s kept", again.AccessToken, data.AccessToken)
+		}
+	})
+
+	for name, other := range map[string]*CredentialResource{"wrong key": encryptedResource(testStateKey(32, 2)), "no key": encryptedResource("")} {
+		t.Run(name, func(t *testing.T) {
+			resp := resource.ReadResponse{State: state}
+			other.Read(context.Background(), resource.ReadRequest{State: state}, &resp)
+			if len(resp.Diagnostics.Errors()) != 1 || resp.Diagnostics.Errors()[0].Summary() != msgStateEncryptionError {
+				t.Errorf("diagnostics = %v, want a state encryption error", resp.Diagnostics)
+			}
+		})
+	}
+}
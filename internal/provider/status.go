@@ -0,0 +1,96 @@
+package provider
+
+import (
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/model"
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/vcertclient"
+)
+
+// token_status fields
+const (
+	fStatusValid               = "valid"
+	fStatusExpired             = "expired"
+	fStatusDaysUntilExpiration = "days_until_expiration"
+	fStatusWithinRefreshWindow = "within_refresh_window"
+	fStatusIssuedAt            = "issued_at"
+	fStatusExpiration          = "expiration"
+)
+
+// tokenStatusAttributeTypes describes the token_status object
+var tokenStatusAttributeTypes = map[string]attr.Type{
+	fStatusValid:               types.BoolType,
+	fStatusExpired:             types.BoolType,
+	fStatusDaysUntilExpiration: types.Int64Type,
+	fStatusWithinRefreshWindow: types.BoolType,
+	fStatusIssuedAt:            types.Int64Type,
+	fStatusExpiration:          types.Int64Type,
+}
+
+// auth_attempts fields
+const (
+	fAttemptMethod    = "method"
+	fAttemptSucceeded = "succeeded"
+	fAttemptError     = "error"
+)
+
+// authAttemptType describes an entry of the auth_attempts list
+var authAttemptType = types.ObjectType{AttrTypes: map[string]attr.Type{
+	fAttemptMethod:    types.StringType,
+	fAttemptSucceeded: types.BoolType,
+	fAttemptError:     types.StringType,
+}}
+
+// setSelectedAuthMethod records the authentication method that got the token pair of resp, and whether it was a
+// fallback
+func setSelectedAuthMethod(data *model.CredentialResourceData, resp *vcertclient.RefreshTokenResponse) {
+	data.SelectedAuthMethod = stringOrNull(resp.Method)
+	data.UsedFallbackMethod = types.BoolValue(resp.UsedFallback)
+}
+
+// setAuthAttempts records the requests sent by the authentication ladder during the last rotation
+func setAuthAttempts(data *model.CredentialResourceData, attempts []vcertclient.AuthAttempt) {
+	elements := make([]attr.Value, 0, len(attempts))
+	for _, attempt := range attempts {
+		elements = append(elements, types.ObjectValueMust(authAttemptType.AttrTypes, map[string]attr.Value{
+			fAttemptMethod:    types.StringValue(attempt.Method),
+			fAttemptSucceeded: types.BoolValue(attempt.Succeeded),
+			fAttemptError:     stringOrNull(attempt.Error),
+		}))
+	}
+	data.AuthAttempts = types.ListValueMust(authAttemptType, elements)
+}
+
+// setTokenStatus summarizes the state of the access token held by data at the given time. The status is null when
+// there is no access token, i.e. in validate_only mode. A token that never expires has no days_until_expiration.
+func setTokenStatus(data *model.CredentialResourceData, now time.Time) {
+	if data.AccessToken.IsNull() || data.ExpirationDate.IsNull() {
+		data.TokenStatus = types.ObjectNull(tokenStatusAttributeTypes)
+		return
+	}
+	if neverExpires(data) {
+		data.TokenStatus = types.ObjectValueMust(tokenStatusAttributeTypes, map[string]attr.Value{
+			fStatusValid:               types.BoolValue(true),
+			fStatusExpired:             types.BoolValue(false),
+			fStatusDaysUntilExpiration: types.Int64Null(),
+			fStatusWithinRefreshWindow: types.BoolValue(false),
+			fStatusIssuedAt:            data.IssuedAt,
+			fStatusExpiration:          data.ExpirationDate,
+		})
+		return
+	}
+
+	remaining := data.ExpirationDate.ValueInt64() - now.Unix()
+	expired := remaining <= 0
+	data.TokenStatus = types.ObjectValueMust(tokenStatusAttributeTypes, map[string]attr.Value{
+		fStatusValid:               types.BoolValue(!expired),
+		fStatusExpired:             types.BoolValue(expired),
+		fStatusDaysUntilExpiration: types.Int64Value(remaining / (24 * 60 * 60)),
+		fStatusWithinRefreshWindow: types.BoolValue(withinRefreshWindow(data, now)),
+		fStatusIssuedAt:            data.IssuedAt,
+		fStatusExpiration:          data.ExpirationDate,
+	})
+}
@@ -0,0 +1,163 @@
+package provider
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/model"
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/tpptest"
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/vcertclient"
+)
+
+func TestSetTokenStatus(t *testing.T) {
+	const day = 24 * 60 * 60
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	issuedAt := types.Int64Value(now.Unix() - 10*day)
+
+	tests := []struct {
+		name       string
+		expiration int64
+		want       map[string]attr.Value
+	}{
+		{"valid", now.Unix() + 45*day + 3600, map[string]attr.Value{
+			fStatusValid:               types.BoolValue(true),
+			fStatusExpired:             types.BoolValue(false),
+			fStatusDaysUntilExpiration: types.Int64Value(45),
+			fStatusWithinRefreshWindow: types.BoolValue(false),
+			fStatusIssuedAt:            issuedAt,
+			fStatusExpiration:          types.Int64Value(now.Unix() + 45*day + 3600),
+		}},
+		{"within refresh window", now.Unix() + 10*day, map[string]attr.Value{
+			fStatusValid:               types.BoolValue(true),
+			fStatusExpired:             types.BoolValue(false),
+			fStatusDaysUntilExpiration: types.Int64Value(10),
+			fStatusWithinRefreshWindow: types.BoolValue(true),
+			fStatusIssuedAt:            issuedAt,
+			fStatusExpiration:          types.Int64Value(now.Unix() + 10*day),
+		}},
+		{"expired", now.Unix(), map[string]attr.Value{
+			fStatusValid:               types.BoolValue(false),
+			fStatusExpired:             types.BoolValue(true),
+			fStatusDaysUntilExpiration: types.Int64Value(0),
+			fStatusWithinRefreshWindow: types.BoolValue(true),
+			fStatusIssuedAt:            issuedAt,
+			fStatusExpiration:          types.Int64Value(now.Unix()),
+		}},
+		{"never expires", 0, map[string]attr.Value{
+			fStatusValid:               types.BoolValue(true),
+			fStatusExpired:             types.BoolValue(false),
+			fStatusDaysUntilExpiration: types.Int64Null(),
+			fStatusWithinRefreshWindow: types.BoolValue(false),
+			fStatusIssuedAt:            issuedAt,
+			fStatusExpiration:          types.Int64Value(0),
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data := &model.CredentialResourceData{
+				AccessToken:    types.StringValue("access"),
+				ExpirationDate: types.Int64Value(test.expiration),
+				IssuedAt:       issuedAt,
+				RefreshWindow:  types.Int64Value(30),
+			}
+			setTokenStatus(data, now)
+
+			fields := data.TokenStatus.Attributes()
+			if len(fields) != len(test.want) {
+				t.Fatalf("token_status = %s, want %d fields", data.TokenStatus, len(test.want))
+			}
+			for name, want := range test.want {
+				if !fields[name].Equal(want) {
+					t.Errorf("token_status.%s = %s, want %s", name, fields[name], want)
+				}
+			}
+		})
+	}
+
+	t.Run("no access token", func(t *testing.T) {
+		data := &model.CredentialResourceData{ExpirationDate: types.Int64Value(now.Unix())}
+		setTokenStatus(data, now)
+		if !data.TokenStatus.IsNull() {
+			t.Errorf("token_status = %s, want null", data.TokenStatus)
+		}
+	})
+}
+
+func TestTokenStatusPlanned(t *testing.T) {
+	server := tpptest.NewServer(t)
+	r, state := importServerState(t, server)
+	data := stateData(t, state)
+	// The token is about to expire, its status changes with the rotation
+	data.ExpirationDate = types.Int64Value(time.Now().Add(time.Hour).Unix())
+	setTokenStatus(&data, time.Now())
+	state = setStateData(t, state, data)
+
+	data.RotateTrigger = types.StringValue("rotate")
+	plan, config := planUpdate(t, r, state, data, fUsername, fPassword, fRotateTrigger)
+	if !unknownAttributes(t, plan)[fTokenStatus] {
+		t.Errorf("%s known in the plan, want it unknown", fTokenStatus)
+	}
+
+	applied := stateData(t, applyUpdate(t, r, state, plan, config))
+	fields := applied.TokenStatus.Attributes()
+	if !fields[fStatusValid].Equal(types.BoolValue(true)) || !fields[fStatusWithinRefreshWindow].Equal(types.BoolValue(false)) ||
+		!fields[fStatusExpiration].Equal(applied.ExpirationDate) {
+		t.Errorf("token_status = %s, want the status of the rotated token", applied.TokenStatus)
+	}
+}
+
+func TestAuthAttemptsState(t *testing.T) {
+	server := tpptest.NewServer(t)
+	r, _ := importServerState(t, server)
+
+	// The refresh token of the import is unknown to TLSPDC, the ladder falls back to the username/password
+	state := readState(t, r, importState(t, r, serverImportID(server)+","+fRefreshToken+"=expired"))
+	attempts := stateData(t, state).AuthAttempts.Elements()
+	if len(attempts) != 2 {
+		t.Fatalf("auth_attempts = %v, want two attempts", attempts)
+	}
+	want := []map[string]attr.Value{
+		{fAttemptMethod: types.StringValue(vcertclient.MethodRefreshToken), fAttemptSucceeded: types.BoolValue(false)},
+		{fAttemptMethod: types.StringValue(vcertclient.MethodUsernamePassword), fAttemptSucceeded: types.BoolValue(true), fAttemptError: types.StringNull()},
+	}
+	for i, attempt := range attempts {
+		fields := attempt.(types.Object).Attributes()
+		for name, value := range want[i] {
+			if !fields[name].Equal(value) {
+				t.Errorf("auth_attempts[%d].%s = %s, want %s", i, name, fields[name], value)
+			}
+		}
+	}
+	if fields := attempts[0].(types.Object).Attributes(); fields[fAttemptError].IsNull() {
+		t.Error("auth_attempts[0].error = null, want the failure of the refresh token")
+	}
+}
+
+func TestUsedFallbackMethod(t *testing.T) {
+	server := tpptest.NewServer(t)
+	r, _ := importServerState(t, server)
+
+	tests := []struct {
+		name string
+		id   string
+		want bool
+	}{
+		{"primary succeeded", serverImportID(server), false},
+		// The refresh token of the import is unknown to TLSPDC
+		{"fallback succeeded", serverImportID(server) + "," + fRefreshToken + "=expired", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data := stateData(t, readState(t, r, importState(t, r, test.id)))
+			if data.SelectedAuthMethod.ValueString() != vcertclient.MethodUsernamePassword {
+				t.Errorf("selected_auth_method = %s, want %s", data.SelectedAuthMethod, vcertclient.MethodUsernamePassword)
+			}
+			if !data.UsedFallbackMethod.Equal(types.BoolValue(test.want)) {
+				t.Errorf("used_fallback_method = %s, want %t", data.UsedFallbackMethod, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/logging"
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/model"
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/vcertclient"
+)
+
+// defaultExpectedTokenType is the token type granted by TLSPDC, expected unless expected_token_type says otherwise
+const defaultExpectedTokenType = "Bearer"
+
+func validateExpectedTokenType(tokenType string) error {
+	if strings.TrimSpace(tokenType) == "" || strings.ContainsFunc(tokenType, unicode.IsSpace) {
+		return fmt.Errorf("%s must be a single word such as %s, got [%s]", fExpectedTokenType, defaultExpectedTokenType, tokenType)
+	}
+	return nil
+}
+
+// checkTokenType records the token type of a newly granted pair in token_type, and checks it is the type expected by
+// data. A pair of another type, e.g. granted by a misconfigured TLSPDC, would be rejected by every consumer: it is
+// revoked rather than stored. A pair whose type is not reported is accepted.
+func checkTokenType(ctx context.Context, data *model.CredentialResourceData, resp *vcertclient.RefreshTokenResponse) error {
+	expected := defaultExpectedTokenType
+	if !data.ExpectedTokenType.IsNull() && !data.ExpectedTokenType.IsUnknown() {
+		expected = data.ExpectedTokenType.ValueString()
+	}
+	if resp.TokenType == "" {
+		logging.Info(ctx, fmt.Sprintf("TLSPDC did not report the token type, expecting %s", expected))
+		data.TokenType = types.StringNull()
+		return nil
+	}
+	if !strings.EqualFold(resp.TokenType, expected) {
+		revokeRejectedPair(ctx, data, resp, fmt.Sprintf("%s type", resp.TokenType))
+		return fmt.Errorf("TLSPDC granted a token of type %s, expected %s as set by %s; check the token settings of TLSPDC and the API integration [%s]",
+			resp.TokenType, expected, fExpectedTokenType, data.ClientID.ValueString())
+	}
+	data.TokenType = types.StringValue(resp.TokenType)
+	return nil
+}
+
+// revokeRejectedPair revokes a newly granted pair that is not stored, so as not to leave a live token behind: it would
+// never be used. kind describes the pair in the logs, e.g. short-lived.
+func revokeRejectedPair(ctx context.Context, data *model.CredentialResourceData, resp *vcertclient.RefreshTokenResponse, kind string) {
+	rejected := *data
+	rejected.AccessToken = types.StringValue(resp.AccessToken)
+	rejected.RefreshToken = types.StringValue(resp.RefreshToken)
+	if err := vcertclient.New(ctx, rejected).RevokeToken(); err != nil {
+		logging.Warn(ctx, fmt.Sprintf("unable to revoke %s token: %s", kind, err.Error()))
+	}
+}
+
+// normalizeTokenPair trims the tokens of a newly granted pair and checks they can be handed as is to the consumers of
+// the provider, e.g. the venafi provider. A malformed token is reported rather than stored.
+func normalizeTokenPair(resp *vcertclient.RefreshTokenResponse) error {
+	var err error
+	resp.AccessToken, err = normalizeToken(fAccessToken, resp.AccessToken)
+	if err != nil {
+		return err
+	}
+	if resp.AccessToken == "" {
+		return fmt.Errorf("TLSPDC granted an empty %s", fAccessToken)
+	}
+
+	// Some grants come without refresh token
+	resp.RefreshToken, err = normalizeToken(fRefreshToken, resp.RefreshToken)
+	return err
+}
+
+// normalizeToken trims the surrounding whitespace of token and checks it only holds printable ASCII characters, as
+// TLSPDC tokens do. The token value is never part of the error.
+func normalizeToken(name string, token string) (string, error) {
+	token = strings.TrimSpace(token)
+	if len(token) > len("bearer ") && strings.EqualFold(token[:len("bearer ")], "bearer ") {
+		return "", fmt.Errorf("TLSPDC granted a malformed %s: it starts with an authorization scheme (Bearer), expected the bare token", name)
+	}
+	for i, char := range token {
+		if unicode.IsSpace(char) {
+			return "", fmt.Errorf("TLSPDC granted a malformed %s: it holds whitespace or a new line at position %d", name, i)
+		}
+		if char > unicode.MaxASCII || !unicode.IsPrint(char) {
+			return "", fmt.Errorf("TLSPDC granted a malformed %s: it holds a non-printable or non-ASCII character at position %d", name, i)
+		}
+	}
+	return token, nil
+}
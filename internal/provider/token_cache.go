@@ -0,0 +1,106 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/logging"
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/model"
+)
+
+const tokenCacheFileMode = 0600
+
+// tokenCache is the content of token_cache_file
+type tokenCache struct {
+	URL          string `json:"url"`
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	Expiration   int64  `json:"expiration"`
+	IssuedAt     int64  `json:"issued_at,omitempty"`
+}
+
+// useTokenCache replaces the token pair of data with the one of token_cache_file when it was issued by the same URL and
+// is neither expired nor within its refresh window. The cached access token is then trusted without contacting TLSPDC.
+func useTokenCache(ctx context.Context, data *model.CredentialResourceData, now time.Time) bool {
+	if data.TokenCacheFile.IsNull() {
+		return false
+	}
+
+	location := data.TokenCacheFile.ValueString()
+	content, err := os.ReadFile(location)
+	if errors.Is(err, os.ErrNotExist) {
+		logging.Info(ctx, fmt.Sprintf("token cache file [%s] not found", location))
+		return false
+	}
+	if err != nil {
+		logging.Warn(ctx, fmt.Sprintf("unable to read token cache file [%s]: %s", location, err.Error()))
+		return false
+	}
+
+	var cache tokenCache
+	if err = json.Unmarshal(content, &cache); err != nil {
+		logging.Warn(ctx, fmt.Sprintf("unable to parse token cache file [%s]: %s", location, err.Error()))
+		return false
+	}
+	if cache.AccessToken == "" || cache.URL != data.URL.ValueString() {
+		logging.Info(ctx, fmt.Sprintf("token cache file [%s] holds no token for [%s]", location, data.URL.ValueString()))
+		return false
+	}
+
+	cached := *data
+	cached.AccessToken = types.StringValue(cache.AccessToken)
+	cached.ExpirationDate = types.Int64Value(cache.Expiration)
+	cached.IssuedAt = types.Int64Null()
+	if cache.IssuedAt > 0 {
+		cached.IssuedAt = types.Int64Value(cache.IssuedAt)
+	}
+	if cache.RefreshToken != "" {
+		cached.RefreshToken = types.StringValue(cache.RefreshToken)
+	}
+	if withinRefreshWindow(&cached, now) || lacksOperationHeadroom(&cached, now) {
+		logging.Info(ctx, fmt.Sprintf("cached access token of [%s] expired or within refresh window", location))
+		return false
+	}
+
+	logging.Info(ctx, fmt.Sprintf("using cached access token of [%s], TLSPDC not contacted", location))
+	*data = cached
+	return true
+}
+
+// storeInTokenCache writes the token pair of data to token_cache_file after a rotation, or when the file does not exist
+// yet. Failures are reported as warnings so that the token pair is still saved to the state.
+func storeInTokenCache(ctx context.Context, data *model.CredentialResourceData, diags *diag.Diagnostics) {
+	if data.TokenCacheFile.IsNull() || data.AccessToken.IsNull() || data.ExpirationDate.IsNull() {
+		return
+	}
+
+	location := data.TokenCacheFile.ValueString()
+	if _, err := os.Stat(location); err == nil && !data.Rotated.ValueBool() {
+		return
+	}
+
+	cache := tokenCache{
+		URL:          data.URL.ValueString(),
+		AccessToken:  data.AccessToken.ValueString(),
+		RefreshToken: data.RefreshToken.ValueString(),
+		Expiration:   data.ExpirationDate.ValueInt64(),
+		IssuedAt:     data.IssuedAt.ValueInt64(),
+	}
+	content, err := json.Marshal(cache)
+	if err == nil {
+		logging.Info(ctx, fmt.Sprintf("writing token pair to token cache file [%s]", location))
+		err = writeFileAtomically(location, content, tokenCacheFileMode)
+	}
+	if err != nil {
+		diags.AddAttributeWarning(path.Root(fTokenCacheFile), msgCredentialResourceError,
+			fmt.Sprintf("unable to write token cache file [%s]: %s", location, err.Error()))
+	}
+}
@@ -0,0 +1,120 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/tpptest"
+)
+
+// writeTokenCache writes cache to a token cache file of the test and returns its location
+func writeTokenCache(t *testing.T, cache tokenCache) string {
+	t.Helper()
+
+	content, err := json.Marshal(cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	location := filepath.Join(t.TempDir(), "token-cache.json")
+	if err = os.WriteFile(location, content, tokenCacheFileMode); err != nil {
+		t.Fatal(err)
+	}
+	return location
+}
+
+func TestTokenCache(t *testing.T) {
+	const day = 24 * time.Hour
+	window := time.Duration(defaultRefreshWindow) * day
+
+	tests := []struct {
+		name string
+		// url is the url the cached token was issued by, the one of the server when empty
+		url        string
+		expiration time.Duration
+		missing    bool
+		wantHit    bool
+	}{
+		{"hit", "", window + day, false, true},
+		{"within refresh window", "", window - day, false, false},
+		{"expired", "", -time.Hour, false, false},
+		{"other url", "https://other.venafi.example/vedsdk", window + day, false, false},
+		{"missing file", "", 0, true, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := tpptest.NewServer(t)
+			cache := tokenCache{
+				URL:          server.URL,
+				AccessToken:  "cached-access",
+				RefreshToken: "cached-refresh",
+				Expiration:   time.Now().Add(test.expiration).Unix(),
+			}
+			if test.url != "" {
+				cache.URL = test.url
+			}
+			location := writeTokenCache(t, cache)
+			if test.missing {
+				location = filepath.Join(t.TempDir(), "token-cache.json")
+			}
+			data := serverCredential(server)
+			data.TokenCacheFile = types.StringValue(location)
+
+			var diags diag.Diagnostics
+			refreshCredential(context.Background(), &data, "", &diags)
+			if diags.HasError() {
+				t.Fatalf("refresh failed: %v", diags)
+			}
+			requests := server.Requests(tpptest.PathAuthorizeOAuth) + server.Requests(tpptest.PathRefreshToken) + server.Requests(tpptest.PathVerifyToken)
+			if test.wantHit {
+				if data.AccessToken.ValueString() != cache.AccessToken || data.RefreshToken.ValueString() != cache.RefreshToken {
+					t.Errorf("tokens = %s, %s, want the cached ones", data.AccessToken, data.RefreshToken)
+				}
+				if requests != 0 {
+					t.Errorf("%d requests sent to TLSPDC, want none", requests)
+				}
+				return
+			}
+
+			if _, ok := server.GrantOf(data.AccessToken.ValueString()); !ok || requests == 0 {
+				t.Fatalf("access_token = %s, want one retrieved from TLSPDC", data.AccessToken)
+			}
+			// The cache is updated with the rotated token pair
+			storeInTokenCache(context.Background(), &data, &diags)
+			content, err := os.ReadFile(location)
+			if err != nil {
+				t.Fatalf("token cache file not written: %s", err)
+			}
+			var stored tokenCache
+			if err = json.Unmarshal(content, &stored); err != nil {
+				t.Fatalf("invalid token cache file: %s", err)
+			}
+			if stored.URL != server.URL || stored.AccessToken != data.AccessToken.ValueString() || stored.Expiration != data.ExpirationDate.ValueInt64() {
+				t.Errorf("token cache = %+v, want the rotated token pair", stored)
+			}
+		})
+	}
+
+	t.Run("offline", func(t *testing.T) {
+		url := tpptest.UnreachableURL(t)
+		data := serverCredential(tpptest.NewServer(t))
+		data.URL = types.StringValue(url)
+		data.TokenCacheFile = types.StringValue(writeTokenCache(t, tokenCache{
+			URL:         url,
+			AccessToken: "cached-access",
+			Expiration:  time.Now().Add(window + day).Unix(),
+		}))
+
+		var diags diag.Diagnostics
+		refreshCredential(context.Background(), &data, "", &diags)
+		if diags.HasError() || data.AccessToken.ValueString() != "cached-access" {
+			t.Errorf("access_token = %s, diagnostics = %v, want the cached token without TLSPDC", data.AccessToken, diags)
+		}
+	})
+}
@@ -0,0 +1,96 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/logging"
+)
+
+// importTokenPrefix starts an import string holding a token JSON response of the vcert CLI (vcert getcred --format
+// json), or the path of a file holding it, optionally followed by a comma and the other attributes
+const importTokenPrefix = "token://"
+
+// vcertTokenJSON holds the fields of a token JSON response of the vcert CLI mapped onto the resource
+type vcertTokenJSON struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	Expires      int64  `json:"expires"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+// tokenValuesMap returns the attributes of a token:// import string, source being what follows the prefix. The
+// expiration is taken from expires, or computed from expires_in at now when only the latter is set.
+func tokenValuesMap(ctx context.Context, source string, now time.Time) (map[string]string, error) {
+	content, attributes, err := splitTokenSource(source)
+	if err != nil {
+		return nil, err
+	}
+
+	var token vcertTokenJSON
+	if err = json.Unmarshal(content, &token); err != nil {
+		return nil, fmt.Errorf("invalid token JSON: %w", err)
+	}
+	if strings.TrimSpace(token.AccessToken) == "" {
+		return nil, fmt.Errorf("the token JSON holds no %s", fAccessToken)
+	}
+
+	dict := make(map[string]string)
+	if attributes != "" {
+		if dict, err = getValuesMap(ctx, attributes); err != nil {
+			return nil, err
+		}
+	}
+
+	tokenValues := map[string]string{fAccessToken: token.AccessToken}
+	if token.RefreshToken != "" {
+		tokenValues[fRefreshToken] = token.RefreshToken
+	}
+	switch {
+	case token.Expires > 0:
+		tokenValues[fExpirationDate] = strconv.FormatInt(token.Expires, 10)
+	case token.ExpiresIn > 0:
+		tokenValues[fExpirationDate] = strconv.FormatInt(now.Unix()+token.ExpiresIn, 10)
+	}
+	for key, value := range tokenValues {
+		if _, ok := dict[key]; ok {
+			return nil, fmt.Errorf("%s is set both in the token JSON and in the import string", key)
+		}
+		logging.Debug(ctx, fmt.Sprintf("credential field found in token JSON: %s", key))
+		dict[key] = value
+	}
+	return dict, nil
+}
+
+// splitTokenSource returns the token JSON of source, read from a file unless source starts with a JSON object, and the
+// attributes following it after a comma
+func splitTokenSource(source string) ([]byte, string, error) {
+	source = strings.TrimSpace(source)
+	if !strings.HasPrefix(source, "{") {
+		location, attributes, _ := strings.Cut(source, importSeparator)
+		content, err := os.ReadFile(location)
+		if err != nil {
+			return nil, "", fmt.Errorf("unable to read token JSON file: %w", err)
+		}
+		return content, attributes, nil
+	}
+
+	var content json.RawMessage
+	decoder := json.NewDecoder(strings.NewReader(source))
+	if err := decoder.Decode(&content); err != nil {
+		return nil, "", fmt.Errorf("invalid token JSON: %w", err)
+	}
+	rest := strings.TrimSpace(source[decoder.InputOffset():])
+	if rest == "" {
+		return content, "", nil
+	}
+	if !strings.HasPrefix(rest, importSeparator) {
+		return nil, "", fmt.Errorf("unexpected content after the token JSON, expected a comma followed by the other attributes")
+	}
+	return content, strings.TrimPrefix(rest, importSeparator), nil
+}
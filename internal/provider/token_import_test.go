@@ -0,0 +1,99 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/tpptest"
+)
+
+// vcertToken is a token JSON response as printed by vcert getcred --format json
+const vcertToken = `{
+  "access_token": "aCcEsS==",
+  "refresh_token": "rEfReSh==",
+  "expires": 1772600767,
+  "expires_in": 7775999,
+  "identity": "local:{0d9ec0a4-b00c-4d4f-98d7-3c0b3a4c6a81}",
+  "refresh_until": 1780376767,
+  "scope": "certificate:manage",
+  "token_type": "Bearer"
+}`
+
+func TestTokenValuesMap(t *testing.T) {
+	now := time.Date(2026, time.March, 4, 5, 6, 7, 0, time.UTC)
+	file := filepath.Join(t.TempDir(), "token.json")
+	if err := os.WriteFile(file, []byte(vcertToken+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	token := map[string]string{fAccessToken: "aCcEsS==", fRefreshToken: "rEfReSh==", fExpirationDate: "1772600767"}
+
+	tests := []struct {
+		name    string
+		source  string
+		want    map[string]string
+		wantErr string
+	}{
+		{"inline", vcertToken, token, ""},
+		{"inline with attributes", vcertToken + ",url=https://tpp.venafi.example,refresh_window=10",
+			map[string]string{fAccessToken: "aCcEsS==", fRefreshToken: "rEfReSh==", fExpirationDate: "1772600767", fURL: "https://tpp.venafi.example",
+				fRefreshWindow: "10"}, ""},
+		{"file", file, token, ""},
+		{"file with attributes", file + ",refresh_window=10",
+			map[string]string{fAccessToken: "aCcEsS==", fRefreshToken: "rEfReSh==", fExpirationDate: "1772600767", fRefreshWindow: "10"}, ""},
+		{"expires_in only", `{"access_token":"aCcEsS==","expires_in":3600}`,
+			map[string]string{fAccessToken: "aCcEsS==", fExpirationDate: fmt.Sprint(now.Unix() + 3600)}, ""},
+		{"no expiration", `{"access_token":"aCcEsS=="}`, map[string]string{fAccessToken: "aCcEsS=="}, ""},
+		{"no access token", `{"refresh_token":"rEfReSh==","expires":1772600767}`, nil, "the token JSON holds no access_token"},
+		{"set twice", vcertToken + ",refresh_token=other", nil, "refresh_token is set both in the token JSON and in the import string"},
+		{"content after the JSON", vcertToken + " url=https://tpp.venafi.example", nil, "unexpected content after the token JSON"},
+		{"invalid JSON", `{"access_token":`, nil, "invalid token JSON"},
+		{"missing file", filepath.Join(t.TempDir(), "missing.json"), nil, "unable to read token JSON file"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := tokenValuesMap(context.Background(), test.source, now)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Errorf("error = %v, want %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if len(got) != len(test.want) {
+				t.Errorf("values = %v, want %v", got, test.want)
+			}
+			for key, value := range test.want {
+				if got[key] != value {
+					t.Errorf("%s = %q, want %q", key, got[key], value)
+				}
+			}
+		})
+	}
+}
+
+func TestImportVcertToken(t *testing.T) {
+	server := tpptest.NewServer(t)
+	r, _ := importServerState(t, server)
+	grant := server.IssueGrant("certificate:manage")
+	grants := len(server.Grants())
+	token := fmt.Sprintf(`{"access_token":%q,"refresh_token":%q,"expires":%d,"token_type":"Bearer"}`, grant.AccessToken, grant.RefreshToken,
+		grant.ExpiresAt.Unix())
+
+	data := stateData(t, readState(t, r, importState(t, r, importTokenPrefix+token+","+serverImportID(server))))
+	if data.AccessToken.ValueString() != grant.AccessToken || data.RefreshToken.ValueString() != grant.RefreshToken {
+		t.Errorf("tokens = %s, %s, want the ones of the token JSON", data.AccessToken, data.RefreshToken)
+	}
+	if data.ExpirationDate.ValueInt64() != grant.ExpiresAt.Unix() {
+		t.Errorf("expiration_date = %s, want %d", data.ExpirationDate, grant.ExpiresAt.Unix())
+	}
+	if len(server.Grants()) != grants {
+		t.Error("new grant requested instead of adopting the imported one")
+	}
+}
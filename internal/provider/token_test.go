@@ -0,0 +1,184 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/tpptest"
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/vcertclient"
+)
+
+func TestNormalizeTokenPair(t *testing.T) {
+	tests := []struct {
+		name         string
+		accessToken  string
+		refreshToken string
+		wantAccess   string
+		wantRefresh  string
+		wantErr      string
+	}{
+		{"well-formed", "aCcEsS==", "rEfReSh==", "aCcEsS==", "rEfReSh==", ""},
+		{"surrounding whitespace", " aCcEsS==\n", "\trEfReSh== ", "aCcEsS==", "rEfReSh==", ""},
+		{"no refresh token", "aCcEsS==", "", "aCcEsS==", "", ""},
+		{"empty access token", " \n", "rEfReSh==", "", "", "TLSPDC granted an empty access_token"},
+		{"authorization scheme", "Bearer aCcEsS==", "rEfReSh==", "", "", "malformed access_token: it starts with an authorization scheme"},
+		{"embedded new line", "aCcEsS==", "rEf\nReSh==", "", "", "malformed refresh_token: it holds whitespace or a new line at position 3"},
+		{"embedded space", "aCc EsS==", "rEfReSh==", "", "", "malformed access_token: it holds whitespace or a new line at position 3"},
+		{"control character", "aCc\x00EsS==", "rEfReSh==", "", "", "malformed access_token: it holds a non-printable or non-ASCII character at position 3"},
+		{"non-ASCII", "aCcEsS==", "rÉfReSh==", "", "", "malformed refresh_token: it holds a non-printable or non-ASCII character at position 1"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp := &vcertclient.RefreshTokenResponse{AccessToken: test.accessToken, RefreshToken: test.refreshToken}
+			err := normalizeTokenPair(resp)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("error = %v, want %q", err, test.wantErr)
+				}
+				// The tokens are secrets, even malformed
+				if strings.Contains(err.Error(), "aCc") || strings.Contains(err.Error(), "rEf") {
+					t.Errorf("token found in the error: %s", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if resp.AccessToken != test.wantAccess || resp.RefreshToken != test.wantRefresh {
+				t.Errorf("tokens = %q, %q, want %q, %q", resp.AccessToken, resp.RefreshToken, test.wantAccess, test.wantRefresh)
+			}
+		})
+	}
+}
+
+func TestMalformedGrantedToken(t *testing.T) {
+	tests := []struct {
+		name string
+		// malform alters the access token granted by TLSPDC
+		malform func(token string) string
+		wantErr string
+	}{
+		{"padded", func(token string) string { return " " + token + "\r\n" }, ""},
+		{"split", func(token string) string { return token[:4] + "\n" + token[4:] }, "malformed access_token"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := tpptest.NewServer(t)
+			grant := server.IssueGrant(vcertclient.DefaultScope)
+			server.Handle(tpptest.PathAuthorizeOAuth, func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"access_token":  test.malform(grant.AccessToken),
+					"refresh_token": grant.RefreshToken,
+					"expires":       grant.ExpiresAt.Unix(),
+					"token_type":    "Bearer",
+				})
+			})
+			data := serverCredential(server)
+
+			var diags diag.Diagnostics
+			err := rotateToken(context.Background(), &data, &diags)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Errorf("error = %v, want %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if data.AccessToken.ValueString() != grant.AccessToken {
+				t.Errorf("access_token = %q, want it trimmed", data.AccessToken.ValueString())
+			}
+		})
+	}
+}
+
+func TestServerNotices(t *testing.T) {
+	server := tpptest.NewServer(t)
+	grant := server.IssueGrant(vcertclient.DefaultScope)
+	server.Handle(tpptest.PathAuthorizeOAuth, func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token":  grant.AccessToken,
+			"refresh_token": grant.RefreshToken,
+			"expires":       grant.ExpiresAt.Unix(),
+			"token_type":    "Bearer",
+			"notice":        "Password expires in 5 days",
+		})
+	})
+	data := serverCredential(server)
+
+	var diags diag.Diagnostics
+	if err := rotateToken(context.Background(), &data, &diags); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if data.AccessToken.ValueString() != grant.AccessToken {
+		t.Errorf("access_token = %s, want %s", data.AccessToken, grant.AccessToken)
+	}
+	warnings := diags.Warnings()
+	if len(warnings) != 1 || warnings[0].Summary() != "TLSPDC Notice" || !strings.Contains(warnings[0].Detail(), "Password expires in 5 days") {
+		t.Errorf("diagnostics = %v, want the notice reported as a warning", diags)
+	}
+}
+
+func TestTokenType(t *testing.T) {
+	tests := []struct {
+		name      string
+		tokenType string
+		expected  types.String
+		want      types.String
+		wantErr   string
+	}{
+		{"Bearer", "Bearer", types.StringNull(), types.StringValue("Bearer"), ""},
+		{"case-insensitive", "bearer", types.StringValue("Bearer"), types.StringValue("bearer"), ""},
+		{"not reported", "", types.StringNull(), types.StringNull(), ""},
+		{"non-Bearer", "MAC", types.StringNull(), types.StringNull(), "TLSPDC granted a token of type MAC, expected Bearer as set by expected_token_type"},
+		{"expected non-Bearer", "MAC", types.StringValue("MAC"), types.StringValue("MAC"), ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := tpptest.NewServer(t)
+			grant := server.IssueGrant(vcertclient.DefaultScope)
+			server.Handle(tpptest.PathAuthorizeOAuth, func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"access_token":  grant.AccessToken,
+					"refresh_token": grant.RefreshToken,
+					"expires":       grant.ExpiresAt.Unix(),
+					"token_type":    test.tokenType,
+				})
+			})
+			data := serverCredential(server)
+			data.ExpectedTokenType = test.expected
+
+			var diags diag.Diagnostics
+			err := rotateToken(context.Background(), &data, &diags)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("error = %v, want %q", err, test.wantErr)
+				}
+				// The pair is not stored, nor left live in TLSPDC
+				if data.AccessToken.ValueString() == grant.AccessToken {
+					t.Error("token pair of another type stored")
+				}
+				if !revoked(t, server, types.StringValue(grant.AccessToken)) {
+					t.Error("token pair of another type not revoked")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if data.AccessToken.ValueString() != grant.AccessToken || !data.TokenType.Equal(test.want) {
+				t.Errorf("access_token = %s with token_type = %s, want %s with %s", data.AccessToken, data.TokenType, grant.AccessToken, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/logging"
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/model"
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/vault"
+)
+
+// loadFromVault replaces the token pair of data with the one stored at vault_token_path, if any. Values missing from
+// the secret are left untouched.
+func loadFromVault(ctx context.Context, data *model.CredentialResourceData, diags *diag.Diagnostics) {
+	if data.VaultTokenPath.IsNull() {
+		return
+	}
+
+	client, err := vault.NewFromEnv()
+	if err != nil {
+		diags.AddAttributeError(path.Root(fVaultTokenPath), msgCredentialResourceError, err.Error())
+		return
+	}
+
+	tokenPath := data.VaultTokenPath.ValueString()
+	logging.Info(ctx, fmt.Sprintf("reading token pair from vault path [%s]", tokenPath))
+	pair, err := client.ReadTokenPair(ctx, tokenPath)
+	if err != nil {
+		diags.AddAttributeError(path.Root(fVaultTokenPath), msgCredentialResourceError, err.Error())
+		return
+	}
+
+	if pair.AccessToken != "" {
+		data.AccessToken = types.StringValue(pair.AccessToken)
+	}
+	if pair.RefreshToken != "" {
+		data.RefreshToken = types.StringValue(pair.RefreshToken)
+	}
+}
+
+// storeInVault writes the token pair of data back to vault_token_path after a rotation, when vault_write_back is set.
+// Failures are reported as warnings so that the rotated token pair is still saved to the state.
+func storeInVault(ctx context.Context, data *model.CredentialResourceData, diags *diag.Diagnostics) {
+	if data.VaultTokenPath.IsNull() || !data.VaultWriteBack.ValueBool() || !data.Rotated.ValueBool() {
+		return
+	}
+
+	client, err := vault.NewFromEnv()
+	if err != nil {
+		diags.AddAttributeWarning(path.Root(fVaultTokenPath), msgCredentialResourceError, err.Error())
+		return
+	}
+
+	tokenPath := data.VaultTokenPath.ValueString()
+	logging.Info(ctx, fmt.Sprintf("writing rotated token pair to vault path [%s]", tokenPath))
+	err = client.WriteTokenPair(ctx, tokenPath, vault.TokenPair{
+		AccessToken:  data.AccessToken.ValueString(),
+		RefreshToken: data.RefreshToken.ValueString(),
+	})
+	if err != nil {
+		diags.AddAttributeWarning(path.Root(fVaultTokenPath), msgCredentialResourceError,
+			fmt.Sprintf("token pair was rotated but could not be written back to vault: %s", err.Error()))
+	}
+}
@@ -0,0 +1,141 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/model"
+)
+
+const vaultTokenPath = "secret/data/venafi/tpp"
+
+// newVault starts a fake Vault holding secret at vaultTokenPath, a KV version 2 path, and returns the secret as
+// updated by the writes
+func newVault(t *testing.T, secret map[string]interface{}) map[string]interface{} {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/"+vaultTokenPath {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Method == http.MethodGet {
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"data": secret}})
+			return
+		}
+		var body struct {
+			Data map[string]interface{} `json:"data"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		for key, value := range body.Data {
+			secret[key] = value
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv("VAULT_ADDR", server.URL)
+	t.Setenv("VAULT_TOKEN", "vault-token")
+	t.Setenv("VAULT_NAMESPACE", "")
+	t.Setenv("VAULT_CACERT", "")
+	return secret
+}
+
+func TestLoadFromVault(t *testing.T) {
+	t.Run("token pair", func(t *testing.T) {
+		newVault(t, map[string]interface{}{"access_token": "vault-access", "refresh_token": "vault-refresh"})
+		data := model.CredentialResourceData{
+			VaultTokenPath: types.StringValue(vaultTokenPath),
+			AccessToken:    types.StringValue("state-access"),
+		}
+
+		var diags diag.Diagnostics
+		loadFromVault(context.Background(), &data, &diags)
+		if diags.HasError() {
+			t.Fatalf("unexpected error: %v", diags)
+		}
+		if data.AccessToken.ValueString() != "vault-access" || data.RefreshToken.ValueString() != "vault-refresh" {
+			t.Errorf("token pair = %s, %s, want the pair of Vault", data.AccessToken, data.RefreshToken)
+		}
+	})
+
+	t.Run("missing values", func(t *testing.T) {
+		newVault(t, map[string]interface{}{"refresh_token": "vault-refresh"})
+		data := model.CredentialResourceData{
+			VaultTokenPath: types.StringValue(vaultTokenPath),
+			AccessToken:    types.StringValue("state-access"),
+		}
+
+		var diags diag.Diagnostics
+		loadFromVault(context.Background(), &data, &diags)
+		if data.AccessToken.ValueString() != "state-access" || data.RefreshToken.ValueString() != "vault-refresh" {
+			t.Errorf("token pair = %s, %s, want the access token of the state kept", data.AccessToken, data.RefreshToken)
+		}
+	})
+
+	t.Run("not set", func(t *testing.T) {
+		// Vault is not needed without vault_token_path
+		t.Setenv("VAULT_ADDR", "")
+		data := model.CredentialResourceData{AccessToken: types.StringValue("state-access")}
+
+		var diags diag.Diagnostics
+		loadFromVault(context.Background(), &data, &diags)
+		if diags.HasError() || data.AccessToken.ValueString() != "state-access" {
+			t.Errorf("access_token = %s, diagnostics = %v, want the state kept", data.AccessToken, diags)
+		}
+	})
+}
+
+func TestStoreInVault(t *testing.T) {
+	rotated := func(writeBack bool) model.CredentialResourceData {
+		return model.CredentialResourceData{
+			VaultTokenPath: types.StringValue(vaultTokenPath),
+			VaultWriteBack: types.BoolValue(writeBack),
+			Rotated:        types.BoolValue(true),
+			AccessToken:    types.StringValue("new-access"),
+			RefreshToken:   types.StringValue("new-refresh"),
+		}
+	}
+
+	t.Run("write back", func(t *testing.T) {
+		secret := newVault(t, map[string]interface{}{"access_token": "old-access", "owner": "team"})
+		data := rotated(true)
+
+		var diags diag.Diagnostics
+		storeInVault(context.Background(), &data, &diags)
+		if diags.HasError() || diags.WarningsCount() != 0 {
+			t.Fatalf("unexpected diagnostics: %v", diags)
+		}
+		if secret["access_token"] != "new-access" || secret["refresh_token"] != "new-refresh" || secret["owner"] != "team" {
+			t.Errorf("secret = %v, want the rotated pair next to the other values", secret)
+		}
+	})
+
+	t.Run("no write back", func(t *testing.T) {
+		secret := newVault(t, map[string]interface{}{"access_token": "old-access"})
+		data := rotated(false)
+
+		var diags diag.Diagnostics
+		storeInVault(context.Background(), &data, &diags)
+		if secret["access_token"] != "old-access" {
+			t.Errorf("secret = %v, want it untouched", secret)
+		}
+	})
+
+	t.Run("vault unavailable", func(t *testing.T) {
+		// The rotated pair must still be saved to the state
+		t.Setenv("VAULT_ADDR", "")
+		data := rotated(true)
+
+		var diags diag.Diagnostics
+		storeInVault(context.Background(), &data, &diags)
+		if diags.HasError() || diags.WarningsCount() != 1 {
+			t.Errorf("diagnostics = %v, want a warning", diags)
+		}
+	})
+}
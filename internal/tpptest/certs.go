@@ -0,0 +1,147 @@
+// Package tpptest provides the certificates and the fake TLSPDC server used by the tests of the provider
+package tpptest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+)
+
+// CA is a certificate authority issuing the certificates of a test
+type CA struct {
+	Certificate *x509.Certificate
+	// PEM is the PEM encoding of Certificate, usable as a trust bundle
+	PEM string
+
+	key *ecdsa.PrivateKey
+}
+
+// CertificateOptions customizes the certificates issued by a CA
+type CertificateOptions struct {
+	// NotBefore and NotAfter default to one hour ago and one year from now
+	NotBefore time.Time
+	NotAfter  time.Time
+	// Hosts are the DNS names and IP addresses the certificate is valid for
+	Hosts []string
+}
+
+// NewCA returns a new self-signed certificate authority
+func NewCA(t testing.TB, commonName string) *CA {
+	t.Helper()
+
+	return newCA(t, commonName, nil)
+}
+
+// Intermediate returns a new intermediate certificate authority signed by the CA
+func (ca *CA) Intermediate(t testing.TB, commonName string) *CA {
+	t.Helper()
+
+	return newCA(t, commonName, ca)
+}
+
+func newCA(t testing.TB, commonName string, parent *CA) *CA {
+	t.Helper()
+
+	key := newKey(t)
+	template := &x509.Certificate{
+		SerialNumber:          serialNumber(t),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(10 * 365 * 24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	issuer, issuerKey := template, key
+	if parent != nil {
+		issuer, issuerKey = parent.Certificate, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, issuer, &key.PublicKey, issuerKey)
+	if err != nil {
+		t.Fatalf("unable to create CA certificate: %s", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("unable to parse CA certificate: %s", err)
+	}
+
+	return &CA{Certificate: cert, PEM: encodeCertificate(der), key: key}
+}
+
+// Issue returns a certificate for commonName signed by the CA, usable for both server and client authentication
+func (ca *CA) Issue(t testing.TB, commonName string, options CertificateOptions) tls.Certificate {
+	t.Helper()
+
+	notBefore, notAfter := options.NotBefore, options.NotAfter
+	if notBefore.IsZero() {
+		notBefore = time.Now().Add(-time.Hour)
+	}
+	if notAfter.IsZero() {
+		notAfter = time.Now().Add(365 * 24 * time.Hour)
+	}
+
+	key := newKey(t)
+	template := &x509.Certificate{
+		SerialNumber: serialNumber(t),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	for _, host := range options.Hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.Certificate, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatalf("unable to create certificate: %s", err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("unable to parse certificate: %s", err)
+	}
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+// CertificatePEM returns the PEM encoding of the leaf of cert
+func CertificatePEM(cert tls.Certificate) string {
+	return encodeCertificate(cert.Certificate[0])
+}
+
+func encodeCertificate(der []byte) string {
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
+
+func newKey(t testing.TB) *ecdsa.PrivateKey {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unable to generate key: %s", err)
+	}
+	return key
+}
+
+func serialNumber(t testing.TB) *big.Int {
+	t.Helper()
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 62))
+	if err != nil {
+		t.Fatalf("unable to generate serial number: %s", err)
+	}
+	return serial
+}
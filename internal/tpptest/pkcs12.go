@@ -0,0 +1,42 @@
+package tpptest
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+// PKCS12 returns the PKCS#12 keystore of cert, protected with password. The further certificates of cert, and chain,
+// are stored along with the leaf.
+func PKCS12(t testing.TB, cert tls.Certificate, chain []*x509.Certificate, password string) []byte {
+	t.Helper()
+
+	for _, der := range cert.Certificate[1:] {
+		issuer, err := x509.ParseCertificate(der)
+		if err != nil {
+			t.Fatalf("unable to parse certificate: %s", err)
+		}
+		chain = append(chain, issuer)
+	}
+	data, err := pkcs12.LegacyDES.Encode(cert.PrivateKey, cert.Leaf, chain, password)
+	if err != nil {
+		t.Fatalf("unable to encode PKCS#12 keystore: %s", err)
+	}
+	return data
+}
+
+// PKCS12File writes the PKCS#12 keystore of cert, protected with password, to a file of the test directory and returns
+// its location
+func PKCS12File(t testing.TB, cert tls.Certificate, chain []*x509.Certificate, password string) string {
+	t.Helper()
+
+	location := filepath.Join(t.TempDir(), "client.p12")
+	if err := os.WriteFile(location, PKCS12(t, cert, chain, password), 0o600); err != nil {
+		t.Fatalf("unable to write PKCS#12 keystore: %s", err)
+	}
+	return location
+}
@@ -0,0 +1,422 @@
+package tpptest
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// paths of the TLSPDC endpoints used by vcert
+const (
+	PathAuthorizeOAuth       = "/vedauth/authorize/oauth"
+	PathAuthorizeCertificate = "/vedauth/authorize/certificate"
+	PathRefreshToken         = "/vedauth/authorize/token"
+	PathVerifyToken          = "/vedauth/authorize/verify"
+	PathRevokeToken          = "/vedauth/revoke/token"
+	PathIdentitySelf         = "/vedsdk/Identity/Self"
+	PathSystemVersion        = "/vedsdk/systemstatus/version"
+
+	oauthBasePath = "/vedauth"
+)
+
+// DefaultTokenLifetime is how long the access tokens issued by a Server are valid, unless set otherwise
+const DefaultTokenLifetime = 90 * 24 * time.Hour
+
+// Grant is a grant issued by a Server
+type Grant struct {
+	ID int
+	// AccessToken and RefreshToken are the latest tokens of the grant
+	AccessToken  string
+	RefreshToken string
+	// Method is the endpoint the grant was requested from: PathAuthorizeOAuth or PathAuthorizeCertificate, or empty
+	// when issued by the test
+	Method    string
+	Scope     string
+	ClientID  string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+	Revoked   bool
+	// Refreshed is the number of times the refresh token of the grant was used
+	Refreshed int
+}
+
+// Server is a fake TLSPDC server, issuing, refreshing, verifying and revoking grants like TLSPDC. Its certificate is
+// issued for 127.0.0.1 by CA.
+type Server struct {
+	*httptest.Server
+	CA *CA
+
+	// Username and Password are the credentials accepted by PathAuthorizeOAuth
+	Username string
+	Password string
+	// TokenLifetime is how long the access tokens are valid, DefaultTokenLifetime when zero
+	TokenLifetime time.Duration
+	// NeverExpires makes the access tokens be granted without an expiration date, returned as 0 as when the API
+	// integration sets no token validity. They are valid for a hundred years.
+	NeverExpires bool
+	// Identity is the name of the identity the tokens belong to, the username of a local identity
+	Identity string
+	// Version is the version reported by PathSystemVersion
+	Version string
+	// OAuthBasePath, when set, replaces /vedauth in the paths of the OAuth endpoints, as a proxy relocating them does
+	OAuthBasePath string
+	// ClockOffset is added to the current time in the Date header of the responses, simulating a clock out of sync
+	ClockOffset time.Duration
+
+	// tokenPrefix tells the tokens of the server from the ones of the other servers of the test
+	tokenPrefix        string
+	mutex              sync.Mutex
+	grants             []*Grant
+	tokens             map[string]*issuedToken
+	sequence           int
+	requests           map[string]int
+	handlers           map[string]http.HandlerFunc
+	clientCertificates []*x509.Certificate
+}
+
+// issuedToken is an access or refresh token of a grant
+type issuedToken struct {
+	grant     *Grant
+	refresh   bool
+	expiresAt time.Time
+}
+
+// NewServer starts a fake TLSPDC server, closed at the end of the test. It accepts the username "tppadmin" with the
+// password "password".
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+
+	ca := NewCA(t, "TLSPDC Test CA")
+	s := &Server{
+		CA:       ca,
+		Username: "tppadmin",
+		Password: "password",
+		Identity: "tppadmin",
+		Version:  "24.1.0.2460",
+		// The tokens of each server are unique, like the ones of distinct TLSPDC instances
+		tokenPrefix: fmt.Sprintf("%x", serialNumber(t).Uint64()),
+		tokens:      make(map[string]*issuedToken),
+		requests:    make(map[string]int),
+		handlers:    make(map[string]http.HandlerFunc),
+	}
+
+	s.Server = httptest.NewUnstartedServer(http.HandlerFunc(s.serveHTTP))
+	s.Server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{ca.Issue(t, "tpp.venafi.example", CertificateOptions{Hosts: []string{"127.0.0.1", "tpp.venafi.example"}})},
+		ClientAuth:   tls.RequestClientCert,
+	}
+	s.Server.StartTLS()
+	t.Cleanup(s.Server.Close)
+	return s
+}
+
+// UnreachableURL returns the URL of a TLSPDC server refusing the connections
+func UnreachableURL(t testing.TB) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to listen: %s", err)
+	}
+	url := "https://" + listener.Addr().String()
+	listener.Close()
+	return url
+}
+
+// TrustBundle returns the PEM trust bundle the certificate of the server is verified with
+func (s *Server) TrustBundle() string {
+	return s.CA.PEM
+}
+
+// Handle serves the requests to path with handler instead of the fake TLSPDC, e.g. to simulate a failure
+func (s *Server) Handle(path string, handler http.HandlerFunc) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.handlers[path] = handler
+}
+
+// Requests returns the number of requests received for path
+func (s *Server) Requests(path string) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.requests[path]
+}
+
+// ClientCertificates returns the leaf certificates presented by the clients, in order, one per request
+func (s *Server) ClientCertificates() []*x509.Certificate {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return append([]*x509.Certificate(nil), s.clientCertificates...)
+}
+
+// Grants returns a copy of the grants issued by the server, in order
+func (s *Server) Grants() []Grant {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	grants := make([]Grant, 0, len(s.grants))
+	for _, grant := range s.grants {
+		grants = append(grants, *grant)
+	}
+	return grants
+}
+
+// GrantOf returns a copy of the grant token, an access or refresh token, belongs to
+func (s *Server) GrantOf(token string) (Grant, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	issued, ok := s.tokens[token]
+	if !ok {
+		return Grant{}, false
+	}
+	return *issued.grant, true
+}
+
+// IssueGrant issues a grant outside of any request, e.g. the one of a token pair a test starts with
+func (s *Server) IssueGrant(scope string) Grant {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return *s.issueGrant("", scope, "")
+}
+
+// Revoke revokes the grant token belongs to, as if an administrator did it
+func (s *Server) Revoke(token string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if issued, ok := s.tokens[token]; ok {
+		issued.grant.Revoked = true
+	}
+}
+
+// Expire makes the access token expire now
+func (s *Server) Expire(accessToken string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if issued, ok := s.tokens[accessToken]; ok {
+		issued.expiresAt = time.Now().Add(-time.Second)
+		issued.grant.ExpiresAt = issued.expiresAt
+	}
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	s.requests[r.URL.Path]++
+	if r.TLS != nil && len(r.TLS.PeerCertificates) > 0 {
+		s.clientCertificates = append(s.clientCertificates, r.TLS.PeerCertificates[0])
+	}
+	handler, ok := s.handlers[r.URL.Path]
+	s.mutex.Unlock()
+	if s.ClockOffset != 0 {
+		w.Header().Set("Date", time.Now().Add(s.ClockOffset).UTC().Format(http.TimeFormat))
+	}
+	if ok {
+		handler(w, r)
+		return
+	}
+
+	requestPath := r.URL.Path
+	if s.OAuthBasePath != "" {
+		if strings.HasPrefix(requestPath, oauthBasePath+"/") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if rest, found := strings.CutPrefix(requestPath, s.OAuthBasePath+"/"); found {
+			requestPath = oauthBasePath + "/" + rest
+		}
+	}
+
+	switch requestPath {
+	case PathAuthorizeOAuth:
+		s.authorize(w, r, false)
+	case PathAuthorizeCertificate:
+		s.authorize(w, r, true)
+	case PathRefreshToken:
+		s.refresh(w, r)
+	case PathVerifyToken:
+		s.verify(w, r)
+	case PathRevokeToken:
+		s.revoke(w, r)
+	case PathIdentitySelf:
+		if _, ok := s.bearerToken(r); !ok {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		writeJSON(w, map[string]interface{}{"Identities": []map[string]string{{"Name": s.Identity}}})
+	case PathSystemVersion:
+		writeJSON(w, map[string]string{"Version": s.Version})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request, clientCertificate bool) {
+	var request struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+		ClientID string `json:"client_id"`
+		Scope    string `json:"scope"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if clientCertificate && (r.TLS == nil || len(r.TLS.PeerCertificates) == 0) {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if !clientCertificate && (request.Username != s.Username || request.Password != s.Password) {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	s.mutex.Lock()
+	grant := *s.issueGrant(r.URL.Path, request.Scope, request.ClientID)
+	s.mutex.Unlock()
+	s.writeTokens(w, grant, true)
+}
+
+func (s *Server) refresh(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		RefreshToken string `json:"refresh_token"`
+		ClientID     string `json:"client_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	s.mutex.Lock()
+	issued, ok := s.tokens[request.RefreshToken]
+	if !ok || !issued.refresh || issued.grant.Revoked || issued.grant.RefreshToken != request.RefreshToken {
+		s.mutex.Unlock()
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	grant := issued.grant
+	delete(s.tokens, request.RefreshToken)
+	s.issueTokens(grant)
+	grant.Refreshed++
+	refreshed := *grant
+	s.mutex.Unlock()
+	s.writeTokens(w, refreshed, false)
+}
+
+func (s *Server) verify(w http.ResponseWriter, r *http.Request) {
+	token, ok := s.bearerToken(r)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	s.mutex.Lock()
+	issued := s.tokens[token]
+	grant := *issued.grant
+	expiresAt := issued.expiresAt
+	s.mutex.Unlock()
+	writeJSON(w, map[string]interface{}{
+		"application":              grant.ClientID,
+		"access_issued_on_ISO8601": grant.IssuedAt.UTC().Format(time.RFC3339),
+		"expires_ISO8601":          expiresAt.UTC().Format(time.RFC3339),
+		"identity":                 s.Identity,
+		"scope":                    grant.Scope,
+		"valid_for":                int(time.Until(expiresAt).Seconds()),
+	})
+}
+
+func (s *Server) revoke(w http.ResponseWriter, r *http.Request) {
+	token, ok := s.bearerToken(r)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	s.mutex.Lock()
+	s.tokens[token].grant.Revoked = true
+	s.mutex.Unlock()
+	w.WriteHeader(http.StatusOK)
+}
+
+// bearerToken returns the access token of the request when it is live: issued by the server, neither expired nor
+// revoked
+func (s *Server) bearerToken(r *http.Request) (string, bool) {
+	token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !found {
+		return "", false
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	issued, ok := s.tokens[token]
+	if !ok || issued.refresh || issued.grant.Revoked || time.Now().After(issued.expiresAt) {
+		return "", false
+	}
+	return token, true
+}
+
+// issueGrant issues a new grant with its token pair. The mutex must be held.
+func (s *Server) issueGrant(method, scope, clientID string) *Grant {
+	grant := &Grant{ID: len(s.grants) + 1, Method: method, Scope: scope, ClientID: clientID}
+	s.grants = append(s.grants, grant)
+	s.issueTokens(grant)
+	return grant
+}
+
+// issueTokens issues a new token pair for grant. The mutex must be held.
+func (s *Server) issueTokens(grant *Grant) {
+	lifetime := s.TokenLifetime
+	if lifetime == 0 {
+		lifetime = DefaultTokenLifetime
+	}
+	if s.NeverExpires {
+		lifetime = 100 * 365 * 24 * time.Hour
+	}
+
+	s.sequence++
+	grant.AccessToken = fmt.Sprintf("access-%s-%d-%d", s.tokenPrefix, grant.ID, s.sequence)
+	grant.RefreshToken = fmt.Sprintf("refresh-%s-%d-%d", s.tokenPrefix, grant.ID, s.sequence)
+	grant.IssuedAt = time.Now()
+	grant.ExpiresAt = grant.IssuedAt.Add(lifetime)
+	s.tokens[grant.AccessToken] = &issuedToken{grant: grant, expiresAt: grant.ExpiresAt}
+	s.tokens[grant.RefreshToken] = &issuedToken{grant: grant, refresh: true, expiresAt: grant.ExpiresAt}
+}
+
+func (s *Server) writeTokens(w http.ResponseWriter, grant Grant, withScope bool) {
+	body := map[string]interface{}{
+		"access_token":  grant.AccessToken,
+		"refresh_token": grant.RefreshToken,
+		"expires":       grant.ExpiresAt.Unix(),
+		"expires_in":    int64(time.Until(grant.ExpiresAt).Seconds()),
+		"identity":      s.Identity,
+		"refresh_until": grant.ExpiresAt.Add(DefaultTokenLifetime).Unix(),
+		"token_type":    "Bearer",
+	}
+	if s.NeverExpires {
+		body["expires"] = 0
+		body["expires_in"] = 0
+	}
+	if withScope {
+		body["scope"] = grant.Scope
+	}
+	writeJSON(w, body)
+}
+
+func writeJSON(w http.ResponseWriter, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,223 @@
+// Package vault contains a minimal client of the HashiCorp Vault KV secrets engine, used to share token pairs
+package vault
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	envAddress   = "VAULT_ADDR"
+	envToken     = "VAULT_TOKEN"
+	envNamespace = "VAULT_NAMESPACE"
+	envCACert    = "VAULT_CACERT"
+
+	headerToken     = "X-Vault-Token"
+	headerNamespace = "X-Vault-Namespace"
+
+	// KeyAccessToken and KeyRefreshToken are the keys of the token pair in the KV secret
+	KeyAccessToken  = "access_token"
+	KeyRefreshToken = "refresh_token"
+
+	requestTimeout = 30 * time.Second
+	msgVaultError  = "vault client error"
+)
+
+// errSecretNotFound is returned when the KV path holds no secret
+var errSecretNotFound = errors.New("secret not found")
+
+type Client struct {
+	address    string
+	token      string
+	namespace  string
+	httpClient *http.Client
+}
+
+// TokenPair is the content of the KV secret. Empty values are not present in the secret.
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+}
+
+// NewFromEnv builds a client out of the standard Vault environment variables: VAULT_ADDR, VAULT_TOKEN and, optionally,
+// VAULT_NAMESPACE and VAULT_CACERT
+func NewFromEnv() (*Client, error) {
+	address := strings.TrimSuffix(os.Getenv(envAddress), "/")
+	if address == "" {
+		return nil, fmt.Errorf("%s: %s is not set", msgVaultError, envAddress)
+	}
+	token := os.Getenv(envToken)
+	if token == "" {
+		return nil, fmt.Errorf("%s: %s is not set", msgVaultError, envToken)
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+	if caCert := os.Getenv(envCACert); caCert != "" {
+		data, err := os.ReadFile(caCert)
+		if err != nil {
+			return nil, fmt.Errorf("%s: unable to read %s: %w", msgVaultError, envCACert, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("%s: no certificate found in %s [%s]", msgVaultError, envCACert, caCert)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	return &Client{
+		address:   address,
+		token:     token,
+		namespace: os.Getenv(envNamespace),
+		httpClient: &http.Client{
+			Timeout: requestTimeout,
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: tlsConfig,
+			},
+		},
+	}, nil
+}
+
+// ReadTokenPair reads the token pair stored at path. path is the API path of the secret, without the /v1 prefix; for
+// KV version 2 mounts it includes the data segment, e.g. secret/data/venafi/tpp.
+func (c *Client) ReadTokenPair(ctx context.Context, path string) (*TokenPair, error) {
+	values, err := c.readSecret(ctx, path)
+	if errors.Is(err, errSecretNotFound) {
+		return nil, fmt.Errorf("%s: no secret at [%s]", msgVaultError, path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:  stringValue(values, KeyAccessToken),
+		RefreshToken: stringValue(values, KeyRefreshToken),
+	}, nil
+}
+
+// WriteTokenPair stores pair at path. The other keys of the secret are kept.
+func (c *Client) WriteTokenPair(ctx context.Context, path string, pair TokenPair) error {
+	values, err := c.readSecret(ctx, path)
+	if errors.Is(err, errSecretNotFound) {
+		values = make(map[string]interface{})
+	} else if err != nil {
+		return err
+	}
+
+	values[KeyAccessToken] = pair.AccessToken
+	values[KeyRefreshToken] = pair.RefreshToken
+
+	var body interface{} = values
+	if isKVv2(path) {
+		body = map[string]interface{}{"data": values}
+	}
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("%s: %w", msgVaultError, err)
+	}
+
+	resp, err := c.do(ctx, http.MethodPost, path, payload)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return statusError(resp, "write", path)
+	}
+	return nil
+}
+
+func (c *Client) readSecret(ctx context.Context, path string) (map[string]interface{}, error) {
+	resp, err := c.do(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, errSecretNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError(resp, "read", path)
+	}
+
+	var secret struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&secret); err != nil {
+		return nil, fmt.Errorf("%s: unable to decode secret at [%s]: %w", msgVaultError, path, err)
+	}
+	if secret.Data == nil {
+		return nil, errSecretNotFound
+	}
+
+	// KV version 2 nests the secret values, next to their metadata
+	if isKVv2(path) {
+		values, ok := secret.Data["data"].(map[string]interface{})
+		if !ok {
+			// deleted secrets are reported with null data
+			return nil, errSecretNotFound
+		}
+		return values, nil
+	}
+	return secret.Data, nil
+}
+
+func (c *Client) do(ctx context.Context, method string, path string, payload []byte) (*http.Response, error) {
+	url := fmt.Sprintf("%s/v1/%s", c.address, strings.TrimPrefix(path, "/"))
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", msgVaultError, err)
+	}
+	req.Header.Set(headerToken, c.token)
+	if c.namespace != "" {
+		req.Header.Set(headerNamespace, c.namespace)
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", msgVaultError, err)
+	}
+	return resp, nil
+}
+
+// isKVv2 reports whether path targets a KV version 2 mount, whose API paths contain a data segment
+func isKVv2(path string) bool {
+	return strings.Contains("/"+strings.Trim(path, "/")+"/", "/data/")
+}
+
+func stringValue(values map[string]interface{}, key string) string {
+	value, _ := values[key].(string)
+	return value
+}
+
+// statusError reports an unexpected status code. The response body only holds Vault error messages, never secrets.
+func statusError(resp *http.Response, operation string, path string) error {
+	var details struct {
+		Errors []string `json:"errors"`
+	}
+	_ = json.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&details)
+	return fmt.Errorf("%s: unable to %s secret at [%s]: status %d %s", msgVaultError, operation, path, resp.StatusCode,
+		strings.Join(details.Errors, "; "))
+}
@@ -0,0 +1,152 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const testToken = "vault-token"
+
+// kvServer is a fake Vault serving KV secrets from memory, the secrets of KV version 2 paths being nested as Vault does
+type kvServer struct {
+	*httptest.Server
+
+	mutex   sync.Mutex
+	secrets map[string]map[string]interface{}
+}
+
+func newKVServer(t *testing.T) *kvServer {
+	t.Helper()
+
+	kv := &kvServer{secrets: make(map[string]map[string]interface{})}
+	kv.Server = httptest.NewServer(http.HandlerFunc(kv.serve))
+	t.Cleanup(kv.Close)
+	t.Setenv(envAddress, kv.URL)
+	t.Setenv(envToken, testToken)
+	t.Setenv(envNamespace, "")
+	t.Setenv(envCACert, "")
+	return kv
+}
+
+func (kv *kvServer) serve(w http.ResponseWriter, r *http.Request) {
+	kv.mutex.Lock()
+	defer kv.mutex.Unlock()
+
+	if r.Header.Get(headerToken) != testToken {
+		w.WriteHeader(http.StatusForbidden)
+		_ = json.NewEncoder(w).Encode(map[string][]string{"errors": {"permission denied"}})
+		return
+	}
+	path := strings.TrimPrefix(r.URL.Path, "/v1/")
+	switch r.Method {
+	case http.MethodGet:
+		values, ok := kv.secrets[path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var data interface{} = values
+		if isKVv2(path) {
+			data = map[string]interface{}{"data": values, "metadata": map[string]interface{}{"version": 1}}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+	case http.MethodPost:
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if isKVv2(path) {
+			body, _ = body["data"].(map[string]interface{})
+		}
+		kv.secrets[path] = body
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func TestTokenPair(t *testing.T) {
+	for _, path := range []string{"secret/data/venafi/tpp", "kv/venafi/tpp"} {
+		t.Run(path, func(t *testing.T) {
+			kv := newKVServer(t)
+			kv.secrets[path] = map[string]interface{}{KeyAccessToken: "access", "owner": "team"}
+			client, err := NewFromEnv()
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			pair, err := client.ReadTokenPair(context.Background(), path)
+			if err != nil {
+				t.Fatalf("unable to read the token pair: %s", err)
+			}
+			if pair.AccessToken != "access" || pair.RefreshToken != "" {
+				t.Errorf("token pair = %+v, want the access token only", pair)
+			}
+
+			err = client.WriteTokenPair(context.Background(), path, TokenPair{AccessToken: "new-access", RefreshToken: "new-refresh"})
+			if err != nil {
+				t.Fatalf("unable to write the token pair: %s", err)
+			}
+			want := map[string]interface{}{KeyAccessToken: "new-access", KeyRefreshToken: "new-refresh", "owner": "team"}
+			if got := kv.secrets[path]; len(got) != len(want) || got[KeyAccessToken] != "new-access" ||
+				got[KeyRefreshToken] != "new-refresh" || got["owner"] != "team" {
+				t.Errorf("secret = %v, want %v", got, want)
+			}
+		})
+	}
+
+	t.Run("no secret", func(t *testing.T) {
+		newKVServer(t)
+		client, _ := NewFromEnv()
+
+		_, err := client.ReadTokenPair(context.Background(), "secret/data/missing")
+		if err == nil || !strings.Contains(err.Error(), "no secret at [secret/data/missing]") {
+			t.Errorf("error = %v, want the secret not found", err)
+		}
+		// Writing creates the secret
+		if err = client.WriteTokenPair(context.Background(), "secret/data/missing", TokenPair{AccessToken: "access"}); err != nil {
+			t.Errorf("unable to write the token pair: %s", err)
+		}
+	})
+
+	t.Run("permission denied", func(t *testing.T) {
+		newKVServer(t)
+		t.Setenv(envToken, "wrong")
+		client, _ := NewFromEnv()
+
+		_, err := client.ReadTokenPair(context.Background(), "secret/data/venafi/tpp")
+		if err == nil || !strings.Contains(err.Error(), "status 403 permission denied") {
+			t.Errorf("error = %v, want the Vault error", err)
+		}
+	})
+}
+
+func TestNewFromEnv(t *testing.T) {
+	t.Setenv(envAddress, "")
+	t.Setenv(envToken, testToken)
+	if _, err := NewFromEnv(); err == nil || !strings.Contains(err.Error(), envAddress) {
+		t.Errorf("error = %v, want %s missing", err, envAddress)
+	}
+
+	t.Setenv(envAddress, "https://vault.example:8200/")
+	t.Setenv(envToken, "")
+	if _, err := NewFromEnv(); err == nil || !strings.Contains(err.Error(), envToken) {
+		t.Errorf("error = %v, want %s missing", err, envToken)
+	}
+
+	t.Setenv(envToken, testToken)
+	t.Setenv(envCACert, "")
+	client, err := NewFromEnv()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if client.address != "https://vault.example:8200" {
+		t.Errorf("address = %s, want the trailing slash trimmed", client.address)
+	}
+}
@@ -0,0 +1,68 @@
+package vcertclient
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// verificationTTL is how long a successful access token verification is trusted without asking TLSPDC again
+const verificationTTL = 60 * time.Second
+
+// verificationCache remembers the access tokens recently verified as valid, so that resources sharing a token, or
+// reading it several times during the same run, do not verify it against TLSPDC each time. Tokens are only kept as
+// fingerprints.
+type verificationCache struct {
+	mutex   sync.Mutex
+	entries map[string]time.Time
+	now     func() time.Time
+}
+
+var verifiedTokens = &verificationCache{
+	entries: make(map[string]time.Time),
+	now:     time.Now,
+}
+
+// tokenFingerprint identifies accessToken on url without keeping the token itself
+func tokenFingerprint(url string, accessToken string) string {
+	sum := sha256.Sum256([]byte(url + "\n" + accessToken))
+	return hex.EncodeToString(sum[:])
+}
+
+// valid reports whether fingerprint was verified as valid less than verificationTTL ago
+func (c *verificationCache) valid(fingerprint string) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	verifiedOn, ok := c.entries[fingerprint]
+	if !ok {
+		return false
+	}
+	if c.now().Sub(verifiedOn) >= verificationTTL {
+		delete(c.entries, fingerprint)
+		return false
+	}
+	return true
+}
+
+func (c *verificationCache) store(fingerprint string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := c.now()
+	// Drop the stale entries as new ones come in, so that the cache does not grow with rotated tokens
+	for key, verifiedOn := range c.entries {
+		if now.Sub(verifiedOn) >= verificationTTL {
+			delete(c.entries, key)
+		}
+	}
+	c.entries[fingerprint] = now
+}
+
+func (c *verificationCache) invalidate(fingerprint string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	delete(c.entries, fingerprint)
+}
@@ -0,0 +1,81 @@
+package vcertclient
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/model"
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/tpptest"
+)
+
+func TestVerificationCache(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	cache := &verificationCache{entries: make(map[string]time.Time), now: func() time.Time { return now }}
+	fingerprint := tokenFingerprint("https://tpp.venafi.example", "access")
+
+	if cache.valid(fingerprint) {
+		t.Error("miss reported as a hit")
+	}
+	cache.store(fingerprint)
+	now = now.Add(verificationTTL - time.Second)
+	if !cache.valid(fingerprint) {
+		t.Error("hit reported as a miss")
+	}
+	if cache.valid(tokenFingerprint("https://other.venafi.example", "access")) {
+		t.Error("token verified on another url reported as a hit")
+	}
+
+	now = now.Add(time.Second)
+	if cache.valid(fingerprint) {
+		t.Error("expired entry reported as a hit")
+	}
+	if _, kept := cache.entries[fingerprint]; kept {
+		t.Error("expired entry kept")
+	}
+
+	cache.store(fingerprint)
+	cache.invalidate(fingerprint)
+	if cache.valid(fingerprint) {
+		t.Error("invalidated entry reported as a hit")
+	}
+
+	// Stale entries are dropped as new ones are stored
+	cache.store(fingerprint)
+	now = now.Add(verificationTTL)
+	cache.store(tokenFingerprint("https://tpp.venafi.example", "other"))
+	if len(cache.entries) != 1 {
+		t.Errorf("%d entries, want the stale one dropped", len(cache.entries))
+	}
+}
+
+func TestVerifyTokenCached(t *testing.T) {
+	server := tpptest.NewServer(t)
+	grant := server.IssueGrant(DefaultScope)
+	data := model.CredentialResourceData{
+		URL:          types.StringValue(server.URL),
+		TrustBundle:  types.StringValue(server.TrustBundle()),
+		AccessToken:  types.StringValue(grant.AccessToken),
+		RefreshToken: types.StringValue(grant.RefreshToken),
+	}
+
+	// Two resources sharing the token verify it once
+	for i := 0; i < 2; i++ {
+		if validity, err := New(context.Background(), data).VerifyToken(); err != nil || validity != TokenValid {
+			t.Fatalf("validity = %v, %v, want a valid token", validity, err)
+		}
+	}
+	if requests := server.Requests(tpptest.PathVerifyToken); requests != 1 {
+		t.Errorf("%d verification(s) sent to TLSPDC, want 1", requests)
+	}
+
+	// A revoked token is verified again
+	if err := New(context.Background(), data).RevokeToken(); err != nil {
+		t.Fatalf("unable to revoke the token: %s", err)
+	}
+	if validity, _ := New(context.Background(), data).VerifyToken(); validity == TokenValid {
+		t.Error("revoked token still valid")
+	}
+}
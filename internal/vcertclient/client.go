@@ -5,17 +5,19 @@ import (
 	"context"
 	"crypto/tls"
 	"crypto/x509"
-	"encoding/pem"
+	"errors"
 	"fmt"
-	"net/http"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/Venafi/vcert/v5"
 	"github.com/Venafi/vcert/v5/pkg/endpoint"
 	"github.com/Venafi/vcert/v5/pkg/venafi/tpp"
-	"github.com/hashicorp/terraform-plugin-log/tflog"
-	"golang.org/x/crypto/pkcs12"
+	"github.com/hashicorp/terraform-plugin-framework/types"
 
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/kubernetes"
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/logging"
 	"github.com/terraform-providers/terraform-provider-venafi-token/internal/model"
 )
 
@@ -24,11 +26,47 @@ const (
 	msgTokenRefreshSuccess = "successfully retrieved new token pair"
 	msgTokenRefreshFail    = "failed to retrieve new token pair with"
 	msgVcertClientError    = "terraform vcert client error"
+
+	// p12PasswordSidecarSuffix is appended to the PKCS#12 file location to find the file holding its password
+	p12PasswordSidecarSuffix = ".pass"
+
+	// ClientCertificateExpirationWarning is how long before its expiration a client certificate is reported
+	ClientCertificateExpirationWarning = 30 * 24 * time.Hour
+
+	// DefaultMaxTotalAttempts is the number of requests sent to TLSPDC to get a new token pair when max_total_attempts
+	// is not set
+	DefaultMaxTotalAttempts = 3
+
+	// DefaultScope is the scope requested by vcert when none is set
+	DefaultScope = "certificate:manage"
+
+	// offlineAccessScope is added to the requested scope to ask for a long-lived refresh token
+	offlineAccessScope = "offline_access"
 )
 
 type Client struct {
-	context  context.Context
+	context context.Context
+	// logger receives the messages of the client
+	logger   Logger
 	credData model.CredentialResourceData
+	// activeURL is the TLSPDC URL the client is currently talking to: url, or fallback_url after a failover
+	activeURL string
+	// clientCertificate and clientCertificatePool are set once the PKCS#12 keystore has been loaded
+	clientCertificate     *tls.Certificate
+	clientCertificatePool *x509.CertPool
+	// clientCertificateCandidates are the further keystores of p12_cert_filenames, when no issuer selects one of them
+	clientCertificateCandidates []tls.Certificate
+	// revokedToken is the access token revoked by the last RevokeToken call
+	revokedToken string
+	// authAttempts records the requests of the last RequestNewTokenPair call
+	authAttempts []AuthAttempt
+	// serverDate is the last Date reported by TLSPDC, observed locally at serverDateObservedAt
+	serverDate           time.Time
+	serverDateObservedAt time.Time
+	// effectiveURL is the base URL of the last request answered by TLSPDC, as actually sent
+	effectiveURL string
+	// notices are the non-fatal notices returned by TLSPDC to the operations of the client, without duplicates
+	notices []string
 }
 
 type RefreshTokenResponse struct {
@@ -36,28 +74,155 @@ type RefreshTokenResponse struct {
 	RefreshToken string
 	Expires      int64
 	ExpiresIn    int64
+	// GrantID is empty when the token does not expose a grant identifier
+	GrantID string
+	// Scope is the scope granted to the token, empty when it could not be determined
+	Scope string
+	// RefreshUntil is the date until which the refresh token can be used, zero when not reported by TLSPDC
+	RefreshUntil int64
+	// TokenType is the type of the access token, e.g. Bearer, empty when not reported by TLSPDC
+	TokenType string
+	// OfflineAccessDenied is set when offline access was requested but TLSPDC rejected it
+	OfflineAccessDenied bool
+	// Warning is the most recent non-fatal issue met while getting the token pair, empty when there was none
+	Warning string
+	// Method is the authentication method that got the token pair
+	Method string
+	// UsedFallback is set when Method is not the first method of the ladder, i.e. a preferred method failed
+	UsedFallback bool
+	// Notices are the non-fatal notices returned by TLSPDC while getting the token pair, e.g. a password about to expire
+	Notices []string
 }
 
 func New(ctx context.Context, data model.CredentialResourceData) *Client {
+	return NewWithLogger(ctx, data, ContextLogger(ctx))
+}
+
+// NewWithLogger returns a client sending its messages to logger rather than to tflog
+func NewWithLogger(ctx context.Context, data model.CredentialResourceData, logger Logger) *Client {
 	return &Client{
-		context:  ctx,
-		credData: data,
+		context:   ctx,
+		logger:    logger,
+		credData:  withoutBlankCredentials(logger, data),
+		activeURL: data.URL.ValueString(),
 	}
 }
 
-func (c *Client) VerifyTokenExpired() (expired bool, err error) {
-	tflog.Info(c.context, "verifying access token validity")
+// withoutBlankCredentials returns a copy of data where the credentials set to an empty string are null, so that they
+// are not submitted to TLSPDC, e.g. a blank password
+func withoutBlankCredentials(logger Logger, data model.CredentialResourceData) model.CredentialResourceData {
+	credentials := []struct {
+		name  string
+		value *types.String
+	}{
+		{"username", &data.Username},
+		{"password", &data.Password},
+		{"p12_cert_filename", &data.P12Certificate},
+		{"k8s_secret_name", &data.K8sSecretName},
+		{"p12_cert_password", &data.P12Password},
+		{"p12_password_command", &data.P12PasswordCommand},
+		{"access_token", &data.AccessToken},
+		{"refresh_token", &data.RefreshToken},
+	}
+	for _, credential := range credentials {
+		if !credential.value.IsNull() && !credential.value.IsUnknown() && credential.value.ValueString() == "" {
+			logger.Debug(fmt.Sprintf("%s is empty, considering it not set", credential.name))
+			*credential.value = types.StringNull()
+		}
+	}
+	return data
+}
 
-	config, err := c.createVCertConfig()
-	if err != nil {
-		tflog.Error(c.context, err.Error())
-		return false, err
+// ClientCertificateExpiration returns the expiration date of the client certificate loaded by the last operation. The
+// zero time is returned when no client certificate was used.
+func (c *Client) ClientCertificateExpiration() time.Time {
+	if c.clientCertificate == nil || c.clientCertificate.Leaf == nil {
+		return time.Time{}
 	}
+	return c.clientCertificate.Leaf.NotAfter
+}
 
-	vClient, err := vcert.NewClient(config, false)
-	if err != nil {
-		tflog.Error(c.context, err.Error())
-		return false, err
+// ServerTime returns the current time according to TLSPDC, based on the Date header of its last response. When no
+// response was received yet, TLSPDC is queried. The second value is false when the time of TLSPDC is unknown.
+func (c *Client) ServerTime() (time.Time, bool) {
+	if c.serverDate.IsZero() {
+		auth := &endpoint.Authentication{
+			AccessToken: c.credData.AccessToken.ValueString(),
+		}
+		// Any response carries the date, the outcome of the verification does not matter
+		_ = c.withFailover(func(connector *tpp.Connector) error {
+			_, opErr := connector.VerifyAccessToken(auth)
+			return opErr
+		})
+	}
+	if c.serverDate.IsZero() {
+		return time.Time{}, false
+	}
+	return c.serverDate.Add(time.Since(c.serverDateObservedAt)), true
+}
+
+func (c *Client) observeServerDate(date time.Time) {
+	c.serverDate = date
+	c.serverDateObservedAt = time.Now()
+}
+
+func (c *Client) observeNotice(notice string) {
+	for _, known := range c.notices {
+		if known == notice {
+			return
+		}
+	}
+	c.logger.Warn(fmt.Sprintf("notice from TLSPDC: %s", notice))
+	c.notices = append(c.notices, notice)
+}
+
+// Notices returns the non-fatal notices returned by TLSPDC to the operations of the client, e.g. a password about to
+// expire
+func (c *Client) Notices() []string {
+	return c.notices
+}
+
+func (c *Client) observeURL(url string) {
+	c.effectiveURL = url
+}
+
+// EffectiveURL returns the base URL the last request answered by TLSPDC was actually sent to, once url or fallback_url
+// was normalized by vcert: lower-cased, with the https scheme, and the WebSDK under the host root, e.g.
+// https://tpp.venafi.example/vedsdk. Empty when no response was received.
+func (c *Client) EffectiveURL() string {
+	return c.effectiveURL
+}
+
+// ActiveURL returns the TLSPDC URL used by the last operation of the client
+func (c *Client) ActiveURL() string {
+	return c.activeURL
+}
+
+// TokenValidity is the outcome of the verification of an access token
+type TokenValidity int
+
+const (
+	// TokenValid is reported when TLSPDC accepted the access token
+	TokenValid TokenValidity = iota
+	// TokenInvalid is reported when TLSPDC rejected the access token as unauthorized and it is not known to be revoked,
+	// i.e. it expired or its expiration date is not known
+	TokenInvalid
+	// TokenUnknown is reported when the access token could not be introspected, e.g. TLSPDC could not be reached
+	TokenUnknown
+	// TokenRevoked is reported when TLSPDC rejected the access token as unauthorized before its expiration date, i.e.
+	// its grant was revoked out-of-band
+	TokenRevoked
+)
+
+// VerifyToken introspects the access token. Errors are only returned when the vcert connector cannot be built; any
+// other failure is reported as TokenUnknown.
+func (c *Client) VerifyToken() (TokenValidity, error) {
+	c.logger.Info("verifying access token validity")
+
+	fingerprint := tokenFingerprint(c.credData.URL.ValueString(), c.credData.AccessToken.ValueString())
+	if verifiedTokens.valid(fingerprint) {
+		c.logger.Info("access token recently verified as valid, skipping verification")
+		return TokenValid, nil
 	}
 
 	auth := &endpoint.Authentication{
@@ -65,126 +230,368 @@ func (c *Client) VerifyTokenExpired() (expired bool, err error) {
 	}
 
 	//Due to limitations in TPP API, we cannot retrieve the access token expiration time from the verify function
-	_, err = vClient.(*tpp.Connector).VerifyAccessToken(auth)
+	var err error
+	// Verifying does not change anything in TLSPDC, it is safe to retry after any connection error
+	for retries := c.verifyMaxRetries(); ; retries-- {
+		err = c.withFailover(func(connector *tpp.Connector) error {
+			_, opErr := connector.VerifyAccessToken(auth)
+			return opErr
+		})
+		if err == nil || !isConnectionError(err) || retries == 0 {
+			break
+		}
+		c.logger.Warn(fmt.Sprintf("retrying token verification after connection error, %d retry(ies) left: %s", retries, err.Error()))
+	}
+	var settingsErr *connectorError
+	if errors.As(err, &settingsErr) || errors.Is(err, ErrServerCertificateUntrusted) || errors.Is(err, ErrClientCertificateExpired) {
+		c.logger.Error(err.Error())
+		return TokenUnknown, err
+	}
 	if err != nil {
 		msg := fmt.Sprintf("%s: %s", msgVcertClientError, err.Error())
-		tflog.Info(c.context, msg)
-		return true, nil
+		c.logger.Info(msg)
+		if errors.Is(err, ErrMaintenanceMode) {
+			c.logger.Warn("TLSPDC is in maintenance mode, the access token could not be verified")
+		}
+		if isUnauthorized(err) {
+			// TLSPDC answers the same for expired and revoked tokens, only the expiration date tells them apart. A
+			// token that never expires, with an expiration of 0, can only have been revoked.
+			if expiration := c.credData.ExpirationDate; !expiration.IsNull() && (expiration.ValueInt64() <= 0 || expiration.ValueInt64() > time.Now().Unix()) {
+				return TokenRevoked, nil
+			}
+			return TokenInvalid, nil
+		}
+		return TokenUnknown, nil
 	}
 
-	return false, nil
+	verifiedTokens.store(fingerprint)
+	return TokenValid, nil
 }
 
-func (c *Client) RequestNewTokenPair() (*RefreshTokenResponse, error) {
-	tflog.Info(c.context, "requesting new token pair")
+// VerifyTokenExpired reports whether the access token must be replaced: it is either rejected by TLSPDC, expired or
+// revoked, or could not be verified
+func (c *Client) VerifyTokenExpired() (expired bool, err error) {
+	validity, err := c.VerifyToken()
+	if err != nil {
+		return false, err
+	}
+	return validity != TokenValid, nil
+}
 
-	tokenMethod := !c.credData.RefreshToken.IsNull()
-	p12Method := !c.credData.P12Certificate.IsNull() && !c.credData.P12Password.IsNull()
-	userMethod := !c.credData.Username.IsNull() && !c.credData.Password.IsNull()
+// isUnauthorized reports whether err is vcert reporting a 401 status from TLSPDC
+func isUnauthorized(err error) bool {
+	return strings.Contains(err.Error(), "Message: 401")
+}
 
-	if !tokenMethod && !p12Method && !userMethod {
-		return nil, fmt.Errorf("%s: no authorization methods specified", msgVcertClientError)
+// authentication methods of the ladder, in order
+const (
+	MethodRefreshToken      = "refresh token"
+	MethodClientCertificate = "client certificate"
+	MethodUsernamePassword  = "username-password"
+)
+
+// AuthAttempt is the outcome of a request sent by the authentication ladder
+type AuthAttempt struct {
+	Method    string
+	Succeeded bool
+	// Error is the failure reason, scrubbed of any credential. Empty when the attempt succeeded
+	Error string
+}
+
+// AuthAttempts returns the requests sent by the last RequestNewTokenPair call, in order
+func (c *Client) AuthAttempts() []AuthAttempt {
+	return c.authAttempts
+}
+
+// rotation policies, deciding whether the refresh token is used to request a new token pair
+const (
+	RotationPolicyPreferRefresh = "prefer_refresh"
+	RotationPolicyAlwaysPrimary = "always_primary"
+)
+
+// ValidateRotationPolicy checks policy is one of the supported rotation policies
+func ValidateRotationPolicy(policy string) error {
+	if policy != RotationPolicyPreferRefresh && policy != RotationPolicyAlwaysPrimary {
+		return fmt.Errorf("rotation policy must be %s or %s, got [%s]", RotationPolicyPreferRefresh, RotationPolicyAlwaysPrimary, policy)
 	}
+	return nil
+}
+
+var (
+	// ErrNoAuthMethod is returned by RequestNewTokenPair when the credential holds neither a refresh token nor a
+	// primary credential (client certificate or username/password)
+	ErrNoAuthMethod = errors.New("no authorization methods specified")
+	// ErrCredentialsRejected is returned by RequestNewTokenPair when every authentication method failed for another
+	// reason than reaching TLSPDC, e.g. an expired refresh token. Trying again is pointless until the credentials are
+	// replaced.
+	ErrCredentialsRejected = errors.New("every authorization method was rejected")
+)
+
+// authMethod is a step of the authentication ladder used to request a new token pair
+type authMethod struct {
+	name    string
+	request func() (*RefreshTokenResponse, error)
+}
 
-	if tokenMethod {
-		tflog.Info(c.context, fmt.Sprintf("%s %s", msgTokenRefreshStart, "refresh token"))
-		resp, err := c.refreshAccessToken()
-		// return if no errors
-		if err == nil {
-			tflog.Info(c.context, msgTokenRefreshSuccess)
-			return resp, nil
+// RequestNewTokenPair walks the authentication ladder (refresh token, client certificate, username-password) until a
+// method succeeds. The refresh token is skipped with the always_primary rotation policy. The number of requests sent
+// to TLSPDC is bounded by max_total_attempts, shared by all methods: a method failing with a connection error is only
+// retried when the budget left still allows every remaining method to be tried once.
+func (c *Client) RequestNewTokenPair() (*RefreshTokenResponse, error) {
+	c.logger.Info("requesting new token pair")
+
+	alwaysPrimary := c.credData.RotationPolicy.ValueString() == RotationPolicyAlwaysPrimary
+	methods := c.authLadder()
+	if len(methods) == 0 {
+		if alwaysPrimary && !c.credData.RefreshToken.IsNull() {
+			return nil, fmt.Errorf("%s: %w, the refresh token is not used with rotation policy %s", msgVcertClientError, ErrNoAuthMethod, RotationPolicyAlwaysPrimary)
 		}
-		// if refresh token fails. Check if there is any other auth method.
-		// if there is another auth method, log warning and continue
-		msg := fmt.Sprintf("%s %s: %s", msgTokenRefreshFail, "refresh token", err.Error())
-		if !p12Method && !userMethod {
-			// no other auth method. Log and return error
-			tflog.Error(c.context, msg)
-			return nil, fmt.Errorf("%s: %w", msgVcertClientError, err)
+		return nil, fmt.Errorf("%s: %w", msgVcertClientError, ErrNoAuthMethod)
+	}
+	if alwaysPrimary && !c.credData.RefreshToken.IsNull() {
+		c.logger.Info(fmt.Sprintf("rotation policy %s, skipping %s", RotationPolicyAlwaysPrimary, MethodRefreshToken))
+	}
+
+	c.authAttempts = nil
+	budget := c.maxTotalAttempts()
+	var lastErr error
+	// fallbackReason records why the previous method was given up
+	var fallbackReason string
+	// transient is set when a method was given up because TLSPDC could not be reached
+	transient := false
+	tried := 0
+	for i, method := range methods {
+		if budget == 0 {
+			break
+		}
+		tried++
+		remainingMethods := len(methods) - i - 1
+
+		c.logger.Info(fmt.Sprintf("%s %s", msgTokenRefreshStart, method.name))
+		for retries := int64(0); ; retries++ {
+			budget--
+			resp, err := method.request()
+			c.recordAttempt(method.name, err)
+			// return if no errors
+			if err == nil {
+				c.logger.Info(msgTokenRefreshSuccess)
+				resp.Method = method.name
+				resp.UsedFallback = i > 0
+				resp.Notices = c.Notices()
+				if resp.Warning == "" && fallbackReason != "" {
+					resp.Warning = fmt.Sprintf("%s, used %s instead", fallbackReason, method.name)
+				}
+				return resp, nil
+			}
+			lastErr = err
+			if errors.Is(err, ErrMaintenanceMode) {
+				c.logger.Error(fmt.Sprintf("%s %s: %s", msgTokenRefreshFail, method.name, err.Error()))
+				return nil, fmt.Errorf("%s: %w", msgVcertClientError, err)
+			}
+			if !isConnectionError(err) || budget <= int64(remainingMethods) || !c.rotateRetryAllowed(retries, err) {
+				break
+			}
+			c.logger.Warn(fmt.Sprintf("retrying %s after connection error, %d attempt(s) left: %s", method.name, budget, err.Error()))
 		}
-		// print warning and let other auth methods be used
-		tflog.Warn(c.context, msg)
-	}
 
-	if p12Method {
-		tflog.Info(c.context, fmt.Sprintf("%s %s", msgTokenRefreshStart, "client certificate"))
-		resp, err := c.getAccessTokenByP12()
-		// return if no errors
-		if err == nil {
-			tflog.Info(c.context, msgTokenRefreshSuccess)
-			return resp, nil
+		// An untrusted TLSPDC certificate is not a rejection of the credentials either
+		if isConnectionError(lastErr) || errors.Is(lastErr, ErrServerCertificateUntrusted) {
+			transient = true
 		}
-		// if client certificate fails. Check if there is user/password method, log warning and continue
-		msg := fmt.Sprintf("%s %s: %s", msgTokenRefreshFail, "client certificate", err.Error())
-		if !userMethod {
-			// no other auth method. Log and return error
-			tflog.Error(c.context, msg)
-			return nil, fmt.Errorf("%s: %w", msgVcertClientError, err)
+		msg := fmt.Sprintf("%s %s: %s", msgTokenRefreshFail, method.name, lastErr.Error())
+		if remainingMethods == 0 || budget == 0 {
+			// no other auth method can be used. Log and return error
+			c.logger.Error(msg)
+			break
 		}
 		// log warning and let other auth methods be used
-		tflog.Warn(c.context, msg)
+		c.logger.Warn(msg)
+		fallbackReason = fmt.Sprintf("%s failed: %s", method.name, lastErr.Error())
 	}
 
-	if userMethod {
-		tflog.Info(c.context, fmt.Sprintf("%s %s", msgTokenRefreshStart, "username-password"))
-		resp, err := c.getAccessTokenByUsernamePassword()
-		// return if no errors
-		if err == nil {
-			tflog.Info(c.context, msgTokenRefreshSuccess)
-			return resp, nil
-		}
-		// no other auth method. Log and return error
-		tflog.Error(c.context, fmt.Sprintf("%s %s: %s", msgTokenRefreshFail, "username-password", err.Error()))
-		return nil, fmt.Errorf("%s: %w", msgVcertClientError, err)
+	if tried < len(methods) {
+		return nil, fmt.Errorf("%s: max_total_attempts (%d) exhausted before trying %s: %w", msgVcertClientError,
+			c.maxTotalAttempts(), methods[tried].name, lastErr)
+	}
+	if !transient {
+		return nil, fmt.Errorf("%s: %w: %w", msgVcertClientError, ErrCredentialsRejected, lastErr)
 	}
+	return nil, fmt.Errorf("%s: %w", msgVcertClientError, lastErr)
+}
 
-	return nil, fmt.Errorf("%s: could not complete refresh token operation: all authentication methods failed", msgVcertClientError)
+// authLadder returns the authentication methods RequestNewTokenPair tries, in order
+func (c *Client) authLadder() []authMethod {
+	var methods []authMethod
+	if !c.credData.RefreshToken.IsNull() && c.credData.RotationPolicy.ValueString() != RotationPolicyAlwaysPrimary {
+		methods = append(methods, authMethod{name: MethodRefreshToken, request: c.refreshAccessToken})
+	}
+	hasUsernamePassword := !c.credData.Username.IsNull() && !c.credData.Password.IsNull()
+	// The client certificate only authenticates the TLS connections when the grant is requested with username/password
+	tlsOnlyCertificate := c.credData.RequireClientCertTLS.ValueBool() && hasUsernamePassword
+	hasKeystore := !c.credData.K8sSecretName.IsNull() ||
+		(!c.credData.P12Certificate.IsNull() && (!c.credData.P12Password.IsNull() || !c.credData.P12PasswordCommand.IsNull() || c.hasP12PasswordSidecar()))
+	if hasKeystore && !tlsOnlyCertificate {
+		methods = append(methods, authMethod{name: MethodClientCertificate, request: c.getAccessTokenByP12})
+	}
+	if hasUsernamePassword {
+		methods = append(methods, authMethod{name: MethodUsernamePassword, request: c.getAccessTokenByUsernamePassword})
+	}
+	return methods
 }
 
-func (c *Client) RevokeToken() error {
-	tflog.Info(c.context, "revoking access token")
+// AuthMethods returns the names of the authentication methods tried to get a new token pair, in order, without
+// contacting TLSPDC
+func (c *Client) AuthMethods() []string {
+	var names []string
+	for _, method := range c.authLadder() {
+		names = append(names, method.name)
+	}
+	return names
+}
 
-	config, err := c.createVCertConfig()
-	if err != nil {
-		tflog.Error(c.context, err.Error())
-		return err
+// rotateRetryAllowed reports whether a method failing with the connection error err can be sent again after retries
+// retries, within rotate_max_retries. Only the requests that never reached TLSPDC are sent again: TLSPDC may have
+// granted a token to a request whose response was lost, and sending it again would create a second grant.
+func (c *Client) rotateRetryAllowed(retries int64, err error) bool {
+	if !c.credData.RotateMaxRetries.IsNull() && retries >= c.credData.RotateMaxRetries.ValueInt64() {
+		return false
+	}
+	if !isUnsentRequestError(err) {
+		c.logger.Warn(fmt.Sprintf("not retrying, TLSPDC may have processed the request: %s", err.Error()))
+		return false
 	}
+	return true
+}
+
+// verifyMaxRetries returns how many times the verification of the access token is retried after a connection error
+func (c *Client) verifyMaxRetries() int64 {
+	if c.credData.VerifyMaxRetries.IsNull() || c.credData.VerifyMaxRetries.ValueInt64() < 0 {
+		return 0
+	}
+	return c.credData.VerifyMaxRetries.ValueInt64()
+}
 
-	vClient, err := vcert.NewClient(config, false)
+func (c *Client) recordAttempt(method string, err error) {
+	attempt := AuthAttempt{Method: method, Succeeded: err == nil}
 	if err != nil {
-		tflog.Error(c.context, err.Error())
-		return err
+		attempt.Error = c.scrub(err.Error())
+	}
+	c.authAttempts = append(c.authAttempts, attempt)
+}
+
+// scrub masks the credentials of the client found in msg
+func (c *Client) scrub(msg string) string {
+	for _, secret := range []string{
+		c.credData.AccessToken.ValueString(),
+		c.credData.RefreshToken.ValueString(),
+		c.credData.Password.ValueString(),
+		c.credData.P12Password.ValueString(),
+	} {
+		if secret != "" {
+			msg = strings.ReplaceAll(msg, secret, "***")
+		}
+	}
+	return msg
+}
+
+// maxTotalAttempts returns the number of requests RequestNewTokenPair may send to TLSPDC
+func (c *Client) maxTotalAttempts() int64 {
+	if c.credData.MaxTotalAttempts.IsNull() || c.credData.MaxTotalAttempts.ValueInt64() < 1 {
+		return DefaultMaxTotalAttempts
+	}
+	return c.credData.MaxTotalAttempts.ValueInt64()
+}
+
+// RevokeToken revokes the grant of the access token. When the access token has already expired and a refresh token is
+// available, the refresh token is first used to get a live access token for the same grant. Should the refresh fail,
+// the revocation is still attempted with the stored access token.
+func (c *Client) RevokeToken() error {
+	c.logger.Info("revoking access token")
+
+	accessToken := c.credData.AccessToken.ValueString()
+	if !c.credData.RefreshToken.IsNull() {
+		expired, err := c.VerifyTokenExpired()
+		if err == nil && expired {
+			c.logger.Info("access token expired, refreshing it before revoking the grant")
+			resp, refreshErr := c.refreshAccessToken()
+			if refreshErr != nil {
+				c.logger.Warn(fmt.Sprintf("unable to refresh expired access token, revoking it anyway: %s", refreshErr.Error()))
+			} else {
+				accessToken = resp.AccessToken
+			}
+		}
 	}
 
 	auth := &endpoint.Authentication{
-		AccessToken: c.credData.AccessToken.ValueString(),
+		AccessToken: accessToken,
 	}
-	err = vClient.(*tpp.Connector).RevokeAccessToken(auth)
+	err := c.withFailover(func(connector *tpp.Connector) error {
+		return connector.RevokeAccessToken(auth)
+	})
+	verifiedTokens.invalidate(tokenFingerprint(c.credData.URL.ValueString(), accessToken))
+	verifiedTokens.invalidate(tokenFingerprint(c.credData.URL.ValueString(), c.credData.AccessToken.ValueString()))
 	if err != nil {
-		tflog.Error(c.context, err.Error())
+		c.logger.Error(err.Error())
 		return err
 	}
 
+	c.revokedToken = accessToken
 	return nil
 }
 
-func (c *Client) refreshAccessToken() (*RefreshTokenResponse, error) {
-	tflog.Info(c.context, "using refresh token authentication method")
+// revocationChecks is how many times a revocation is checked: TLSPDC may take a moment to process it
+const revocationChecks = 3
 
-	config, err := c.createVCertConfig()
-	if err != nil {
-		return nil, err
+// revocationCheckDelay is the delay between two revocation checks, shortened by the tests
+var revocationCheckDelay = 2 * time.Second
+
+// ConfirmRevocation checks that TLSPDC rejects the access token revoked by the last RevokeToken call, asking again a
+// few times while it is still accepted. It reports whether the revocation was confirmed.
+func (c *Client) ConfirmRevocation() bool {
+	auth := &endpoint.Authentication{
+		AccessToken: c.revokedToken,
 	}
-	vClient, err := vcert.NewClient(config, false)
-	if err != nil {
-		return nil, err
+
+	for check := 1; check <= revocationChecks; check++ {
+		if check > 1 {
+			select {
+			case <-c.context.Done():
+				return false
+			case <-time.After(revocationCheckDelay):
+			}
+		}
+
+		// The verification cache is bypassed, only TLSPDC can confirm the revocation
+		err := c.withFailover(func(connector *tpp.Connector) error {
+			_, opErr := connector.VerifyAccessToken(auth)
+			return opErr
+		})
+		if err != nil && isUnauthorized(err) {
+			c.logger.Info(fmt.Sprintf("revocation confirmed after %d check(s)", check))
+			return true
+		}
+		if err != nil {
+			c.logger.Warn(fmt.Sprintf("unable to confirm revocation, check %d of %d: %s", check, revocationChecks, err.Error()))
+		} else {
+			c.logger.Warn(fmt.Sprintf("revoked access token still accepted by TLSPDC, check %d of %d", check, revocationChecks))
+		}
 	}
+	return false
+}
+
+func (c *Client) refreshAccessToken() (*RefreshTokenResponse, error) {
+	c.logger.Info("using refresh token authentication method")
 
 	auth := &endpoint.Authentication{
 		RefreshToken: c.credData.RefreshToken.ValueString(),
 		ClientId:     c.credData.ClientID.ValueString(),
 	}
-	resp, err := vClient.(*tpp.Connector).RefreshAccessToken(auth)
+	var resp tpp.OauthRefreshAccessTokenResponse
+	err := c.withFailover(func(connector *tpp.Connector) error {
+		var opErr error
+		resp, opErr = connector.RefreshAccessToken(auth)
+		return opErr
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -193,13 +600,38 @@ func (c *Client) refreshAccessToken() (*RefreshTokenResponse, error) {
 		AccessToken:  resp.Access_token,
 		RefreshToken: resp.Refresh_token,
 		Expires:      int64(resp.Expires),
+		GrantID:      grantID(resp.Access_token),
+		Scope:        c.grantedScope(resp.Access_token),
+		RefreshUntil: int64(resp.Refresh_until),
+		TokenType:    resp.Token_type,
 	}
 
 	return &refreshResp, nil
 }
 
+// grantedScope introspects accessToken to find its scope, as the refresh token response does not include it. An empty
+// string is returned when the token cannot be introspected.
+func (c *Client) grantedScope(accessToken string) string {
+	auth := &endpoint.Authentication{
+		AccessToken: accessToken,
+	}
+
+	var resp tpp.OauthVerifyTokenResponse
+	err := c.withFailover(func(connector *tpp.Connector) error {
+		var opErr error
+		resp, opErr = connector.VerifyAccessToken(auth)
+		return opErr
+	})
+	if err != nil {
+		c.logger.Warn(fmt.Sprintf("unable to retrieve granted scope: %s", err.Error()))
+		return ""
+	}
+
+	return resp.Scope
+}
+
 func (c *Client) getAccessTokenByP12() (*RefreshTokenResponse, error) {
-	tflog.Info(c.context, "using client certificate authentication method")
+	c.logger.Info("using client certificate authentication method")
 
 	err := c.configureTLSClient()
 	if err != nil {
@@ -210,24 +642,41 @@ func (c *Client) getAccessTokenByP12() (*RefreshTokenResponse, error) {
 }
 
 func (c *Client) getAccessTokenByUsernamePassword() (*RefreshTokenResponse, error) {
-	tflog.Info(c.context, "using username-password authentication method")
+	c.logger.Info("using username-password authentication method")
 
 	return c.getAccessToken(false)
 }
 
 func (c *Client) getAccessToken(useClientCertificate bool) (*RefreshTokenResponse, error) {
-	config, err := c.createVCertConfig()
-	if err != nil {
-		return nil, err
+	scope := c.credData.Scope.ValueString()
+	if !c.credData.OfflineAccess.ValueBool() {
+		return c.requestToken(useClientCertificate, scope)
 	}
 
-	vClient, err := vcert.NewClient(config, false)
+	if scope == "" {
+		scope = DefaultScope
+	}
+	resp, err := c.requestToken(useClientCertificate, fmt.Sprintf("%s;%s", scope, offlineAccessScope))
+	if err == nil || !isBadRequest(err) {
+		return resp, err
+	}
+
+	// TLSPDC rejects the scopes it does not know about
+	rejection := err
+	c.logger.Warn(fmt.Sprintf("offline access rejected by TLSPDC, requesting token without it: %s", rejection.Error()))
+	resp, err = c.requestToken(useClientCertificate, scope)
 	if err != nil {
 		return nil, err
 	}
+	resp.OfflineAccessDenied = true
+	resp.Warning = fmt.Sprintf("offline access rejected by TLSPDC, token pair requested without it: %s", rejection.Error())
+	return resp, nil
+}
 
+func (c *Client) requestToken(useClientCertificate bool, scope string) (*RefreshTokenResponse, error) {
 	auth := &endpoint.Authentication{
 		ClientId: c.credData.ClientID.ValueString(),
+		Scope:    scope,
 	}
 
 	if useClientCertificate {
@@ -237,7 +686,12 @@ func (c *Client) getAccessToken(useClientCertificate bool) (*RefreshTokenRespons
 		auth.Password = c.credData.Password.ValueString()
 	}
 
-	resp, err := vClient.(*tpp.Connector).GetRefreshToken(auth)
+	var resp tpp.OauthGetRefreshTokenResponse
+	err := c.withFailover(func(connector *tpp.Connector) error {
+		var opErr error
+		resp, opErr = connector.GetRefreshToken(auth)
+		return opErr
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -246,79 +700,266 @@ func (c *Client) getAccessToken(useClientCertificate bool) (*RefreshTokenRespons
 		AccessToken:  resp.Access_token,
 		RefreshToken: resp.Refresh_token,
 		Expires:      int64(resp.Expires),
+		GrantID:      grantID(resp.Access_token),
+		Scope:        resp.Scope,
+		RefreshUntil: int64(resp.Refresh_until),
+		TokenType:    resp.Token_type,
 	}
 	return &refreshResp, nil
 }
 
-func (c *Client) configureTLSClient() error {
-	tflog.Info(c.context, "configuring TLS client")
+// isBadRequest reports whether err is vcert rejecting an authorization request with a 400 status. vcert drops the
+// response body, so the reason cannot be told apart.
+func isBadRequest(err error) bool {
+	return strings.Contains(err.Error(), "Status: 400")
+}
 
-	p12Location := c.credData.P12Certificate.ValueString()
-	password := c.credData.P12Password.ValueString()
+func (c *Client) configureTLSClient() error {
+	c.logger.Info("configuring TLS client")
 
-	data, err := os.ReadFile(p12Location)
+	keystores, password, err := c.keystores()
 	if err != nil {
-		return fmt.Errorf("%s: unable to read PKCS#12 file at [%s]: %w", msgVcertClientError, p12Location, err)
+		return err
+	}
+
+	var candidates []clientCertificate
+	for _, data := range keystores {
+		// We have a PKCS12 file to use, set it up for cert authentication
+		cert, caCertPool, err := parsePKCS12(data, password)
+		if err != nil {
+			return fmt.Errorf("%s: %w", msgVcertClientError, err)
+		}
+		candidates = append(candidates, clientCertificate{cert: cert, pool: caCertPool})
 	}
 
-	// We have a PKCS12 file to use, set it up for cert authentication
-	blocks, err := pkcs12.ToPEM(data, password)
+	selected, others, err := selectClientCertificate(candidates, c.credData.ClientCertIssuer.ValueString())
 	if err != nil {
-		return fmt.Errorf("%s: failed converting PKCS#12 archive file to PEM blocks: %w", msgVcertClientError, err)
+		return fmt.Errorf("%s: %w", msgVcertClientError, err)
+	}
+	cert := selected.cert
+
+	// TLSPDC would only report a generic handshake failure
+	now := time.Now()
+	if err = checkClientCertificateValidity(cert, now); err != nil {
+		return fmt.Errorf("%s: %w", msgVcertClientError, err)
+	}
+	if remaining := cert.Leaf.NotAfter.Sub(now); remaining < ClientCertificateExpirationWarning {
+		c.logger.Warn(fmt.Sprintf("client certificate [%s] expires on %s", cert.Leaf.Subject.String(), cert.Leaf.NotAfter.Format(time.RFC3339)))
 	}
 
-	var pemData []byte
-	for _, b := range blocks {
-		pemData = append(pemData, pem.EncodeToMemory(b)...)
+	// The certificate is presented by the HTTP client built for the vcert connector
+	c.clientCertificate = cert
+	c.clientCertificatePool = selected.pool
+	for _, other := range others {
+		if err = checkClientCertificateValidity(other.cert, now); err != nil {
+			c.logger.Warn(fmt.Sprintf("ignoring keystore of p12_cert_filenames: %s", err.Error()))
+			continue
+		}
+		c.clientCertificateCandidates = append(c.clientCertificateCandidates, *other.cert)
 	}
 
-	// Construct TLS certificate from PEM data
-	cert, err := tls.X509KeyPair(pemData, pemData)
+	c.logger.Info("TLS client configured")
+	return nil
+}
+
+// keystores returns the PKCS#12 keystores to pick the client certificate from, along with their password: the one of
+// k8s_secret_name or p12_cert_filename first, then the ones of p12_cert_filenames
+func (c *Client) keystores() ([][]byte, string, error) {
+	var keystores [][]byte
+	var password string
+	var locations []string
+	if !c.credData.K8sSecretName.IsNull() {
+		keystore, err := c.kubernetesKeystore()
+		if err != nil {
+			return nil, "", err
+		}
+		keystores = append(keystores, keystore.Data)
+		password = keystore.Password
+	} else {
+		var err error
+		if password, err = c.p12Password(); err != nil {
+			return nil, "", err
+		}
+		locations = append(locations, c.credData.P12Certificate.ValueString())
+	}
+	for _, element := range c.credData.P12CertFilenames.Elements() {
+		if location, ok := element.(types.String); ok && !location.IsNull() && location.ValueString() != "" {
+			locations = append(locations, location.ValueString())
+		}
+	}
+
+	for _, p12Location := range locations {
+		kind, data, err := readCredentialInput("PKCS#12", p12Location)
+		if err != nil {
+			return nil, "", err
+		}
+		if kind == inputPEM {
+			return nil, "", fmt.Errorf("%s: PKCS#12 keystore must be a file path or base64-encoded, got PEM data", msgVcertClientError)
+		}
+		keystores = append(keystores, data)
+	}
+	return keystores, password, nil
+}
+
+// kubernetesKeystore reads the PKCS#12 keystore, and its password, from k8s_secret_name with the service account of the
+// pod the provider runs in
+func (c *Client) kubernetesKeystore() (*kubernetes.Keystore, error) {
+	client, err := kubernetes.NewInCluster()
 	if err != nil {
-		return fmt.Errorf("%s: failed reading PEM data to build X.509 certificate: %w", msgVcertClientError, err)
+		return nil, fmt.Errorf("%s: k8s_secret_name is set: %w", msgVcertClientError, err)
 	}
 
-	caCertPool := x509.NewCertPool()
-	caCertPool.AppendCertsFromPEM(pemData)
+	namespace, name := c.credData.K8sSecretNamespace.ValueString(), c.credData.K8sSecretName.ValueString()
+	c.logger.Info(fmt.Sprintf("reading PKCS#12 keystore from kubernetes secret [%s/%s]", namespace, name))
+	keystore, err := client.ReadKeystore(c.context, namespace, name)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", msgVcertClientError, err)
+	}
+	return keystore, nil
+}
+
+// hasP12PasswordSidecar reports whether the PKCS#12 password should be, and can be, read from a sidecar file
+func (c *Client) hasP12PasswordSidecar() bool {
+	if !c.credData.P12PasswordFromSidecar.ValueBool() || c.credData.P12Certificate.IsNull() {
+		return false
+	}
+	_, err := os.Stat(c.credData.P12Certificate.ValueString() + p12PasswordSidecarSuffix)
+	return err == nil
+}
 
-	// Setup TLS configuration
-	tlsConfig := tls.Config{
-		Renegotiation: tls.RenegotiateFreelyAsClient,
-		Certificates:  []tls.Certificate{cert},
-		RootCAs:       caCertPool,
+// p12Password returns the password of the PKCS#12 keystore. When no password was provided, it is read from the output
+// of p12_password_command if set, then from the sidecar file if p12_password_from_sidecar is enabled. The password
+// itself is never logged.
+func (c *Client) p12Password() (string, error) {
+	if !c.credData.P12Password.IsNull() {
+		return c.credData.P12Password.ValueString(), nil
+	}
+	if !c.credData.P12PasswordCommand.IsNull() {
+		password, err := runPasswordCommand(c.context, c.logger, c.credData.P12PasswordCommand.ValueString())
+		if err != nil {
+			return "", err
+		}
+		c.maskSecret(password)
+		return password, nil
+	}
+	if !c.credData.P12PasswordFromSidecar.ValueBool() {
+		return "", nil
 	}
 
-	// Create own Transport to allow HTTP1.1 connections
-	transport := &http.Transport{
-		// Only one request is made with a client
-		DisableKeepAlives: true,
-		// This is to allow for http1.1 connections
-		ForceAttemptHTTP2: false,
-		TLSClientConfig:   &tlsConfig,
+	location := c.credData.P12Certificate.ValueString() + p12PasswordSidecarSuffix
+	c.logger.Info(fmt.Sprintf("reading PKCS#12 password from sidecar file [%s]", location))
+	data, err := os.ReadFile(location)
+	if err != nil {
+		return "", fmt.Errorf("%s: unable to read PKCS#12 password file at [%s]: %w", msgVcertClientError, location, err)
 	}
 
-	//Setting Default HTTP Transport
-	http.DefaultTransport = transport
+	password := strings.TrimSpace(string(data))
+	c.maskSecret(password)
+	return password, nil
+}
 
-	tflog.Info(c.context, "TLS client configured")
-	return nil
+// maskSecret hides secret, e.g. a password the client resolved itself, from the messages it logs from now on
+func (c *Client) maskSecret(secret string) {
+	c.context = logging.MaskValues(c.context, secret)
+	if _, ok := c.logger.(contextLogger); ok {
+		c.logger = ContextLogger(c.context)
+	}
 }
 
 func (c *Client) createVCertConfig() (*vcert.Config, error) {
-	config := vcert.Config{
+	settings, err := c.connectionSettings()
+	if err != nil {
+		return nil, err
+	}
+
+	return NewVCertConfig(*settings)
+}
+
+// connectionSettings reads the connection attributes of the credential, loading the trust bundle file if any
+func (c *Client) connectionSettings() (*ConnectionSettings, error) {
+	settings := ConnectionSettings{
+		URL:           c.activeURL,
 		ConnectorType: endpoint.ConnectorTypeTPP,
-		BaseUrl:       c.credData.URL.ValueString(),
-		LogVerbose:    true,
+		// vcert logs requests and responses when verbose, which is only wanted at the debug level
+		Verbose: logging.LevelFrom(c.context) == logging.LevelDebug,
+
+		ClientCertificate:           c.clientCertificate,
+		ClientCertificateCandidates: c.clientCertificateCandidates,
+		ClientCertificatePool:       c.clientCertificatePool,
+		Context:                     c.context,
+		ServerDateObserver:          c.observeServerDate,
+		NoticeObserver:              c.observeNotice,
+		URLObserver:                 c.observeURL,
+	}
+
+	// Mutual TLS is required for every request, whatever the grant type
+	if c.credData.RequireClientCertTLS.ValueBool() && c.clientCertificate == nil && (!c.credData.P12Certificate.IsNull() || !c.credData.K8sSecretName.IsNull()) {
+		if err := c.configureTLSClient(); err != nil {
+			return nil, err
+		}
+		settings.ClientCertificate = c.clientCertificate
+		settings.ClientCertificatePool = c.clientCertificatePool
+		settings.ClientCertificateCandidates = c.clientCertificateCandidates
+	}
+
+	if !c.credData.TraceFile.IsNull() {
+		settings.TraceFile = c.credData.TraceFile.ValueString()
+	}
+
+	if !c.credData.ExpectedServerSANs.IsNull() {
+		for _, element := range c.credData.ExpectedServerSANs.Elements() {
+			if san, ok := element.(types.String); ok && !san.IsNull() && san.ValueString() != "" {
+				settings.ExpectedServerSANs = append(settings.ExpectedServerSANs, san.ValueString())
+			}
+		}
+	}
+
+	if !c.credData.MaxIdleConns.IsNull() {
+		settings.MaxIdleConns = int(c.credData.MaxIdleConns.ValueInt64())
+	}
+
+	if !c.credData.IdleConnTimeout.IsNull() {
+		settings.IdleConnTimeout = time.Duration(c.credData.IdleConnTimeout.ValueInt64()) * time.Second
+	}
+
+	if !c.credData.MaxResponseBytes.IsNull() {
+		settings.MaxResponseBytes = c.credData.MaxResponseBytes.ValueInt64()
+	}
+
+	if !c.credData.OAuthPathOverride.IsNull() {
+		settings.OAuthPath = c.credData.OAuthPathOverride.ValueString()
+	}
+
+	if !c.credData.TLSHandshakeTimeout.IsNull() {
+		settings.TLSHandshakeTimeout = time.Duration(c.credData.TLSHandshakeTimeout.ValueInt64()) * time.Second
 	}
 
 	if !c.credData.TrustBundle.IsNull() {
-		location := c.credData.TrustBundle.ValueString()
-		data, err := os.ReadFile(location)
+		trustBundle, err := LoadTrustBundle(c.credData.TrustBundle.ValueString())
+		if err != nil {
+			return nil, err
+		}
+		settings.TrustBundle = trustBundle
+	}
+	if !c.credData.TrustBundleSystemName.IsNull() {
+		trustBundle, err := LoadSystemTrustBundle(c.credData.TrustBundleSystemName.ValueString())
+		if err != nil {
+			return nil, err
+		}
+		if settings.TrustBundle != "" && !strings.HasSuffix(settings.TrustBundle, "\n") {
+			settings.TrustBundle += "\n"
+		}
+		settings.TrustBundle += trustBundle
+	}
+	if !c.credData.ProxyTrustBundle.IsNull() {
+		proxyTrustBundle, err := LoadTrustBundle(c.credData.ProxyTrustBundle.ValueString())
 		if err != nil {
-			return nil, fmt.Errorf("%s: unable to read trust bundle file at [%s]: %w", msgVcertClientError, location, err)
+			return nil, err
 		}
-		config.ConnectionTrust = string(data)
+		settings.ProxyTrustBundle = proxyTrustBundle
 	}
+	settings.AppendSystemRoots = c.credData.TrustBundleSystemRoots.ValueBool()
+	settings.InsecureSkipVerify = c.credData.InsecureSkipVerify.ValueBool()
 
-	return &config, nil
+	return &settings, nil
 }
@@ -0,0 +1,704 @@
+package vcertclient
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflogtest"
+
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/kubernetes"
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/model"
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/tpptest"
+)
+
+// recordingLogger keeps the messages of the client, prefixed with their level
+type recordingLogger struct {
+	messages []string
+}
+
+func (l *recordingLogger) Debug(msg string) { l.record("DEBUG", msg) }
+func (l *recordingLogger) Info(msg string)  { l.record("INFO", msg) }
+func (l *recordingLogger) Warn(msg string)  { l.record("WARN", msg) }
+func (l *recordingLogger) Error(msg string) { l.record("ERROR", msg) }
+
+func (l *recordingLogger) record(level, msg string) {
+	l.messages = append(l.messages, fmt.Sprintf("%s %s", level, msg))
+}
+
+// find returns the first message starting with prefix, e.g. "WARN client certificate", or an empty string
+func (l *recordingLogger) find(prefix string) string {
+	for _, message := range l.messages {
+		if strings.HasPrefix(message, prefix) {
+			return message
+		}
+	}
+	return ""
+}
+
+func TestP12PasswordSidecar(t *testing.T) {
+	const password = "sidecar-s3cr3t"
+
+	t.Run("sidecar present", func(t *testing.T) {
+		keystore := filepath.Join(t.TempDir(), "client.p12")
+		if err := os.WriteFile(keystore+p12PasswordSidecarSuffix, []byte(password+"\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		var output bytes.Buffer
+		ctx := tflogtest.RootLogger(context.Background(), &output)
+		client := New(ctx, model.CredentialResourceData{
+			P12Certificate:         types.StringValue(keystore),
+			P12PasswordFromSidecar: types.BoolValue(true),
+		})
+
+		if !client.hasP12PasswordSidecar() {
+			t.Fatal("sidecar not detected")
+		}
+		if methods := client.AuthMethods(); len(methods) != 1 || methods[0] != MethodClientCertificate {
+			t.Errorf("auth methods = %v, want [%s]", methods, MethodClientCertificate)
+		}
+		got, err := client.p12Password()
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if got != password {
+			t.Errorf("password = %q, want %q", got, password)
+		}
+
+		// The resolved password is masked from whatever the client logs afterwards, e.g. an error echoing it
+		client.logger.Error("keystore rejected with password " + password)
+		if strings.Contains(output.String(), password) {
+			t.Errorf("password found in the logs: %s", output.String())
+		}
+		if !strings.Contains(output.String(), "keystore rejected with password") {
+			t.Errorf("message missing from the logs: %s", output.String())
+		}
+	})
+
+	t.Run("sidecar absent", func(t *testing.T) {
+		keystore := filepath.Join(t.TempDir(), "client.p12")
+		client := New(context.Background(), model.CredentialResourceData{
+			P12Certificate:         types.StringValue(keystore),
+			P12PasswordFromSidecar: types.BoolValue(true),
+		})
+
+		if client.hasP12PasswordSidecar() {
+			t.Fatal("sidecar detected")
+		}
+		if methods := client.AuthMethods(); len(methods) != 0 {
+			t.Errorf("auth methods = %v, want none", methods)
+		}
+		if _, err := client.p12Password(); err == nil || !strings.Contains(err.Error(), keystore+p12PasswordSidecarSuffix) {
+			t.Errorf("error = %v, want the sidecar location", err)
+		}
+	})
+
+	t.Run("sidecar disabled", func(t *testing.T) {
+		keystore := filepath.Join(t.TempDir(), "client.p12")
+		if err := os.WriteFile(keystore+p12PasswordSidecarSuffix, []byte(password), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		client := New(context.Background(), model.CredentialResourceData{P12Certificate: types.StringValue(keystore)})
+
+		if client.hasP12PasswordSidecar() {
+			t.Fatal("sidecar used although p12_password_from_sidecar is not set")
+		}
+		if got, err := client.p12Password(); err != nil || got != "" {
+			t.Errorf("password = %q, %v, want an empty password", got, err)
+		}
+	})
+}
+
+func TestClientCertificateExpiration(t *testing.T) {
+	server := tpptest.NewServer(t)
+	ca := tpptest.NewCA(t, "Client CA")
+	credential := func(cert tls.Certificate) model.CredentialResourceData {
+		return model.CredentialResourceData{
+			URL:            types.StringValue(server.URL),
+			TrustBundle:    types.StringValue(server.TrustBundle()),
+			P12Certificate: types.StringValue(tpptest.PKCS12File(t, cert, nil, "secret")),
+			P12Password:    types.StringValue("secret"),
+		}
+	}
+
+	t.Run("close to expiring", func(t *testing.T) {
+		cert := ca.Issue(t, "client", tpptest.CertificateOptions{NotAfter: time.Now().Add(10 * 24 * time.Hour)})
+		logger := &recordingLogger{}
+		client := NewWithLogger(context.Background(), credential(cert), logger)
+
+		if _, err := client.RequestNewTokenPair(); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if !client.ClientCertificateExpiration().Equal(cert.Leaf.NotAfter) {
+			t.Errorf("expiration = %s, want %s", client.ClientCertificateExpiration(), cert.Leaf.NotAfter)
+		}
+		if logger.find("WARN client certificate [CN=client] expires on") == "" {
+			t.Errorf("no expiration warning in %v", logger.messages)
+		}
+	})
+
+	t.Run("far from expiring", func(t *testing.T) {
+		logger := &recordingLogger{}
+		client := NewWithLogger(context.Background(), credential(ca.Issue(t, "client", tpptest.CertificateOptions{})), logger)
+
+		if _, err := client.RequestNewTokenPair(); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if message := logger.find("WARN client certificate"); message != "" {
+			t.Errorf("unexpected warning: %s", message)
+		}
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		cert := ca.Issue(t, "client", tpptest.CertificateOptions{NotAfter: time.Now().Add(-time.Minute)})
+		client := New(context.Background(), credential(cert))
+		requests := server.Requests(tpptest.PathAuthorizeCertificate)
+
+		if _, err := client.RequestNewTokenPair(); !errors.Is(err, ErrClientCertificateExpired) {
+			t.Errorf("error = %v, want an expired client certificate", err)
+		}
+		if server.Requests(tpptest.PathAuthorizeCertificate) != requests {
+			t.Error("expired client certificate sent to TLSPDC")
+		}
+	})
+}
+
+func TestMaxTotalAttempts(t *testing.T) {
+	// every method of the authentication ladder is configured
+	credential := func(t *testing.T, url string, trustBundle string, maxTotalAttempts int64) model.CredentialResourceData {
+		ca := tpptest.NewCA(t, "Client CA")
+		return model.CredentialResourceData{
+			URL:              types.StringValue(url),
+			TrustBundle:      types.StringValue(trustBundle),
+			RefreshToken:     types.StringValue("refresh"),
+			P12Certificate:   types.StringValue(tpptest.PKCS12File(t, ca.Issue(t, "client", tpptest.CertificateOptions{}), nil, "secret")),
+			P12Password:      types.StringValue("secret"),
+			Username:         types.StringValue("tppadmin"),
+			Password:         types.StringValue("password"),
+			MaxTotalAttempts: types.Int64Value(maxTotalAttempts),
+		}
+	}
+
+	t.Run("unreachable", func(t *testing.T) {
+		// Connection errors are retried, within the budget
+		server := tpptest.NewServer(t)
+		client := New(context.Background(), credential(t, tpptest.UnreachableURL(t), server.TrustBundle(), 5))
+
+		if _, err := client.RequestNewTokenPair(); err == nil {
+			t.Fatal("token pair retrieved from an unreachable TLSPDC")
+		}
+		attempts := client.AuthAttempts()
+		if len(attempts) != 5 {
+			t.Fatalf("%d attempt(s), want 5: %+v", len(attempts), attempts)
+		}
+		tried := make(map[string]bool)
+		for _, attempt := range attempts {
+			tried[attempt.Method] = true
+		}
+		for _, method := range []string{MethodRefreshToken, MethodClientCertificate, MethodUsernamePassword} {
+			if !tried[method] {
+				t.Errorf("%s not tried", method)
+			}
+		}
+	})
+
+	t.Run("rejected", func(t *testing.T) {
+		server := tpptest.NewServer(t)
+		paths := []string{tpptest.PathRefreshToken, tpptest.PathAuthorizeCertificate, tpptest.PathAuthorizeOAuth}
+		for _, path := range paths {
+			server.Handle(path, func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusBadRequest)
+			})
+		}
+		client := New(context.Background(), credential(t, server.URL, server.TrustBundle(), 2))
+
+		_, err := client.RequestNewTokenPair()
+		if err == nil || !strings.Contains(err.Error(), "max_total_attempts (2) exhausted before trying "+MethodUsernamePassword) {
+			t.Errorf("error = %v, want the budget exhausted", err)
+		}
+		requests := 0
+		for _, path := range paths {
+			requests += server.Requests(path)
+		}
+		if requests != 2 {
+			t.Errorf("%d request(s) sent to TLSPDC, want 2", requests)
+		}
+	})
+}
+
+func TestRetryBudgets(t *testing.T) {
+	// retries counts the messages of logger announcing a retry of operation
+	retries := func(logger *recordingLogger, operation string) int {
+		count := 0
+		for _, message := range logger.messages {
+			if strings.HasPrefix(message, "WARN retrying "+operation+" after connection error") {
+				count++
+			}
+		}
+		return count
+	}
+
+	t.Run("verify", func(t *testing.T) {
+		tests := []struct {
+			name       string
+			maxRetries types.Int64
+			want       int
+		}{
+			{"default", types.Int64Null(), 0},
+			{"negative", types.Int64Value(-1), 0},
+			{"set", types.Int64Value(2), 2},
+		}
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				logger := &recordingLogger{}
+				client := NewWithLogger(context.Background(), model.CredentialResourceData{
+					URL:              types.StringValue(tpptest.UnreachableURL(t)),
+					AccessToken:      types.StringValue("access"),
+					VerifyMaxRetries: test.maxRetries,
+				}, logger)
+
+				if validity, err := client.VerifyToken(); validity != TokenUnknown || err != nil {
+					t.Fatalf("validity = %v, %v, want unknown", validity, err)
+				}
+				if got := retries(logger, "token verification"); got != test.want {
+					t.Errorf("%d retry(ies), want %d", got, test.want)
+				}
+			})
+		}
+	})
+
+	t.Run("rotate", func(t *testing.T) {
+		tests := []struct {
+			name       string
+			maxRetries types.Int64
+			// want is the number of attempts, within max_total_attempts
+			want int
+		}{
+			{"default", types.Int64Null(), 5},
+			{"none", types.Int64Value(0), 1},
+			{"set", types.Int64Value(2), 3},
+		}
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				client := New(context.Background(), model.CredentialResourceData{
+					URL:              types.StringValue(tpptest.UnreachableURL(t)),
+					Username:         types.StringValue("tppadmin"),
+					Password:         types.StringValue("password"),
+					RotateMaxRetries: test.maxRetries,
+					MaxTotalAttempts: types.Int64Value(5),
+				})
+
+				if _, err := client.RequestNewTokenPair(); err == nil {
+					t.Fatal("token pair retrieved from an unreachable TLSPDC")
+				}
+				if attempts := client.AuthAttempts(); len(attempts) != test.want {
+					t.Errorf("%d attempt(s), want %d: %+v", len(attempts), test.want, attempts)
+				}
+			})
+		}
+	})
+
+	t.Run("rotate retry allowed", func(t *testing.T) {
+		// Only the requests that never reached TLSPDC are sent again, since a token may have been granted otherwise
+		tests := []struct {
+			name    string
+			retries int64
+			err     error
+			want    bool
+		}{
+			{"refused", 0, &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, true},
+			{"DNS", 0, &net.DNSError{Err: "no such host", Name: "tpp.venafi.example"}, true},
+			{"response timeout", 0, &net.OpError{Op: "read", Net: "tcp", Err: os.ErrDeadlineExceeded}, false},
+			{"budget spent", 2, &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, false},
+		}
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				client := NewWithLogger(context.Background(), model.CredentialResourceData{RotateMaxRetries: types.Int64Value(2)}, &recordingLogger{})
+				if got := client.rotateRetryAllowed(test.retries, test.err); got != test.want {
+					t.Errorf("retry allowed = %t, want %t", got, test.want)
+				}
+			})
+		}
+	})
+}
+
+func TestRevokeExpiredAccessToken(t *testing.T) {
+	server := tpptest.NewServer(t)
+	credential := func(grant tpptest.Grant) model.CredentialResourceData {
+		return model.CredentialResourceData{
+			URL:          types.StringValue(server.URL),
+			TrustBundle:  types.StringValue(server.TrustBundle()),
+			AccessToken:  types.StringValue(grant.AccessToken),
+			RefreshToken: types.StringValue(grant.RefreshToken),
+		}
+	}
+
+	t.Run("refresh token valid", func(t *testing.T) {
+		grant := server.IssueGrant(DefaultScope)
+		server.Expire(grant.AccessToken)
+
+		if err := New(context.Background(), credential(grant)).RevokeToken(); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		revoked := server.Grants()[grant.ID-1]
+		if !revoked.Revoked || revoked.Refreshed != 1 {
+			t.Errorf("grant = %+v, want it refreshed then revoked", revoked)
+		}
+	})
+
+	t.Run("refresh token rejected", func(t *testing.T) {
+		// Revoking is still attempted, with the expired access token
+		grant := server.IssueGrant(DefaultScope)
+		server.Expire(grant.AccessToken)
+		data := credential(grant)
+		data.RefreshToken = types.StringValue("unknown")
+
+		if err := New(context.Background(), data).RevokeToken(); err == nil {
+			t.Error("expired access token revoked")
+		}
+		if server.Requests(tpptest.PathRevokeToken) == 0 {
+			t.Error("revocation not attempted")
+		}
+	})
+}
+
+func TestFallbackWarning(t *testing.T) {
+	server := tpptest.NewServer(t)
+	credential := model.CredentialResourceData{
+		URL:          types.StringValue(server.URL),
+		TrustBundle:  types.StringValue(server.TrustBundle()),
+		RefreshToken: types.StringValue("expired"),
+		Username:     types.StringValue(server.Username),
+		Password:     types.StringValue(server.Password),
+	}
+
+	t.Run("fallback", func(t *testing.T) {
+		resp, err := New(context.Background(), credential).RequestNewTokenPair()
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if !resp.UsedFallback || !strings.HasPrefix(resp.Warning, MethodRefreshToken+" failed: ") ||
+			!strings.HasSuffix(resp.Warning, ", used "+MethodUsernamePassword+" instead") {
+			t.Errorf("warning = %q, want the failure of the refresh token", resp.Warning)
+		}
+	})
+
+	t.Run("no fallback", func(t *testing.T) {
+		data := credential
+		data.RefreshToken = types.StringNull()
+
+		resp, err := New(context.Background(), data).RequestNewTokenPair()
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if resp.UsedFallback || resp.Warning != "" {
+			t.Errorf("warning = %q, want none", resp.Warning)
+		}
+	})
+}
+
+func TestAuthAttempts(t *testing.T) {
+	server := tpptest.NewServer(t)
+	credential := model.CredentialResourceData{
+		URL:          types.StringValue(server.URL),
+		TrustBundle:  types.StringValue(server.TrustBundle()),
+		RefreshToken: types.StringValue("expired"),
+		Username:     types.StringValue(server.Username),
+		Password:     types.StringValue(server.Password),
+	}
+
+	t.Run("fallback", func(t *testing.T) {
+		client := New(context.Background(), credential)
+		if _, err := client.RequestNewTokenPair(); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		attempts := client.AuthAttempts()
+		if len(attempts) != 2 {
+			t.Fatalf("attempts = %+v, want the refresh token then the username/password", attempts)
+		}
+		if attempts[0].Method != MethodRefreshToken || attempts[0].Succeeded || attempts[0].Error == "" {
+			t.Errorf("first attempt = %+v, want the refresh token failing", attempts[0])
+		}
+		if attempts[1] != (AuthAttempt{Method: MethodUsernamePassword, Succeeded: true}) {
+			t.Errorf("second attempt = %+v, want the username/password succeeding", attempts[1])
+		}
+	})
+
+	t.Run("all failing", func(t *testing.T) {
+		data := credential
+		data.Password = types.StringValue("wrong")
+		client := New(context.Background(), data)
+		if _, err := client.RequestNewTokenPair(); err == nil {
+			t.Fatal("no error, want the ladder to fail")
+		}
+		var methods []string
+		for _, attempt := range client.AuthAttempts() {
+			if attempt.Succeeded || attempt.Error == "" {
+				t.Errorf("attempt = %+v, want a failure", attempt)
+			}
+			methods = append(methods, attempt.Method)
+		}
+		if want := MethodRefreshToken + " " + MethodUsernamePassword; strings.Join(methods, " ") != want {
+			t.Errorf("methods = %v, want %s", methods, want)
+		}
+	})
+
+	t.Run("scrubbed", func(t *testing.T) {
+		data := credential
+		data.RefreshToken = types.StringValue("s3cr3t-refresh")
+		data.Password = types.StringValue("s3cr3t-password")
+		client := New(context.Background(), data)
+		client.recordAttempt(MethodRefreshToken, errors.New("refresh token s3cr3t-refresh rejected for password s3cr3t-password"))
+
+		if got := client.AuthAttempts()[0].Error; got != "refresh token *** rejected for password ***" {
+			t.Errorf("error = %q, want the credentials masked", got)
+		}
+	})
+}
+
+func TestVerifyTokenRevoked(t *testing.T) {
+	server := tpptest.NewServer(t)
+	tests := []struct {
+		name string
+		// prepare alters the grant and returns the expiration date known for its access token
+		prepare func(grant tpptest.Grant) int64
+		url     string
+		want    TokenValidity
+	}{
+		{"valid", func(grant tpptest.Grant) int64 { return grant.ExpiresAt.Unix() }, server.URL, TokenValid},
+		{"revoked out-of-band", func(grant tpptest.Grant) int64 {
+			server.Revoke(grant.AccessToken)
+			return grant.ExpiresAt.Unix()
+		}, server.URL, TokenRevoked},
+		{"revoked, never expiring", func(grant tpptest.Grant) int64 {
+			server.Revoke(grant.AccessToken)
+			return 0
+		}, server.URL, TokenRevoked},
+		{"expired", func(grant tpptest.Grant) int64 {
+			server.Expire(grant.AccessToken)
+			return time.Now().Add(-time.Second).Unix()
+		}, server.URL, TokenInvalid},
+		{"rejected, expiration unknown", func(grant tpptest.Grant) int64 {
+			server.Revoke(grant.AccessToken)
+			return -1
+		}, server.URL, TokenInvalid},
+		{"unreachable", func(grant tpptest.Grant) int64 { return grant.ExpiresAt.Unix() }, tpptest.UnreachableURL(t), TokenUnknown},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			grant := server.IssueGrant(DefaultScope)
+			data := model.CredentialResourceData{
+				URL:              types.StringValue(test.url),
+				TrustBundle:      types.StringValue(server.TrustBundle()),
+				AccessToken:      types.StringValue(grant.AccessToken),
+				ExpirationDate:   types.Int64Null(),
+				VerifyMaxRetries: types.Int64Value(0),
+			}
+			if expiration := test.prepare(grant); expiration >= 0 {
+				data.ExpirationDate = types.Int64Value(expiration)
+			}
+
+			validity, err := New(context.Background(), data).VerifyToken()
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if validity != test.want {
+				t.Errorf("validity = %d, want %d", validity, test.want)
+			}
+		})
+	}
+}
+
+func TestRotationPolicy(t *testing.T) {
+	server := tpptest.NewServer(t)
+
+	tests := []struct {
+		name        string
+		policy      types.String
+		primary     bool
+		wantMethods []string
+		wantRefresh bool
+		wantErr     error
+	}{
+		{"default", types.StringNull(), true, []string{MethodRefreshToken, MethodUsernamePassword}, true, nil},
+		{"prefer_refresh", types.StringValue(RotationPolicyPreferRefresh), true, []string{MethodRefreshToken, MethodUsernamePassword}, true, nil},
+		{"always_primary", types.StringValue(RotationPolicyAlwaysPrimary), true, []string{MethodUsernamePassword}, false, nil},
+		{"always_primary without primary credential", types.StringValue(RotationPolicyAlwaysPrimary), false, nil, false, ErrNoAuthMethod},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			grant := server.IssueGrant(DefaultScope)
+			refreshes := server.Requests(tpptest.PathRefreshToken)
+			data := model.CredentialResourceData{
+				URL:            types.StringValue(server.URL),
+				TrustBundle:    types.StringValue(server.TrustBundle()),
+				RefreshToken:   types.StringValue(grant.RefreshToken),
+				RotationPolicy: test.policy,
+			}
+			if test.primary {
+				data.Username = types.StringValue(server.Username)
+				data.Password = types.StringValue(server.Password)
+			}
+			client := New(context.Background(), data)
+			if methods := client.AuthMethods(); strings.Join(methods, ",") != strings.Join(test.wantMethods, ",") {
+				t.Errorf("auth methods = %v, want %v", methods, test.wantMethods)
+			}
+
+			resp, err := client.RequestNewTokenPair()
+			if test.wantErr != nil {
+				if !errors.Is(err, test.wantErr) {
+					t.Errorf("error = %v, want %v", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if refreshed := server.Requests(tpptest.PathRefreshToken) > refreshes; refreshed != test.wantRefresh {
+				t.Errorf("refresh token used = %t, want %t", refreshed, test.wantRefresh)
+			}
+			if current, _ := server.GrantOf(resp.AccessToken); (current.ID == grant.ID) != test.wantRefresh {
+				t.Errorf("token pair of grant %d, refreshed grant %d, want a refresh = %t", current.ID, grant.ID, test.wantRefresh)
+			}
+		})
+	}
+}
+
+func TestConfirmRevocation(t *testing.T) {
+	delay := revocationCheckDelay
+	revocationCheckDelay = 10 * time.Millisecond
+	t.Cleanup(func() { revocationCheckDelay = delay })
+
+	tests := []struct {
+		name string
+		// accepted is how many checks still accept the access token once revoked
+		accepted   int
+		want       bool
+		wantChecks int
+	}{
+		{"confirmed right away", 0, true, 1},
+		{"confirmed on the second check", 1, true, 2},
+		{"still accepted", revocationChecks, false, revocationChecks},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := tpptest.NewServer(t)
+			grant := server.IssueGrant(DefaultScope)
+			// TLSPDC processes the revocation asynchronously
+			server.Handle(tpptest.PathRevokeToken, func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+			server.Handle(tpptest.PathVerifyToken, func(w http.ResponseWriter, _ *http.Request) {
+				if server.Requests(tpptest.PathVerifyToken) > test.accepted {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"application":              grant.ClientID,
+					"access_issued_on_ISO8601": grant.IssuedAt.UTC().Format(time.RFC3339),
+					"expires_ISO8601":          grant.ExpiresAt.UTC().Format(time.RFC3339),
+					"identity":                 server.Identity,
+					"scope":                    grant.Scope,
+					"valid_for":                int(time.Until(grant.ExpiresAt).Seconds()),
+				})
+			})
+
+			logger := &recordingLogger{}
+			client := NewWithLogger(context.Background(), model.CredentialResourceData{
+				URL:         types.StringValue(server.URL),
+				TrustBundle: types.StringValue(server.TrustBundle()),
+				AccessToken: types.StringValue(grant.AccessToken),
+			}, logger)
+			if err := client.RevokeToken(); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if got := client.ConfirmRevocation(); got != test.want {
+				t.Errorf("confirmed = %t, want %t", got, test.want)
+			}
+			if checks := server.Requests(tpptest.PathVerifyToken); checks != test.wantChecks {
+				t.Errorf("%d check(s), want %d", checks, test.wantChecks)
+			}
+			if !test.want && logger.find("WARN revoked access token still accepted by TLSPDC, check 3 of 3") == "" {
+				t.Errorf("no warning in %v", logger.messages)
+			}
+		})
+	}
+}
+
+func TestBlankCredentials(t *testing.T) {
+	server := tpptest.NewServer(t)
+	credential := func(username, password, refreshToken string) model.CredentialResourceData {
+		return model.CredentialResourceData{
+			URL:          types.StringValue(server.URL),
+			TrustBundle:  types.StringValue(server.TrustBundle()),
+			Username:     types.StringValue(username),
+			Password:     types.StringValue(password),
+			RefreshToken: types.StringValue(refreshToken),
+		}
+	}
+
+	tests := []struct {
+		name        string
+		data        model.CredentialResourceData
+		wantMethods []string
+	}{
+		{"blank password", credential(server.Username, "", ""), nil},
+		{"blank username", credential("", server.Password, ""), nil},
+		{"blank refresh token", credential(server.Username, server.Password, ""), []string{MethodUsernamePassword}},
+		{"all set", credential(server.Username, server.Password, "refresh"), []string{MethodRefreshToken, MethodUsernamePassword}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := New(context.Background(), test.data)
+			if methods := client.AuthMethods(); strings.Join(methods, ",") != strings.Join(test.wantMethods, ",") {
+				t.Errorf("methods = %v, want %v", methods, test.wantMethods)
+			}
+		})
+	}
+
+	t.Run("blank password not submitted", func(t *testing.T) {
+		requests := server.Requests(tpptest.PathAuthorizeOAuth)
+		if _, err := New(context.Background(), credential(server.Username, "", "")).RequestNewTokenPair(); err == nil {
+			t.Error("token pair retrieved with a blank password")
+		}
+		if server.Requests(tpptest.PathAuthorizeOAuth) != requests {
+			t.Error("blank password sent to TLSPDC")
+		}
+	})
+}
+
+func TestKubernetesSecretOutsideCluster(t *testing.T) {
+	t.Setenv("KUBERNETES_SERVICE_HOST", "")
+	t.Setenv("KUBERNETES_SERVICE_PORT", "")
+	server := tpptest.NewServer(t)
+	client := New(context.Background(), model.CredentialResourceData{
+		URL:                types.StringValue(server.URL),
+		TrustBundle:        types.StringValue(server.TrustBundle()),
+		K8sSecretName:      types.StringValue("client-keystore"),
+		K8sSecretNamespace: types.StringValue("venafi"),
+	})
+
+	if methods := client.AuthMethods(); len(methods) != 1 || methods[0] != MethodClientCertificate {
+		t.Errorf("methods = %v, want %s", methods, MethodClientCertificate)
+	}
+	_, err := client.RequestNewTokenPair()
+	if !errors.Is(err, kubernetes.ErrNotInCluster) || !strings.Contains(err.Error(), "k8s_secret_name is set") {
+		t.Errorf("error = %v, want %v", err, kubernetes.ErrNotInCluster)
+	}
+	if server.Requests(tpptest.PathAuthorizeCertificate) != 0 {
+		t.Error("request sent to TLSPDC without a client certificate")
+	}
+}
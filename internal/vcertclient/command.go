@@ -0,0 +1,61 @@
+package vcertclient
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// passwordCommandTimeout bounds the execution of p12_password_command
+const passwordCommandTimeout = 30 * time.Second
+
+// passwordCommandEnv lists the environment variables passed down to p12_password_command. Everything else, the
+// credentials of the provider included, is left out.
+var passwordCommandEnv = []string{"PATH", "HOME", "USER", "LOGNAME", "LANG", "TMPDIR", "SYSTEMROOT", "APPDATA", "USERPROFILE"}
+
+// runPasswordCommand runs command and returns its trimmed standard output. The command line is split on whitespace and
+// run without a shell. Neither its output nor its error stream is ever logged.
+func runPasswordCommand(ctx context.Context, logger Logger, command string) (string, error) {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return "", fmt.Errorf("%s: PKCS#12 password command is empty", msgVcertClientError)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, passwordCommandTimeout)
+	defer cancel()
+
+	logger.Info(fmt.Sprintf("running PKCS#12 password command [%s]", args[0]))
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Env = sanitizedEnv()
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+
+	err := cmd.Run()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return "", fmt.Errorf("%s: PKCS#12 password command [%s] did not complete within %s", msgVcertClientError, args[0], passwordCommandTimeout)
+	}
+	if err != nil {
+		return "", fmt.Errorf("%s: PKCS#12 password command [%s] failed: %w", msgVcertClientError, args[0], err)
+	}
+
+	password := strings.TrimSpace(stdout.String())
+	if password == "" {
+		return "", fmt.Errorf("%s: PKCS#12 password command [%s] returned an empty password", msgVcertClientError, args[0])
+	}
+	return password, nil
+}
+
+func sanitizedEnv() []string {
+	var env []string
+	for _, name := range passwordCommandEnv {
+		if value, ok := os.LookupEnv(name); ok {
+			env = append(env, name+"="+value)
+		}
+	}
+	return env
+}
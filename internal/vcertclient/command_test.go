@@ -0,0 +1,106 @@
+package vcertclient
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflogtest"
+
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/model"
+)
+
+// passwordCommand writes a shell script with the given body and returns its location
+func passwordCommand(t *testing.T, body string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("password commands are shell scripts")
+	}
+
+	location := filepath.Join(t.TempDir(), "password-helper")
+	if err := os.WriteFile(location, []byte("#!/bin/sh\n"+body+"\n"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	return location
+}
+
+func TestRunPasswordCommand(t *testing.T) {
+	t.Run("password echoed", func(t *testing.T) {
+		command := passwordCommand(t, `echo "  $1-s3cr3t  "`)
+
+		password, err := runPasswordCommand(context.Background(), &recordingLogger{}, command+" keystore")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if password != "keystore-s3cr3t" {
+			t.Errorf("password = %q, want the trimmed output", password)
+		}
+	})
+
+	t.Run("environment sanitized", func(t *testing.T) {
+		t.Setenv("VCERT_PASSWORD", "provider-password")
+		command := passwordCommand(t, `echo "password:${VCERT_PASSWORD}"`)
+
+		password, err := runPasswordCommand(context.Background(), &recordingLogger{}, command)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if password != "password:" {
+			t.Errorf("password = %q, want the credentials of the provider left out of the environment", password)
+		}
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		command := passwordCommand(t, `echo "s3cr3t"; echo "s3cr3t on stderr" >&2; exit 3`)
+
+		_, err := runPasswordCommand(context.Background(), &recordingLogger{}, command)
+		if err == nil || !strings.Contains(err.Error(), "failed: exit status 3") {
+			t.Errorf("error = %v, want the exit status", err)
+		}
+		if err != nil && strings.Contains(err.Error(), "s3cr3t") {
+			t.Errorf("error = %s, want the output left out", err)
+		}
+	})
+
+	t.Run("empty password", func(t *testing.T) {
+		command := passwordCommand(t, `echo "   "`)
+
+		if _, err := runPasswordCommand(context.Background(), &recordingLogger{}, command); err == nil ||
+			!strings.Contains(err.Error(), "returned an empty password") {
+			t.Errorf("error = %v, want the empty password rejected", err)
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		command := passwordCommand(t, `exec sleep 10`)
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		if _, err := runPasswordCommand(ctx, &recordingLogger{}, command); err == nil || !strings.Contains(err.Error(), "did not complete") {
+			t.Errorf("error = %v, want the command timed out", err)
+		}
+	})
+
+	t.Run("password masked", func(t *testing.T) {
+		command := passwordCommand(t, `echo "command-s3cr3t"`)
+		var output bytes.Buffer
+		client := New(tflogtest.RootLogger(context.Background(), &output), model.CredentialResourceData{
+			P12Certificate:     types.StringValue(filepath.Join(t.TempDir(), "client.p12")),
+			P12PasswordCommand: types.StringValue(command),
+		})
+
+		if password, err := client.p12Password(); err != nil || password != "command-s3cr3t" {
+			t.Fatalf("password = %q, %v, want the output of the command", password, err)
+		}
+		client.logger.Error("keystore rejected with password command-s3cr3t")
+		if strings.Contains(output.String(), "command-s3cr3t") {
+			t.Errorf("password found in the logs: %s", output.String())
+		}
+	})
+}
@@ -0,0 +1,84 @@
+package vcertclient
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"time"
+
+	"github.com/Venafi/vcert/v5"
+	"github.com/Venafi/vcert/v5/pkg/endpoint"
+)
+
+// ConnectionSettings holds everything required to build a vcert configuration, independently of where the values
+// were read from
+type ConnectionSettings struct {
+	URL           string
+	ConnectorType endpoint.ConnectorType
+	// TrustBundle is the PEM content of the trust bundle, not its location
+	TrustBundle string
+	Verbose     bool
+
+	// TLSHandshakeTimeout bounds the TLS negotiation with TLSPDC. defaultTLSHandshakeTimeout is used when zero
+	TLSHandshakeTimeout time.Duration
+	// ClientCertificate is presented to TLSPDC during the TLS handshake when set
+	ClientCertificate *tls.Certificate
+	// ClientCertificateCandidates are presented instead of ClientCertificate when TLSPDC only accepts certificates
+	// issued by their CA. Go picks the first acceptable certificate during the handshake.
+	ClientCertificateCandidates []tls.Certificate
+	// ClientCertificatePool is used as trust anchors when no trust bundle is set
+	ClientCertificatePool *x509.CertPool
+	// OAuthPath replaces the standard /vedauth base path of the OAuth endpoints when set
+	OAuthPath string
+	// Context bounds the time spent waiting for a request slot to TLSPDC. context.Background is used when nil
+	Context context.Context
+	// ServerDateObserver is called with the Date header of every TLSPDC response, when set
+	ServerDateObserver func(date time.Time)
+	// URLObserver is called with the base URL of every request answered by TLSPDC, as actually sent, when set
+	URLObserver func(url string)
+	// NoticeObserver is called with every non-fatal notice returned by TLSPDC, when set
+	NoticeObserver func(notice string)
+	// MaxResponseBytes caps the size of the response bodies. DefaultMaxResponseBytes is used when zero
+	MaxResponseBytes int64
+	// TraceFile is the file the requests are traced to, when set
+	TraceFile string
+	// ExpectedServerSANs fails the TLS handshake when none of the SANs of the TLSPDC certificate is listed, when set
+	ExpectedServerSANs []string
+	// MaxIdleConns enables keep-alives, keeping up to that many idle connections to TLSPDC for the next operations.
+	// Connections are closed after each request when zero
+	MaxIdleConns int
+	// IdleConnTimeout is how long an idle connection is kept open. DefaultIdleConnTimeout is used when zero
+	IdleConnTimeout time.Duration
+	// AppendSystemRoots trusts the system roots in addition to TrustBundle
+	AppendSystemRoots bool
+	// InsecureSkipVerify disables the verification of the TLSPDC certificate. ExpectedServerSANs is still checked
+	InsecureSkipVerify bool
+	// ProxyTrustBundle is the PEM content of the CA certificates of a TLS-intercepting proxy, trusted in addition to
+	// TrustBundle, or to the system roots, when the requests to URL go through a proxy
+	ProxyTrustBundle string
+
+	// ipLiteralHost is the bracketed IPv6 host, and port, the requests are sent to when URL holds an IPv6 literal
+	ipLiteralHost string
+}
+
+// NewVCertConfig builds a vcert configuration out of the given connection settings
+func NewVCertConfig(settings ConnectionSettings) (*vcert.Config, error) {
+	baseURL := settings.URL
+	if vcertURL, host, ok := splitIPv6URL(settings.URL); ok {
+		baseURL = vcertURL
+		settings.ipLiteralHost = host
+	}
+
+	httpClient, err := newHTTPClient(settings)
+	if err != nil {
+		return nil, err
+	}
+
+	return &vcert.Config{
+		ConnectorType:   settings.ConnectorType,
+		BaseUrl:         baseURL,
+		ConnectionTrust: settings.TrustBundle,
+		LogVerbose:      settings.Verbose,
+		Client:          httpClient,
+	}, nil
+}
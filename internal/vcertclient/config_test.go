@@ -0,0 +1,88 @@
+package vcertclient
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Venafi/vcert/v5/pkg/endpoint"
+
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/tpptest"
+)
+
+func TestNewVCertConfig(t *testing.T) {
+	ca := tpptest.NewCA(t, "Test CA")
+
+	t.Run("settings", func(t *testing.T) {
+		config, err := NewVCertConfig(ConnectionSettings{
+			URL:           "https://tpp.venafi.example/vedsdk",
+			ConnectorType: endpoint.ConnectorTypeTPP,
+			TrustBundle:   ca.PEM,
+			Verbose:       true,
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if config.BaseUrl != "https://tpp.venafi.example/vedsdk" {
+			t.Errorf("base url = %q", config.BaseUrl)
+		}
+		if config.ConnectorType != endpoint.ConnectorTypeTPP {
+			t.Errorf("connector type = %v", config.ConnectorType)
+		}
+		if config.ConnectionTrust != ca.PEM {
+			t.Errorf("connection trust = %q, want the trust bundle", config.ConnectionTrust)
+		}
+		if !config.LogVerbose {
+			t.Error("verbose not set")
+		}
+		if config.Client == nil {
+			t.Error("no HTTP client")
+		}
+	})
+
+	t.Run("trust bundle", func(t *testing.T) {
+		server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {}))
+		server.TLS = &tls.Config{Certificates: []tls.Certificate{ca.Issue(t, "tpp", tpptest.CertificateOptions{Hosts: []string{"127.0.0.1"}})}}
+		server.StartTLS()
+		defer server.Close()
+
+		trusted, err := NewVCertConfig(ConnectionSettings{URL: server.URL, TrustBundle: ca.PEM})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		resp, err := trusted.Client.Get(server.URL)
+		if err != nil {
+			t.Fatalf("server not trusted with the trust bundle: %s", err)
+		}
+		resp.Body.Close()
+
+		untrusted, err := NewVCertConfig(ConnectionSettings{URL: server.URL, TrustBundle: tpptest.NewCA(t, "Other CA").PEM})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if resp, err = untrusted.Client.Get(server.URL); err == nil {
+			resp.Body.Close()
+			t.Fatal("server trusted with the trust bundle of another CA")
+		}
+	})
+
+	t.Run("invalid trust bundle", func(t *testing.T) {
+		truncated := ca.PEM[:len(ca.PEM)/2]
+		_, err := NewVCertConfig(ConnectionSettings{URL: "https://tpp.venafi.example", TrustBundle: truncated})
+		if err == nil || !strings.Contains(err.Error(), "invalid trust bundle") {
+			t.Errorf("error = %v, want an invalid trust bundle", err)
+		}
+	})
+
+	t.Run("IPv6 literal", func(t *testing.T) {
+		config, err := NewVCertConfig(ConnectionSettings{URL: "https://[2001:db8::1]:8443/vedsdk"})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if want := "https://" + ipLiteralPlaceholderHost + ":8443/vedsdk"; config.BaseUrl != want {
+			t.Errorf("base url = %q, want %q", config.BaseUrl, want)
+		}
+	})
+}
@@ -0,0 +1,105 @@
+package vcertclient
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/Venafi/vcert/v5"
+	"github.com/Venafi/vcert/v5/pkg/venafi/tpp"
+)
+
+// connectorError is returned when the vcert connector cannot be built out of the credential attributes. It is never
+// the result of talking to TLSPDC.
+type connectorError struct {
+	err error
+}
+
+func (e *connectorError) Error() string {
+	return e.err.Error()
+}
+
+func (e *connectorError) Unwrap() error {
+	return e.err
+}
+
+// newConnector builds a TLSPDC connector for the active URL of the client
+func (c *Client) newConnector() (*tpp.Connector, error) {
+	config, err := c.createVCertConfig()
+	if err != nil {
+		return nil, &connectorError{err: err}
+	}
+
+	vClient, err := vcert.NewClient(config, false)
+	if err != nil {
+		return nil, &connectorError{err: err}
+	}
+
+	return vClient.(*tpp.Connector), nil
+}
+
+// withFailover runs operation against url. When it fails with a connection error and a fallback_url is set, the same
+// operation is run again against fallback_url, with the same credentials and trust bundle. Authentication errors never
+// trigger a failover.
+func (c *Client) withFailover(operation func(connector *tpp.Connector) error) error {
+	c.activeURL = c.credData.URL.ValueString()
+	err := c.runOperation(operation)
+	if err == nil || c.credData.FallbackURL.IsNull() || !isConnectionError(err) {
+		return err
+	}
+
+	c.logger.Warn(fmt.Sprintf("unable to reach [%s], retrying with fallback url [%s]: %s", c.activeURL, c.credData.FallbackURL.ValueString(), err.Error()))
+	c.activeURL = c.credData.FallbackURL.ValueString()
+	return c.runOperation(operation)
+}
+
+func (c *Client) runOperation(operation func(connector *tpp.Connector) error) error {
+	connector, err := c.newConnector()
+	if err != nil {
+		return err
+	}
+	err = operation(connector)
+	// Not kept as a connection error: retrying or failing over would not help
+	if c.clientCertificate != nil && isClientCertificateExpiredAlert(err) {
+		leaf := c.clientCertificate.Leaf
+		return fmt.Errorf("%s: %w: TLSPDC rejected [%s], valid from %s to %s: %s", msgVcertClientError, ErrClientCertificateExpired,
+			leaf.Subject.String(), leaf.NotBefore.UTC().Format(time.RFC3339), leaf.NotAfter.UTC().Format(time.RFC3339), err.Error())
+	}
+	if isCertificateVerificationError(err) {
+		return fmt.Errorf("%s: %w: %w", msgVcertClientError, ErrServerCertificateUntrusted, err)
+	}
+	return err
+}
+
+// isUnsentRequestError reports whether err is a connection error that happened before the request could reach TLSPDC:
+// DNS resolution, connection to TLSPDC or to the proxy, or TLS handshake. Other connection errors, e.g. a timeout
+// waiting for the response, leave it unknown whether TLSPDC processed the request.
+func isUnsentRequestError(err error) bool {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && (opErr.Op == "dial" || opErr.Op == "proxyconnect") {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout() && strings.Contains(err.Error(), "TLS handshake timeout")
+}
+
+// isConnectionError reports whether err was caused by the network (DNS resolution, refused connection, timeout)
+// rather than by a response of TLSPDC
+func isConnectionError(err error) bool {
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return true
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
@@ -0,0 +1,87 @@
+package vcertclient
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/model"
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/tpptest"
+)
+
+func TestFailover(t *testing.T) {
+	credential := func(server *tpptest.Server, url, fallbackURL string) model.CredentialResourceData {
+		return model.CredentialResourceData{
+			URL:         types.StringValue(url),
+			FallbackURL: types.StringValue(fallbackURL),
+			Username:    types.StringValue(server.Username),
+			Password:    types.StringValue(server.Password),
+			TrustBundle: types.StringValue(server.TrustBundle()),
+		}
+	}
+
+	t.Run("primary down, fallback up", func(t *testing.T) {
+		fallback := tpptest.NewServer(t)
+		client := New(context.Background(), credential(fallback, tpptest.UnreachableURL(t), fallback.URL))
+
+		resp, err := client.RequestNewTokenPair()
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if _, ok := fallback.GrantOf(resp.AccessToken); !ok {
+			t.Error("token pair not issued by the fallback")
+		}
+		if client.ActiveURL() != fallback.URL {
+			t.Errorf("active url = %s, want the fallback url %s", client.ActiveURL(), fallback.URL)
+		}
+	})
+
+	t.Run("both down", func(t *testing.T) {
+		server := tpptest.NewServer(t)
+		client := New(context.Background(), credential(server, tpptest.UnreachableURL(t), tpptest.UnreachableURL(t)))
+
+		_, err := client.RequestNewTokenPair()
+		if err == nil {
+			t.Fatal("token pair retrieved with both urls down")
+		}
+		if !isConnectionError(err) || errors.Is(err, ErrCredentialsRejected) {
+			t.Errorf("error = %s, want a connection error", err)
+		}
+		if server.Requests(tpptest.PathAuthorizeOAuth) != 0 {
+			t.Error("request sent to an unrelated server")
+		}
+	})
+
+	t.Run("primary up", func(t *testing.T) {
+		primary, fallback := tpptest.NewServer(t), tpptest.NewServer(t)
+		data := credential(primary, primary.URL, fallback.URL)
+		data.TrustBundle = types.StringValue(primary.TrustBundle() + fallback.TrustBundle())
+		client := New(context.Background(), data)
+
+		if _, err := client.RequestNewTokenPair(); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if client.ActiveURL() != primary.URL || fallback.Requests(tpptest.PathAuthorizeOAuth) != 0 {
+			t.Errorf("active url = %s, want the primary url %s", client.ActiveURL(), primary.URL)
+		}
+	})
+
+	t.Run("authentication error", func(t *testing.T) {
+		primary, fallback := tpptest.NewServer(t), tpptest.NewServer(t)
+		data := credential(primary, primary.URL, fallback.URL)
+		data.Password = types.StringValue("wrong")
+		data.TrustBundle = types.StringValue(primary.TrustBundle() + fallback.TrustBundle())
+		client := New(context.Background(), data)
+
+		_, err := client.RequestNewTokenPair()
+		if err == nil || !strings.Contains(err.Error(), "Status: 400") {
+			t.Fatalf("error = %v, want the rejection of the primary", err)
+		}
+		if fallback.Requests(tpptest.PathAuthorizeOAuth) != 0 {
+			t.Error("failed over after an authentication error")
+		}
+	})
+}
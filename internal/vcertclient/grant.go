@@ -0,0 +1,34 @@
+package vcertclient
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+)
+
+// grantID returns the grant identifier carried by the access token, if any. TLSPDC issues opaque tokens, so an empty
+// string is returned unless the token is a JWT with a "jti" (or "grant_id") claim.
+func grantID(accessToken string) string {
+	parts := strings.Split(accessToken, ".")
+	if len(parts) != 3 {
+		return ""
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return ""
+	}
+
+	var claims struct {
+		JTI     string `json:"jti"`
+		GrantID string `json:"grant_id"`
+	}
+	if err = json.Unmarshal(payload, &claims); err != nil {
+		return ""
+	}
+
+	if claims.GrantID != "" {
+		return claims.GrantID
+	}
+	return claims.JTI
+}
@@ -0,0 +1,102 @@
+package vcertclient
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// inputKind is the form in which a certificate or trust bundle attribute was provided
+type inputKind int
+
+const (
+	inputEmpty inputKind = iota
+	inputPath
+	inputPEM
+	inputBase64
+)
+
+func (k inputKind) String() string {
+	switch k {
+	case inputEmpty:
+		return "empty"
+	case inputPath:
+		return "file path"
+	case inputPEM:
+		return "PEM"
+	case inputBase64:
+		return "base64"
+	default:
+		return "unknown"
+	}
+}
+
+// classifyCredentialInput decides whether s is a file path, inline PEM data or base64-encoded data, and returns the
+// decoded content for the inline forms. The decision depends on s, and on the existence of a file for ambiguous values:
+//   - values containing a PEM header are PEM, with Windows line endings normalized
+//   - values starting like a path (/, ~, .) or holding characters that base64 never uses are paths
+//   - values naming an existing file are paths, since relative paths like conf/tpp or plain file names like cert_p12
+//     also decode as base64
+//   - values that decode as base64, standard or another variant (unpadded, URL-safe), are base64
+//   - anything else is a path
+func classifyCredentialInput(s string) (inputKind, []byte) {
+	trimmed := strings.TrimSpace(s)
+	switch {
+	case trimmed == "":
+		return inputEmpty, nil
+	case strings.Contains(trimmed, "-----BEGIN "):
+		return inputPEM, []byte(strings.ReplaceAll(trimmed, "\r\n", "\n"))
+	case looksLikePath(trimmed):
+		return inputPath, nil
+	}
+
+	if _, err := os.Stat(trimmed); err == nil {
+		return inputPath, nil
+	}
+	for _, encoding := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if decoded, ok := decodeBase64(trimmed, encoding); ok {
+			return inputBase64, decoded
+		}
+	}
+	return inputPath, nil
+}
+
+// looksLikePath reports whether s can only be a path. Base64 of DER or PEM data never starts with '/', and the standard
+// alphabet has no '.', ':', '\' or spaces.
+func looksLikePath(s string) bool {
+	return strings.HasPrefix(s, "/") || strings.HasPrefix(s, "~") || strings.ContainsAny(s, `.:\ `)
+}
+
+// decodeBase64 decodes s with encoding, ignoring the line breaks tools add to wrap long base64 values
+func decodeBase64(s string, encoding *base64.Encoding) ([]byte, bool) {
+	compact := strings.NewReplacer("\r", "", "\n", "", "\t", "").Replace(s)
+	decoded, err := encoding.DecodeString(compact)
+	if err != nil || len(decoded) == 0 {
+		return nil, false
+	}
+	return decoded, true
+}
+
+// readCredentialInput returns the content of a certificate or trust bundle attribute, reading the file when the value
+// is a path. name is used in error messages.
+func readCredentialInput(name, value string) (inputKind, []byte, error) {
+	kind, decoded := classifyCredentialInput(value)
+	switch kind {
+	case inputEmpty:
+		return kind, nil, fmt.Errorf("%s: %s is empty", msgVcertClientError, name)
+	case inputPath:
+		data, err := os.ReadFile(value)
+		if errors.Is(err, os.ErrNotExist) && !looksLikePath(strings.TrimSpace(value)) {
+			return kind, nil, fmt.Errorf("%s: %s is neither an existing file nor base64 data, in the standard or URL-safe alphabet, with or without padding",
+				msgVcertClientError, name)
+		}
+		if err != nil {
+			return kind, nil, fmt.Errorf("%s: unable to read %s file at [%s]: %w", msgVcertClientError, name, value, err)
+		}
+		return kind, data, nil
+	default:
+		return kind, decoded, nil
+	}
+}
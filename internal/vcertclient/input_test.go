@@ -0,0 +1,135 @@
+package vcertclient
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/tpptest"
+)
+
+func TestClassifyCredentialInput(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		wantKind    inputKind
+		wantDecoded string
+	}{
+		{"empty", "", inputEmpty, ""},
+		{"blank", " \n\t", inputEmpty, ""},
+		{"absolute path", "/etc/venafi/bundle.pem", inputPath, ""},
+		{"home path", "~/bundle.pem", inputPath, ""},
+		{"relative path with extension", "certs/client.p12", inputPath, ""},
+		{"windows path", `C:\venafi\client.p12`, inputPath, ""},
+		{"PEM", "-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----", inputPEM, "-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----"},
+		{"PEM with Windows line endings", "-----BEGIN CERTIFICATE-----\r\nMIIB\r\n-----END CERTIFICATE-----\r\n", inputPEM, "-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----"},
+		{"base64", "aGVsbG8gd29ybGQ=", inputBase64, "hello world"},
+		{"base64 without padding", "aGVsbG8gd29ybGQ", inputBase64, "hello world"},
+		{"base64 wrapped", "aGVsbG8g\nd29ybGQ=", inputBase64, "hello world"},
+		{"URL-safe base64", "-_-_", inputBase64, "\xfb\xff\xbf"},
+		{"not base64", "client_p12!", inputPath, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			kind, decoded := classifyCredentialInput(test.input)
+			if kind != test.wantKind {
+				t.Errorf("kind = %s, want %s", kind, test.wantKind)
+			}
+			if string(decoded) != test.wantDecoded {
+				t.Errorf("decoded = %q, want %q", decoded, test.wantDecoded)
+			}
+		})
+	}
+
+	t.Run("existing file looking like base64", func(t *testing.T) {
+		// conf/tpp decodes as base64, but names an existing file
+		directory := t.TempDir()
+		if err := os.Mkdir(filepath.Join(directory, "conf"), 0o700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(directory, "conf", "tpp"), []byte("content"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if kind, _ := classifyCredentialInput("conf/tpp"); kind != inputBase64 {
+			t.Fatalf("kind = %s out of the directory, want %s", kind, inputBase64)
+		}
+
+		previous, err := os.Getwd()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = os.Chdir(directory); err != nil {
+			t.Fatal(err)
+		}
+		defer os.Chdir(previous)
+
+		kind, _ := classifyCredentialInput("conf/tpp")
+		if kind != inputPath {
+			t.Errorf("kind = %s, want %s", kind, inputPath)
+		}
+		if _, data, err := readCredentialInput("trust bundle", "conf/tpp"); err != nil || string(data) != "content" {
+			t.Errorf("content = %q, %v, want the file content", data, err)
+		}
+	})
+}
+
+func TestReadCredentialInput(t *testing.T) {
+	if _, _, err := readCredentialInput("trust bundle", ""); err == nil || !strings.Contains(err.Error(), "trust bundle is empty") {
+		t.Errorf("error = %v, want an empty trust bundle", err)
+	}
+	if _, _, err := readCredentialInput("trust bundle", "/nonexistent/bundle.pem"); err == nil || !strings.Contains(err.Error(), "unable to read trust bundle file") {
+		t.Errorf("error = %v, want a missing file", err)
+	}
+	if _, _, err := readCredentialInput("PKCS#12", "client_p12!"); err == nil || !strings.Contains(err.Error(), "neither an existing file nor base64 data") {
+		t.Errorf("error = %v, want neither a file nor base64", err)
+	}
+}
+
+func TestBase64Variants(t *testing.T) {
+	ca := tpptest.NewCA(t, "Test CA")
+	keystore, err := os.ReadFile(tpptest.PKCS12File(t, ca.Issue(t, "client", tpptest.CertificateOptions{}), nil, "secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, input := range []struct {
+		name string
+		data []byte
+	}{
+		{"PKCS#12", keystore},
+		{"trust bundle", []byte(ca.PEM)},
+	} {
+		for _, variant := range []struct {
+			name     string
+			encoding *base64.Encoding
+		}{
+			{"standard", base64.StdEncoding},
+			{"standard without padding", base64.RawStdEncoding},
+			{"URL-safe", base64.URLEncoding},
+			{"URL-safe without padding", base64.RawURLEncoding},
+		} {
+			t.Run(input.name+", "+variant.name, func(t *testing.T) {
+				kind, data, err := readCredentialInput(input.name, variant.encoding.EncodeToString(input.data))
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+				if kind != inputBase64 || !bytes.Equal(data, input.data) {
+					t.Errorf("kind = %s, want the %s decoded from base64", kind, input.name)
+				}
+			})
+		}
+	}
+
+	t.Run("keystore usable", func(t *testing.T) {
+		_, data, err := readCredentialInput("PKCS#12", base64.RawURLEncoding.EncodeToString(keystore))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if _, _, err = parsePKCS12(data, "secret"); err != nil {
+			t.Errorf("unable to parse the decoded keystore: %s", err)
+		}
+	})
+}
@@ -0,0 +1,55 @@
+package vcertclient
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ipLiteralPlaceholderHost replaces a bracketed IPv6 literal in the URL handed to vcert, whose URL validation only
+// accepts host names and IPv4 addresses. The requests are sent to the literal by ipLiteralRewriter.
+const ipLiteralPlaceholderHost = "tpp-ipv6-literal.invalid"
+
+// splitIPv6URL returns rawURL with its IPv6 literal host replaced by ipLiteralPlaceholderHost, along with the
+// bracketed host and port to send the requests to. ok is false when the host of rawURL is not an IPv6 literal.
+func splitIPv6URL(rawURL string) (vcertURL string, host string, ok bool) {
+	withScheme := rawURL
+	if !strings.Contains(withScheme, "://") {
+		withScheme = "https://" + withScheme
+	}
+	parsed, err := url.Parse(withScheme)
+	if err != nil {
+		return rawURL, "", false
+	}
+	ip := net.ParseIP(parsed.Hostname())
+	if ip == nil || ip.To4() != nil {
+		return rawURL, "", false
+	}
+
+	host = parsed.Host
+	placeholder := *parsed
+	placeholder.Host = ipLiteralPlaceholderHost
+	if port := parsed.Port(); port != "" {
+		placeholder.Host += ":" + port
+	}
+	return placeholder.String(), host, true
+}
+
+// ipLiteralRewriter sends the requests addressed to ipLiteralPlaceholderHost to the IPv6 literal host of the URL.
+// The TLS server name and the Host header follow the literal, as if vcert had used it.
+type ipLiteralRewriter struct {
+	next http.RoundTripper
+	host string
+}
+
+func (t *ipLiteralRewriter) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Hostname() != ipLiteralPlaceholderHost {
+		return t.next.RoundTrip(req)
+	}
+
+	rewritten := req.Clone(req.Context())
+	rewritten.URL.Host = t.host
+	rewritten.Host = ""
+	return t.next.RoundTrip(rewritten)
+}
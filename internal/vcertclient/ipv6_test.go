@@ -0,0 +1,79 @@
+package vcertclient
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/model"
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/tpptest"
+)
+
+func TestSplitIPv6URL(t *testing.T) {
+	tests := []struct {
+		url          string
+		wantVcertURL string
+		wantHost     string
+		wantOK       bool
+	}{
+		{"https://[2001:db8::1]:443/vedsdk", "https://" + ipLiteralPlaceholderHost + ":443/vedsdk", "[2001:db8::1]:443", true},
+		{"https://[2001:db8::1]/vedsdk", "https://" + ipLiteralPlaceholderHost + "/vedsdk", "[2001:db8::1]", true},
+		{"https://[2001:db8::1]", "https://" + ipLiteralPlaceholderHost, "[2001:db8::1]", true},
+		{"[::1]:8443/vedsdk", "https://" + ipLiteralPlaceholderHost + ":8443/vedsdk", "[::1]:8443", true},
+		{"https://192.0.2.1:443/vedsdk", "https://192.0.2.1:443/vedsdk", "", false},
+		{"https://tpp.venafi.example/vedsdk", "https://tpp.venafi.example/vedsdk", "", false},
+		// An IPv4-mapped address is an IPv4 address for vcert
+		{"https://[::ffff:192.0.2.1]/vedsdk", "https://[::ffff:192.0.2.1]/vedsdk", "", false},
+	}
+	for _, test := range tests {
+		t.Run(test.url, func(t *testing.T) {
+			vcertURL, host, ok := splitIPv6URL(test.url)
+			if vcertURL != test.wantVcertURL || host != test.wantHost || ok != test.wantOK {
+				t.Errorf("splitIPv6URL = %q, %q, %t, want %q, %q, %t", vcertURL, host, ok, test.wantVcertURL, test.wantHost, test.wantOK)
+			}
+		})
+	}
+}
+
+func TestIPv6LiteralURL(t *testing.T) {
+	listener, err := net.Listen("tcp6", "[::1]:0")
+	if err != nil {
+		t.Skipf("IPv6 loopback not available: %s", err)
+	}
+	// The fake TLSPDC answering on the IPv6 loopback, with a certificate issued for it
+	server := tpptest.NewServer(t)
+	ipv6 := httptest.NewUnstartedServer(server.Config.Handler)
+	ipv6.Listener.Close()
+	ipv6.Listener = listener
+	ipv6.TLS = &tls.Config{Certificates: []tls.Certificate{server.CA.Issue(t, "tpp.venafi.example", tpptest.CertificateOptions{Hosts: []string{"::1"}})}}
+	ipv6.StartTLS()
+	t.Cleanup(ipv6.Close)
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+
+	for _, url := range []string{"https://[::1]:" + port + "/vedsdk", "https://[::1]:" + port} {
+		t.Run(url, func(t *testing.T) {
+			client := New(context.Background(), model.CredentialResourceData{
+				URL:         types.StringValue(url),
+				TrustBundle: types.StringValue(server.TrustBundle()),
+				Username:    types.StringValue(server.Username),
+				Password:    types.StringValue(server.Password),
+			})
+
+			resp, err := client.RequestNewTokenPair()
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if _, ok := server.GrantOf(resp.AccessToken); !ok {
+				t.Error("token pair not issued by the server")
+			}
+			if active := client.ActiveURL(); strings.Contains(active, ipLiteralPlaceholderHost) {
+				t.Errorf("active url = %s, want the IPv6 literal", active)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package vcertclient
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// DefaultMaxConcurrentRequests is the number of requests sent at the same time to a TLSPDC host when not configured
+const DefaultMaxConcurrentRequests = 8
+
+var (
+	limitersMutex         sync.Mutex
+	maxConcurrentRequests = DefaultMaxConcurrentRequests
+	// limiters holds a semaphore per TLSPDC host, shared by every resource of the provider process
+	limiters = make(map[string]chan struct{})
+)
+
+// SetMaxConcurrentRequests sets how many requests may be sent at the same time to a given TLSPDC host by the provider
+// process. It applies to the hosts not contacted yet, so it is meant to be called while configuring the provider.
+func SetMaxConcurrentRequests(limit int) {
+	limitersMutex.Lock()
+	defer limitersMutex.Unlock()
+
+	if limit < 1 {
+		limit = DefaultMaxConcurrentRequests
+	}
+	maxConcurrentRequests = limit
+}
+
+func hostLimiter(host string) chan struct{} {
+	limitersMutex.Lock()
+	defer limitersMutex.Unlock()
+
+	limiter, ok := limiters[host]
+	if !ok {
+		limiter = make(chan struct{}, maxConcurrentRequests)
+		limiters[host] = limiter
+	}
+	return limiter
+}
+
+// concurrencyLimiter queues the requests exceeding the concurrency limit of their host. vcert builds its requests
+// without context, so waiting is bounded by the context of the operation instead.
+type concurrencyLimiter struct {
+	next    http.RoundTripper
+	context context.Context
+}
+
+func (t *concurrencyLimiter) RoundTrip(req *http.Request) (*http.Response, error) {
+	limiter := hostLimiter(req.URL.Host)
+	select {
+	case limiter <- struct{}{}:
+	case <-t.context.Done():
+		return nil, fmt.Errorf("waiting for a request slot to [%s]: %w", req.URL.Host, t.context.Err())
+	}
+	release := func() { <-limiter }
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+
+	// The slot is held until the response has been read, as the connection stays open until then
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+// releasingBody frees the request slot of its response when closed
+type releasingBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
@@ -0,0 +1,84 @@
+package vcertclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// concurrencyRecorder answers the requests after a delay, recording how many were in flight at the same time
+type concurrencyRecorder struct {
+	inFlight    atomic.Int32
+	maxInFlight atomic.Int32
+}
+
+func (t *concurrencyRecorder) RoundTrip(*http.Request) (*http.Response, error) {
+	current := t.inFlight.Add(1)
+	defer t.inFlight.Add(-1)
+	for {
+		recorded := t.maxInFlight.Load()
+		if current <= recorded || t.maxInFlight.CompareAndSwap(recorded, current) {
+			break
+		}
+	}
+	time.Sleep(10 * time.Millisecond)
+	return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("{}"))}, nil
+}
+
+func TestConcurrencyLimiter(t *testing.T) {
+	SetMaxConcurrentRequests(3)
+	t.Cleanup(func() { SetMaxConcurrentRequests(DefaultMaxConcurrentRequests) })
+
+	t.Run("cap", func(t *testing.T) {
+		recorder := &concurrencyRecorder{}
+		limiter := &concurrencyLimiter{next: recorder, context: context.Background()}
+
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				req, _ := http.NewRequest(http.MethodGet, "https://cap.tpp.venafi.example/vedsdk/", nil)
+				resp, err := limiter.RoundTrip(req)
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+					return
+				}
+				resp.Body.Close()
+			}()
+		}
+		wg.Wait()
+
+		if inFlight := recorder.maxInFlight.Load(); inFlight > 3 || inFlight < 1 {
+			t.Errorf("%d request(s) in flight at the same time, want at most 3", inFlight)
+		}
+	})
+
+	t.Run("context done while queued", func(t *testing.T) {
+		host := "https://queued.tpp.venafi.example/vedsdk/"
+		limiter := &concurrencyLimiter{next: &concurrencyRecorder{}, context: context.Background()}
+		// Every slot is held by a response not read yet
+		for i := 0; i < 3; i++ {
+			req, _ := http.NewRequest(http.MethodGet, host, nil)
+			resp, err := limiter.RoundTrip(req)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			defer resp.Body.Close()
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		queued := &concurrencyLimiter{next: &concurrencyRecorder{}, context: ctx}
+		req, _ := http.NewRequest(http.MethodGet, host, nil)
+		if _, err := queued.RoundTrip(req); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("error = %v, want the deadline of the operation", err)
+		}
+	})
+}
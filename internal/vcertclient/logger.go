@@ -0,0 +1,42 @@
+package vcertclient
+
+import (
+	"context"
+
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/logging"
+)
+
+// Logger receives the messages of the client. The provider logs them with tflog, see ContextLogger; tools embedding
+// the client, e.g. a small CLI, can send them anywhere else.
+type Logger interface {
+	Debug(msg string)
+	Info(msg string)
+	Warn(msg string)
+	Error(msg string)
+}
+
+// contextLogger logs to tflog, with the log level and the masked values set in its context
+type contextLogger struct {
+	ctx context.Context
+}
+
+// ContextLogger returns the Logger of the provider, logging to tflog with the log level and the masked values of ctx
+func ContextLogger(ctx context.Context) Logger {
+	return contextLogger{ctx: ctx}
+}
+
+func (l contextLogger) Debug(msg string) {
+	logging.Debug(l.ctx, msg)
+}
+
+func (l contextLogger) Info(msg string) {
+	logging.Info(l.ctx, msg)
+}
+
+func (l contextLogger) Warn(msg string) {
+	logging.Warn(l.ctx, msg)
+}
+
+func (l contextLogger) Error(msg string) {
+	logging.Error(l.ctx, msg)
+}
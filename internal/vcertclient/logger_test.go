@@ -0,0 +1,80 @@
+package vcertclient
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflogtest"
+
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/logging"
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/model"
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/tpptest"
+)
+
+func TestNewWithLogger(t *testing.T) {
+	server := tpptest.NewServer(t)
+	server.Password = "tpp-s3cr3t"
+	data := model.CredentialResourceData{
+		URL:          types.StringValue(server.URL),
+		TrustBundle:  types.StringValue(server.TrustBundle()),
+		RefreshToken: types.StringValue("unknown"),
+		Username:     types.StringValue(server.Username),
+		Password:     types.StringValue(server.Password),
+	}
+	logger := &recordingLogger{}
+
+	if _, err := NewWithLogger(context.Background(), data, logger).RequestNewTokenPair(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	// The messages reach the logger in order, at their level
+	want := []string{
+		"INFO requesting new token pair",
+		"INFO " + msgTokenRefreshStart + " " + MethodRefreshToken,
+		// Falling back to the next method is not an error
+		"WARN " + msgTokenRefreshFail + " " + MethodRefreshToken,
+		"INFO " + msgTokenRefreshStart + " " + MethodUsernamePassword,
+		"INFO " + msgTokenRefreshSuccess,
+	}
+	next := 0
+	for _, message := range logger.messages {
+		if next < len(want) && strings.HasPrefix(message, want[next]) {
+			next++
+		}
+	}
+	if next != len(want) {
+		t.Errorf("messages = %q, want %q in order", logger.messages, want)
+	}
+	for _, message := range logger.messages {
+		if strings.Contains(message, server.Password) {
+			t.Errorf("password logged: %s", message)
+		}
+	}
+}
+
+func TestContextLogger(t *testing.T) {
+	var output bytes.Buffer
+	ctx := tflogtest.RootLogger(context.Background(), &output)
+	ctx = logging.MaskValues(logging.WithLevel(ctx, logging.LevelSummary), "s3cr3t")
+	logger := ContextLogger(ctx)
+
+	logger.Debug("debug message")
+	logger.Info("info message")
+	logger.Warn("warn message with s3cr3t")
+	logger.Error("error message")
+	logs := output.String()
+	for _, message := range []string{"info message", "warn message", "error message"} {
+		if !strings.Contains(logs, message) {
+			t.Errorf("%q not logged to tflog", message)
+		}
+	}
+	// The log level and the masked values of the context apply
+	if strings.Contains(logs, "debug message") {
+		t.Error("debug message logged at the summary level")
+	}
+	if strings.Contains(logs, "s3cr3t") {
+		t.Error("masked value logged")
+	}
+}
@@ -0,0 +1,102 @@
+package vcertclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// noticeKeys are the top-level keys of a JSON response body holding non-fatal notices, compared case-insensitively
+var noticeKeys = []string{"warning", "warnings", "notice", "notices"}
+
+// noticeRecorder reports the non-fatal notices returned by TLSPDC, e.g. a password about to expire, found in the
+// Warning headers or in the notice keys of the JSON bodies of successful responses
+type noticeRecorder struct {
+	next     http.RoundTripper
+	observer func(notice string)
+}
+
+func (t *noticeRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil || resp.StatusCode >= http.StatusBadRequest {
+		return resp, err
+	}
+
+	for _, header := range resp.Header.Values("Warning") {
+		if notice := warningHeaderText(header); notice != "" {
+			t.observer(notice)
+		}
+	}
+
+	contentType := strings.ToLower(resp.Header.Get("Content-Type"))
+	if contentType != "" && !strings.Contains(contentType, "json") {
+		return resp, nil
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	for _, notice := range bodyNotices(body) {
+		t.observer(notice)
+	}
+	return resp, nil
+}
+
+// warningHeaderText returns the text of a Warning header, e.g. `299 - "Password expires in 5 days"`, without the
+// optional date following it, or the whole header when it does not follow RFC 7234
+func warningHeaderText(header string) string {
+	if _, quoted, ok := strings.Cut(header, `"`); ok {
+		if text, _, ok := strings.Cut(quoted, `"`); ok {
+			return strings.TrimSpace(text)
+		}
+	}
+	return strings.TrimSpace(header)
+}
+
+// bodyNotices returns the notices of a JSON response body, held as a string or a list of strings under one of
+// noticeKeys. Bodies that are not JSON objects hold none.
+func bodyNotices(body []byte) []string {
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(body, &fields) != nil {
+		return nil
+	}
+
+	var notices []string
+	for key, value := range fields {
+		if !containsFold(noticeKeys, key) {
+			continue
+		}
+		var single string
+		if json.Unmarshal(value, &single) == nil {
+			notices = appendNotice(notices, single)
+			continue
+		}
+		var list []string
+		if json.Unmarshal(value, &list) == nil {
+			for _, notice := range list {
+				notices = appendNotice(notices, notice)
+			}
+		}
+	}
+	return notices
+}
+
+func appendNotice(notices []string, notice string) []string {
+	if notice = strings.TrimSpace(notice); notice != "" {
+		notices = append(notices, notice)
+	}
+	return notices
+}
+
+func containsFold(values []string, value string) bool {
+	for _, candidate := range values {
+		if strings.EqualFold(candidate, value) {
+			return true
+		}
+	}
+	return false
+}
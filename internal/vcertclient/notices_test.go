@@ -0,0 +1,86 @@
+package vcertclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/model"
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/tpptest"
+)
+
+func TestBodyNotices(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{"string", `{"access_token":"access","Warning":"Password expires in 5 days"}`, []string{"Password expires in 5 days"}},
+		{"list", `{"notices":["Password expires in 5 days"," ","License expires soon"]}`, []string{"Password expires in 5 days", "License expires soon"}},
+		{"none", `{"access_token":"access"}`, nil},
+		{"other type", `{"warnings":{"password":"expires"}}`, nil},
+		{"not an object", `["Password expires in 5 days"]`, nil},
+		{"not JSON", `Password expires in 5 days`, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := bodyNotices([]byte(test.body)); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("notices = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestWarningHeaderText(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{`299 - "Password expires in 5 days"`, "Password expires in 5 days"},
+		{`299 tpp.venafi.example "Password expires in 5 days" "Wed, 21 Oct 2026 07:28:00 GMT"`, "Password expires in 5 days"},
+		{"Password expires in 5 days ", "Password expires in 5 days"},
+	}
+	for _, test := range tests {
+		if got := warningHeaderText(test.header); got != test.want {
+			t.Errorf("text of [%s] = %q, want %q", test.header, got, test.want)
+		}
+	}
+}
+
+func TestTokenNotices(t *testing.T) {
+	server := tpptest.NewServer(t)
+	grant := server.IssueGrant(DefaultScope)
+	server.Handle(tpptest.PathAuthorizeOAuth, func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		// The same notice, in a header and in the body, is reported once
+		w.Header().Add("Warning", `299 - "Password expires in 5 days"`)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token":  grant.AccessToken,
+			"refresh_token": grant.RefreshToken,
+			"expires":       grant.ExpiresAt.Unix(),
+			"token_type":    "Bearer",
+			"warnings":      []string{"Password expires in 5 days", "License expires soon"},
+		})
+	})
+	client := New(context.Background(), model.CredentialResourceData{
+		URL:         types.StringValue(server.URL),
+		TrustBundle: types.StringValue(server.TrustBundle()),
+		Username:    types.StringValue(server.Username),
+		Password:    types.StringValue(server.Password),
+	})
+
+	resp, err := client.RequestNewTokenPair()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if resp.AccessToken != grant.AccessToken {
+		t.Errorf("access_token = %s, want %s", resp.AccessToken, grant.AccessToken)
+	}
+	if want := []string{"Password expires in 5 days", "License expires soon"}; !reflect.DeepEqual(resp.Notices, want) {
+		t.Errorf("notices = %q, want %q", resp.Notices, want)
+	}
+}
@@ -0,0 +1,104 @@
+package vcertclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/pkcs12"
+)
+
+const (
+	pemTypeCertificate = "CERTIFICATE"
+	pemTypePrivateKey  = "PRIVATE KEY"
+)
+
+// parsePKCS12 decodes a PKCS#12 archive into the TLS client certificate to present and a pool holding the CA
+// certificates of the archive. The leaf is the certificate matching the private key, wherever it is in the archive;
+// every other certificate is sent as part of the chain.
+func parsePKCS12(data []byte, password string) (*tls.Certificate, *x509.CertPool, error) {
+	blocks, err := pkcs12.ToPEM(data, password)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed converting PKCS#12 archive file to PEM blocks: %w", err)
+	}
+
+	var keyPEM []byte
+	var certs []*x509.Certificate
+	for _, block := range blocks {
+		switch block.Type {
+		case pemTypePrivateKey:
+			if keyPEM != nil {
+				return nil, nil, errors.New("PKCS#12 archive contains more than one private key")
+			}
+			keyPEM = pem.EncodeToMemory(&pem.Block{Type: block.Type, Bytes: block.Bytes})
+		case pemTypeCertificate:
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed parsing certificate from PKCS#12 archive: %w", err)
+			}
+			certs = append(certs, cert)
+		}
+	}
+	if keyPEM == nil {
+		return nil, nil, errors.New("PKCS#12 archive does not contain a private key")
+	}
+
+	leafIndex := -1
+	var clientCert tls.Certificate
+	for i, cert := range certs {
+		certPEM := pem.EncodeToMemory(&pem.Block{Type: pemTypeCertificate, Bytes: cert.Raw})
+		// X509KeyPair fails when the public key of the certificate does not match the private key
+		candidate, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err == nil {
+			clientCert = candidate
+			leafIndex = i
+			break
+		}
+	}
+	if leafIndex < 0 {
+		return nil, nil, errors.New("no certificate in PKCS#12 archive matches its private key")
+	}
+	clientCert.Leaf = certs[leafIndex]
+
+	pool := x509.NewCertPool()
+	for i, cert := range certs {
+		if i == leafIndex {
+			continue
+		}
+		clientCert.Certificate = append(clientCert.Certificate, cert.Raw)
+		if cert.IsCA {
+			pool.AddCert(cert)
+		}
+	}
+
+	return &clientCert, pool, nil
+}
+
+// clientCertificate is a keystore loaded from p12_cert_filename or p12_cert_filenames
+type clientCertificate struct {
+	cert *tls.Certificate
+	pool *x509.CertPool
+}
+
+// selectClientCertificate returns the candidate whose issuer common name, or full issuer, is issuer, along with no
+// other candidate. Without issuer, the first candidate is returned along with the others, which the TLS handshake
+// may pick instead depending on the CAs accepted by TLSPDC.
+func selectClientCertificate(candidates []clientCertificate, issuer string) (clientCertificate, []clientCertificate, error) {
+	if issuer == "" {
+		return candidates[0], candidates[1:], nil
+	}
+
+	issuers := make([]string, 0, len(candidates))
+	for _, candidate := range candidates {
+		leafIssuer := candidate.cert.Leaf.Issuer
+		if strings.EqualFold(leafIssuer.CommonName, issuer) || strings.EqualFold(leafIssuer.String(), issuer) {
+			return candidate, nil, nil
+		}
+		issuers = append(issuers, leafIssuer.String())
+	}
+	return clientCertificate{}, nil, fmt.Errorf("no client certificate issued by [%s], found certificates issued by [%s]",
+		issuer, strings.Join(issuers, "], ["))
+}
@@ -0,0 +1,222 @@
+package vcertclient
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/model"
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/tpptest"
+)
+
+func TestParsePKCS12Chain(t *testing.T) {
+	root := tpptest.NewCA(t, "Root CA")
+	intermediate := root.Intermediate(t, "Intermediate CA")
+	leaf := intermediate.Issue(t, "client", tpptest.CertificateOptions{})
+
+	t.Run("leaf first", func(t *testing.T) {
+		data := tpptest.PKCS12(t, leaf, []*x509.Certificate{intermediate.Certificate, root.Certificate}, "secret")
+		checkPKCS12Chain(t, data, leaf, root, intermediate)
+	})
+
+	t.Run("leaf last", func(t *testing.T) {
+		// The intermediate is stored as the certificate of the key, the leaf matching the key along with the CAs
+		misordered := tls.Certificate{Certificate: [][]byte{intermediate.Certificate.Raw}, PrivateKey: leaf.PrivateKey, Leaf: intermediate.Certificate}
+		data := tpptest.PKCS12(t, misordered, []*x509.Certificate{root.Certificate, leaf.Leaf}, "secret")
+		checkPKCS12Chain(t, data, leaf, root, intermediate)
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		data := tpptest.PKCS12(t, leaf, nil, "secret")
+		if _, _, err := parsePKCS12(data, "other"); err == nil {
+			t.Error("keystore decoded with the wrong password")
+		}
+	})
+}
+
+func checkPKCS12Chain(t *testing.T, data []byte, leaf tls.Certificate, root, intermediate *tpptest.CA) {
+	t.Helper()
+
+	cert, pool, err := parsePKCS12(data, "secret")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if cert.Leaf.Subject.CommonName != "client" || !bytes.Equal(cert.Certificate[0], leaf.Leaf.Raw) {
+		t.Errorf("leaf = %s, want the certificate of the private key", cert.Leaf.Subject)
+	}
+	if len(cert.Certificate) != 3 {
+		t.Errorf("chain holds %d certificates, want 3", len(cert.Certificate))
+	}
+	// Only the CA certificates are trusted
+	if _, err = intermediate.Certificate.Verify(x509.VerifyOptions{Roots: pool}); err != nil {
+		t.Errorf("CA certificates not added to the pool: %s", err)
+	}
+	if _, err = root.Certificate.Verify(x509.VerifyOptions{Roots: pool}); err != nil {
+		t.Errorf("root not added to the pool: %s", err)
+	}
+}
+
+func TestClientCertificatePresented(t *testing.T) {
+	server := tpptest.NewServer(t)
+	root := tpptest.NewCA(t, "Root CA")
+	intermediate := root.Intermediate(t, "Intermediate CA")
+	leaf := intermediate.Issue(t, "client", tpptest.CertificateOptions{})
+
+	client := New(context.Background(), model.CredentialResourceData{
+		URL:            types.StringValue(server.URL),
+		TrustBundle:    types.StringValue(server.TrustBundle()),
+		P12Certificate: types.StringValue(tpptest.PKCS12File(t, leaf, []*x509.Certificate{intermediate.Certificate, root.Certificate}, "secret")),
+		P12Password:    types.StringValue("secret"),
+	})
+
+	resp, err := client.RequestNewTokenPair()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if resp.Method != MethodClientCertificate {
+		t.Errorf("method = %s, want %s", resp.Method, MethodClientCertificate)
+	}
+	presented := server.ClientCertificates()
+	if len(presented) == 0 || !bytes.Equal(presented[0].Raw, leaf.Leaf.Raw) {
+		t.Fatalf("presented certificates = %v, want the leaf", presented)
+	}
+	if grant, ok := server.GrantOf(resp.AccessToken); !ok || grant.Method != tpptest.PathAuthorizeCertificate {
+		t.Errorf("grant = %+v, want a grant of the certificate endpoint", grant)
+	}
+}
+
+func TestRequireClientCertTLS(t *testing.T) {
+	ca := tpptest.NewCA(t, "Client CA")
+	cert := ca.Issue(t, "client", tpptest.CertificateOptions{})
+	credential := func(server *tpptest.Server, require bool) model.CredentialResourceData {
+		return model.CredentialResourceData{
+			URL:                  types.StringValue(server.URL),
+			TrustBundle:          types.StringValue(server.TrustBundle()),
+			P12Certificate:       types.StringValue(tpptest.PKCS12File(t, cert, nil, "secret")),
+			P12Password:          types.StringValue("secret"),
+			Username:             types.StringValue(server.Username),
+			Password:             types.StringValue(server.Password),
+			RequireClientCertTLS: types.BoolValue(require),
+		}
+	}
+
+	t.Run("username/password over mutual TLS", func(t *testing.T) {
+		server := tpptest.NewServer(t)
+		data := credential(server, true)
+
+		resp, err := New(context.Background(), data).RequestNewTokenPair()
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if resp.Method != MethodUsernamePassword {
+			t.Errorf("method = %s, want %s", resp.Method, MethodUsernamePassword)
+		}
+		if grant, ok := server.GrantOf(resp.AccessToken); !ok || grant.Method != tpptest.PathAuthorizeOAuth {
+			t.Errorf("grant = %+v, want a grant of the username/password endpoint", grant)
+		}
+		if server.Requests(tpptest.PathAuthorizeCertificate) != 0 {
+			t.Error("client certificate used for the grant")
+		}
+
+		// The connections verifying the token present the certificate as well
+		data.AccessToken = types.StringValue(resp.AccessToken)
+		if _, err = New(context.Background(), data).VerifyToken(); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		presented := server.ClientCertificates()
+		requests := server.Requests(tpptest.PathAuthorizeOAuth) + server.Requests(tpptest.PathVerifyToken)
+		if len(presented) < requests {
+			t.Fatalf("%d certificate(s) presented for %d requests, want one per request", len(presented), requests)
+		}
+		for _, certificate := range presented {
+			if !bytes.Equal(certificate.Raw, cert.Leaf.Raw) {
+				t.Errorf("presented certificate = %s, want the client certificate", certificate.Subject)
+			}
+		}
+	})
+
+	t.Run("not required", func(t *testing.T) {
+		// The client certificate authenticates the grant, ahead of username/password
+		server := tpptest.NewServer(t)
+		resp, err := New(context.Background(), credential(server, false)).RequestNewTokenPair()
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if resp.Method != MethodClientCertificate || server.Requests(tpptest.PathAuthorizeOAuth) != 0 {
+			t.Errorf("method = %s, want %s", resp.Method, MethodClientCertificate)
+		}
+	})
+}
+
+func TestSelectClientCertificate(t *testing.T) {
+	first := tpptest.NewCA(t, "First CA").Issue(t, "client", tpptest.CertificateOptions{})
+	second := tpptest.NewCA(t, "Second CA").Issue(t, "client", tpptest.CertificateOptions{})
+	candidates := []clientCertificate{{cert: &first}, {cert: &second}}
+
+	tests := []struct {
+		name       string
+		issuer     string
+		want       *tls.Certificate
+		wantOthers int
+		wantErr    string
+	}{
+		{"no issuer", "", &first, 1, ""},
+		{"common name", "Second CA", &second, 0, ""},
+		{"case-insensitive", "second ca", &second, 0, ""},
+		{"full issuer", second.Leaf.Issuer.String(), &second, 0, ""},
+		{"no match", "Other CA", nil, 0, "no client certificate issued by [Other CA]"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			selected, others, err := selectClientCertificate(candidates, test.issuer)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Errorf("error = %v, want %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if selected.cert != test.want || len(others) != test.wantOthers {
+				t.Errorf("selected = %s with %d other(s), want %s with %d", selected.cert.Leaf.Issuer, len(others), test.want.Leaf.Issuer, test.wantOthers)
+			}
+		})
+	}
+}
+
+func TestClientCertIssuer(t *testing.T) {
+	server := tpptest.NewServer(t)
+	first := tpptest.NewCA(t, "First CA").Issue(t, "client", tpptest.CertificateOptions{})
+	second := tpptest.NewCA(t, "Second CA").Issue(t, "client", tpptest.CertificateOptions{})
+	filenames, diags := types.ListValueFrom(context.Background(), types.StringType, []string{tpptest.PKCS12File(t, second, nil, "secret")})
+	if diags.HasError() {
+		t.Fatalf("invalid p12_cert_filenames: %v", diags)
+	}
+
+	client := New(context.Background(), model.CredentialResourceData{
+		URL:              types.StringValue(server.URL),
+		TrustBundle:      types.StringValue(server.TrustBundle()),
+		P12Certificate:   types.StringValue(tpptest.PKCS12File(t, first, nil, "secret")),
+		P12CertFilenames: filenames,
+		P12Password:      types.StringValue("secret"),
+		ClientCertIssuer: types.StringValue("Second CA"),
+	})
+	if _, err := client.RequestNewTokenPair(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	presented := server.ClientCertificates()
+	if len(presented) == 0 {
+		t.Fatal("no client certificate presented")
+	}
+	for _, certificate := range presented {
+		if !bytes.Equal(certificate.Raw, second.Leaf.Raw) {
+			t.Errorf("presented certificate issued by %s, want the one issued by Second CA", certificate.Issuer)
+		}
+	}
+}
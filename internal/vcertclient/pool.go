@@ -0,0 +1,71 @@
+package vcertclient
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultIdleConnTimeout is how long an idle connection to TLSPDC is kept open when pooling is enabled and no timeout
+// is configured
+const DefaultIdleConnTimeout = 30 * time.Second
+
+var (
+	transportsMutex sync.Mutex
+	// transports holds the pooling transports, shared by the resources of the provider process with the same TLS
+	// settings so that their connections are reused
+	transports = make(map[string]*http.Transport)
+)
+
+// pooledTransport returns the transport of the pool matching settings, storing transport in the pool when there is
+// none yet. The idle connections of a transport are only reused by clients presenting the same TLS settings.
+func pooledTransport(settings ConnectionSettings, transport *http.Transport) *http.Transport {
+	key := transportKey(settings)
+
+	transportsMutex.Lock()
+	defer transportsMutex.Unlock()
+
+	if pooled, ok := transports[key]; ok {
+		return pooled
+	}
+	transports[key] = transport
+	return transport
+}
+
+// connectionReuser keeps the connections of the pool open after each request. vcert asks for every connection to be
+// closed once its request completed, which would leave nothing to reuse.
+type connectionReuser struct {
+	next http.RoundTripper
+}
+
+func (t *connectionReuser) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !req.Close {
+		return t.next.RoundTrip(req)
+	}
+	reused := req.Clone(req.Context())
+	reused.Close = false
+	return t.next.RoundTrip(reused)
+}
+
+// transportKey fingerprints the settings the transport is built from
+func transportKey(settings ConnectionSettings) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%d|%d|%d|%s|%s|%t|%t|%s|", settings.MaxIdleConns, settings.IdleConnTimeout, settings.TLSHandshakeTimeout,
+		strings.Join(settings.ExpectedServerSANs, ";"), settings.TrustBundle, settings.AppendSystemRoots, settings.InsecureSkipVerify,
+		settings.ProxyTrustBundle)
+	if settings.ClientCertificate != nil {
+		for _, cert := range settings.ClientCertificate.Certificate {
+			hash.Write(cert)
+		}
+	}
+	for _, candidate := range settings.ClientCertificateCandidates {
+		for _, cert := range candidate.Certificate {
+			hash.Write(cert)
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
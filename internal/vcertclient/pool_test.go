@@ -0,0 +1,104 @@
+package vcertclient
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/tpptest"
+)
+
+func TestConnectionPooling(t *testing.T) {
+	server := tpptest.NewServer(t)
+
+	tests := []struct {
+		name         string
+		maxIdleConns int
+		idleTimeout  time.Duration
+		wantTimeout  time.Duration
+	}{
+		{"configured", 4, 90 * time.Second, 90 * time.Second},
+		{"default timeout", 2, 0, DefaultIdleConnTimeout},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			settings := ConnectionSettings{
+				URL:             server.URL,
+				TrustBundle:     server.TrustBundle(),
+				MaxIdleConns:    test.maxIdleConns,
+				IdleConnTimeout: test.idleTimeout,
+			}
+			if _, err := newHTTPClient(settings); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			transportsMutex.Lock()
+			transport, ok := transports[transportKey(settings)]
+			transportsMutex.Unlock()
+			if !ok {
+				t.Fatal("transport not pooled")
+			}
+			if transport.DisableKeepAlives {
+				t.Error("keep-alives disabled")
+			}
+			if transport.MaxIdleConns != test.maxIdleConns || transport.MaxIdleConnsPerHost != test.maxIdleConns {
+				t.Errorf("max idle conns = %d, per host %d, want %d", transport.MaxIdleConns, transport.MaxIdleConnsPerHost, test.maxIdleConns)
+			}
+			if transport.IdleConnTimeout != test.wantTimeout {
+				t.Errorf("idle conn timeout = %s, want %s", transport.IdleConnTimeout, test.wantTimeout)
+			}
+
+			// The clients of the next operations share the transport
+			if _, err := newHTTPClient(settings); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			transportsMutex.Lock()
+			shared := transports[transportKey(settings)]
+			transportsMutex.Unlock()
+			if shared != transport {
+				t.Error("transport not shared with the next client")
+			}
+		})
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		settings := ConnectionSettings{URL: server.URL, TrustBundle: server.TrustBundle(), IdleConnTimeout: time.Minute}
+		if _, err := newHTTPClient(settings); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		transportsMutex.Lock()
+		_, ok := transports[transportKey(settings)]
+		transportsMutex.Unlock()
+		if ok {
+			t.Error("transport pooled without max_idle_conns")
+		}
+	})
+
+	t.Run("connection reused", func(t *testing.T) {
+		location := filepath.Join(t.TempDir(), "trace.log")
+		settings := ConnectionSettings{URL: server.URL, TrustBundle: server.TrustBundle(), MaxIdleConns: 1, TraceFile: location}
+		// One client per operation, as the provider does
+		for i := 0; i < 2; i++ {
+			config, err := NewVCertConfig(settings)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			resp, err := config.Client.Get(server.URL + tpptest.PathSystemVersion)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			resp.Body.Close()
+		}
+
+		content, err := os.ReadFile(location)
+		if err != nil {
+			t.Fatalf("trace file not written: %s", err)
+		}
+		lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+		if len(lines) != 2 || !strings.Contains(lines[1], "reused_conn=true") {
+			t.Errorf("trace = %q, want the connection reused by the second request", content)
+		}
+	})
+}
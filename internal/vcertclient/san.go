@@ -0,0 +1,56 @@
+package vcertclient
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// verifyServerSANs returns a VerifyPeerCertificate callback failing the TLS handshake when none of the subject
+// alternative names of the TLSPDC certificate is in expected. It runs after, not instead of, the verification of the
+// certificate chain.
+func verifyServerSANs(expected []string) func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+	return func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		if len(rawCerts) == 0 {
+			return errors.New("TLSPDC presented no certificate")
+		}
+		leaf, err := x509.ParseCertificate(rawCerts[0])
+		if err != nil {
+			return fmt.Errorf("unable to parse the TLSPDC certificate: %w", err)
+		}
+
+		presented := serverSANs(leaf)
+		for _, san := range presented {
+			for _, name := range expected {
+				if sanMatches(san, name) {
+					return nil
+				}
+			}
+		}
+		return fmt.Errorf("none of the TLSPDC certificate SANs [%s] is expected [%s]", strings.Join(presented, ", "),
+			strings.Join(expected, ", "))
+	}
+}
+
+// serverSANs lists the DNS names and IP addresses of the subject alternative names of cert
+func serverSANs(cert *x509.Certificate) []string {
+	sans := make([]string, 0, len(cert.DNSNames)+len(cert.IPAddresses))
+	sans = append(sans, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	return sans
+}
+
+// sanMatches compares a SAN of the certificate with an expected name. DNS names are case-insensitive and IP addresses
+// are compared in their canonical form, so that 2001:db8:0::1 matches 2001:db8::1.
+func sanMatches(san string, expected string) bool {
+	expected = strings.Trim(strings.TrimSpace(expected), "[]")
+	if expectedIP := net.ParseIP(expected); expectedIP != nil {
+		sanIP := net.ParseIP(san)
+		return sanIP != nil && sanIP.Equal(expectedIP)
+	}
+	return strings.EqualFold(strings.TrimSuffix(san, "."), strings.TrimSuffix(expected, "."))
+}
@@ -0,0 +1,69 @@
+package vcertclient
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/tpptest"
+)
+
+func TestExpectedServerSANs(t *testing.T) {
+	// The certificate of the server is issued for tpp.venafi.example and 127.0.0.1
+	server := tpptest.NewServer(t)
+
+	tests := []struct {
+		name     string
+		expected []string
+		wantErr  bool
+	}{
+		{"not set", nil, false},
+		{"DNS name", []string{"tpp.venafi.example"}, false},
+		{"DNS name in another case, fully qualified", []string{"TPP.Venafi.example."}, false},
+		{"IP address", []string{"10.0.0.1", "127.0.0.1"}, false},
+		{"none matching", []string{"other.venafi.example", "10.0.0.1"}, true},
+		{"parent domain", []string{"venafi.example"}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config, err := NewVCertConfig(ConnectionSettings{URL: server.URL, TrustBundle: server.TrustBundle(), ExpectedServerSANs: test.expected})
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			resp, err := config.Client.Get(server.URL + tpptest.PathSystemVersion)
+			if err == nil {
+				resp.Body.Close()
+			}
+			if test.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "none of the TLSPDC certificate SANs [tpp.venafi.example, 127.0.0.1] is expected") {
+					t.Errorf("error = %v, want the SANs rejected", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+		})
+	}
+}
+
+func TestSANMatches(t *testing.T) {
+	tests := []struct {
+		san      string
+		expected string
+		want     bool
+	}{
+		{"tpp.venafi.example", " tpp.venafi.example ", true},
+		{"2001:db8::1", "2001:db8:0:0::1", true},
+		{"2001:db8::1", "[2001:db8::1]", true},
+		{"2001:db8::1", "2001:db8::2", false},
+		// An IP address is never matched by a DNS name, even one that looks like it
+		{"tpp.venafi.example", "127.0.0.1", false},
+		{"*.venafi.example", "tpp.venafi.example", false},
+	}
+	for _, test := range tests {
+		if got := sanMatches(test.san, test.expected); got != test.want {
+			t.Errorf("sanMatches(%q, %q) = %t, want %t", test.san, test.expected, got, test.want)
+		}
+	}
+}
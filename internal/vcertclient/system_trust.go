@@ -0,0 +1,97 @@
+package vcertclient
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// systemCertificateFiles are the usual locations of the system CA bundle, as searched by the Go standard library
+var systemCertificateFiles = []string{
+	"/etc/ssl/certs/ca-certificates.crt",                // Debian/Ubuntu/Gentoo etc.
+	"/etc/pki/tls/certs/ca-bundle.crt",                  // Fedora/RHEL 6
+	"/etc/ssl/ca-bundle.pem",                            // OpenSUSE
+	"/etc/pki/tls/cacert.pem",                           // OpenELEC
+	"/etc/pki/ca-trust/extracted/pem/tls-ca-bundle.pem", // CentOS/RHEL 7
+	"/etc/ssl/cert.pem",                                 // Alpine Linux, macOS, BSDs
+}
+
+// systemCertificateDirectories are the usual locations of the system CA certificates, one per file
+var systemCertificateDirectories = []string{
+	"/etc/ssl/certs",
+	"/etc/pki/tls/certs",
+}
+
+// LoadSystemTrustBundle returns the PEM content of the CA certificates of the system trust store whose subject common
+// name, or full subject, is name. The SSL_CERT_FILE and SSL_CERT_DIR environment variables are honored. Only trust
+// stores kept as PEM files are supported, i.e. not the Windows certificate store or the macOS keychain.
+func LoadSystemTrustBundle(name string) (string, error) {
+	files, directories := systemCertificateFiles, systemCertificateDirectories
+	if file := os.Getenv("SSL_CERT_FILE"); file != "" {
+		files = []string{file}
+	}
+	if dirs := os.Getenv("SSL_CERT_DIR"); dirs != "" {
+		directories = filepath.SplitList(dirs)
+	}
+
+	for _, directory := range directories {
+		entries, err := os.ReadDir(directory)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				files = append(files, filepath.Join(directory, entry.Name()))
+			}
+		}
+	}
+
+	var bundle strings.Builder
+	seen := make(map[string]bool)
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		for _, cert := range matchingCertificates(data, name) {
+			fingerprint := string(cert.Raw)
+			if seen[fingerprint] {
+				continue
+			}
+			seen[fingerprint] = true
+			bundle.Write(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}))
+		}
+	}
+
+	if bundle.Len() == 0 {
+		return "", fmt.Errorf("%s: no CA certificate named [%s] found in the system trust store", msgVcertClientError, name)
+	}
+	return bundle.String(), nil
+}
+
+// matchingCertificates returns the CA certificates of the PEM data whose subject common name, or full subject, is name.
+// Blocks that cannot be parsed are skipped.
+func matchingCertificates(data []byte, name string) []*x509.Certificate {
+	var matches []*x509.Certificate
+	rest := data
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return matches
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil || !cert.IsCA {
+			continue
+		}
+		if cert.Subject.CommonName == name || cert.Subject.String() == name {
+			matches = append(matches, cert)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package vcertclient
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/model"
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/tpptest"
+)
+
+// systemTrustStore makes the CA certificates of bundle, and the ones of files, the system trust store of the test
+func systemTrustStore(t *testing.T, bundle string, files map[string]string) {
+	t.Helper()
+
+	file := filepath.Join(t.TempDir(), "ca-certificates.crt")
+	if err := os.WriteFile(file, []byte(bundle), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	directory := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(directory, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("SSL_CERT_FILE", file)
+	t.Setenv("SSL_CERT_DIR", directory)
+}
+
+// bundleCertificates returns the certificates of a PEM bundle
+func bundleCertificates(t *testing.T, bundle string) []*x509.Certificate {
+	t.Helper()
+
+	var certs []*x509.Certificate
+	rest := []byte(bundle)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return certs
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			t.Fatalf("invalid certificate: %s", err)
+		}
+		certs = append(certs, cert)
+	}
+}
+
+func TestLoadSystemTrustBundle(t *testing.T) {
+	corporate := tpptest.NewCA(t, "Corporate Root CA")
+	other := tpptest.NewCA(t, "Other Root CA")
+	// A leaf is not a CA, even when named like one
+	leaf := tpptest.CertificatePEM(other.Issue(t, "Corporate Root CA", tpptest.CertificateOptions{}))
+	systemTrustStore(t, other.PEM+leaf+corporate.PEM, map[string]string{
+		// The same CA is usually both in the bundle and in the directory
+		"corporate.pem": corporate.PEM,
+		"readme.txt":    "not a certificate",
+	})
+
+	tests := []struct {
+		name     string
+		lookup   string
+		wantCA   *tpptest.CA
+		wantFail bool
+	}{
+		{"common name", "Corporate Root CA", corporate, false},
+		{"full subject", other.Certificate.Subject.String(), other, false},
+		{"not found", "Unknown Root CA", nil, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bundle, err := LoadSystemTrustBundle(test.lookup)
+			if test.wantFail {
+				if err == nil || !strings.Contains(err.Error(), "no CA certificate named [Unknown Root CA]") {
+					t.Errorf("error = %v, want the CA not found", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			certs := bundleCertificates(t, bundle)
+			if len(certs) != 1 || !certs[0].Equal(test.wantCA.Certificate) {
+				t.Errorf("bundle has %d certificates, want the CA once", len(certs))
+			}
+		})
+	}
+}
+
+func TestTrustBundleSystemName(t *testing.T) {
+	server := tpptest.NewServer(t)
+	systemTrustStore(t, server.CA.PEM, nil)
+	credential := model.CredentialResourceData{
+		URL:                   types.StringValue(server.URL),
+		TrustBundleSystemName: types.StringValue(server.CA.Certificate.Subject.CommonName),
+		Username:              types.StringValue(server.Username),
+		Password:              types.StringValue(server.Password),
+	}
+
+	if _, err := New(context.Background(), credential).RequestNewTokenPair(); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	credential.TrustBundleSystemName = types.StringValue("Unknown Root CA")
+	if _, err := New(context.Background(), credential).RequestNewTokenPair(); err == nil || !strings.Contains(err.Error(), "no CA certificate named") {
+		t.Errorf("error = %v, want the CA not found", err)
+	}
+}
@@ -0,0 +1,128 @@
+package vcertclient
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/http/httptrace"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// traceFileMode restricts the trace file to its owner, although it never holds secrets
+const traceFileMode = 0600
+
+// traceMutex serializes the writes of concurrent requests to the trace files
+var traceMutex sync.Mutex
+
+// requestTracer appends a line per request to a file: method, URL without query, status and timings of the connection
+// steps. Headers and bodies are never written, as they hold credentials and tokens.
+type requestTracer struct {
+	next     http.RoundTripper
+	location string
+}
+
+// requestTimings records when each step of a request completed. A dial may complete after the request, which got a
+// connection idled by another one: mu guards the steps recorded by the trace.
+type requestTimings struct {
+	mu          sync.Mutex
+	start       time.Time
+	dnsDone     time.Time
+	connectDone time.Time
+	tlsDone     time.Time
+	firstByte   time.Time
+	reusedConn  bool
+	tlsVersion  uint16
+	remoteAddr  string
+}
+
+func (t *requestTracer) RoundTrip(req *http.Request) (*http.Response, error) {
+	timings := &requestTimings{start: time.Now()}
+	trace := &httptrace.ClientTrace{
+		DNSDone: func(_ httptrace.DNSDoneInfo) {
+			timings.mu.Lock()
+			defer timings.mu.Unlock()
+			timings.dnsDone = time.Now()
+		},
+		ConnectDone: func(_, addr string, _ error) {
+			timings.mu.Lock()
+			defer timings.mu.Unlock()
+			timings.connectDone = time.Now()
+			timings.remoteAddr = addr
+		},
+		TLSHandshakeDone: func(state tls.ConnectionState, _ error) {
+			timings.mu.Lock()
+			defer timings.mu.Unlock()
+			timings.tlsDone = time.Now()
+			timings.tlsVersion = state.Version
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			timings.mu.Lock()
+			defer timings.mu.Unlock()
+			timings.reusedConn = info.Reused
+		},
+		GotFirstResponseByte: func() {
+			timings.mu.Lock()
+			defer timings.mu.Unlock()
+			timings.firstByte = time.Now()
+		},
+	}
+
+	resp, err := t.next.RoundTrip(req.WithContext(httptrace.WithClientTrace(req.Context(), trace)))
+	t.write(req, resp, err, timings)
+	return resp, err
+}
+
+func (t *requestTracer) write(req *http.Request, resp *http.Response, err error, timings *requestTimings) {
+	url := *req.URL
+	url.RawQuery = ""
+	url.User = nil
+
+	timings.mu.Lock()
+	fields := []string{
+		timings.start.UTC().Format(time.RFC3339Nano),
+		req.Method,
+		url.String(),
+	}
+	if resp != nil {
+		fields = append(fields, fmt.Sprintf("status=%d", resp.StatusCode))
+	}
+	if err != nil {
+		fields = append(fields, fmt.Sprintf("error=%q", err.Error()))
+	}
+	if timings.remoteAddr != "" {
+		fields = append(fields, "remote="+timings.remoteAddr)
+	}
+	fields = append(fields, fmt.Sprintf("reused_conn=%t", timings.reusedConn))
+	if timings.tlsVersion != 0 {
+		fields = append(fields, "tls="+tls.VersionName(timings.tlsVersion))
+	}
+	for _, step := range []struct {
+		name string
+		at   time.Time
+	}{
+		{"dns", timings.dnsDone},
+		{"connect", timings.connectDone},
+		{"tls_handshake", timings.tlsDone},
+		{"first_byte", timings.firstByte},
+	} {
+		if !step.at.IsZero() {
+			fields = append(fields, fmt.Sprintf("%s=%s", step.name, step.at.Sub(timings.start).Round(time.Microsecond)))
+		}
+	}
+	fields = append(fields, fmt.Sprintf("total=%s", time.Since(timings.start).Round(time.Microsecond)))
+	timings.mu.Unlock()
+
+	traceMutex.Lock()
+	defer traceMutex.Unlock()
+
+	// Tracing is a debugging aid, failing to write the trace must not fail the request
+	file, openErr := os.OpenFile(t.location, os.O_APPEND|os.O_CREATE|os.O_WRONLY, traceFileMode)
+	if openErr != nil {
+		return
+	}
+	defer file.Close()
+	_, _ = file.WriteString(strings.Join(fields, " ") + "\n")
+}
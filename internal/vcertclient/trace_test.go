@@ -0,0 +1,84 @@
+package vcertclient
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/model"
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/tpptest"
+)
+
+func TestTraceFile(t *testing.T) {
+	server := tpptest.NewServer(t)
+	location := filepath.Join(t.TempDir(), "trace.log")
+	client := New(context.Background(), model.CredentialResourceData{
+		URL:         types.StringValue(server.URL),
+		TrustBundle: types.StringValue(server.TrustBundle()),
+		Username:    types.StringValue(server.Username),
+		Password:    types.StringValue(server.Password),
+		TraceFile:   types.StringValue(location),
+	})
+
+	resp, err := client.RequestNewTokenPair()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	client.credData.AccessToken = types.StringValue(resp.AccessToken)
+	client.credData.RefreshToken = types.StringValue(resp.RefreshToken)
+	if err = client.RevokeToken(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	content, err := os.ReadFile(location)
+	if err != nil {
+		t.Fatalf("trace file not written: %s", err)
+	}
+	trace := string(content)
+	for _, want := range []string{
+		"POST " + server.URL + tpptest.PathAuthorizeOAuth + " status=200",
+		"GET " + server.URL + tpptest.PathRevokeToken + " status=200",
+		"tls=TLS 1.3",
+	} {
+		if !strings.Contains(trace, want) {
+			t.Errorf("trace = %q, want %q", trace, want)
+		}
+	}
+	for name, secret := range map[string]string{
+		"password":      server.Password,
+		"access token":  resp.AccessToken,
+		"refresh token": resp.RefreshToken,
+	} {
+		if strings.Contains(trace, secret) {
+			t.Errorf("%s found in the trace: %s", name, trace)
+		}
+	}
+	if info, err := os.Stat(location); err == nil && info.Mode().Perm() != traceFileMode {
+		t.Errorf("trace file mode = %s, want %s", info.Mode().Perm(), os.FileMode(traceFileMode))
+	}
+
+	t.Run("credentials in the url", func(t *testing.T) {
+		location := filepath.Join(t.TempDir(), "trace.log")
+		config, err := NewVCertConfig(ConnectionSettings{URL: server.URL, TrustBundle: server.TrustBundle(), TraceFile: location})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		url := strings.Replace(server.URL, "https://", "https://tppadmin:s3cr3t@", 1) + tpptest.PathSystemVersion + "?apikey=s3cr3t"
+		if resp, err := config.Client.Get(url); err == nil {
+			resp.Body.Close()
+		}
+
+		content, err := os.ReadFile(location)
+		if err != nil {
+			t.Fatalf("trace file not written: %s", err)
+		}
+		if trace := string(content); strings.Contains(trace, "s3cr3t") || !strings.Contains(trace, http.MethodGet+" "+server.URL+tpptest.PathSystemVersion+" ") {
+			t.Errorf("trace = %q, want the url without credentials nor query", trace)
+		}
+	})
+}
@@ -0,0 +1,385 @@
+package vcertclient
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	defaultTLSHandshakeTimeout = 10 * time.Second
+	defaultDialTimeout         = 30 * time.Second
+	defaultRequestTimeout      = 30 * time.Second
+
+	// sniffLength is the number of bytes read to detect the type of a response without content type
+	sniffLength = 512
+
+	// DefaultMaxResponseBytes is the largest response body read from TLSPDC when not configured
+	DefaultMaxResponseBytes = 1 << 20
+
+	// oauthBasePath is the base path of the TLSPDC OAuth endpoints used by vcert
+	oauthBasePath = "/vedauth"
+	// vedsdkPath is the base path of the TLSPDC WebSDK used by vcert
+	vedsdkPath = "/vedsdk"
+)
+
+// newHTTPClient builds the HTTP client used by the vcert connector. vcert only applies the trust bundle to the clients
+// it creates itself, so the trust anchors are set here as well.
+func newHTTPClient(settings ConnectionSettings) (*http.Client, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:    tls.VersionTLS12,
+		Renegotiation: tls.RenegotiateFreelyAsClient,
+	}
+
+	if settings.TrustBundle != "" {
+		pool := x509.NewCertPool()
+		if settings.AppendSystemRoots {
+			// The system roots are left out when they cannot be loaded, e.g. on systems without a bundle
+			if systemPool, err := x509.SystemCertPool(); err == nil {
+				pool = systemPool
+			}
+		}
+		pool, err := parseTrustBundle([]byte(settings.TrustBundle), pool)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid trust bundle: %w", msgVcertClientError, err)
+		}
+		tlsConfig.RootCAs = pool
+	} else if settings.ClientCertificatePool != nil {
+		tlsConfig.RootCAs = settings.ClientCertificatePool
+	}
+
+	if settings.ProxyTrustBundle != "" && proxyInUse(settings.URL) {
+		pool, err := withProxyTrustBundle(tlsConfig.RootCAs, settings.ProxyTrustBundle)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	tlsConfig.InsecureSkipVerify = settings.InsecureSkipVerify
+	if len(settings.ExpectedServerSANs) > 0 {
+		tlsConfig.VerifyPeerCertificate = verifyServerSANs(settings.ExpectedServerSANs)
+	}
+
+	if settings.ClientCertificate != nil {
+		tlsConfig.Certificates = []tls.Certificate{*settings.ClientCertificate}
+		tlsConfig.Certificates = append(tlsConfig.Certificates, settings.ClientCertificateCandidates...)
+	}
+
+	handshakeTimeout := settings.TLSHandshakeTimeout
+	if handshakeTimeout <= 0 {
+		handshakeTimeout = defaultTLSHandshakeTimeout
+	}
+
+	// Create own Transport to allow HTTP1.1 connections
+	transport := &http.Transport{
+		Proxy: proxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout: defaultDialTimeout,
+		}).DialContext,
+		// Only one request is made with a client, unless connections are pooled
+		DisableKeepAlives: true,
+		// This is to allow for http1.1 connections
+		ForceAttemptHTTP2:   false,
+		TLSHandshakeTimeout: handshakeTimeout,
+		TLSClientConfig:     tlsConfig,
+	}
+
+	if settings.MaxIdleConns > 0 {
+		transport.DisableKeepAlives = false
+		transport.MaxIdleConns = settings.MaxIdleConns
+		transport.MaxIdleConnsPerHost = settings.MaxIdleConns
+		transport.IdleConnTimeout = settings.IdleConnTimeout
+		if transport.IdleConnTimeout <= 0 {
+			transport.IdleConnTimeout = DefaultIdleConnTimeout
+		}
+		// A client is built for every operation, the connections are pooled by a transport shared between them
+		transport = pooledTransport(settings, transport)
+	}
+
+	ctx := settings.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	maxResponseBytes := settings.MaxResponseBytes
+	if maxResponseBytes <= 0 {
+		maxResponseBytes = DefaultMaxResponseBytes
+	}
+
+	var base http.RoundTripper = transport
+	if settings.MaxIdleConns > 0 {
+		base = &connectionReuser{next: transport}
+	}
+	if settings.TraceFile != "" {
+		base = &requestTracer{next: base, location: settings.TraceFile}
+	}
+
+	var roundTripper http.RoundTripper = &concurrencyLimiter{
+		next:    &responseInspector{next: &responseLimiter{next: base, maxBytes: maxResponseBytes}},
+		context: ctx,
+	}
+	if settings.ServerDateObserver != nil {
+		roundTripper = &dateRecorder{next: roundTripper, observer: settings.ServerDateObserver}
+	}
+	if settings.NoticeObserver != nil {
+		roundTripper = &noticeRecorder{next: roundTripper, observer: settings.NoticeObserver}
+	}
+	if settings.OAuthPath != "" {
+		roundTripper = &oauthPathRewriter{next: roundTripper, oauthPath: NormalizeOAuthPath(settings.OAuthPath)}
+	}
+	if settings.URLObserver != nil {
+		roundTripper = &urlRecorder{next: roundTripper, observer: settings.URLObserver}
+	}
+	if settings.ipLiteralHost != "" {
+		roundTripper = &ipLiteralRewriter{next: roundTripper, host: settings.ipLiteralHost}
+	}
+
+	return &http.Client{
+		Timeout:   defaultRequestTimeout,
+		Transport: roundTripper,
+	}, nil
+}
+
+// NormalizeOAuthPath returns path with a single leading slash and no trailing slash, e.g. "custom/auth/" becomes
+// "/custom/auth"
+func NormalizeOAuthPath(path string) string {
+	return "/" + strings.Trim(strings.TrimSpace(path), "/")
+}
+
+// ValidateOAuthPath checks that path can replace the base path of the OAuth endpoints
+func ValidateOAuthPath(path string) error {
+	normalized := NormalizeOAuthPath(path)
+	if normalized == "/" {
+		return fmt.Errorf("OAuth path must not be empty")
+	}
+	if strings.ContainsAny(normalized, "?#") || strings.Contains(normalized, "://") {
+		return fmt.Errorf("OAuth path [%s] must be a path only, without scheme, host, query or fragment", path)
+	}
+	return nil
+}
+
+// dateRecorder reports the Date header of the responses, giving the clock of TLSPDC
+type dateRecorder struct {
+	next     http.RoundTripper
+	observer func(date time.Time)
+}
+
+func (t *dateRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	if date, parseErr := http.ParseTime(resp.Header.Get("Date")); parseErr == nil {
+		t.observer(date)
+	}
+	return resp, nil
+}
+
+// urlRecorder reports the base URL of the requests answered by TLSPDC, once vcert normalized url and the IPv6 literal
+// was restored. vcert sends every request under the host root, to /vedsdk or /vedauth.
+type urlRecorder struct {
+	next     http.RoundTripper
+	observer func(url string)
+}
+
+func (t *urlRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	t.observer(fmt.Sprintf("%s://%s%s", req.URL.Scheme, req.URL.Host, vedsdkPath))
+	return resp, nil
+}
+
+// oauthPathRewriter sends the requests to the OAuth endpoints under oauthPath instead of /vedauth, for deployments
+// relocating them behind a proxy. Other requests are left untouched.
+type oauthPathRewriter struct {
+	next      http.RoundTripper
+	oauthPath string
+}
+
+func (t *oauthPathRewriter) RoundTrip(req *http.Request) (*http.Response, error) {
+	index := strings.Index(req.URL.Path, oauthBasePath+"/")
+	if index < 0 {
+		return t.next.RoundTrip(req)
+	}
+
+	rewritten := req.Clone(req.Context())
+	rewritten.URL.Path = req.URL.Path[:index] + t.oauthPath + req.URL.Path[index+len(oauthBasePath):]
+	rewritten.URL.RawPath = ""
+	return t.next.RoundTrip(rewritten)
+}
+
+// errResponseTooLarge is returned when a response body exceeds the configured limit
+var errResponseTooLarge = errors.New("response from TLSPDC exceeds max_response_bytes")
+
+// responseLimiter caps how much of a response body is read, guarding against huge responses from a misconfigured or
+// compromised endpoint
+type responseLimiter struct {
+	next     http.RoundTripper
+	maxBytes int64
+}
+
+func (t *responseLimiter) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	if resp.ContentLength > t.maxBytes {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%w: %d bytes announced, limit is %d", errResponseTooLarge, resp.ContentLength, t.maxBytes)
+	}
+	resp.Body = &limitedBody{ReadCloser: resp.Body, remaining: t.maxBytes, maxBytes: t.maxBytes}
+	return resp, nil
+}
+
+// limitedBody fails the read once more than maxBytes have been received
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+	maxBytes  int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining < 0 {
+		return 0, fmt.Errorf("%w: limit is %d bytes", errResponseTooLarge, b.maxBytes)
+	}
+	// Read one byte past the limit to tell a body of exactly maxBytes from a larger one
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.remaining -= int64(n)
+	if b.remaining < 0 {
+		return n + int(b.remaining), fmt.Errorf("%w: limit is %d bytes", errResponseTooLarge, b.maxBytes)
+	}
+	return n, err
+}
+
+// proxyFromEnvironment returns the proxy of a request as set by the environment variables. Replaced by the tests, since
+// net/http reads them only once and never proxies the requests to localhost.
+var proxyFromEnvironment = http.ProxyFromEnvironment
+
+// proxyInUse reports whether the requests to url are sent through a proxy, as set by the HTTPS_PROXY, HTTP_PROXY and
+// NO_PROXY environment variables
+func proxyInUse(url string) bool {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return false
+	}
+	proxyURL, err := proxyFromEnvironment(req)
+	return err == nil && proxyURL != nil
+}
+
+// withProxyTrustBundle returns a copy of roots, the system roots when nil, trusting the CA certificates of the PEM
+// proxyTrustBundle as well. They verify the proxy itself when it is reached over TLS, and the TLSPDC certificates it
+// re-signs when it intercepts TLS. Go applies the same TLS configuration to both connections.
+func withProxyTrustBundle(roots *x509.CertPool, proxyTrustBundle string) (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+	if roots != nil {
+		pool = roots.Clone()
+	} else if systemPool, err := x509.SystemCertPool(); err == nil {
+		pool = systemPool
+	}
+	pool, err := parseTrustBundle([]byte(proxyTrustBundle), pool)
+	if err != nil {
+		return nil, fmt.Errorf("%s: invalid proxy trust bundle: %w", msgVcertClientError, err)
+	}
+	return pool, nil
+}
+
+// errHTMLResponse is returned when TLSPDC answers with an HTML page instead of JSON, which happens when a proxy or an
+// SSO portal intercepts the API calls
+var errHTMLResponse = errors.New("received an HTML response, likely a proxy/SSO interception; check url and network path")
+
+// ErrMaintenanceMode is returned when TLSPDC answers that it is in maintenance mode. Retrying, or trying another
+// authentication method, does not help until the maintenance ends.
+var ErrMaintenanceMode = errors.New("TLSPDC is in maintenance mode; retry later")
+
+// responseInspector checks the responses before vcert parses them, to turn unexpected content into meaningful errors
+type responseInspector struct {
+	next http.RoundTripper
+}
+
+func (t *responseInspector) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	maintenance, err := isMaintenanceResponse(resp)
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	if maintenance {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%w (status: %s)", ErrMaintenanceMode, resp.Status)
+	}
+
+	html, err := isHTMLResponse(resp)
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	if html {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%w (status: %s)", errHTMLResponse, resp.Status)
+	}
+
+	return resp, nil
+}
+
+// isMaintenanceResponse reports whether resp is the answer of TLSPDC in maintenance mode: 503 Service Unavailable with
+// a body mentioning the maintenance, as an HTML page or JSON. Other 503 responses, e.g. of an overloaded load balancer,
+// are left to the usual error handling. The body remains readable from the start.
+func isMaintenanceResponse(resp *http.Response) (bool, error) {
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		return false, nil
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return false, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return bytes.Contains(bytes.ToLower(body), []byte("maintenance")), nil
+}
+
+// isHTMLResponse reports whether resp holds an HTML page, based on its content type or, when missing, on the beginning
+// of its body. The body remains readable from the start.
+func isHTMLResponse(resp *http.Response) (bool, error) {
+	contentType := strings.ToLower(resp.Header.Get("Content-Type"))
+	if strings.HasPrefix(contentType, "text/html") {
+		return true, nil
+	}
+	if contentType != "" {
+		return false, nil
+	}
+
+	reader := bufio.NewReader(resp.Body)
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{reader, resp.Body}
+	start, err := reader.Peek(sniffLength)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, bufio.ErrBufferFull) {
+		return false, err
+	}
+
+	start = bytes.ToLower(bytes.TrimSpace(start))
+	return bytes.HasPrefix(start, []byte("<!doctype html")) || bytes.HasPrefix(start, []byte("<html")), nil
+}
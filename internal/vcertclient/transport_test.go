@@ -0,0 +1,428 @@
+package vcertclient
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/model"
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/tpptest"
+)
+
+func TestTLSHandshakeTimeout(t *testing.T) {
+	// The server accepts the TCP connections but never answers the TLS handshake
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		var connections []net.Conn
+		defer func() {
+			for _, connection := range connections {
+				connection.Close()
+			}
+		}()
+		for {
+			connection, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			connections = append(connections, connection)
+		}
+	}()
+
+	url := "https://" + listener.Addr().String()
+	config, err := NewVCertConfig(ConnectionSettings{URL: url, TLSHandshakeTimeout: 200 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	start := time.Now()
+	resp, err := config.Client.Get(url)
+	if err == nil {
+		resp.Body.Close()
+		t.Fatal("request succeeded without a TLS handshake")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("request gave up after %s, want about the handshake timeout", elapsed)
+	}
+	if !isConnectionError(err) || !isUnsentRequestError(err) {
+		t.Errorf("error = %s, want a TLS handshake timeout", err)
+	}
+}
+
+func TestHTMLResponse(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+	}{
+		{"content type", "text/html; charset=utf-8", "<p>Sign in</p>"},
+		{"sniffed", "", "\n  <!DOCTYPE html><html><body>Sign in</body></html>"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := tpptest.NewServer(t)
+			server.Handle(tpptest.PathAuthorizeOAuth, func(w http.ResponseWriter, _ *http.Request) {
+				// A nil content type keeps net/http from detecting it
+				w.Header()["Content-Type"] = nil
+				if test.contentType != "" {
+					w.Header().Set("Content-Type", test.contentType)
+				}
+				w.Write([]byte(test.body))
+			})
+			client := New(context.Background(), model.CredentialResourceData{
+				URL:         types.StringValue(server.URL),
+				TrustBundle: types.StringValue(server.TrustBundle()),
+				Username:    types.StringValue(server.Username),
+				Password:    types.StringValue(server.Password),
+			})
+
+			_, err := client.RequestNewTokenPair()
+			if !errors.Is(err, errHTMLResponse) {
+				t.Errorf("error = %v, want an HTML response", err)
+			}
+		})
+	}
+
+	t.Run("JSON without content type", func(t *testing.T) {
+		server := tpptest.NewServer(t)
+		server.Handle(tpptest.PathSystemVersion, func(w http.ResponseWriter, _ *http.Request) {
+			w.Header()["Content-Type"] = nil
+			w.Write([]byte(`{"Version":"24.1"}`))
+		})
+		config, err := NewVCertConfig(ConnectionSettings{URL: server.URL, TrustBundle: server.TrustBundle()})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		resp, err := config.Client.Get(server.URL + tpptest.PathSystemVersion)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		defer resp.Body.Close()
+		if body, _ := io.ReadAll(resp.Body); string(body) != `{"Version":"24.1"}` {
+			t.Errorf("body = %q, want it untouched", body)
+		}
+	})
+}
+
+func TestOAuthPathOverride(t *testing.T) {
+	server := tpptest.NewServer(t)
+	server.OAuthBasePath = "/proxy/auth"
+	credential := model.CredentialResourceData{
+		URL:         types.StringValue(server.URL),
+		TrustBundle: types.StringValue(server.TrustBundle()),
+		Username:    types.StringValue(server.Username),
+		Password:    types.StringValue(server.Password),
+	}
+
+	t.Run("overridden", func(t *testing.T) {
+		data := credential
+		data.OAuthPathOverride = types.StringValue("proxy/auth/")
+		client := New(context.Background(), data)
+
+		resp, err := client.RequestNewTokenPair()
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if server.Requests("/proxy/auth/authorize/oauth") != 1 || server.Requests(tpptest.PathAuthorizeOAuth) != 0 {
+			t.Error("token pair not requested from the overridden path")
+		}
+		// The other endpoints are not relocated
+		if version, _ := client.ServerDetails(resp.AccessToken); version != server.Version {
+			t.Errorf("version = %q, want %s", version, server.Version)
+		}
+	})
+
+	t.Run("standard path", func(t *testing.T) {
+		if _, err := New(context.Background(), credential).RequestNewTokenPair(); err == nil {
+			t.Error("token pair retrieved from the relocated endpoint")
+		}
+	})
+}
+
+func TestValidateOAuthPath(t *testing.T) {
+	for _, path := range []string{"/proxy/auth", "proxy/auth/", " vedauth "} {
+		if err := ValidateOAuthPath(path); err != nil {
+			t.Errorf("%q rejected: %s", path, err)
+		}
+	}
+	for _, path := range []string{"", "/", "https://proxy/auth", "/auth?x=1", "/auth#x"} {
+		if err := ValidateOAuthPath(path); err == nil {
+			t.Errorf("%q accepted", path)
+		}
+	}
+}
+
+func TestMaxResponseBytes(t *testing.T) {
+	const limit = 1024
+	tests := []struct {
+		name string
+		// size is the size of the body, streamed when its length is not announced
+		size     int
+		announce bool
+		wantErr  bool
+	}{
+		{"within the limit", limit, false, false},
+		{"streamed past the limit", 64 * limit, false, true},
+		{"announced past the limit", 64 * limit, true, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := tpptest.NewServer(t)
+			server.Handle(tpptest.PathSystemVersion, func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				if test.announce {
+					w.Header().Set("Content-Length", strconv.Itoa(test.size))
+				}
+				chunk := []byte(strings.Repeat(" ", 256))
+				for written := 0; written < test.size; written += len(chunk) {
+					w.Write(chunk)
+					w.(http.Flusher).Flush()
+				}
+			})
+			config, err := NewVCertConfig(ConnectionSettings{URL: server.URL, TrustBundle: server.TrustBundle(), MaxResponseBytes: limit})
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			resp, err := config.Client.Get(server.URL + tpptest.PathSystemVersion)
+			var body []byte
+			if err == nil {
+				body, err = io.ReadAll(resp.Body)
+				resp.Body.Close()
+			}
+			if test.wantErr {
+				if !errors.Is(err, errResponseTooLarge) {
+					t.Errorf("error = %v, want the response too large", err)
+				}
+				return
+			}
+			if err != nil || len(body) != test.size {
+				t.Errorf("read %d bytes, error = %v, want the whole body", len(body), err)
+			}
+		})
+	}
+
+	t.Run("token request", func(t *testing.T) {
+		server := tpptest.NewServer(t)
+		server.Handle(tpptest.PathAuthorizeOAuth, func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"access_token":"` + strings.Repeat("a", 4*limit) + `"}`))
+		})
+		client := New(context.Background(), model.CredentialResourceData{
+			URL:              types.StringValue(server.URL),
+			TrustBundle:      types.StringValue(server.TrustBundle()),
+			Username:         types.StringValue(server.Username),
+			Password:         types.StringValue(server.Password),
+			MaxResponseBytes: types.Int64Value(limit),
+		})
+
+		if _, err := client.RequestNewTokenPair(); err == nil || !strings.Contains(err.Error(), "exceeds max_response_bytes") {
+			t.Errorf("error = %v, want the response too large", err)
+		}
+	})
+}
+
+// interceptingProxy routes the requests through a proxy intercepting TLS, which re-signs the certificate of server with
+// a CA of its own. It returns the CA of the proxy and the number of requests it forwarded to server.
+func interceptingProxy(t *testing.T, server *tpptest.Server) (*tpptest.CA, *atomic.Int32) {
+	t.Helper()
+
+	proxyCA := tpptest.NewCA(t, "Proxy CA")
+	cert := proxyCA.Issue(t, "intercepted", tpptest.CertificateOptions{Hosts: []string{"127.0.0.1"}})
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM([]byte(server.TrustBundle()))
+	upstream := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+
+	forwarded := &atomic.Int32{}
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+
+		intercepted := tls.Server(conn, &tls.Config{Certificates: []tls.Certificate{cert}})
+		reader := bufio.NewReader(intercepted)
+		for {
+			req, err := http.ReadRequest(reader)
+			if err != nil {
+				return
+			}
+			req.RequestURI = ""
+			req.URL.Scheme = "https"
+			req.URL.Host = r.Host
+			resp, err := upstream.Do(req)
+			if err != nil {
+				return
+			}
+			forwarded.Add(1)
+			resp.Write(intercepted)
+			resp.Body.Close()
+		}
+	}))
+	t.Cleanup(proxy.Close)
+
+	proxyURL, err := url.Parse(proxy.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := proxyFromEnvironment
+	proxyFromEnvironment = http.ProxyURL(proxyURL)
+	t.Cleanup(func() { proxyFromEnvironment = previous })
+	return proxyCA, forwarded
+}
+
+func TestProxyTrustBundle(t *testing.T) {
+	server := tpptest.NewServer(t)
+	proxyCA, forwarded := interceptingProxy(t, server)
+	credential := model.CredentialResourceData{
+		URL:         types.StringValue(server.URL),
+		TrustBundle: types.StringValue(server.TrustBundle()),
+		Username:    types.StringValue(server.Username),
+		Password:    types.StringValue(server.Password),
+	}
+
+	t.Run("proxy CA not trusted", func(t *testing.T) {
+		if _, err := New(context.Background(), credential).RequestNewTokenPair(); !errors.Is(err, ErrServerCertificateUntrusted) {
+			t.Errorf("error = %v, want the certificate of the proxy untrusted", err)
+		}
+	})
+
+	t.Run("proxy CA trusted", func(t *testing.T) {
+		credential.ProxyTrustBundle = types.StringValue(proxyCA.PEM)
+		resp, err := New(context.Background(), credential).RequestNewTokenPair()
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if _, ok := server.GrantOf(resp.AccessToken); !ok || forwarded.Load() == 0 {
+			t.Errorf("access_token = %s, want one retrieved through the proxy", resp.AccessToken)
+		}
+	})
+}
+
+func TestEffectiveURL(t *testing.T) {
+	server := tpptest.NewServer(t)
+	host := strings.TrimPrefix(server.URL, "https://")
+	want := server.URL + vedsdkPath
+
+	tests := []struct {
+		name        string
+		url         string
+		fallbackURL types.String
+		want        string
+	}{
+		{"as is", server.URL, types.StringNull(), want},
+		{"WebSDK path", server.URL + "/vedsdk/", types.StringNull(), want},
+		{"upper case scheme", "HTTPS://" + host, types.StringNull(), want},
+		{"no scheme", host, types.StringNull(), want},
+		{"fallback", tpptest.UnreachableURL(t), types.StringValue(host), want},
+		{"no response", tpptest.UnreachableURL(t), types.StringNull(), ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := New(context.Background(), model.CredentialResourceData{
+				URL:         types.StringValue(test.url),
+				FallbackURL: test.fallbackURL,
+				TrustBundle: types.StringValue(server.TrustBundle()),
+				Username:    types.StringValue(server.Username),
+				Password:    types.StringValue(server.Password),
+			})
+
+			_, err := client.RequestNewTokenPair()
+			if (err == nil) != (test.want != "") {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if client.EffectiveURL() != test.want {
+				t.Errorf("effective url = %q, want %q", client.EffectiveURL(), test.want)
+			}
+		})
+	}
+}
+
+func TestMaintenanceMode(t *testing.T) {
+	tests := []struct {
+		name            string
+		status          int
+		contentType     string
+		body            string
+		wantMaintenance bool
+	}{
+		{"HTML page", http.StatusServiceUnavailable, "text/html", "<html><body><h1>Under Maintenance</h1></body></html>", true},
+		{"JSON", http.StatusServiceUnavailable, "application/json", `{"error":"TPP is in maintenance mode"}`, true},
+		{"other 503", http.StatusServiceUnavailable, "text/plain", "no healthy upstream", false},
+		{"maintenance mentioned by another status", http.StatusBadRequest, "application/json", `{"error":"maintenance window ended"}`, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := tpptest.NewServer(t)
+			server.Handle(tpptest.PathAuthorizeOAuth, func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set("Content-Type", test.contentType)
+				w.WriteHeader(test.status)
+				w.Write([]byte(test.body))
+			})
+			client := New(context.Background(), model.CredentialResourceData{
+				URL:         types.StringValue(server.URL),
+				TrustBundle: types.StringValue(server.TrustBundle()),
+				Username:    types.StringValue(server.Username),
+				Password:    types.StringValue(server.Password),
+			})
+
+			_, err := client.RequestNewTokenPair()
+			if err == nil {
+				t.Fatal("token pair retrieved")
+			}
+			if errors.Is(err, ErrMaintenanceMode) != test.wantMaintenance {
+				t.Errorf("error = %v, want maintenance mode %t", err, test.wantMaintenance)
+			}
+		})
+	}
+
+	t.Run("neither retried nor falling back", func(t *testing.T) {
+		server := tpptest.NewServer(t)
+		paths := []string{tpptest.PathRefreshToken, tpptest.PathAuthorizeOAuth}
+		for _, path := range paths {
+			server.Handle(path, func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				w.Write([]byte("TLSPDC is down for maintenance"))
+			})
+		}
+		client := New(context.Background(), model.CredentialResourceData{
+			URL:              types.StringValue(server.URL),
+			TrustBundle:      types.StringValue(server.TrustBundle()),
+			RefreshToken:     types.StringValue("refresh"),
+			Username:         types.StringValue(server.Username),
+			Password:         types.StringValue(server.Password),
+			MaxTotalAttempts: types.Int64Value(5),
+		})
+
+		if _, err := client.RequestNewTokenPair(); !errors.Is(err, ErrMaintenanceMode) {
+			t.Fatalf("error = %v, want %v", err, ErrMaintenanceMode)
+		}
+		if server.Requests(tpptest.PathRefreshToken) != 1 || server.Requests(tpptest.PathAuthorizeOAuth) != 0 {
+			t.Errorf("%d refresh and %d username-password request(s), want a single refresh", server.Requests(tpptest.PathRefreshToken),
+				server.Requests(tpptest.PathAuthorizeOAuth))
+		}
+	})
+}
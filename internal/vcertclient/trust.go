@@ -0,0 +1,78 @@
+package vcertclient
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+)
+
+// LoadTrustBundle returns the PEM content of the trust bundle, which can be given as a file path, inline PEM data or
+// base64-encoded PEM data. Every certificate in the bundle is verified to be parsable.
+func LoadTrustBundle(value string) (string, error) {
+	kind, data, err := readCredentialInput("trust bundle", value)
+	if err != nil {
+		return "", err
+	}
+
+	if kind == inputBase64 && !bytes.Contains(data, []byte("-----BEGIN ")) {
+		return "", fmt.Errorf("%s: invalid base64 trust bundle: decoded content is not PEM-encoded", msgVcertClientError)
+	}
+
+	_, err = parseTrustBundle(data, x509.NewCertPool())
+	if err != nil {
+		return "", fmt.Errorf("%s: invalid trust bundle (%s): %w", msgVcertClientError, kind, err)
+	}
+
+	return string(data), nil
+}
+
+// parseTrustBundle adds the certificates of PEM data to pool. Unlike x509.CertPool.AppendCertsFromPEM, it fails when
+// any block cannot be parsed, so truncated bundles are detected.
+func parseTrustBundle(data []byte, pool *x509.CertPool) (*x509.CertPool, error) {
+	count := 0
+	rest := data
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse certificate #%d: %w", count+1, err)
+		}
+		pool.AddCert(cert)
+		count++
+	}
+
+	if len(bytes.TrimSpace(rest)) > 0 {
+		return nil, errors.New("unexpected data found after the last PEM block, the bundle may be truncated")
+	}
+	if count == 0 {
+		return nil, errors.New("no PEM-encoded certificates found")
+	}
+
+	return pool, nil
+}
+
+// TrustBundleCertificateCount returns the number of certificates of the PEM content returned by LoadTrustBundle or
+// LoadSystemTrustBundle
+func TrustBundleCertificateCount(bundle string) int {
+	count := 0
+	rest := []byte(bundle)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return count
+		}
+		if block.Type == "CERTIFICATE" {
+			count++
+		}
+	}
+}
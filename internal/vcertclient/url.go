@@ -0,0 +1,44 @@
+package vcertclient
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ValidateURL checks that rawURL can address TLSPDC: an optional http or https scheme, which vcert turns into https,
+// followed by a host
+func ValidateURL(rawURL string) error {
+	withScheme := rawURL
+	if !strings.Contains(withScheme, "://") {
+		withScheme = "https://" + withScheme
+	}
+	parsed, err := url.Parse(withScheme)
+	if err != nil {
+		return fmt.Errorf("invalid TLSPDC URL %q: %w", rawURL, err)
+	}
+	if scheme := strings.ToLower(parsed.Scheme); scheme != "https" && scheme != "http" {
+		return fmt.Errorf("invalid TLSPDC URL %q: unsupported scheme %s, expected https", rawURL, parsed.Scheme)
+	}
+	if parsed.Hostname() == "" {
+		return fmt.Errorf("invalid TLSPDC URL %q: no host, expected e.g. https://tpp.venafi.example/vedsdk", rawURL)
+	}
+	return nil
+}
+
+// NormalizedURL returns the base URL vcert sends the requests for rawURL to: lower-cased, with the https scheme, and
+// the WebSDK under the host root, e.g. https://tpp.venafi.example/vedsdk for TPP.venafi.example/vedsdk/
+func NormalizedURL(rawURL string) (string, error) {
+	if err := ValidateURL(rawURL); err != nil {
+		return "", err
+	}
+	withScheme := strings.ToLower(rawURL)
+	if !strings.Contains(withScheme, "://") {
+		withScheme = "https://" + withScheme
+	}
+	parsed, err := url.Parse(withScheme)
+	if err != nil {
+		return "", fmt.Errorf("invalid TLSPDC URL %q: %w", rawURL, err)
+	}
+	return "https://" + parsed.Host + vedsdkPath, nil
+}
@@ -0,0 +1,68 @@
+package vcertclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrServerCertificateUntrusted is returned when the certificate presented by TLSPDC cannot be verified against the
+// trust anchors: trust_bundle, or the system roots when no trust bundle is set
+var ErrServerCertificateUntrusted = errors.New("TLSPDC certificate could not be verified")
+
+// ErrClientCertificateExpired is returned when the client certificate of the PKCS#12 keystore is expired or not valid
+// yet, whether it is detected before connecting or reported by TLSPDC during the TLS handshake
+var ErrClientCertificateExpired = errors.New("client certificate is expired or not yet valid")
+
+// alertCertificateExpired is the message of the TLS alert sent by TLSPDC when it rejects an expired client certificate
+const alertCertificateExpired = "tls: expired certificate"
+
+// certificateVerificationMessages are the messages of the Go verification errors, for the errors vcert does not wrap
+var certificateVerificationMessages = []string{
+	"tls: failed to verify certificate",
+	"x509: certificate signed by unknown authority",
+	"x509: certificate is valid for",
+	"x509: certificate has expired or is not yet valid",
+	"x509: certificate is not valid for any names",
+}
+
+// isCertificateVerificationError reports whether err was caused by the verification of the TLSPDC certificate
+func isCertificateVerificationError(err error) bool {
+	if err == nil {
+		return false
+	}
+	var verificationErr *tls.CertificateVerificationError
+	var unknownAuthorityErr x509.UnknownAuthorityError
+	var invalidErr x509.CertificateInvalidError
+	var hostnameErr x509.HostnameError
+	if errors.As(err, &verificationErr) || errors.As(err, &unknownAuthorityErr) || errors.As(err, &invalidErr) || errors.As(err, &hostnameErr) {
+		return true
+	}
+	for _, message := range certificateVerificationMessages {
+		if strings.Contains(err.Error(), message) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkClientCertificateValidity returns an error naming the validity period of cert when now falls outside of it
+func checkClientCertificateValidity(cert *tls.Certificate, now time.Time) error {
+	leaf := cert.Leaf
+	switch {
+	case now.After(leaf.NotAfter):
+		return fmt.Errorf("%w: [%s] expired on %s", ErrClientCertificateExpired, leaf.Subject.String(), leaf.NotAfter.UTC().Format(time.RFC3339))
+	case now.Before(leaf.NotBefore):
+		return fmt.Errorf("%w: [%s] is not valid before %s", ErrClientCertificateExpired, leaf.Subject.String(), leaf.NotBefore.UTC().Format(time.RFC3339))
+	}
+	return nil
+}
+
+// isClientCertificateExpiredAlert reports whether err is TLSPDC aborting the TLS handshake because the client
+// certificate is expired, e.g. when the clocks of the provider and TLSPDC disagree
+func isClientCertificateExpiredAlert(err error) bool {
+	return err != nil && strings.Contains(err.Error(), alertCertificateExpired)
+}
@@ -0,0 +1,119 @@
+package vcertclient
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/model"
+	"github.com/terraform-providers/terraform-provider-venafi-token/internal/tpptest"
+)
+
+func TestIsCertificateVerificationError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"unknown authority", fmt.Errorf("Get: %w", x509.UnknownAuthorityError{}), true},
+		{"hostname", fmt.Errorf("Get: %w", x509.HostnameError{Certificate: &x509.Certificate{}, Host: "tpp.venafi.example"}), true},
+		{"message only", errors.New("vcert error: x509: certificate signed by unknown authority"), true},
+		{"connection refused", errors.New("dial tcp 127.0.0.1:443: connect: connection refused"), false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := isCertificateVerificationError(test.err); got != test.want {
+				t.Errorf("verification error = %t, want %t", got, test.want)
+			}
+		})
+	}
+}
+
+func TestCheckClientCertificateValidity(t *testing.T) {
+	ca := tpptest.NewCA(t, "Client CA")
+	notBefore := time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)
+	notAfter := time.Date(2027, time.January, 1, 0, 0, 0, 0, time.UTC)
+	cert := ca.Issue(t, "client", tpptest.CertificateOptions{NotBefore: notBefore, NotAfter: notAfter})
+
+	tests := []struct {
+		name string
+		now  time.Time
+		// want is part of the error, none is expected when empty
+		want string
+	}{
+		{"valid", notBefore.Add(24 * time.Hour), ""},
+		{"expired", notAfter.Add(time.Second), "[CN=client] expired on 2027-01-01T00:00:00Z"},
+		{"not yet valid", notBefore.Add(-time.Second), "[CN=client] is not valid before 2026-01-01T00:00:00Z"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := checkClientCertificateValidity(&cert, test.now)
+			if test.want == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrClientCertificateExpired) || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("error = %v, want %q", err, test.want)
+			}
+		})
+	}
+}
+
+func TestIsClientCertificateExpiredAlert(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"expired alert", errors.New("Post: remote error: tls: expired certificate"), true},
+		{"other alert", errors.New("Post: remote error: tls: bad certificate"), false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := isClientCertificateExpiredAlert(test.err); got != test.want {
+				t.Errorf("expired alert = %t, want %t", got, test.want)
+			}
+		})
+	}
+}
+
+func TestServerCertificateUntrusted(t *testing.T) {
+	server := tpptest.NewServer(t)
+	grant := server.IssueGrant(DefaultScope)
+	// The trust bundle does not hold the CA of the server certificate
+	data := model.CredentialResourceData{
+		URL:              types.StringValue(server.URL),
+		TrustBundle:      types.StringValue(tpptest.NewCA(t, "Other CA").PEM),
+		Username:         types.StringValue(server.Username),
+		Password:         types.StringValue(server.Password),
+		AccessToken:      types.StringValue(grant.AccessToken),
+		VerifyMaxRetries: types.Int64Value(0),
+	}
+
+	t.Run("rotation", func(t *testing.T) {
+		client := New(context.Background(), data)
+		if _, err := client.RequestNewTokenPair(); !errors.Is(err, ErrServerCertificateUntrusted) {
+			t.Errorf("error = %v, want %v", err, ErrServerCertificateUntrusted)
+		}
+		// The username/password is not tried again, it would fail the same way
+		if attempts := client.AuthAttempts(); len(attempts) != 1 {
+			t.Errorf("%d attempt(s), want 1: %+v", len(attempts), attempts)
+		}
+	})
+
+	t.Run("verification", func(t *testing.T) {
+		if _, err := New(context.Background(), data).VerifyToken(); !errors.Is(err, ErrServerCertificateUntrusted) {
+			t.Errorf("error = %v, want %v", err, ErrServerCertificateUntrusted)
+		}
+	})
+}
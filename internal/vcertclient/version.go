@@ -0,0 +1,52 @@
+package vcertclient
+
+import (
+	"fmt"
+	"runtime/debug"
+
+	"github.com/Venafi/vcert/v5/pkg/endpoint"
+	"github.com/Venafi/vcert/v5/pkg/venafi/tpp"
+)
+
+const vcertModulePath = "github.com/Venafi/vcert/v5"
+
+// SDKVersion returns the version of the vcert SDK the provider was built with, or an empty string when the build
+// information is not available
+func SDKVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+
+	for _, dep := range info.Deps {
+		if dep.Path != vcertModulePath {
+			continue
+		}
+		if dep.Replace != nil {
+			return dep.Replace.Version
+		}
+		return dep.Version
+	}
+	return ""
+}
+
+// ServerDetails returns the version of TLSPDC and the name of the identity accessToken belongs to, e.g. the username
+// it was granted to. Empty strings are returned when they cannot be retrieved.
+func (c *Client) ServerDetails(accessToken string) (version string, identity string) {
+	err := c.withFailover(func(connector *tpp.Connector) error {
+		// Authenticating with an access token retrieves the identity it belongs to
+		opErr := connector.Authenticate(&endpoint.Authentication{AccessToken: accessToken})
+		if opErr != nil {
+			return opErr
+		}
+		identity = connector.Identity.Name
+		version, opErr = connector.RetrieveSystemVersion()
+		return opErr
+	})
+	if err != nil {
+		c.logger.Warn(fmt.Sprintf("unable to retrieve TLSPDC version: %s", err.Error()))
+		return "", identity
+	}
+
+	return version, identity
+}
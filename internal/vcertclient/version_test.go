@@ -0,0 +1,12 @@
+package vcertclient
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSDKVersion(t *testing.T) {
+	if version := SDKVersion(); !strings.HasPrefix(version, "v5.") {
+		t.Errorf("SDK version = %q, want the v5 version of vcert", version)
+	}
+}
@@ -4,6 +4,9 @@ import (
 	"context"
 	"flag"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/hashicorp/terraform-plugin-framework/providerserver"
 	"github.com/terraform-providers/terraform-provider-venafi-token/internal/provider"
@@ -18,10 +21,21 @@ func main() {
 	flag.BoolVar(&debug, "debug", false, "set to true to run the provider with support for debuggers like delve")
 	flag.Parse()
 
+	// Terraform stops the provider gracefully, a SIGTERM is not: shut down before exiting all the same
+	terminated := make(chan os.Signal, 1)
+	signal.Notify(terminated, syscall.SIGTERM)
+	go func() {
+		<-terminated
+		provider.Shutdown()
+		os.Exit(1)
+	}()
+
 	err := providerserver.Serve(context.Background(), provider.New, providerserver.ServeOpts{
 		Address: "registry.terraform.io/Venafi/venafi-token",
 		Debug:   debug,
 	})
+	// Serve returns once Terraform is done with the provider
+	provider.Shutdown()
 	if err != nil {
 		log.Fatal(err.Error())
 	}